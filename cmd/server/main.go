@@ -7,7 +7,9 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 	"time"
 
 	awssdk "github.com/aws/aws-sdk-go-v2/aws"
@@ -20,13 +22,15 @@ import (
 	"snoozeql/internal/api/middleware"
 	"snoozeql/internal/config"
 	"snoozeql/internal/discovery"
+	"snoozeql/internal/leader"
 	"snoozeql/internal/metrics"
 	"snoozeql/internal/models"
+	"snoozeql/internal/notify"
 	"snoozeql/internal/provider"
-	awsprovider "snoozeql/internal/provider/aws"
-	gcpprovider "snoozeql/internal/provider/gcp"
+	"snoozeql/internal/provider/accounts"
 	"snoozeql/internal/scheduler"
 	"snoozeql/internal/store"
+	"snoozeql/internal/tracker"
 
 	"github.com/go-chi/chi/v5"
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
@@ -40,10 +44,17 @@ var (
 	eventStore          *store.EventStore
 	scheduleStore       *store.ScheduleStore
 	recommendationStore *store.RecommendationStore
+	overrideStore       *store.OverrideStore
+	calendarStore       *store.CalendarStore
+	idlePolicyStore     *store.IdlePolicyStore
+	actionStore         *store.ScheduledActionStore
 	metricsStore        *metrics.MetricsStore
 	metricsCollector    *metrics.MetricsCollector
 )
 
+// pendingActionsWindow is how far ahead the dashboard's pending actions are counted
+const pendingActionsWindow = 24 * time.Hour
+
 // BulkOperationRequest represents a request to start/stop multiple instances
 type BulkOperationRequest struct {
 	InstanceIDs []string `json:"instance_ids"`
@@ -107,74 +118,7 @@ func main() {
 		log.Printf("Warning: Failed to load cloud accounts: %v", err)
 	} else {
 		log.Printf("✓ Loaded %d cloud accounts from database", len(cloudAccounts))
-
-		for _, account := range cloudAccounts {
-			if account.Provider == "aws" {
-				var accessKey, secretKey string
-				if cred, ok := account.Credentials["aws_access_key_id"]; ok {
-					if str, ok := cred.(string); ok {
-						accessKey = str
-						log.Printf("DEBUG: Found access_key_id: %s", str[:10]+"...")
-					}
-				}
-				if cred, ok := account.Credentials["aws_secret_access_key"]; ok {
-					if str, ok := cred.(string); ok {
-						secretKey = str
-						log.Printf("DEBUG: Found secret_access_key: %s", str[:10]+"...")
-					}
-				}
-
-				if accessKey == "" || secretKey == "" {
-					log.Printf("Warning: Skipping AWS account %s - missing credentials", account.Name)
-					continue
-				}
-
-				regions := account.Regions
-				if len(regions) == 0 {
-					regions = []string{"us-east-1"}
-				}
-
-				for _, region := range regions {
-					awsProvider, err := awsprovider.NewRDSProvider(region, "", []string{}, accessKey, secretKey)
-					if err == nil {
-						providerKey := fmt.Sprintf("aws_%s_%s", account.ID, region)
-						providerRegistry.Register(providerKey, awsProvider)
-						log.Printf("✓ Registered AWS provider for account: %s (region: %s, key: %s)", account.Name, region, providerKey)
-					} else {
-						log.Printf("Warning: Failed to register AWS provider for %s in region %s: %v", account.Name, region, err)
-					}
-				}
-			} else if account.Provider == "gcp" {
-				var projectID, serviceAccountKey string
-				if cred, ok := account.Credentials["gcp_project_id"]; ok {
-					if str, ok := cred.(string); ok {
-						projectID = str
-					}
-				}
-				if cred, ok := account.Credentials["gcp_service_account_key"]; ok {
-					if str, ok := cred.(string); ok {
-						serviceAccountKey = str
-					}
-				}
-
-				if projectID == "" {
-					log.Printf("Warning: Skipping GCP account %s - missing project ID", account.Name)
-					continue
-				}
-
-				gcpProvider, err := gcpprovider.NewCloudSQLProvider(projectID, "", []string{}, serviceAccountKey)
-				if err != nil {
-					log.Printf("Warning: Failed to create GCP provider for %s: %v", account.Name, err)
-					continue
-				}
-
-				providerKey := fmt.Sprintf("gcp_%s", account.ID)
-				providerRegistry.Register(providerKey, gcpProvider)
-				log.Printf("✓ Registered GCP provider for account: %s (project: %s, key: %s)", account.Name, projectID, providerKey)
-			} else {
-				log.Printf("Skipping %s provider (not supported yet): %s", account.Provider, account.Name)
-			}
-		}
+		accounts.Register(providerRegistry, cloudAccounts)
 	}
 
 	if len(providerRegistry.Providers) == 0 {
@@ -187,6 +131,10 @@ func main() {
 	eventStore = store.NewEventStore(db)
 	scheduleStore = store.NewScheduleStore(db)
 	recommendationStore = store.NewRecommendationStore(db)
+	overrideStore = store.NewOverrideStore(db)
+	calendarStore = store.NewCalendarStore(db)
+	idlePolicyStore = store.NewIdlePolicyStore(db)
+	actionStore = store.NewScheduledActionStore(db)
 
 	// Initialize metrics store and collector first (before analyzer)
 	metricsStore = metrics.NewMetricsStore(db)
@@ -207,27 +155,84 @@ func main() {
 
 	discoveryService = discovery.NewDiscoveryService(providerRegistry, instanceStore, accountStore, eventStore, cfg.Discovery_enabled, cfg.Discovery_interval, []string{})
 
-	// Start discovery in background
-	ctx := context.Background()
-	go discoveryService.RunContinuous(ctx)
-
-	// Start metrics collection in background (real-time: immediate + every 15 minutes)
-	go metricsCollector.RunContinuous(ctx)
-	log.Printf("✓ Started metrics collector (15-minute interval, 5-minute granularity)")
+	// Cancelled on SIGINT/SIGTERM so background jobs stop and the leader lease is released
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Historical backfill: 7-minute delay + hourly (self-healing gap fill)
-	go metricsCollector.RunHistoricalBackfill(ctx)
-	log.Printf("✓ Started historical backfill (7-min delay, hourly interval, 3-day window)")
-
-	// Start metrics retention cleanup in background
+	// Background jobs that must only run on one replica at a time
 	retentionCleaner := metrics.NewRetentionCleaner(metricsStore, db)
-	go retentionCleaner.RunContinuous(ctx)
-	log.Printf("✓ Started metrics retention cleaner (7-day retention, 24h interval)")
+	schedulerService := scheduler.NewScheduler(scheduleStore, providerRegistry, instanceStore, eventStore, overrideStore, calendarStore, actionStore)
+	// Follow start/stop actions until they reach the target state
+	var actionTracker *tracker.Tracker
+	if cfg.Action_timeout > 0 {
+		actionTracker = tracker.NewTracker(providerRegistry, eventStore, time.Duration(cfg.Action_timeout)*time.Minute)
+		schedulerService.SetTracker(actionTracker)
+		discoveryService.SetTracker(actionTracker)
+		log.Printf("✓ Action tracking enabled (%d-minute timeout)", cfg.Action_timeout)
+	}
+	if cfg.Activity_guard_enabled && cfg.Activity_recheck_interval > 0 {
+		schedulerService.SetActivityGuard(metricsStore, scheduler.ActivityGuard{
+			MaxConnections:  float64(cfg.Activity_max_connections),
+			MaxCPUPercent:   float64(cfg.Activity_max_cpu_percent),
+			RecheckInterval: time.Duration(cfg.Activity_recheck_interval) * time.Minute,
+			MaxDelay:        time.Duration(cfg.Activity_max_delay) * time.Minute,
+		})
+		log.Printf("✓ Activity guard enabled (defer stops above %d connections or %d%% CPU, up to %d minutes)",
+			cfg.Activity_max_connections, cfg.Activity_max_cpu_percent, cfg.Activity_max_delay)
+	}
+	if cfg.Idle_policy_interval > 0 {
+		schedulerService.SetIdlePolicies(idlePolicyStore, metricsStore, time.Duration(cfg.Idle_policy_interval)*time.Minute)
+		log.Printf("✓ Idle policies enabled (%d-minute interval)", cfg.Idle_policy_interval)
+	}
+	if cfg.Maintenance_wake_lead > 0 {
+		schedulerService.SetMaintenanceWake(time.Duration(cfg.Maintenance_wake_lead) * time.Minute)
+		log.Printf("✓ Maintenance wakes enabled (%d minutes before windows with pending maintenance)", cfg.Maintenance_wake_lead)
+	}
+	if cfg.Reconcile_enabled && cfg.Reconcile_interval > 0 {
+		schedulerService.SetReconcileInterval(time.Duration(cfg.Reconcile_interval) * time.Minute)
+		log.Printf("✓ Reconciliation enabled (%d-minute interval)", cfg.Reconcile_interval)
+	}
+
+	// Pre-stop warnings with signed "extend 1 hour" links (config validation requires
+	// the secret when Slack is configured)
+	var linkSigner *notify.LinkSigner
+	if cfg.Action_signing_secret != "" {
+		linkSigner = notify.NewLinkSigner(cfg.Public_url, cfg.Action_signing_secret)
+	}
+	if cfg.Slack_webhook_url != "" && cfg.Pre_stop_minutes > 0 {
+		schedulerService.SetNotifier(notify.NewSlackNotifier(cfg.Slack_webhook_url, linkSigner), time.Duration(cfg.Pre_stop_minutes)*time.Minute)
+		log.Printf("✓ Pre-stop Slack warnings enabled (%d minutes ahead)", cfg.Pre_stop_minutes)
+	}
+	overrideHandler := handlers.NewOverrideHandler(overrideStore, instanceStore, eventStore, linkSigner)
+	leaderJobs := []func(ctx context.Context){
+		// Discovery writes instance state and auto-restart events
+		discoveryService.RunContinuous,
+		// Metrics collection (real-time: immediate + every 15 minutes)
+		metricsCollector.RunContinuous,
+		// Historical backfill: 7-minute delay + hourly (self-healing gap fill)
+		metricsCollector.RunHistoricalBackfill,
+		// Metrics retention cleanup
+		retentionCleaner.RunContinuous,
+		// Scheduler daemon
+		schedulerService.RunContinuous,
+	}
 
-	// Start scheduler daemon in background
-	schedulerService := scheduler.NewScheduler(scheduleStore, providerRegistry, instanceStore, eventStore)
-	go schedulerService.RunContinuous(ctx)
-	log.Printf("✓ Started scheduler daemon (1-minute interval)")
+	electorDone := make(chan struct{})
+	if cfg.Leader_election_enabled {
+		// Only the replica holding the lease runs the jobs; the others take over if it dies
+		elector := leader.NewElector(store.NewLeaseStore(db), "background_jobs", time.Duration(cfg.Leader_lease_seconds)*time.Second)
+		go func() {
+			elector.Run(ctx, leaderJobs...)
+			close(electorDone)
+		}()
+		log.Printf("✓ Started leader election (%ds lease) for discovery, scheduler, metrics collector and retention cleaner", cfg.Leader_lease_seconds)
+	} else {
+		for _, job := range leaderJobs {
+			go job(ctx)
+		}
+		close(electorDone)
+		log.Printf("✓ Started discovery, scheduler, metrics collector and retention cleaner (leader election disabled)")
+	}
 
 	// Initialize router
 	r := chi.NewRouter()
@@ -247,6 +252,8 @@ func main() {
 	r.Route("/api", func(r chi.Router) {
 		r.Use(middleware.APIKeyAuth(cfg))
 		r.Route("/v1", func(r chi.Router) {
+			scheduleHandler := handlers.NewScheduleHandler(scheduleStore, instanceStore, eventStore, calendarStore, overrideStore)
+
 			// Health/Status
 			r.Get("/", func(w http.ResponseWriter, r *http.Request) {
 				w.WriteHeader(http.StatusOK)
@@ -287,11 +294,10 @@ func main() {
 					}
 				}
 
-				// Get pending recommendations count
-				recommendations, err := instanceStore.ListRecommendationsByStatus(r.Context(), "pending")
+				// Starts and stops the schedules will run over the next day
+				pendingActions, err := scheduleHandler.CountUpcoming(r.Context(), instances, time.Now(), pendingActionsWindow)
 				if err != nil {
-					log.Printf("ERROR listing recommendations: %v", err)
-					recommendations = []map[string]interface{}{}
+					log.Printf("ERROR counting upcoming actions: %v", err)
 				}
 
 				stats := map[string]interface{}{
@@ -299,7 +305,7 @@ func main() {
 					"running_instances": runningCount,
 					"stopped_instances": stoppedCount,
 					"savings_7d":        savings7d,
-					"pending_actions":   len(recommendations),
+					"pending_actions":   pendingActions,
 				}
 
 				json.NewEncoder(w).Encode(stats)
@@ -614,8 +620,22 @@ func main() {
 				json.NewEncoder(w).Encode(BulkOperationResponse{Success: success, Failed: failed})
 			})
 
+			// Overrides (keep-alive, skip-next)
+			r.Get("/instances/{id}/overrides", func(w http.ResponseWriter, r *http.Request) {
+				id := chi.URLParam(r, "id")
+				overrideHandler.ListOverrides(w, r, id)
+			})
+			r.Post("/instances/{id}/overrides", func(w http.ResponseWriter, r *http.Request) {
+				id := chi.URLParam(r, "id")
+				overrideHandler.CreateOverride(w, r, id)
+			})
+			r.Delete("/instances/{id}/overrides/{overrideID}", func(w http.ResponseWriter, r *http.Request) {
+				id := chi.URLParam(r, "id")
+				overrideID := chi.URLParam(r, "overrideID")
+				overrideHandler.CancelOverride(w, r, id, overrideID)
+			})
+
 			// Schedules (using ScheduleHandler with real store)
-			scheduleHandler := handlers.NewScheduleHandler(scheduleStore, instanceStore, eventStore)
 			r.Get("/schedules", scheduleHandler.GetAllSchedules)
 			r.Get("/schedules/{id}", func(w http.ResponseWriter, r *http.Request) {
 				id := chi.URLParam(r, "id")
@@ -638,7 +658,68 @@ func main() {
 				id := chi.URLParam(r, "id")
 				scheduleHandler.DisableSchedule(w, r, id)
 			})
+			r.Get("/schedules/{id}/upcoming", func(w http.ResponseWriter, r *http.Request) {
+				id := chi.URLParam(r, "id")
+				scheduleHandler.UpcomingForSchedule(w, r, id)
+			})
+			r.Get("/instances/{id}/upcoming", func(w http.ResponseWriter, r *http.Request) {
+				id := chi.URLParam(r, "id")
+				scheduleHandler.UpcomingForInstance(w, r, id)
+			})
 			r.Post("/schedules/preview-filter", scheduleHandler.PreviewFilter)
+			r.Post("/schedules/simulate", scheduleHandler.SimulateSchedule)
+			r.Get("/schedules/conflicts", scheduleHandler.ListConflicts)
+
+			// One-off scheduled actions
+			scheduledActionHandler := handlers.NewScheduledActionHandler(actionStore, instanceStore, eventStore)
+			r.Get("/scheduled-actions", scheduledActionHandler.ListScheduledActions)
+			r.Post("/scheduled-actions", scheduledActionHandler.CreateScheduledAction)
+			r.Get("/scheduled-actions/{id}", func(w http.ResponseWriter, r *http.Request) {
+				id := chi.URLParam(r, "id")
+				scheduledActionHandler.GetScheduledAction(w, r, id)
+			})
+			r.Delete("/scheduled-actions/{id}", func(w http.ResponseWriter, r *http.Request) {
+				id := chi.URLParam(r, "id")
+				scheduledActionHandler.CancelScheduledAction(w, r, id)
+			})
+
+			// Exception calendars (holidays, shutdown weeks)
+			calendarHandler := handlers.NewCalendarHandler(calendarStore, scheduleStore)
+			r.Get("/calendars", calendarHandler.ListCalendars)
+			r.Post("/calendars", calendarHandler.CreateCalendar)
+			r.Get("/calendars/{id}", func(w http.ResponseWriter, r *http.Request) {
+				id := chi.URLParam(r, "id")
+				calendarHandler.GetCalendar(w, r, id)
+			})
+			r.Put("/calendars/{id}", func(w http.ResponseWriter, r *http.Request) {
+				id := chi.URLParam(r, "id")
+				calendarHandler.UpdateCalendar(w, r, id)
+			})
+			r.Delete("/calendars/{id}", func(w http.ResponseWriter, r *http.Request) {
+				id := chi.URLParam(r, "id")
+				calendarHandler.DeleteCalendar(w, r, id)
+			})
+			r.Post("/calendars/{id}/import", func(w http.ResponseWriter, r *http.Request) {
+				id := chi.URLParam(r, "id")
+				calendarHandler.ImportICS(w, r, id)
+			})
+
+			// Idle (auto-sleep) policies
+			idlePolicyHandler := handlers.NewIdlePolicyHandler(idlePolicyStore)
+			r.Get("/idle-policies", idlePolicyHandler.ListIdlePolicies)
+			r.Post("/idle-policies", idlePolicyHandler.CreateIdlePolicy)
+			r.Get("/idle-policies/{id}", func(w http.ResponseWriter, r *http.Request) {
+				id := chi.URLParam(r, "id")
+				idlePolicyHandler.GetIdlePolicy(w, r, id)
+			})
+			r.Put("/idle-policies/{id}", func(w http.ResponseWriter, r *http.Request) {
+				id := chi.URLParam(r, "id")
+				idlePolicyHandler.UpdateIdlePolicy(w, r, id)
+			})
+			r.Delete("/idle-policies/{id}", func(w http.ResponseWriter, r *http.Request) {
+				id := chi.URLParam(r, "id")
+				idlePolicyHandler.DeleteIdlePolicy(w, r, id)
+			})
 
 			// Recommendations
 			recommendationHandler := handlers.NewRecommendationHandler(
@@ -938,6 +1019,8 @@ func main() {
 						"name":              a.Name,
 						"provider":          a.Provider,
 						"regions":           a.Regions,
+						"managed_tags":      a.ManagedTags,
+						"discovery_filters": a.DiscoveryFilters,
 						"connection_status": a.ConnectionStatus,
 						"last_sync_at":      a.LastSyncAt,
 						"last_error":        a.LastError,
@@ -949,10 +1032,12 @@ func main() {
 
 			r.Post("/cloud-accounts", func(w http.ResponseWriter, r *http.Request) {
 				var input struct {
-					Name        string         `json:"name"`
-					Provider    string         `json:"provider"`
-					Regions     []string       `json:"regions"`
-					Credentials map[string]any `json:"credentials"`
+					Name             string                  `json:"name"`
+					Provider         string                  `json:"provider"`
+					Regions          []string                `json:"regions"`
+					Credentials      map[string]any          `json:"credentials"`
+					ManagedTags      []string                `json:"managed_tags"`
+					DiscoveryFilters []models.ProviderFilter `json:"discovery_filters"`
 				}
 				if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 					w.Header().Set("Content-Type", "application/json")
@@ -964,12 +1049,20 @@ func main() {
 				log.Printf("DEBUG: Creating account: name=%s, provider=%s, regions=%v", input.Name, input.Provider, input.Regions)
 
 				account := &models.CloudAccount{
-					ID:          "",
-					Name:        input.Name,
-					Provider:    input.Provider,
-					Credentials: input.Credentials,
-					Regions:     input.Regions,
-					CreatedAt:   time.Now(),
+					ID:               "",
+					Name:             input.Name,
+					Provider:         input.Provider,
+					Credentials:      input.Credentials,
+					Regions:          input.Regions,
+					ManagedTags:      input.ManagedTags,
+					DiscoveryFilters: input.DiscoveryFilters,
+					CreatedAt:        time.Now(),
+				}
+				if errMsg := accounts.ValidateDiscoveryConfig(*account); errMsg != "" {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusBadRequest)
+					json.NewEncoder(w).Encode(map[string]string{"error": errMsg})
+					return
 				}
 
 				if err := store.NewCloudAccountStore(db).CreateCloudAccount(account); err != nil {
@@ -1001,6 +1094,10 @@ func main() {
 		})
 	})
 
+	// Signed action links from notifications (authenticated by signature, not API key)
+	r.Get(notify.ExtendPath, overrideHandler.ConfirmExtend)
+	r.Post(notify.ExtendPath, overrideHandler.ExtendFromLink)
+
 	// Health check
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -1016,7 +1113,22 @@ func main() {
 	addr := fmt.Sprintf("%s:%s", cfg.Server_host, port)
 	log.Printf("starting server on %s", addr)
 
-	if err := http.ListenAndServe(addr, r); err != nil {
+	server := &http.Server{Addr: addr, Handler: r}
+	go func() {
+		<-ctx.Done()
+		log.Printf("shutting down server")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatalf("server failed: %v", err)
 	}
+
+	// Wait for the elector to release the lease so another replica can take over right away
+	<-electorDone
+	if actionTracker != nil {
+		actionTracker.Stop()
+	}
 }
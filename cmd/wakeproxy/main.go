@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"snoozeql/internal/config"
+	"snoozeql/internal/provider"
+	"snoozeql/internal/provider/accounts"
+	"snoozeql/internal/store"
+	"snoozeql/internal/wakeproxy"
+)
+
+func main() {
+	log.SetOutput(os.Stdout)
+	log.SetFlags(log.LstdFlags | log.Lmicroseconds | log.Lshortfile)
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	routes, err := wakeproxy.ParseRoutes(cfg.Wakeproxy_routes)
+	if err != nil {
+		log.Fatalf("invalid WAKEPROXY_ROUTES: %v", err)
+	}
+
+	db, err := store.NewPostgres(cfg.Database_url)
+	if err != nil {
+		log.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+	log.Printf("✓ Connected to database")
+
+	// Providers are needed to start instances and follow their status
+	providerRegistry := provider.NewRegistry()
+	cloudAccounts, err := store.NewCloudAccountStore(db).ListCloudAccounts()
+	if err != nil {
+		log.Fatalf("failed to load cloud accounts: %v", err)
+	}
+	accounts.Register(providerRegistry, cloudAccounts)
+
+	proxy := wakeproxy.NewProxy(
+		providerRegistry,
+		store.NewInstanceStore(db),
+		store.NewEventStore(db),
+		store.NewOverrideStore(db),
+		routes,
+		time.Duration(cfg.Wakeproxy_wake_timeout)*time.Minute,
+		time.Duration(cfg.Wakeproxy_keepalive)*time.Minute,
+	)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := proxy.Run(ctx); err != nil {
+		log.Fatalf("wake proxy failed: %v", err)
+	}
+	log.Printf("Wake proxy stopped")
+}
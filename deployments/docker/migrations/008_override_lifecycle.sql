@@ -0,0 +1,11 @@
+-- Override lifecycle support (keep-alive, skip-next)
+-- The scheduler looks up active overrides every minute, and only one active override
+-- of each type/action may exist per instance (a new one replaces the old one)
+
+CREATE INDEX IF NOT EXISTS idx_overrides_active ON overrides(instance_id) WHERE expired = FALSE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_overrides_unique_active
+    ON overrides(instance_id, type, COALESCE(skip_action, ''))
+    WHERE expired = FALSE;
+
+COMMENT ON INDEX idx_overrides_unique_active IS 'At most one active override per instance, type and skip action';
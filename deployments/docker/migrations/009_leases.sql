@@ -0,0 +1,14 @@
+-- Leader election leases
+-- Each background job group (scheduler, metrics) is run by whichever server replica
+-- holds its lease. A holder renews its lease well before expires_at; if it dies, another
+-- replica takes over once the lease has expired.
+
+CREATE TABLE IF NOT EXISTS leases (
+    name VARCHAR(100) PRIMARY KEY,
+    holder VARCHAR(255) NOT NULL,
+    acquired_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    renewed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+COMMENT ON TABLE leases IS 'Leader election leases for background jobs (one holder per lease)';
@@ -0,0 +1,21 @@
+-- Holiday / exception calendars
+-- A calendar is a named list of date ranges (entered directly or imported from .ics).
+-- Schedules reference calendars in their exceptions column to suppress wakes or force
+-- sleep on those dates. Dates are calendar dates in each schedule's timezone.
+
+CREATE TABLE IF NOT EXISTS exception_calendars (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT,
+    entries JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TRIGGER update_exception_calendars_updated_at BEFORE UPDATE ON exception_calendars
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS exceptions JSONB NOT NULL DEFAULT '[]';
+
+COMMENT ON TABLE exception_calendars IS 'Named holiday/shutdown calendars referenced by schedules';
+COMMENT ON COLUMN schedules.exceptions IS 'Calendar references: [{"calendar_id": "...", "mode": "suppress_wake"|"force_sleep"}]';
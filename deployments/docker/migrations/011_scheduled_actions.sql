@@ -0,0 +1,25 @@
+-- One-off scheduled actions ("stop this instance at 18:30 on Friday")
+-- Each action starts or stops either a single instance or every instance matching
+-- its selectors, once, at execute_at. Events created by an action carry its ID in
+-- their metadata (scheduled_action_id).
+
+CREATE TABLE IF NOT EXISTS scheduled_actions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    action VARCHAR(10) NOT NULL CHECK (action IN ('start', 'stop')),
+    execute_at TIMESTAMPTZ NOT NULL,
+    instance_id UUID REFERENCES instances(id) ON DELETE CASCADE,
+    selectors JSONB NOT NULL DEFAULT '[]',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'running', 'completed', 'failed', 'cancelled')),
+    reason TEXT,
+    created_by VARCHAR(255),
+    executed_at TIMESTAMPTZ,
+    instance_count INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_actions_pending ON scheduled_actions(execute_at) WHERE status = 'pending';
+
+COMMENT ON TABLE scheduled_actions IS 'One-shot start/stop actions at an absolute time';
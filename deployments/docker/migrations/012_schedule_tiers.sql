@@ -0,0 +1,8 @@
+-- Ordered wake/sleep tiers for schedules
+-- ordering holds {"tiers": [{"name": "...", "selectors": [...]}], "timeout_minutes": 15,
+-- "failure_policy": "abort"|"continue"}. Instances wake tier by tier (each tier must be
+-- available before the next starts) and sleep in reverse order. NULL means no ordering.
+
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS ordering JSONB;
+
+COMMENT ON COLUMN schedules.ordering IS 'Optional ordered wake/sleep tiers with timeout and failure policy';
@@ -0,0 +1,7 @@
+-- Schedule priority for instances matched by more than one schedule
+-- When matching schedules disagree on whether an instance should be awake, the one with
+-- the highest priority wins (ties go to the older schedule).
+
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS priority INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN schedules.priority IS 'Higher priority wins when schedules matching the same instance disagree';
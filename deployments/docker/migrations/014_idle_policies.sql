@@ -0,0 +1,22 @@
+-- Idle policies (auto-sleep after inactivity, independent of schedules)
+-- Running instances matching a policy's selectors are stopped once their 5-minute
+-- metrics (metrics_5min) have stayed at or below max_connections and max_cpu_percent
+-- for idle_minutes. Stops are recorded with triggered_by = 'idle_policy'.
+
+CREATE TABLE IF NOT EXISTS idle_policies (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT,
+    selectors JSONB NOT NULL DEFAULT '[]',
+    max_connections DOUBLE PRECISION NOT NULL DEFAULT 0,
+    max_cpu_percent DOUBLE PRECISION NOT NULL DEFAULT 3,
+    idle_minutes INTEGER NOT NULL DEFAULT 45 CHECK (idle_minutes >= 10),
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TRIGGER update_idle_policies_updated_at BEFORE UPDATE ON idle_policies
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE idle_policies IS 'Stop matching instances after a period of inactivity';
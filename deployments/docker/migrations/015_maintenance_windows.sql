@@ -0,0 +1,12 @@
+-- Provider backup/maintenance windows on instances
+-- Windows are in UTC, in RDS format: backup "hh24:mi-hh24:mi", maintenance "ddd:hh24:mi-ddd:hh24:mi".
+-- The scheduler delays stops that collide with them, and can wake instances before a
+-- maintenance window with pending maintenance actions so the patches apply.
+
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS backup_window VARCHAR(32);
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS maintenance_window VARCHAR(32);
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS pending_maintenance JSONB NOT NULL DEFAULT '[]';
+
+COMMENT ON COLUMN instances.backup_window IS 'Daily backup window (UTC, hh24:mi-hh24:mi)';
+COMMENT ON COLUMN instances.maintenance_window IS 'Weekly maintenance window (UTC, ddd:hh24:mi-ddd:hh24:mi)';
+COMMENT ON COLUMN instances.pending_maintenance IS 'Maintenance actions the provider has queued for the instance';
@@ -0,0 +1,10 @@
+-- Persist how a schedule's selectors combine, and selectors that exclude instances
+-- Existing schedules keep matching any of their selectors ("or"), as the scheduler
+-- always did.
+
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS selector_operator VARCHAR(3) NOT NULL DEFAULT 'or'
+    CHECK (selector_operator IN ('and', 'or'));
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS exclusions JSONB NOT NULL DEFAULT '[]';
+
+COMMENT ON COLUMN schedules.selector_operator IS 'How selectors combine: "or" (any matches) or "and" (all match)';
+COMMENT ON COLUMN schedules.exclusions IS 'Selectors for instances the schedule never applies to';
@@ -0,0 +1,8 @@
+-- Selector expressions: a text alternative to a schedule's selectors, e.g.
+-- tag.env in ("dev", "qa") && engine == "postgres" && !name ~ "^keep-"
+-- When set, the expression decides which instances the schedule applies to (exclusions
+-- still apply) and selectors/selector_operator are ignored.
+
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS selector_expression TEXT;
+
+COMMENT ON COLUMN schedules.selector_expression IS 'Selector expression, used instead of selectors when set';
@@ -0,0 +1,9 @@
+-- Awake-hours budgets for schedules
+-- budget holds {"daily_hours": 10, "weekly_hours": 50}. Matching instances are stopped
+-- once they have been awake that long in the current day/week (in the schedule's
+-- timezone), counted from wake/sleep events. Stops are recorded with
+-- triggered_by = 'budget'.
+
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS budget JSONB;
+
+COMMENT ON COLUMN schedules.budget IS 'Awake-hours budget (daily_hours, weekly_hours), NULL for none';
@@ -0,0 +1,9 @@
+-- Cluster membership of instances
+-- Aurora clusters are discovered as instances of their own, with provider_id
+-- 'cluster:{identifier}', and are started/stopped with StartDBCluster/StopDBCluster.
+-- Their member instances can't be stopped individually: cluster_id holds the provider_id
+-- of the cluster entry, and members are left out of selectors and instance-level actions.
+
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS cluster_id VARCHAR(255);
+
+COMMENT ON COLUMN instances.cluster_id IS 'provider_id of the cluster this instance is a member of, NULL for standalone instances';
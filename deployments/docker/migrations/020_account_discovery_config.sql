@@ -0,0 +1,11 @@
+-- Per-account discovery configuration
+-- managed_tags lists tag keys that opt instances in: only instances carrying one of them
+-- are marked managed (an empty list manages every instance, as before).
+-- discovery_filters holds server-side filters for the provider's list call, e.g.
+-- [{"name": "engine", "values": ["postgres", "mysql"]}] for RDS DescribeDBInstances.
+
+ALTER TABLE cloud_accounts ADD COLUMN IF NOT EXISTS managed_tags JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE cloud_accounts ADD COLUMN IF NOT EXISTS discovery_filters JSONB NOT NULL DEFAULT '[]';
+
+COMMENT ON COLUMN cloud_accounts.managed_tags IS 'Tag keys that opt instances in to being managed, empty for all';
+COMMENT ON COLUMN cloud_accounts.discovery_filters IS 'Server-side filters (name, values) applied when listing instances';
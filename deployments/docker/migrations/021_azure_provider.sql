@@ -0,0 +1,10 @@
+-- Azure Database for PostgreSQL/MySQL Flexible Server accounts
+-- Azure accounts authenticate as a service principal: credentials hold
+-- azure_subscription_id, azure_tenant_id, azure_client_id and azure_client_secret.
+-- Their instances have provider 'azure' and the server's ARM resource ID as provider_id.
+
+ALTER TABLE cloud_accounts DROP CONSTRAINT IF EXISTS cloud_accounts_provider_check;
+ALTER TABLE cloud_accounts ADD CONSTRAINT cloud_accounts_provider_check CHECK (provider IN ('aws', 'gcp', 'azure'));
+
+ALTER TABLE instances DROP CONSTRAINT IF EXISTS instances_provider_check;
+ALTER TABLE instances ADD CONSTRAINT instances_provider_check CHECK (provider IN ('aws', 'gcp', 'azure'));
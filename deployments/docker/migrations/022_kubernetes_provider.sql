@@ -0,0 +1,11 @@
+-- Kubernetes accounts for databases running as StatefulSets or CloudNativePG clusters
+-- Credentials hold a JSON kubeconfig (empty for in-cluster), an optional context and an
+-- optional label_selector (default snoozeql.io/discover=true); regions list the
+-- namespaces to discover in (empty for all). Instances have provider 'kubernetes' and
+-- provider_id '{statefulset|cnpg}/{namespace}/{name}'.
+
+ALTER TABLE cloud_accounts DROP CONSTRAINT IF EXISTS cloud_accounts_provider_check;
+ALTER TABLE cloud_accounts ADD CONSTRAINT cloud_accounts_provider_check CHECK (provider IN ('aws', 'gcp', 'azure', 'kubernetes'));
+
+ALTER TABLE instances DROP CONSTRAINT IF EXISTS instances_provider_check;
+ALTER TABLE instances ADD CONSTRAINT instances_provider_check CHECK (provider IN ('aws', 'gcp', 'azure', 'kubernetes'));
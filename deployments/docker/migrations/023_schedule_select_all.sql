@@ -0,0 +1,8 @@
+-- Schedules without selectors used to apply to every instance. Selector matching now
+-- treats an empty list as selecting nothing, so give such schedules an empty selector,
+-- which matches every instance, to keep what they applied to.
+
+UPDATE schedules
+SET selectors = '[{}]'::jsonb, updated_at = NOW()
+WHERE (selectors IS NULL OR selectors = '[]'::jsonb OR selectors = 'null'::jsonb)
+  AND selector_expression IS NULL;
@@ -0,0 +1,13 @@
+-- Claim time of scheduled actions
+-- The scheduler sets an action to 'running' when it claims it. If the leader dies before
+-- recording the outcome the action would stay 'running' forever, so claimed_at records
+-- when it was claimed and actions still running long after that are claimed again.
+
+ALTER TABLE scheduled_actions ADD COLUMN IF NOT EXISTS claimed_at TIMESTAMPTZ;
+
+-- Actions left running before this column existed are claimed again on the next run
+UPDATE scheduled_actions SET claimed_at = created_at WHERE status = 'running' AND claimed_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_actions_running ON scheduled_actions(claimed_at) WHERE status = 'running';
+
+COMMENT ON COLUMN scheduled_actions.claimed_at IS 'When the scheduler last claimed the action, NULL while pending';
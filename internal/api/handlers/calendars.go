@@ -0,0 +1,233 @@
+// API handlers for exception (holiday/shutdown) calendars
+
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"snoozeql/internal/calendar"
+	"snoozeql/internal/models"
+	"snoozeql/internal/store"
+)
+
+// maxICSSize limits the size of imported .ics files
+const maxICSSize = 5 << 20
+
+// CalendarHandler handles exception calendar HTTP requests
+type CalendarHandler struct {
+	calendarStore *store.CalendarStore
+	scheduleStore *store.ScheduleStore
+}
+
+// NewCalendarHandler creates a new calendar handler
+func NewCalendarHandler(calendarStore *store.CalendarStore, scheduleStore *store.ScheduleStore) *CalendarHandler {
+	return &CalendarHandler{
+		calendarStore: calendarStore,
+		scheduleStore: scheduleStore,
+	}
+}
+
+// CalendarRequest is the request body for creating or updating a calendar
+type CalendarRequest struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Entries     []models.CalendarEntry `json:"entries"`
+}
+
+// ListCalendars returns all exception calendars
+// GET /api/v1/calendars
+func (h *CalendarHandler) ListCalendars(w http.ResponseWriter, r *http.Request) {
+	calendars, err := h.calendarStore.ListCalendars(r.Context())
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to list calendars"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(calendars)
+}
+
+// GetCalendar returns a single calendar by ID
+// GET /api/v1/calendars/{id}
+func (h *CalendarHandler) GetCalendar(w http.ResponseWriter, r *http.Request, id string) {
+	cal, err := h.calendarStore.GetCalendar(r.Context(), id)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Calendar not found"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(cal)
+}
+
+// CreateCalendar creates a calendar from a name and date ranges
+// POST /api/v1/calendars
+func (h *CalendarHandler) CreateCalendar(w http.ResponseWriter, r *http.Request) {
+	var req CalendarRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	cal, errMsg := buildCalendar(req)
+	if errMsg != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": errMsg})
+		return
+	}
+
+	if err := h.calendarStore.CreateCalendar(r.Context(), cal); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to create calendar (name must be unique)"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(cal)
+}
+
+// UpdateCalendar replaces a calendar's name, description and entries
+// PUT /api/v1/calendars/{id}
+func (h *CalendarHandler) UpdateCalendar(w http.ResponseWriter, r *http.Request, id string) {
+	var req CalendarRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	cal, errMsg := buildCalendar(req)
+	if errMsg != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": errMsg})
+		return
+	}
+	cal.ID = id
+
+	if err := h.calendarStore.UpdateCalendar(r.Context(), cal); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Calendar not found"})
+			return
+		}
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to update calendar (name must be unique)"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(cal)
+}
+
+// DeleteCalendar deletes a calendar that no schedule references
+// DELETE /api/v1/calendars/{id}
+func (h *CalendarHandler) DeleteCalendar(w http.ResponseWriter, r *http.Request, id string) {
+	schedules, err := h.scheduleStore.ListSchedules()
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to delete calendar"})
+		return
+	}
+	for _, schedule := range schedules {
+		for _, exception := range schedule.Exceptions {
+			if exception.CalendarID == id {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusConflict)
+				json.NewEncoder(w).Encode(map[string]string{"error": "Calendar is used by schedule '" + schedule.Name + "'"})
+				return
+			}
+		}
+	}
+
+	if err := h.calendarStore.DeleteCalendar(r.Context(), id); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Calendar not found"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ImportICS imports the events of an iCalendar (.ics) file into a calendar.
+// Entries are merged with the existing ones unless ?replace=true.
+// POST /api/v1/calendars/{id}/import (body: .ics file)
+func (h *CalendarHandler) ImportICS(w http.ResponseWriter, r *http.Request, id string) {
+	ctx := r.Context()
+	cal, err := h.calendarStore.GetCalendar(ctx, id)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Calendar not found"})
+		return
+	}
+
+	entries, warnings, err := calendar.ParseICS(io.LimitReader(r.Body, maxICSSize))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid iCalendar file: " + err.Error()})
+		return
+	}
+
+	if strings.EqualFold(r.URL.Query().Get("replace"), "true") {
+		cal.Entries = entries
+	} else {
+		cal.Entries = calendar.MergeEntries(cal.Entries, entries)
+	}
+
+	if err := h.calendarStore.UpdateCalendar(ctx, cal); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to save calendar"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"calendar": cal,
+		"imported": len(entries),
+		"warnings": warnings,
+	})
+}
+
+// buildCalendar validates a create/update request and converts it into a calendar
+// Returns an error message if the request is invalid
+func buildCalendar(req CalendarRequest) (*models.ExceptionCalendar, string) {
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, "name is required"
+	}
+
+	entries, err := calendar.NormalizeEntries(req.Entries)
+	if err != nil {
+		return nil, err.Error()
+	}
+
+	return &models.ExceptionCalendar{
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		Entries:     entries,
+	}, ""
+}
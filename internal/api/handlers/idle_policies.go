@@ -0,0 +1,206 @@
+// API handlers for idle (auto-sleep) policies
+
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"snoozeql/internal/models"
+	"snoozeql/internal/selector"
+	"snoozeql/internal/store"
+)
+
+// Idle policy defaults (zero connections and CPU under 3% for 45 minutes) and limits
+const (
+	defaultIdleMinutes        = 45
+	defaultIdleMaxConnections = 0.0
+	defaultIdleMaxCPUPercent  = 3.0
+	minIdleMinutes            = 10
+	maxIdleMinutes            = 7 * 24 * 60
+)
+
+// IdlePolicyHandler handles idle policy HTTP requests
+type IdlePolicyHandler struct {
+	idlePolicyStore *store.IdlePolicyStore
+}
+
+// NewIdlePolicyHandler creates a new idle policy handler
+func NewIdlePolicyHandler(idlePolicyStore *store.IdlePolicyStore) *IdlePolicyHandler {
+	return &IdlePolicyHandler{idlePolicyStore: idlePolicyStore}
+}
+
+// IdlePolicyRequest is the request body for creating or updating an idle policy
+// Omitted thresholds fall back to the defaults
+type IdlePolicyRequest struct {
+	Name           string            `json:"name"`
+	Description    string            `json:"description"`
+	Selectors      []models.Selector `json:"selectors"`
+	MaxConnections *float64          `json:"max_connections"`
+	MaxCPUPercent  *float64          `json:"max_cpu_percent"`
+	IdleMinutes    *int              `json:"idle_minutes"`
+	Enabled        *bool             `json:"enabled"`
+}
+
+// ListIdlePolicies returns all idle policies
+// GET /api/v1/idle-policies
+func (h *IdlePolicyHandler) ListIdlePolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := h.idlePolicyStore.ListIdlePolicies(r.Context())
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to list idle policies"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(policies)
+}
+
+// GetIdlePolicy returns a single idle policy by ID
+// GET /api/v1/idle-policies/{id}
+func (h *IdlePolicyHandler) GetIdlePolicy(w http.ResponseWriter, r *http.Request, id string) {
+	policy, err := h.idlePolicyStore.GetIdlePolicy(r.Context(), id)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Idle policy not found"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(policy)
+}
+
+// CreateIdlePolicy creates an idle policy
+// POST /api/v1/idle-policies
+func (h *IdlePolicyHandler) CreateIdlePolicy(w http.ResponseWriter, r *http.Request) {
+	var req IdlePolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	policy, errMsg := buildIdlePolicy(req)
+	if errMsg != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": errMsg})
+		return
+	}
+
+	if err := h.idlePolicyStore.CreateIdlePolicy(r.Context(), policy); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to create idle policy (name must be unique)"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(policy)
+}
+
+// UpdateIdlePolicy replaces an idle policy's settings
+// PUT /api/v1/idle-policies/{id}
+func (h *IdlePolicyHandler) UpdateIdlePolicy(w http.ResponseWriter, r *http.Request, id string) {
+	var req IdlePolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	policy, errMsg := buildIdlePolicy(req)
+	if errMsg != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": errMsg})
+		return
+	}
+	policy.ID = id
+
+	if err := h.idlePolicyStore.UpdateIdlePolicy(r.Context(), policy); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Idle policy not found"})
+			return
+		}
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to update idle policy (name must be unique)"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(policy)
+}
+
+// DeleteIdlePolicy deletes an idle policy
+// DELETE /api/v1/idle-policies/{id}
+func (h *IdlePolicyHandler) DeleteIdlePolicy(w http.ResponseWriter, r *http.Request, id string) {
+	if err := h.idlePolicyStore.DeleteIdlePolicy(r.Context(), id); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Idle policy not found"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// buildIdlePolicy validates a create/update request and converts it into an idle policy
+// Returns an error message if the request is invalid
+func buildIdlePolicy(req IdlePolicyRequest) (*models.IdlePolicy, string) {
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, "name is required"
+	}
+	// An idle policy without selectors would put every instance to sleep
+	if len(req.Selectors) == 0 {
+		return nil, "at least one selector is required"
+	}
+	if errMsg := selector.Validate(req.Selectors); errMsg != "" {
+		return nil, errMsg
+	}
+
+	policy := &models.IdlePolicy{
+		Name:           strings.TrimSpace(req.Name),
+		Description:    req.Description,
+		Selectors:      req.Selectors,
+		MaxConnections: defaultIdleMaxConnections,
+		MaxCPUPercent:  defaultIdleMaxCPUPercent,
+		IdleMinutes:    defaultIdleMinutes,
+		Enabled:        true,
+	}
+	if req.MaxConnections != nil {
+		if *req.MaxConnections < 0 {
+			return nil, "max_connections must not be negative"
+		}
+		policy.MaxConnections = *req.MaxConnections
+	}
+	if req.MaxCPUPercent != nil {
+		if *req.MaxCPUPercent < 0 || *req.MaxCPUPercent > 100 {
+			return nil, "max_cpu_percent must be between 0 and 100"
+		}
+		policy.MaxCPUPercent = *req.MaxCPUPercent
+	}
+	if req.IdleMinutes != nil {
+		if *req.IdleMinutes < minIdleMinutes || *req.IdleMinutes > maxIdleMinutes {
+			return nil, "idle_minutes must be between 10 and 10080"
+		}
+		policy.IdleMinutes = *req.IdleMinutes
+	}
+	if req.Enabled != nil {
+		policy.Enabled = *req.Enabled
+	}
+	return policy, ""
+}
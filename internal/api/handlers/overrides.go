@@ -0,0 +1,319 @@
+// API handlers for instance overrides (keep-alive, skip-next)
+
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+
+	"snoozeql/internal/models"
+	"snoozeql/internal/notify"
+	"snoozeql/internal/store"
+)
+
+// OverrideHandler handles override-related HTTP requests
+type OverrideHandler struct {
+	overrideStore *store.OverrideStore
+	instanceStore *store.InstanceStore
+	eventStore    *store.EventStore
+	links         *notify.LinkSigner
+}
+
+// NewOverrideHandler creates a new override handler
+// links verifies signed extend links from notifications (may be nil to disable them)
+func NewOverrideHandler(overrideStore *store.OverrideStore, instanceStore *store.InstanceStore, eventStore *store.EventStore, links *notify.LinkSigner) *OverrideHandler {
+	return &OverrideHandler{
+		overrideStore: overrideStore,
+		instanceStore: instanceStore,
+		eventStore:    eventStore,
+		links:         links,
+	}
+}
+
+// CreateOverrideRequest is the request body for creating an override
+type CreateOverrideRequest struct {
+	Type            string     `json:"type"`                       // "keep_alive" or "skip_next"
+	SkipAction      *string    `json:"skip_action,omitempty"`      // "start" or "stop" (skip_next only)
+	UntilTime       *time.Time `json:"until_time,omitempty"`       // Absolute expiry
+	DurationMinutes int        `json:"duration_minutes,omitempty"` // Relative expiry, used when until_time is not set
+	Reason          *string    `json:"reason,omitempty"`
+	CreatedBy       string     `json:"created_by,omitempty"`
+}
+
+// ListOverrides returns the overrides for an instance
+// GET /api/v1/instances/{id}/overrides?include_expired=true
+func (h *OverrideHandler) ListOverrides(w http.ResponseWriter, r *http.Request, instanceID string) {
+	ctx := r.Context()
+
+	// Expire stale overrides so the list reflects the current state
+	if _, err := h.overrideStore.ExpireOverrides(ctx, time.Now()); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to list overrides"})
+		return
+	}
+
+	includeExpired := r.URL.Query().Get("include_expired") == "true"
+	overrides, err := h.overrideStore.ListOverridesByInstance(ctx, instanceID, includeExpired)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to list overrides"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(overrides)
+}
+
+// CreateOverride creates a keep-alive or skip-next override for an instance
+// POST /api/v1/instances/{id}/overrides
+func (h *OverrideHandler) CreateOverride(w http.ResponseWriter, r *http.Request, instanceID string) {
+	var req CreateOverrideRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	override, errMsg := buildOverride(instanceID, req, time.Now())
+	if errMsg != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": errMsg})
+		return
+	}
+
+	ctx := r.Context()
+	instance, err := h.instanceStore.GetInstanceByID(ctx, instanceID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Instance not found"})
+		return
+	}
+
+	if err := h.overrideStore.CreateOverride(ctx, override); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to create override"})
+		return
+	}
+
+	h.createEvent(ctx, instance, "override_create", override)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(override)
+}
+
+// CancelOverride cancels an active override
+// DELETE /api/v1/instances/{id}/overrides/{overrideID}
+func (h *OverrideHandler) CancelOverride(w http.ResponseWriter, r *http.Request, instanceID string, overrideID string) {
+	ctx := r.Context()
+
+	override, err := h.overrideStore.GetOverride(ctx, overrideID)
+	if err != nil || override.InstanceID != instanceID {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Override not found"})
+		return
+	}
+
+	if err := h.overrideStore.ExpireOverride(ctx, overrideID); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Override already expired"})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to cancel override"})
+		return
+	}
+
+	if instance, err := h.instanceStore.GetInstanceByID(ctx, instanceID); err == nil {
+		h.createEvent(ctx, instance, "override_cancel", override)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// extendConfirmPage asks for confirmation before an extend link takes effect, so link
+// previews (Slack/email unfurlers, prefetchers) opening the link don't extend anything.
+// The form posts back to the same signed URL.
+var extendConfirmPage = template.Must(template.New("extend").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Keep {{.Name}} awake</title></head>
+<body>
+<p>Keep <strong>{{.Name}}</strong> awake for another hour?</p>
+<form method="post" action="{{.Action}}"><button type="submit">Extend 1 hour</button></form>
+</body>
+</html>
+`))
+
+// ConfirmExtend shows the confirmation page of a signed "extend 1 hour" link sent in
+// pre-stop warnings. Opening the link changes nothing; the page's form does.
+// GET /actions/extend?instance_id=...&at=...&expires=...&sig=...
+func (h *OverrideHandler) ConfirmExtend(w http.ResponseWriter, r *http.Request) {
+	instance, _, ok := h.verifyExtendLink(w, r, time.Now())
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	extendConfirmPage.Execute(w, map[string]string{
+		"Name":   instance.Name,
+		"Action": notify.ExtendPath + "?" + r.URL.RawQuery,
+	})
+}
+
+// ExtendFromLink handles the confirmed "extend 1 hour" link sent in pre-stop warnings.
+// It creates a keep-alive override lasting an hour past the later of the scheduled
+// stop, now, and any keep-alive already in place.
+// POST /actions/extend?instance_id=...&at=...&expires=...&sig=...
+func (h *OverrideHandler) ExtendFromLink(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	instance, stopAt, ok := h.verifyExtendLink(w, r, now)
+	if !ok {
+		return
+	}
+	instanceID := instance.ID
+
+	ctx := r.Context()
+
+	base := now
+	if stopAt.After(base) {
+		base = stopAt
+	}
+	existing, err := h.overrideStore.ListOverridesByInstance(ctx, instanceID, false)
+	if err != nil {
+		http.Error(w, "Failed to extend instance", http.StatusInternalServerError)
+		return
+	}
+	for _, override := range existing {
+		if override.Type == models.OverrideKeepAlive && override.IsActive(now) && override.UntilTime != nil && override.UntilTime.After(base) {
+			base = *override.UntilTime
+		}
+	}
+
+	until := base.Add(time.Hour)
+	reason := "Extended from pre-stop warning"
+	override := &models.Override{
+		InstanceID: instanceID,
+		Type:       models.OverrideKeepAlive,
+		UntilTime:  &until,
+		Reason:     &reason,
+		CreatedBy:  "notification",
+	}
+	if err := h.overrideStore.CreateOverride(ctx, override); err != nil {
+		http.Error(w, "Failed to extend instance", http.StatusInternalServerError)
+		return
+	}
+
+	h.createEvent(ctx, instance, "override_create", override)
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "%s will be kept awake until %s\n", instance.Name, until.Format(time.RFC1123))
+}
+
+// verifyExtendLink checks the signature of an extend link and loads its instance,
+// writing the error response if that fails
+func (h *OverrideHandler) verifyExtendLink(w http.ResponseWriter, r *http.Request, now time.Time) (*models.Instance, time.Time, bool) {
+	if h.links == nil {
+		http.Error(w, "Extend links are not enabled", http.StatusNotFound)
+		return nil, time.Time{}, false
+	}
+
+	instanceID, stopAt, err := h.links.VerifyExtend(r.URL.Query(), now)
+	if err != nil {
+		http.Error(w, "Invalid extend link: "+err.Error(), http.StatusForbidden)
+		return nil, time.Time{}, false
+	}
+
+	instance, err := h.instanceStore.GetInstanceByID(r.Context(), instanceID)
+	if err != nil {
+		http.Error(w, "Instance not found", http.StatusNotFound)
+		return nil, time.Time{}, false
+	}
+	return instance, stopAt, true
+}
+
+// buildOverride validates a create request and converts it into an override
+// Returns an error message if the request is invalid
+func buildOverride(instanceID string, req CreateOverrideRequest, now time.Time) (*models.Override, string) {
+	override := &models.Override{
+		InstanceID: instanceID,
+		Type:       req.Type,
+		Reason:     req.Reason,
+		CreatedBy:  req.CreatedBy,
+	}
+	if override.CreatedBy == "" {
+		override.CreatedBy = "api"
+	}
+
+	if req.UntilTime != nil {
+		override.UntilTime = req.UntilTime
+	} else if req.DurationMinutes > 0 {
+		until := now.Add(time.Duration(req.DurationMinutes) * time.Minute)
+		override.UntilTime = &until
+	}
+
+	if override.UntilTime != nil && !override.UntilTime.After(now) {
+		return nil, "until_time must be in the future"
+	}
+
+	switch req.Type {
+	case models.OverrideKeepAlive:
+		if override.UntilTime == nil {
+			return nil, "keep_alive overrides require until_time or duration_minutes"
+		}
+		if req.SkipAction != nil {
+			return nil, "skip_action is only valid for skip_next overrides"
+		}
+	case models.OverrideSkipNext:
+		if req.SkipAction == nil || (*req.SkipAction != "start" && *req.SkipAction != "stop") {
+			return nil, "skip_next overrides require skip_action 'start' or 'stop'"
+		}
+		override.SkipAction = req.SkipAction
+	default:
+		return nil, "type must be 'keep_alive' or 'skip_next'"
+	}
+
+	return override, ""
+}
+
+// createEvent logs an override operation against the instance
+func (h *OverrideHandler) createEvent(ctx context.Context, instance *models.Instance, eventType string, override *models.Override) {
+	if h.eventStore == nil {
+		return
+	}
+
+	metadata, _ := json.Marshal(map[string]any{
+		"override_id":   override.ID,
+		"override_type": override.Type,
+		"skip_action":   override.SkipAction,
+		"until_time":    override.UntilTime,
+		"created_by":    override.CreatedBy,
+	})
+
+	event := &models.Event{
+		InstanceID:     instance.ID,
+		EventType:      eventType,
+		TriggeredBy:    "manual",
+		PreviousStatus: instance.Status,
+		NewStatus:      instance.Status,
+		Metadata:       metadata,
+	}
+	_ = h.eventStore.CreateEvent(ctx, event)
+}
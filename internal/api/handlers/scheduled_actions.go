@@ -0,0 +1,193 @@
+// API handlers for one-off scheduled actions
+
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"snoozeql/internal/models"
+	"snoozeql/internal/selector"
+	"snoozeql/internal/store"
+)
+
+// ScheduledActionHandler handles one-off scheduled action HTTP requests
+type ScheduledActionHandler struct {
+	actionStore   *store.ScheduledActionStore
+	instanceStore *store.InstanceStore
+	eventStore    *store.EventStore
+}
+
+// NewScheduledActionHandler creates a new scheduled action handler
+func NewScheduledActionHandler(actionStore *store.ScheduledActionStore, instanceStore *store.InstanceStore, eventStore *store.EventStore) *ScheduledActionHandler {
+	return &ScheduledActionHandler{
+		actionStore:   actionStore,
+		instanceStore: instanceStore,
+		eventStore:    eventStore,
+	}
+}
+
+// CreateScheduledActionRequest is the request body for creating a scheduled action
+type CreateScheduledActionRequest struct {
+	Name       string            `json:"name"`
+	Action     string            `json:"action"` // "start" or "stop"
+	ExecuteAt  time.Time         `json:"execute_at"`
+	InstanceID *string           `json:"instance_id,omitempty"` // Either instance_id...
+	Selectors  []models.Selector `json:"selectors,omitempty"`   // ...or selectors
+	Reason     *string           `json:"reason,omitempty"`
+	CreatedBy  string            `json:"created_by,omitempty"`
+}
+
+// ListScheduledActions returns scheduled actions, optionally filtered by status
+// GET /api/v1/scheduled-actions?status=pending
+func (h *ScheduledActionHandler) ListScheduledActions(w http.ResponseWriter, r *http.Request) {
+	actions, err := h.actionStore.ListScheduledActions(r.Context(), r.URL.Query().Get("status"))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to list scheduled actions"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(actions)
+}
+
+// GetScheduledAction returns a scheduled action along with the events it recorded
+// GET /api/v1/scheduled-actions/{id}
+func (h *ScheduledActionHandler) GetScheduledAction(w http.ResponseWriter, r *http.Request, id string) {
+	ctx := r.Context()
+	action, err := h.actionStore.GetScheduledAction(ctx, id)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Scheduled action not found"})
+		return
+	}
+
+	events := []models.Event{}
+	if h.eventStore != nil {
+		if actionEvents, err := h.eventStore.ListEventsByScheduledAction(ctx, id); err == nil {
+			events = actionEvents
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"action": action,
+		"events": events,
+	})
+}
+
+// CreateScheduledAction schedules a one-off start or stop
+// POST /api/v1/scheduled-actions
+func (h *ScheduledActionHandler) CreateScheduledAction(w http.ResponseWriter, r *http.Request) {
+	var req CreateScheduledActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	action, errMsg := buildScheduledAction(req, time.Now())
+	if errMsg != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": errMsg})
+		return
+	}
+
+	ctx := r.Context()
+	if action.InstanceID != nil {
+		if _, err := h.instanceStore.GetInstanceByID(ctx, *action.InstanceID); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Instance not found"})
+			return
+		}
+	}
+
+	if err := h.actionStore.CreateScheduledAction(ctx, action); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to create scheduled action"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(action)
+}
+
+// CancelScheduledAction cancels a pending scheduled action
+// DELETE /api/v1/scheduled-actions/{id}
+func (h *ScheduledActionHandler) CancelScheduledAction(w http.ResponseWriter, r *http.Request, id string) {
+	ctx := r.Context()
+	if _, err := h.actionStore.GetScheduledAction(ctx, id); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Scheduled action not found"})
+		return
+	}
+
+	if err := h.actionStore.CancelScheduledAction(ctx, id); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Scheduled action is no longer pending"})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to cancel scheduled action"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// buildScheduledAction validates a create request and converts it into a scheduled action
+// Returns an error message if the request is invalid
+func buildScheduledAction(req CreateScheduledActionRequest, now time.Time) (*models.ScheduledAction, string) {
+	if req.Action != "start" && req.Action != "stop" {
+		return nil, "action must be 'start' or 'stop'"
+	}
+	if req.ExecuteAt.IsZero() {
+		return nil, "execute_at is required"
+	}
+	if !req.ExecuteAt.After(now) {
+		return nil, "execute_at must be in the future"
+	}
+
+	hasInstance := req.InstanceID != nil && *req.InstanceID != ""
+	if hasInstance == (len(req.Selectors) > 0) {
+		return nil, "exactly one of instance_id or selectors is required"
+	}
+	if errMsg := selector.Validate(req.Selectors); errMsg != "" {
+		return nil, errMsg
+	}
+
+	action := &models.ScheduledAction{
+		Name:      req.Name,
+		Action:    req.Action,
+		ExecuteAt: req.ExecuteAt,
+		Selectors: req.Selectors,
+		Reason:    req.Reason,
+		CreatedBy: req.CreatedBy,
+	}
+	if hasInstance {
+		action.InstanceID = req.InstanceID
+	}
+	if action.Name == "" {
+		action.Name = req.Action + " at " + req.ExecuteAt.Format(time.RFC3339)
+	}
+	if action.CreatedBy == "" {
+		action.CreatedBy = "api"
+	}
+	return action, ""
+}
@@ -5,10 +5,19 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"maps"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/gorhill/cronexpr"
+	"snoozeql/internal/calendar"
 	"snoozeql/internal/models"
 	"snoozeql/internal/scheduler"
+	"snoozeql/internal/selector"
 	"snoozeql/internal/store"
 )
 
@@ -17,14 +26,18 @@ type ScheduleHandler struct {
 	scheduleStore *store.ScheduleStore
 	instanceStore *store.InstanceStore
 	eventStore    *store.EventStore
+	calendarStore *store.CalendarStore
+	overrideStore *store.OverrideStore
 }
 
 // NewScheduleHandler creates a new schedule handler
-func NewScheduleHandler(scheduleStore *store.ScheduleStore, instanceStore *store.InstanceStore, eventStore *store.EventStore) *ScheduleHandler {
+func NewScheduleHandler(scheduleStore *store.ScheduleStore, instanceStore *store.InstanceStore, eventStore *store.EventStore, calendarStore *store.CalendarStore, overrideStore *store.OverrideStore) *ScheduleHandler {
 	return &ScheduleHandler{
 		scheduleStore: scheduleStore,
 		instanceStore: instanceStore,
 		eventStore:    eventStore,
+		calendarStore: calendarStore,
+		overrideStore: overrideStore,
 	}
 }
 
@@ -92,6 +105,42 @@ func (h *ScheduleHandler) CreateSchedule(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if errMsg := validateTiming(&schedule); errMsg != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": errMsg})
+		return
+	}
+
+	schedule.Operator = selector.Operator(schedule.Operator)
+	if errMsg := validateScheduleSelection(&schedule); errMsg != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": errMsg})
+		return
+	}
+
+	if errMsg := h.validateExceptions(r.Context(), schedule.Exceptions); errMsg != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": errMsg})
+		return
+	}
+
+	if errMsg := validateOrdering(schedule.Ordering); errMsg != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": errMsg})
+		return
+	}
+
+	if errMsg := validateBudget(schedule.Budget); errMsg != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": errMsg})
+		return
+	}
+
 	if err := h.scheduleStore.CreateSchedule(&schedule); err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -104,29 +153,75 @@ func (h *ScheduleHandler) CreateSchedule(w http.ResponseWriter, r *http.Request)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(schedule)
+	json.NewEncoder(w).Encode(scheduleResponse{Schedule: &schedule, Conflicts: h.conflictsWith(r.Context(), schedule)})
 }
 
-// UpdateSchedule updates an existing schedule
+// UpdateSchedule updates an existing schedule. Fields missing from the request keep
+// their stored values, so clients that don't know about a field don't wipe it.
 func (h *ScheduleHandler) UpdateSchedule(w http.ResponseWriter, r *http.Request, id string) {
-	var schedule models.Schedule
-	if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+	existing, err := h.scheduleStore.GetSchedule(id)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Schedule not found"})
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		return
+	}
+	schedule, err := mergeSchedule(existing, body)
+	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
 		return
 	}
 
-	// Get the existing schedule name for logging
-	var existingName string
-	if existing, err := h.scheduleStore.GetSchedule(id); err == nil {
-		existingName = existing.Name
+	if errMsg := validateTiming(schedule); errMsg != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": errMsg})
+		return
+	}
+
+	schedule.Operator = selector.Operator(schedule.Operator)
+	if errMsg := validateScheduleSelection(schedule); errMsg != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": errMsg})
+		return
+	}
+
+	if errMsg := h.validateExceptions(r.Context(), schedule.Exceptions); errMsg != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": errMsg})
+		return
+	}
+
+	if errMsg := validateOrdering(schedule.Ordering); errMsg != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": errMsg})
+		return
+	}
+
+	if errMsg := validateBudget(schedule.Budget); errMsg != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": errMsg})
+		return
 	}
 
 	// Ensure the ID from the URL is used
 	schedule.ID = id
 
-	if err := h.scheduleStore.UpdateSchedule(&schedule); err != nil {
+	if err := h.scheduleStore.UpdateSchedule(schedule); err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Schedule not found"})
@@ -134,11 +229,155 @@ func (h *ScheduleHandler) UpdateSchedule(w http.ResponseWriter, r *http.Request,
 	}
 
 	// Log the event
-	h.CreateEvent(r.Context(), "schedule_update", existingName, "updated", "updated")
+	h.CreateEvent(r.Context(), "schedule_update", existing.Name, "updated", "updated")
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(schedule)
+	json.NewEncoder(w).Encode(scheduleResponse{Schedule: schedule, Conflicts: h.conflictsWith(r.Context(), *schedule)})
+}
+
+// mergeSchedule applies the JSON fields of an update request to a stored schedule.
+// Fields the request leaves out keep their stored values. Selectors and an expression
+// exclude each other, so sending one clears the other unless both are sent.
+func mergeSchedule(existing *models.Schedule, body []byte) (*models.Schedule, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+
+	stored, err := json.Marshal(existing)
+	if err != nil {
+		return nil, err
+	}
+	var merged map[string]json.RawMessage
+	if err := json.Unmarshal(stored, &merged); err != nil {
+		return nil, err
+	}
+	maps.Copy(merged, fields)
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	var schedule models.Schedule
+	if err := json.Unmarshal(data, &schedule); err != nil {
+		return nil, err
+	}
+
+	_, hasSelectors := fields["selectors"]
+	_, hasExpression := fields["expression"]
+	if hasSelectors && !hasExpression && len(schedule.Selectors) > 0 {
+		schedule.Expression = ""
+	}
+	if hasExpression && !hasSelectors && schedule.Expression != "" {
+		schedule.Selectors = nil
+	}
+	return &schedule, nil
+}
+
+// scheduleResponse is a saved schedule plus warnings about schedules it conflicts with
+type scheduleResponse struct {
+	*models.Schedule
+	Conflicts []scheduler.ScheduleConflict `json:"conflicts,omitempty"`
+}
+
+// conflictsWith returns the conflicts between the schedule and other enabled schedules
+// over the default horizon. Errors are logged and reported as no conflicts, since the
+// schedule has already been saved.
+func (h *ScheduleHandler) conflictsWith(ctx context.Context, schedule models.Schedule) []scheduler.ScheduleConflict {
+	if !schedule.Enabled {
+		return nil
+	}
+
+	conflicts, err := h.findConflicts(ctx, scheduler.DefaultConflictHorizon)
+	if err != nil {
+		log.Printf("Warning: Failed to check conflicts for schedule %s: %v", schedule.Name, err)
+		return nil
+	}
+
+	var involved []scheduler.ScheduleConflict
+	for _, conflict := range conflicts {
+		if conflict.ScheduleA.ID == schedule.ID || conflict.ScheduleB.ID == schedule.ID {
+			involved = append(involved, conflict)
+		}
+	}
+	if len(involved) > 0 {
+		log.Printf("Schedule '%s' conflicts with %d other schedules", schedule.Name, len(involved))
+	}
+	return involved
+}
+
+// findConflicts runs the conflict analyzer over all stored schedules and instances
+func (h *ScheduleHandler) findConflicts(ctx context.Context, horizon time.Duration) ([]scheduler.ScheduleConflict, error) {
+	schedules, err := h.scheduleStore.ListSchedules()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedules: %w", err)
+	}
+	instances, err := h.instanceStore.ListInstances(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list instances: %w", err)
+	}
+
+	calendars, err := h.loadCalendars(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return scheduler.FindConflicts(schedules, instances, calendars, now, now.Add(horizon)), nil
+}
+
+// loadCalendars returns all exception calendars by ID (nil if there is no calendar store)
+func (h *ScheduleHandler) loadCalendars(ctx context.Context) (scheduler.Calendars, error) {
+	if h.calendarStore == nil {
+		return nil, nil
+	}
+	list, err := h.calendarStore.ListCalendars(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list calendars: %w", err)
+	}
+	return scheduler.NewCalendars(list), nil
+}
+
+// loadOverrides returns the active overrides grouped by instance ID (nil if there is no override store)
+func (h *ScheduleHandler) loadOverrides(ctx context.Context) (map[string][]models.Override, error) {
+	if h.overrideStore == nil {
+		return nil, nil
+	}
+	active, err := h.overrideStore.ListActiveOverrides(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list overrides: %w", err)
+	}
+	return scheduler.GroupOverrides(active), nil
+}
+
+// ListConflicts returns pairs of enabled schedules that want shared instances in
+// different states, with the windows in which they disagree and which one wins
+// GET /api/v1/schedules/conflicts
+func (h *ScheduleHandler) ListConflicts(w http.ResponseWriter, r *http.Request) {
+	horizon := scheduler.DefaultConflictHorizon
+	if daysParam := r.URL.Query().Get("days"); daysParam != "" {
+		days, err := strconv.Atoi(daysParam)
+		if err != nil || days < 1 || days > 90 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "days must be between 1 and 90"})
+			return
+		}
+		horizon = time.Duration(days) * 24 * time.Hour
+	}
+
+	conflicts, err := h.findConflicts(r.Context(), horizon)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to analyze conflicts"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(conflicts)
 }
 
 // DeleteSchedule deletes a schedule
@@ -224,8 +463,13 @@ func (h *ScheduleHandler) DisableSchedule(w http.ResponseWriter, r *http.Request
 // POST /api/v1/schedules/preview-filter
 func (h *ScheduleHandler) PreviewFilter(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Selectors []models.Selector `json:"selectors"`
-		Operator  string            `json:"operator"` // "and" or "or", default "and"
+		Selectors  []models.Selector          `json:"selectors"`
+		Operator   string                     `json:"operator"`   // "and" or "or", default "or" as for schedules
+		Expression string                     `json:"expression"` // Selector expression, instead of selectors
+		Exclusions []models.Selector          `json:"exclusions"` // Optional: instances to leave out
+		Exceptions []models.ScheduleException `json:"exceptions"` // Optional: calendar exceptions to preview
+		Timezone   string                     `json:"timezone"`   // Timezone for exception dates, default UTC
+		Days       int                        `json:"days"`       // Exception look-ahead in days, default 90
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		w.Header().Set("Content-Type", "application/json")
@@ -234,21 +478,10 @@ func (h *ScheduleHandler) PreviewFilter(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Default to "and" if not specified
-	if req.Operator == "" {
-		req.Operator = "and"
-	}
+	req.Operator = selector.Operator(req.Operator)
 
-	// Validate operator
-	if req.Operator != "and" && req.Operator != "or" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Operator must be 'and' or 'or'"})
-		return
-	}
-
-	// Validate selectors
-	if errMsg := scheduler.ValidateSelectors(req.Selectors); errMsg != "" {
+	// Validate operator, selectors and exclusions
+	if errMsg := validateSelection(req.Selectors, req.Operator, req.Expression, req.Exclusions); errMsg != "" {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": errMsg})
@@ -265,18 +498,287 @@ func (h *ScheduleHandler) PreviewFilter(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Filter instances
+	var expr *selector.Expression
+	if req.Expression != "" {
+		expr, _ = selector.ParseExpression(req.Expression) // Validated above
+	}
 	var matched []models.Instance
 	for _, inst := range instances {
-		if scheduler.MatchInstance(&inst, req.Selectors, req.Operator) {
+		if expr != nil {
+			if expr.Match(inst) && !selector.Excluded(inst, req.Exclusions) {
+				matched = append(matched, inst)
+			}
+		} else if selector.Match(inst, req.Selectors, req.Operator, req.Exclusions) {
 			matched = append(matched, inst)
 		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]any{
+	response := map[string]any{
 		"matched_count": len(matched),
 		"total_count":   len(instances),
 		"instances":     matched,
-	})
+	}
+
+	// Show the upcoming dates on which the schedule's exceptions apply
+	if len(req.Exceptions) > 0 {
+		if errMsg := h.validateExceptions(r.Context(), req.Exceptions); errMsg != "" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": errMsg})
+			return
+		}
+		response["exceptions"] = h.previewExceptions(r.Context(), req.Exceptions, req.Timezone, req.Days)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// SimulateSchedule returns the start/stop actions a schedule would fire over the next days,
+// per matching instance, without executing anything
+// POST /api/v1/schedules/simulate
+func (h *ScheduleHandler) SimulateSchedule(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ScheduleID string           `json:"schedule_id"` // Simulate an existing schedule...
+		Schedule   *models.Schedule `json:"schedule"`    // ...or an unsaved one
+		Days       int              `json:"days"`        // Horizon in days, default 14, max 90
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	if (req.ScheduleID == "") == (req.Schedule == nil) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Provide either schedule_id or schedule"})
+		return
+	}
+
+	if req.Days == 0 {
+		req.Days = 14
+	}
+	if req.Days < 0 || req.Days > 90 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "days must be between 1 and 90"})
+		return
+	}
+
+	schedule := req.Schedule
+	if req.ScheduleID != "" {
+		existing, err := h.scheduleStore.GetSchedule(req.ScheduleID)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Schedule not found"})
+			return
+		}
+		schedule = existing
+	}
+
+	if errMsg := validateTiming(schedule); errMsg != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": errMsg})
+		return
+	}
+	if errMsg := validateSelection(schedule.Selectors, schedule.Operator, schedule.Expression, schedule.Exclusions); errMsg != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": errMsg})
+		return
+	}
+	if errMsg := h.validateExceptions(r.Context(), schedule.Exceptions); errMsg != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": errMsg})
+		return
+	}
+
+	instances, err := h.instanceStore.ListInstances(r.Context())
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to list instances"})
+		return
+	}
+
+	calendars, err := h.loadCalendars(r.Context())
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to list calendars"})
+		return
+	}
+
+	overrides, err := h.loadOverrides(r.Context())
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to list overrides"})
+		return
+	}
+
+	now := time.Now()
+	simulation := scheduler.Simulate(*schedule, instances, calendars, overrides, now, now.AddDate(0, 0, req.Days))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(simulation)
+}
+
+// ExceptionPreview lists the upcoming dates covered by one of a schedule's exceptions
+type ExceptionPreview struct {
+	CalendarID   string                 `json:"calendar_id"`
+	CalendarName string                 `json:"calendar_name"`
+	Mode         string                 `json:"mode"`
+	Entries      []models.CalendarEntry `json:"entries"`
+}
+
+// previewExceptions returns the calendar entries that fall within the next days
+func (h *ScheduleHandler) previewExceptions(ctx context.Context, exceptions []models.ScheduleException, timezone string, days int) []ExceptionPreview {
+	if days <= 0 {
+		days = 90
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	from := time.Now().In(loc)
+	until := from.AddDate(0, 0, days)
+
+	previews := []ExceptionPreview{}
+	for _, exception := range exceptions {
+		cal, err := h.calendarStore.GetCalendar(ctx, exception.CalendarID)
+		if err != nil {
+			continue
+		}
+		previews = append(previews, ExceptionPreview{
+			CalendarID:   cal.ID,
+			CalendarName: cal.Name,
+			Mode:         exception.Mode,
+			Entries:      calendar.Upcoming(*cal, from, until),
+		})
+	}
+	return previews
+}
+
+// validateExceptions checks that each exception has a valid mode and an existing calendar
+// Returns error message if invalid, empty string if valid
+func (h *ScheduleHandler) validateExceptions(ctx context.Context, exceptions []models.ScheduleException) string {
+	for _, exception := range exceptions {
+		if exception.Mode != models.ExceptionSuppressWake && exception.Mode != models.ExceptionForceSleep {
+			return "Exception mode must be 'suppress_wake' or 'force_sleep'"
+		}
+		if h.calendarStore == nil {
+			return "Exception calendars are not available"
+		}
+		if _, err := h.calendarStore.GetCalendar(ctx, exception.CalendarID); err != nil {
+			return "Exception calendar not found: " + exception.CalendarID
+		}
+	}
+	return ""
+}
+
+// validateTiming checks a schedule's timezone and CRON expressions
+// Returns error message if invalid, empty string if valid
+func validateTiming(schedule *models.Schedule) string {
+	if schedule.Timezone != "" {
+		if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+			return "Invalid timezone: " + schedule.Timezone
+		}
+	}
+	if schedule.WakeCron == "" && schedule.SleepCron == "" {
+		return "Schedule must have a wake_cron or sleep_cron"
+	}
+	if schedule.WakeCron != "" {
+		if _, err := cronexpr.Parse(schedule.WakeCron); err != nil {
+			return "Invalid wake_cron: " + err.Error()
+		}
+	}
+	if schedule.SleepCron != "" {
+		if _, err := cronexpr.Parse(schedule.SleepCron); err != nil {
+			return "Invalid sleep_cron: " + err.Error()
+		}
+	}
+	return ""
+}
+
+// validateSelection checks a selector operator, selectors or expression, and exclusions
+// Returns an error message if invalid, empty string if valid
+func validateSelection(selectors []models.Selector, operator, expression string, exclusions []models.Selector) string {
+	if errMsg := selector.ValidateOperator(operator); errMsg != "" {
+		return errMsg
+	}
+	if expression != "" {
+		if len(selectors) > 0 {
+			return "Provide either selectors or expression, not both"
+		}
+		if _, err := selector.ParseExpression(expression); err != nil {
+			return "Invalid expression: " + err.Error()
+		}
+	}
+	if errMsg := selector.Validate(selectors); errMsg != "" {
+		return errMsg
+	}
+	if errMsg := selector.Validate(exclusions); errMsg != "" {
+		return "Exclusions: " + errMsg
+	}
+	return ""
+}
+
+// validateScheduleSelection checks a schedule's selection, which must not be empty:
+// an empty selector list selects nothing, so a schedule without selectors or an
+// expression would never apply. An empty selector ({}) selects every instance.
+// Returns an error message if invalid, empty string if valid
+func validateScheduleSelection(schedule *models.Schedule) string {
+	if len(schedule.Selectors) == 0 && schedule.Expression == "" {
+		return "Schedule must have selectors or an expression (use [{}] to select every instance)"
+	}
+	return validateSelection(schedule.Selectors, schedule.Operator, schedule.Expression, schedule.Exclusions)
+}
+
+// validateBudget checks a schedule's awake-hours budget
+// Returns error message if invalid, empty string if valid
+func validateBudget(budget *models.ScheduleBudget) string {
+	if budget == nil {
+		return ""
+	}
+	if budget.DailyHours == 0 && budget.WeeklyHours == 0 {
+		return "Budget must set daily_hours or weekly_hours"
+	}
+	if budget.DailyHours < 0 || budget.DailyHours > 24 {
+		return "Budget daily_hours must be between 0 and 24"
+	}
+	if budget.WeeklyHours < 0 || budget.WeeklyHours > 168 {
+		return "Budget weekly_hours must be between 0 and 168"
+	}
+	return ""
+}
+
+// validateOrdering checks a schedule's wake/sleep tiers
+// Returns error message if invalid, empty string if valid
+func validateOrdering(ordering *models.ScheduleOrdering) string {
+	if ordering == nil {
+		return ""
+	}
+	if ordering.FailurePolicy != "" && ordering.FailurePolicy != models.TierFailureAbort && ordering.FailurePolicy != models.TierFailureContinue {
+		return "Ordering failure_policy must be 'abort' or 'continue'"
+	}
+	if ordering.TimeoutMinutes < 0 {
+		return "Ordering timeout_minutes must not be negative"
+	}
+	for i, tier := range ordering.Tiers {
+		if len(tier.Selectors) == 0 {
+			return fmt.Sprintf("Tier %d must have at least one selector", i+1)
+		}
+		if errMsg := selector.Validate(tier.Selectors); errMsg != "" {
+			return fmt.Sprintf("Tier %d: %s", i+1, errMsg)
+		}
+	}
+	return ""
 }
@@ -0,0 +1,209 @@
+// API handlers for upcoming schedule actions
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"snoozeql/internal/models"
+	"snoozeql/internal/scheduler"
+)
+
+const (
+	defaultUpcomingLimit = 5
+	maxUpcomingLimit     = 50
+)
+
+// InstanceUpcoming lists the next actions for one instance
+type InstanceUpcoming struct {
+	InstanceID   string                     `json:"instance_id"`
+	InstanceName string                     `json:"instance_name"`
+	Status       string                     `json:"status"`
+	Actions      []scheduler.UpcomingAction `json:"actions"`
+}
+
+// upcomingParams reads ?limit= (default 5, max 50) and ?include_skipped=true
+// Returns error message if invalid, empty string if valid
+func upcomingParams(r *http.Request) (int, bool, string) {
+	limit := defaultUpcomingLimit
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed < 1 || parsed > maxUpcomingLimit {
+			return 0, false, "limit must be between 1 and 50"
+		}
+		limit = parsed
+	}
+	return limit, r.URL.Query().Get("include_skipped") == "true", ""
+}
+
+// upcomingInputs are the schedules, calendars and overrides upcoming actions are computed from
+type upcomingInputs struct {
+	schedules []models.Schedule
+	calendars scheduler.Calendars
+	overrides map[string][]models.Override
+}
+
+// loadUpcomingInputs loads everything needed to compute upcoming actions
+func (h *ScheduleHandler) loadUpcomingInputs(ctx context.Context) (*upcomingInputs, error) {
+	schedules, err := h.scheduleStore.ListSchedules()
+	if err != nil {
+		return nil, err
+	}
+	calendars, err := h.loadCalendars(ctx)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := h.loadOverrides(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &upcomingInputs{schedules: schedules, calendars: calendars, overrides: overrides}, nil
+}
+
+// upcomingFor returns every action the instance goes through over the upcoming horizon
+func (in *upcomingInputs) upcomingFor(instance models.Instance, now time.Time) []scheduler.UpcomingAction {
+	return scheduler.Upcoming(instance, in.schedules, in.calendars, in.overrides[instance.ID], now, now.Add(scheduler.UpcomingHorizon))
+}
+
+// CountUpcoming returns how many start/stop actions the instances will go through
+// between now and now+within, leaving out skipped ones
+func (h *ScheduleHandler) CountUpcoming(ctx context.Context, instances []models.Instance, now time.Time, within time.Duration) (int, error) {
+	inputs, err := h.loadUpcomingInputs(ctx)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, instance := range instances {
+		for _, action := range scheduler.Upcoming(instance, inputs.schedules, inputs.calendars, inputs.overrides[instance.ID], now, now.Add(within)) {
+			if !action.Skipped {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
+// UpcomingForSchedule returns the next wake/sleep fire times of a schedule, overall and
+// for each matching instance, after exception calendars, priorities, overrides and
+// already-in-state skips
+// GET /api/v1/schedules/{id}/upcoming
+func (h *ScheduleHandler) UpcomingForSchedule(w http.ResponseWriter, r *http.Request, id string) {
+	limit, includeSkipped, errMsg := upcomingParams(r)
+	if errMsg != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": errMsg})
+		return
+	}
+
+	schedule, err := h.scheduleStore.GetSchedule(id)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Schedule not found"})
+		return
+	}
+
+	inputs, err := h.loadUpcomingInputs(r.Context())
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to load schedules"})
+		return
+	}
+
+	instances, err := h.instanceStore.ListInstances(r.Context())
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to list instances"})
+		return
+	}
+
+	now := time.Now()
+	actions := []scheduler.UpcomingAction{}
+	perInstance := []InstanceUpcoming{}
+	if schedule.Enabled {
+		// The schedule's own fires, before per-instance skips
+		sim := scheduler.Simulate(*schedule, nil, inputs.calendars, nil, now, now.Add(scheduler.UpcomingHorizon))
+		var fires []scheduler.UpcomingAction
+		for _, action := range sim.Actions {
+			fires = append(fires, scheduler.UpcomingAction{SimulatedAction: action, ScheduleID: schedule.ID, ScheduleName: schedule.Name})
+		}
+		actions = scheduler.NextActions(fires, limit, includeSkipped)
+
+		// Instance timelines include every matching schedule, so keep only this one's actions
+		for _, instance := range instances {
+			if !scheduler.MatchesSchedule(instance, *schedule) {
+				continue
+			}
+			var own []scheduler.UpcomingAction
+			for _, action := range inputs.upcomingFor(instance, now) {
+				if action.ScheduleID == schedule.ID {
+					own = append(own, action)
+				}
+			}
+			perInstance = append(perInstance, InstanceUpcoming{
+				InstanceID:   instance.ID,
+				InstanceName: instance.Name,
+				Status:       instance.Status,
+				Actions:      scheduler.NextActions(own, limit, includeSkipped),
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"schedule_id":   schedule.ID,
+		"schedule_name": schedule.Name,
+		"timezone":      schedule.Timezone,
+		"enabled":       schedule.Enabled,
+		"actions":       actions,
+		"instances":     perInstance,
+	})
+}
+
+// UpcomingForInstance returns the next wake/sleep actions for an instance across all
+// matching schedules, after exception calendars, priorities, overrides and
+// already-in-state skips
+// GET /api/v1/instances/{id}/upcoming
+func (h *ScheduleHandler) UpcomingForInstance(w http.ResponseWriter, r *http.Request, id string) {
+	limit, includeSkipped, errMsg := upcomingParams(r)
+	if errMsg != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": errMsg})
+		return
+	}
+
+	instance, err := h.instanceStore.GetInstanceByID(r.Context(), id)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Instance not found"})
+		return
+	}
+
+	inputs, err := h.loadUpcomingInputs(r.Context())
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to load schedules"})
+		return
+	}
+
+	now := time.Now()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(InstanceUpcoming{
+		InstanceID:   instance.ID,
+		InstanceName: instance.Name,
+		Status:       instance.Status,
+		Actions:      scheduler.NextActions(inputs.upcomingFor(*instance, now), limit, includeSkipped),
+	})
+}
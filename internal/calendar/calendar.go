@@ -0,0 +1,93 @@
+// Package calendar handles exception (holiday/shutdown) calendars: date range
+// validation, date lookups and iCalendar import.
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"snoozeql/internal/models"
+)
+
+// DateLayout is the format of calendar entry dates
+const DateLayout = "2006-01-02"
+
+// NormalizeEntries validates entries, defaults empty end dates to the start date
+// and sorts them by start date
+func NormalizeEntries(entries []models.CalendarEntry) ([]models.CalendarEntry, error) {
+	normalized := make([]models.CalendarEntry, 0, len(entries))
+	for i, entry := range entries {
+		start, err := time.Parse(DateLayout, entry.StartDate)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: start_date must be YYYY-MM-DD", i+1)
+		}
+		if entry.EndDate == "" {
+			entry.EndDate = entry.StartDate
+		}
+		end, err := time.Parse(DateLayout, entry.EndDate)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: end_date must be YYYY-MM-DD", i+1)
+		}
+		if end.Before(start) {
+			return nil, fmt.Errorf("entry %d: end_date is before start_date", i+1)
+		}
+		normalized = append(normalized, entry)
+	}
+
+	sort.SliceStable(normalized, func(i, j int) bool {
+		return normalized[i].StartDate < normalized[j].StartDate
+	})
+	return normalized, nil
+}
+
+// EntryOn returns the calendar entry covering the calendar date of t (in t's location)
+func EntryOn(cal models.ExceptionCalendar, t time.Time) (models.CalendarEntry, bool) {
+	// YYYY-MM-DD strings compare in date order
+	date := t.Format(DateLayout)
+	for _, entry := range cal.Entries {
+		end := entry.EndDate
+		if end == "" {
+			end = entry.StartDate
+		}
+		if entry.StartDate <= date && date <= end {
+			return entry, true
+		}
+	}
+	return models.CalendarEntry{}, false
+}
+
+// Upcoming returns the entries that overlap the dates from..until (inclusive, in from's location)
+func Upcoming(cal models.ExceptionCalendar, from, until time.Time) []models.CalendarEntry {
+	fromDate := from.Format(DateLayout)
+	untilDate := until.In(from.Location()).Format(DateLayout)
+
+	upcoming := []models.CalendarEntry{} // Initialize as empty slice, not nil
+	for _, entry := range cal.Entries {
+		end := entry.EndDate
+		if end == "" {
+			end = entry.StartDate
+		}
+		if end >= fromDate && entry.StartDate <= untilDate {
+			upcoming = append(upcoming, entry)
+		}
+	}
+	return upcoming
+}
+
+// MergeEntries adds entries to existing ones, dropping exact duplicates
+func MergeEntries(existing, added []models.CalendarEntry) []models.CalendarEntry {
+	seen := make(map[models.CalendarEntry]bool)
+	merged := []models.CalendarEntry{}
+	for _, entry := range append(append([]models.CalendarEntry{}, existing...), added...) {
+		if seen[entry] {
+			continue
+		}
+		seen[entry] = true
+		merged = append(merged, entry)
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].StartDate < merged[j].StartDate
+	})
+	return merged
+}
@@ -0,0 +1,398 @@
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"snoozeql/internal/models"
+)
+
+// yearlyHorizon is how many years past the current one yearly recurring events are
+// expanded to. Occurrences more than a year in the past are left out.
+const yearlyHorizon = 5
+
+// ParseICS reads the VEVENTs of an iCalendar (.ics) file as calendar entries.
+// All-day events map to their dates (DTEND is exclusive); timed events cover the
+// dates they touch. Yearly recurring events (RRULE:FREQ=YEARLY, the usual form for
+// holidays) are expanded from a year ago to yearlyHorizon years ahead, honouring
+// INTERVAL, COUNT, UNTIL, BYMONTH, BYMONTHDAY and ordinal BYDAY ("4TH" for
+// Thanksgiving). Yearly rules using other parts are not imported, and other
+// recurrence rules only import their first occurrence; both are reported in the
+// returned warnings.
+func ParseICS(r io.Reader) ([]models.CalendarEntry, []string, error) {
+	return parseICS(r, time.Now())
+}
+
+func parseICS(r io.Reader, now time.Time) ([]models.CalendarEntry, []string, error) {
+	lines, err := unfoldLines(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read calendar: %w", err)
+	}
+
+	entries := []models.CalendarEntry{}
+	warnings := []string{}
+	var event map[string]icsProperty
+	sawCalendar := false
+
+	for _, line := range lines {
+		name, prop, ok := parseProperty(line)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case name == "BEGIN" && strings.EqualFold(prop.value, "VCALENDAR"):
+			sawCalendar = true
+		case name == "BEGIN" && strings.EqualFold(prop.value, "VEVENT"):
+			event = make(map[string]icsProperty)
+		case name == "END" && strings.EqualFold(prop.value, "VEVENT"):
+			if event == nil {
+				continue
+			}
+			eventEntries, warning, err := eventToEntries(event, now)
+			if err != nil {
+				return nil, nil, err
+			}
+			if warning != "" {
+				warnings = append(warnings, warning)
+			}
+			entries = append(entries, eventEntries...)
+			event = nil
+		case event != nil:
+			if _, exists := event[name]; !exists {
+				event[name] = prop
+			}
+		}
+	}
+
+	if !sawCalendar {
+		return nil, nil, fmt.Errorf("not an iCalendar file (missing BEGIN:VCALENDAR)")
+	}
+
+	normalized, err := NormalizeEntries(entries)
+	if err != nil {
+		return nil, nil, err
+	}
+	return normalized, warnings, nil
+}
+
+// icsProperty is a content line's parameters and value
+type icsProperty struct {
+	params map[string]string
+	value  string
+}
+
+// unfoldLines splits the input into content lines, joining folded continuation lines
+func unfoldLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseProperty splits "NAME;PARAM=VALUE:value" into its parts
+func parseProperty(line string) (string, icsProperty, bool) {
+	// The value starts at the first colon that isn't inside a quoted parameter
+	inQuotes := false
+	colon := -1
+	for i, c := range line {
+		if c == '"' {
+			inQuotes = !inQuotes
+		} else if c == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", icsProperty{}, false
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	prop := icsProperty{params: make(map[string]string), value: line[colon+1:]}
+	for _, param := range parts[1:] {
+		if key, value, ok := strings.Cut(param, "="); ok {
+			prop.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), prop, true
+}
+
+// eventToEntries converts a VEVENT into entries (more than one for yearly recurrences)
+func eventToEntries(event map[string]icsProperty, now time.Time) ([]models.CalendarEntry, string, error) {
+	dtstart, ok := event["DTSTART"]
+	if !ok {
+		return nil, "", nil
+	}
+	start, allDay, err := parseICSDate(dtstart)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid DTSTART %q: %w", dtstart.value, err)
+	}
+
+	end := start
+	if dtend, ok := event["DTEND"]; ok {
+		endDate, endAllDay, err := parseICSDate(dtend)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid DTEND %q: %w", dtend.value, err)
+		}
+		end = endDate
+		// All-day DTEND (and a timed DTEND at midnight) is exclusive
+		if endAllDay || (!allDay && isMidnight(dtend.value)) {
+			end = end.AddDate(0, 0, -1)
+		}
+	} else if duration, ok := event["DURATION"]; ok {
+		if days := parseDurationDays(duration.value); days > 0 {
+			end = start.AddDate(0, 0, days-1)
+		}
+	}
+	if end.Before(start) {
+		end = start
+	}
+
+	name := unescapeText(event["SUMMARY"].value)
+	entry := models.CalendarEntry{
+		Name:      name,
+		StartDate: start.Format(DateLayout),
+		EndDate:   end.Format(DateLayout),
+	}
+
+	rrule, recurring := event["RRULE"]
+	if !recurring {
+		return []models.CalendarEntry{entry}, "", nil
+	}
+
+	rule := parseRRule(rrule.value)
+	if rule["FREQ"] != "YEARLY" {
+		warning := fmt.Sprintf("event %q repeats %s; only its first occurrence was imported", name, strings.ToLower(rule["FREQ"]))
+		return []models.CalendarEntry{entry}, warning, nil
+	}
+
+	entries, warning := expandYearly(name, start, int(end.Sub(start).Hours()/24), rule, now)
+	return entries, warning, nil
+}
+
+// yearlyRuleParts are the RRULE parts expandYearly understands
+var yearlyRuleParts = map[string]bool{
+	"FREQ": true, "INTERVAL": true, "COUNT": true, "UNTIL": true, "WKST": true,
+	"BYMONTH": true, "BYMONTHDAY": true, "BYDAY": true,
+}
+
+// expandYearly returns the occurrences of a yearly rule from a year before now to
+// yearlyHorizon years after it, each lasting days+1 dates. COUNT counts occurrences
+// from DTSTART, including the ones left out. Rules it can't expand correctly yield no
+// entries and a warning, rather than entries on the wrong dates.
+func expandYearly(name string, start time.Time, days int, rule map[string]string, now time.Time) ([]models.CalendarEntry, string) {
+	unsupported := func(part string) string {
+		return fmt.Sprintf("event %q repeats yearly with unsupported %s; it was not imported", name, part)
+	}
+	for part := range rule {
+		if !yearlyRuleParts[part] {
+			return nil, unsupported(part)
+		}
+	}
+
+	interval := 1
+	if n, err := strconv.Atoi(rule["INTERVAL"]); err == nil && n > 0 {
+		interval = n
+	}
+	count := 0
+	if n, err := strconv.Atoi(rule["COUNT"]); err == nil && n > 0 {
+		count = n
+	}
+	var until time.Time
+	if u := rule["UNTIL"]; len(u) >= 8 {
+		until, _ = time.Parse("20060102", u[:8])
+	}
+
+	months := []time.Month{start.Month()}
+	if rule["BYMONTH"] != "" {
+		months = nil
+		for _, value := range strings.Split(rule["BYMONTH"], ",") {
+			month, err := strconv.Atoi(value)
+			if err != nil || month < 1 || month > 12 {
+				return nil, unsupported("BYMONTH=" + rule["BYMONTH"])
+			}
+			months = append(months, time.Month(month))
+		}
+	}
+
+	var weekdays []nthWeekday
+	if rule["BYDAY"] != "" {
+		if rule["BYMONTH"] == "" || rule["BYMONTHDAY"] != "" {
+			return nil, unsupported("BYDAY without BYMONTH or with BYMONTHDAY")
+		}
+		for _, value := range strings.Split(rule["BYDAY"], ",") {
+			weekday, ok := parseNthWeekday(value)
+			if !ok {
+				return nil, unsupported("BYDAY=" + rule["BYDAY"])
+			}
+			weekdays = append(weekdays, weekday)
+		}
+	}
+
+	monthDays := []int{start.Day()}
+	if rule["BYMONTHDAY"] != "" {
+		monthDays = nil
+		for _, value := range strings.Split(rule["BYMONTHDAY"], ",") {
+			day, err := strconv.Atoi(value)
+			if err != nil || day == 0 || day < -31 || day > 31 {
+				return nil, unsupported("BYMONTHDAY=" + rule["BYMONTHDAY"])
+			}
+			monthDays = append(monthDays, day)
+		}
+	}
+
+	from := now.AddDate(-1, 0, 0).Format(DateLayout)
+	lastYear := now.Year() + yearlyHorizon
+
+	entries := []models.CalendarEntry{}
+	seen := 0
+	for year := start.Year(); year <= lastYear; year += interval {
+		for _, date := range yearlyDates(year, months, weekdays, monthDays) {
+			if date.Before(start) {
+				continue
+			}
+			if !until.IsZero() && date.After(until) {
+				return entries, ""
+			}
+			seen++
+			if count > 0 && seen > count {
+				return entries, ""
+			}
+			if date.Format(DateLayout) < from {
+				continue
+			}
+			entries = append(entries, models.CalendarEntry{
+				Name:      name,
+				StartDate: date.Format(DateLayout),
+				EndDate:   date.AddDate(0, 0, days).Format(DateLayout),
+			})
+		}
+	}
+	return entries, ""
+}
+
+// nthWeekday is an ordinal BYDAY value: the nth (from the end if negative) weekday of a month
+type nthWeekday struct {
+	n       int
+	weekday time.Weekday
+}
+
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// parseNthWeekday parses a BYDAY value like "4TH" or "-1MO". Values without an
+// ordinal (every Monday of the month) aren't holiday rules and are not supported.
+func parseNthWeekday(value string) (nthWeekday, bool) {
+	if len(value) < 3 {
+		return nthWeekday{}, false
+	}
+	weekday, ok := icsWeekdays[value[len(value)-2:]]
+	if !ok {
+		return nthWeekday{}, false
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(value[:len(value)-2], "+"))
+	if err != nil || n == 0 || n < -5 || n > 5 {
+		return nthWeekday{}, false
+	}
+	return nthWeekday{n: n, weekday: weekday}, true
+}
+
+// yearlyDates returns the dates a yearly rule selects in a year, in order. Dates that
+// don't exist in that year (Feb 29, a fifth Monday) are skipped.
+func yearlyDates(year int, months []time.Month, weekdays []nthWeekday, monthDays []int) []time.Time {
+	var dates []time.Time
+	for _, month := range months {
+		first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+		length := first.AddDate(0, 1, -1).Day()
+		if len(weekdays) > 0 {
+			for _, wd := range weekdays {
+				var day int
+				if wd.n > 0 {
+					day = 1 + (int(wd.weekday)-int(first.Weekday())+7)%7 + (wd.n-1)*7
+				} else {
+					last := time.Date(year, month, length, 0, 0, 0, 0, time.UTC)
+					day = length - (int(last.Weekday())-int(wd.weekday)+7)%7 + (wd.n+1)*7
+				}
+				if day >= 1 && day <= length {
+					dates = append(dates, time.Date(year, month, day, 0, 0, 0, 0, time.UTC))
+				}
+			}
+			continue
+		}
+		for _, day := range monthDays {
+			if day < 0 {
+				day = length + 1 + day
+			}
+			if day >= 1 && day <= length {
+				dates = append(dates, time.Date(year, month, day, 0, 0, 0, 0, time.UTC))
+			}
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	return dates
+}
+
+// parseICSDate returns the calendar date of a DATE or DATE-TIME value and whether it was a DATE
+// Times keep their own calendar date (TZID/UTC offsets are not applied)
+func parseICSDate(prop icsProperty) (time.Time, bool, error) {
+	value := strings.TrimSpace(prop.value)
+	if len(value) < 8 {
+		return time.Time{}, false, fmt.Errorf("too short")
+	}
+	date, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	allDay := prop.params["VALUE"] == "DATE" || len(value) == 8
+	return date, allDay, nil
+}
+
+func isMidnight(value string) bool {
+	_, clock, ok := strings.Cut(value, "T")
+	return ok && strings.HasPrefix(clock, "000000")
+}
+
+// parseDurationDays returns the whole days in a DURATION like "P1D" or "P2W" (0 if none)
+func parseDurationDays(value string) int {
+	value = strings.TrimPrefix(strings.ToUpper(value), "P")
+	value, _, _ = strings.Cut(value, "T")
+	if n, ok := strings.CutSuffix(value, "W"); ok {
+		weeks, _ := strconv.Atoi(n)
+		return weeks * 7
+	}
+	if n, ok := strings.CutSuffix(value, "D"); ok {
+		days, _ := strconv.Atoi(n)
+		return days
+	}
+	return 0
+}
+
+func parseRRule(value string) map[string]string {
+	rule := make(map[string]string)
+	for _, part := range strings.Split(value, ";") {
+		if key, val, ok := strings.Cut(part, "="); ok {
+			rule[strings.ToUpper(key)] = strings.ToUpper(val)
+		}
+	}
+	return rule
+}
+
+func unescapeText(value string) string {
+	replacer := strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return strings.TrimSpace(replacer.Replace(value))
+}
@@ -0,0 +1,104 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseICSYearly(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		event    string
+		want     []string // Start dates
+		warnings int
+	}{
+		{
+			name:  "old DTSTART reaches the current year",
+			event: "DTSTART;VALUE=DATE:20100101\nRRULE:FREQ=YEARLY",
+			want:  []string{"2026-01-01", "2027-01-01", "2028-01-01", "2029-01-01", "2030-01-01", "2031-01-01"},
+		},
+		{
+			name:  "thanksgiving",
+			event: "DTSTART;VALUE=DATE:20101125\nRRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=4TH",
+			want:  []string{"2025-11-27", "2026-11-26", "2027-11-25", "2028-11-23", "2029-11-22", "2030-11-28", "2031-11-27"},
+		},
+		{
+			name:  "last monday of may",
+			event: "DTSTART;VALUE=DATE:20250526\nRRULE:FREQ=YEARLY;BYMONTH=5;BYDAY=-1MO;COUNT=3",
+			want:  []string{"2026-05-25", "2027-05-31"},
+		},
+		{
+			name:  "count counts occurrences before the window",
+			event: "DTSTART;VALUE=DATE:20200704\nRRULE:FREQ=YEARLY;COUNT=7",
+			want:  []string{"2026-07-04"},
+		},
+		{
+			name:  "until",
+			event: "DTSTART;VALUE=DATE:20251225\nRRULE:FREQ=YEARLY;UNTIL=20271231",
+			want:  []string{"2025-12-25", "2026-12-25", "2027-12-25"},
+		},
+		{
+			name:  "interval",
+			event: "DTSTART;VALUE=DATE:20240101\nRRULE:FREQ=YEARLY;INTERVAL=2;COUNT=3",
+			want:  []string{"2026-01-01", "2028-01-01"},
+		},
+		{
+			name:  "leap day is skipped in other years",
+			event: "DTSTART;VALUE=DATE:20240229\nRRULE:FREQ=YEARLY",
+			want:  []string{"2028-02-29"},
+		},
+		{
+			name:     "byday without ordinal is rejected",
+			event:    "DTSTART;VALUE=DATE:20250106\nRRULE:FREQ=YEARLY;BYMONTH=1;BYDAY=MO",
+			warnings: 1,
+		},
+		{
+			name:     "byweekno is rejected",
+			event:    "DTSTART;VALUE=DATE:20250106\nRRULE:FREQ=YEARLY;BYWEEKNO=2",
+			warnings: 1,
+		},
+		{
+			name:     "monthly imports its first occurrence",
+			event:    "DTSTART;VALUE=DATE:20260105\nRRULE:FREQ=MONTHLY",
+			want:     []string{"2026-01-05"},
+			warnings: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ics := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Holiday\n" + tt.event + "\nEND:VEVENT\nEND:VCALENDAR\n"
+			entries, warnings, err := parseICS(strings.NewReader(ics), now)
+			if err != nil {
+				t.Fatalf("parseICS: %v", err)
+			}
+			if len(warnings) != tt.warnings {
+				t.Errorf("warnings = %v, want %d", warnings, tt.warnings)
+			}
+			var got []string
+			for _, entry := range entries {
+				got = append(got, entry.StartDate)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("dates = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseICSMultiDay(t *testing.T) {
+	ics := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Break\nDTSTART;VALUE=DATE:20251224\nDTEND;VALUE=DATE:20251227\nRRULE:FREQ=YEARLY;COUNT=2\nEND:VEVENT\nEND:VCALENDAR\n"
+	entries, _, err := parseICS(strings.NewReader(ics), time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("parseICS: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if entries[1].StartDate != "2026-12-24" || entries[1].EndDate != "2026-12-26" {
+		t.Errorf("second entry = %s..%s, want 2026-12-24..2026-12-26", entries[1].StartDate, entries[1].EndDate)
+	}
+}
@@ -23,11 +23,42 @@ type Config struct {
 	Discovery_enabled  bool
 	Discovery_interval int // Discovery interval in seconds
 
+	// Scheduler settings
+	Reconcile_enabled  bool
+	Reconcile_interval int // Reconcile interval in minutes
+	Action_timeout     int // Minutes a start/stop may take to reach its target state (0 disables tracking)
+
+	// Activity guard settings (defer scheduled stops of databases in use)
+	Activity_guard_enabled    bool
+	Activity_max_connections  int // Defer while connections are above this
+	Activity_max_cpu_percent  int // Defer while CPU utilization is above this
+	Activity_recheck_interval int // Re-check interval in minutes
+	Activity_max_delay        int // Maximum deferral in minutes
+
+	// Idle policy settings (auto-sleep after inactivity)
+	Idle_policy_interval int // Evaluation interval in minutes (0 disables idle policies)
+
+	// Maintenance settings
+	Maintenance_wake_lead int // Minutes before a maintenance window with pending maintenance to wake stopped instances (0 disables)
+
+	// Wake proxy settings (cmd/wakeproxy)
+	Wakeproxy_routes       string // Comma-separated "listen=instance@upstream" routes
+	Wakeproxy_wake_timeout int    // Minutes a client is held while its instance wakes
+	Wakeproxy_keepalive    int    // Minutes connection traffic keeps the instance awake (0 disables)
+
+	// Leader election settings (for running multiple replicas)
+	Leader_election_enabled bool
+	Leader_lease_seconds    int
+
 	// Notification settings
 	Slack_webhook_url string
 	Slack_app_token   string
 	Pre_stop_minutes  int
 
+	// Signed action links (e.g. "extend 1 hour" in pre-stop warnings)
+	Public_url            string
+	Action_signing_secret string
+
 	// Aggregation settings
 	Aggregation_enabled   bool
 	Aggregation_threshold int
@@ -56,10 +87,39 @@ func Load() (*Config, error) {
 	cfg.Discovery_enabled = getEnvBool("DISCOVERY_ENABLED", true)
 	cfg.Discovery_interval = getEnvInt("DISCOVERY_INTERVAL_SECONDS", 30)
 
+	// Scheduler settings
+	cfg.Reconcile_enabled = getEnvBool("RECONCILE_ENABLED", true)
+	cfg.Reconcile_interval = getEnvInt("RECONCILE_INTERVAL_MINUTES", 5)
+	cfg.Action_timeout = getEnvInt("ACTION_TIMEOUT_MINUTES", 20)
+
+	// Activity guard settings (off by default, so upgrading doesn't change when stops happen)
+	cfg.Activity_guard_enabled = getEnvBool("ACTIVITY_GUARD_ENABLED", false)
+	cfg.Activity_max_connections = getEnvInt("ACTIVITY_MAX_CONNECTIONS", 0)
+	cfg.Activity_max_cpu_percent = getEnvInt("ACTIVITY_MAX_CPU_PERCENT", 10)
+	cfg.Activity_recheck_interval = getEnvInt("ACTIVITY_RECHECK_MINUTES", 5)
+	cfg.Activity_max_delay = getEnvInt("ACTIVITY_MAX_DELAY_MINUTES", 60)
+
+	// Idle policy settings
+	cfg.Idle_policy_interval = getEnvInt("IDLE_POLICY_INTERVAL_MINUTES", 5)
+
+	// Maintenance settings
+	cfg.Maintenance_wake_lead = getEnvInt("MAINTENANCE_WAKE_LEAD_MINUTES", 0)
+
+	// Wake proxy settings
+	cfg.Wakeproxy_routes = getEnv("WAKEPROXY_ROUTES", "")
+	cfg.Wakeproxy_wake_timeout = getEnvInt("WAKEPROXY_WAKE_TIMEOUT_MINUTES", 15)
+	cfg.Wakeproxy_keepalive = getEnvInt("WAKEPROXY_KEEPALIVE_MINUTES", 30)
+
+	// Leader election settings
+	cfg.Leader_election_enabled = getEnvBool("LEADER_ELECTION_ENABLED", true)
+	cfg.Leader_lease_seconds = getEnvInt("LEADER_LEASE_SECONDS", 15)
+
 	// Notification settings
 	cfg.Slack_webhook_url = getEnv("SLACK_WEBHOOK_URL", "")
 	cfg.Slack_app_token = getEnv("SLACK_APP_TOKEN", "")
 	cfg.Pre_stop_minutes = getEnvInt("PRE_STOP_MINUTES", 10)
+	cfg.Public_url = getEnv("PUBLIC_URL", "http://localhost:8080")
+	cfg.Action_signing_secret = getEnv("ACTION_SIGNING_SECRET", "")
 
 	// Aggregation settings
 	cfg.Aggregation_enabled = getEnvBool("AGGREGATION_ENABLED", true)
@@ -108,5 +168,9 @@ func (c *Config) Validate() error {
 	if c.Database_url == "" {
 		return fmt.Errorf("DATABASE_URL is required")
 	}
+	// Extend links must verify on every replica and after restarts
+	if c.Slack_webhook_url != "" && c.Pre_stop_minutes > 0 && c.Action_signing_secret == "" {
+		return fmt.Errorf("ACTION_SIGNING_SECRET is required when SLACK_WEBHOOK_URL is set")
+	}
 	return nil
 }
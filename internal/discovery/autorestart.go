@@ -0,0 +1,95 @@
+package discovery
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"snoozeql/internal/models"
+)
+
+// awsAutoStartAfter is how long AWS lets an RDS instance stay stopped before it starts
+// it again. A start sooner after a recorded stop was done by someone, not by AWS.
+const awsAutoStartAfter = 7*24*time.Hour - time.Hour
+
+// instanceKey identifies an instance across discovery runs before it has a database ID
+func instanceKey(instance models.Instance) string {
+	return instance.Provider + "/" + instance.CloudAccountID + "/" + instance.ProviderID
+}
+
+// previousStatuses returns the stored status of every known instance by instanceKey
+func (d *DiscoveryService) previousStatuses(ctx context.Context) map[string]string {
+	statuses := make(map[string]string)
+	if d.instanceStore == nil {
+		return statuses
+	}
+	instances, err := d.instanceStore.ListInstances(ctx)
+	if err != nil {
+		log.Printf("Warning: Failed to load instance statuses: %v", err)
+		return statuses
+	}
+	for _, instance := range instances {
+		statuses[instanceKey(instance)] = instance.Status
+	}
+	return statuses
+}
+
+// detectAutoRestart records an aws_auto_restart event when an RDS instance went from
+// stopped to starting/available and its latest state event is a stop recorded long
+// enough ago for AWS to have started it, i.e. SnoozeQL stopped it and nobody in SnoozeQL
+// started it since. Without a recorded stop the start is left alone: the instance may
+// have been stopped and started outside SnoozeQL. The scheduler re-stops auto-restarted
+// instances while their schedule wants them asleep. Paused Redshift clusters are never
+// resumed by AWS.
+func (d *DiscoveryService) detectAutoRestart(ctx context.Context, instance models.Instance, previous string) {
+	if d.eventStore == nil || instance.Provider != "aws" || previous != "stopped" {
+		return
+	}
+	if strings.HasPrefix(instance.ProviderID, models.RedshiftIDPrefix) {
+		return
+	}
+	if instance.Status != "starting" && instance.Status != "available" {
+		return
+	}
+
+	latest, err := d.eventStore.LatestStateEvent(ctx, instance.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Printf("%s started without a recorded stop - not an AWS auto-restart", instance.Name)
+		return
+	}
+	if err != nil {
+		log.Printf("Warning: Failed to check events for %s: %v", instance.Name, err)
+		return
+	}
+	if latest.EventType != "sleep" && latest.EventType != "stop" {
+		// Started by a user, schedule or the wake proxy, or already detected
+		return
+	}
+	stoppedFor := time.Since(latest.CreatedAt)
+	if stoppedFor < awsAutoStartAfter {
+		log.Printf("%s started outside SnoozeQL after %s stopped - not an AWS auto-restart", instance.Name, stoppedFor.Round(time.Minute))
+		return
+	}
+	metadata := map[string]any{
+		"stopped_at":   latest.CreatedAt,
+		"stopped_days": int(stoppedFor.Hours() / 24),
+	}
+
+	log.Printf("Detected AWS auto-restart of %s (%s -> %s)", instance.Name, previous, instance.Status)
+	eventMetadata, _ := json.Marshal(metadata)
+	event := &models.Event{
+		InstanceID:     instance.ID,
+		EventType:      "aws_auto_restart",
+		TriggeredBy:    "aws",
+		PreviousStatus: previous,
+		NewStatus:      instance.Status,
+		Metadata:       eventMetadata,
+	}
+	if err := d.eventStore.CreateEvent(ctx, event); err != nil {
+		log.Printf("Warning: Failed to create aws_auto_restart event for %s: %v", instance.Name, err)
+	}
+}
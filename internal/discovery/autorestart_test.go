@@ -0,0 +1,90 @@
+package discovery
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"snoozeql/internal/models"
+)
+
+// fakeEvents keeps created events in memory and serves a fixed latest state event
+type fakeEvents struct {
+	latest  *models.Event
+	err     error
+	created []models.Event
+}
+
+func (f *fakeEvents) CreateEvent(ctx context.Context, event *models.Event) error {
+	f.created = append(f.created, *event)
+	return nil
+}
+
+func (f *fakeEvents) LatestStateEvent(ctx context.Context, instanceID string) (*models.Event, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.latest == nil {
+		return nil, sql.ErrNoRows
+	}
+	return f.latest, nil
+}
+
+func TestDetectAutoRestart(t *testing.T) {
+	daysAgo := func(days float64) time.Time { return time.Now().Add(-time.Duration(days * 24 * float64(time.Hour))) }
+	rds := models.Instance{ID: "i-1", Name: "orders", Provider: "aws", ProviderID: "orders", Status: "available"}
+
+	tests := []struct {
+		name     string
+		instance models.Instance
+		previous string
+		latest   *models.Event
+		err      error
+		want     bool
+	}{
+		{name: "stopped a week ago", instance: rds, previous: "stopped", latest: &models.Event{EventType: "sleep", CreatedAt: daysAgo(7)}, want: true},
+		{name: "stopped manually a week ago", instance: rds, previous: "stopped", latest: &models.Event{EventType: "stop", CreatedAt: daysAgo(7.5)}, want: true},
+		{name: "stopped recently", instance: rds, previous: "stopped", latest: &models.Event{EventType: "sleep", CreatedAt: daysAgo(2)}},
+		{name: "no recorded stop", instance: rds, previous: "stopped"},
+		{name: "events unavailable", instance: rds, previous: "stopped", err: errors.New("connection refused")},
+		{name: "woken by SnoozeQL", instance: rds, previous: "stopped", latest: &models.Event{EventType: "wake", CreatedAt: daysAgo(8)}},
+		{name: "already detected", instance: rds, previous: "stopped", latest: &models.Event{EventType: "aws_auto_restart", CreatedAt: daysAgo(8)}},
+		{name: "was not stopped", instance: rds, previous: "starting", latest: &models.Event{EventType: "sleep", CreatedAt: daysAgo(7)}},
+		{
+			name:     "still stopped",
+			instance: models.Instance{ID: "i-1", Name: "orders", Provider: "aws", ProviderID: "orders", Status: "stopped"},
+			previous: "stopped",
+			latest:   &models.Event{EventType: "sleep", CreatedAt: daysAgo(7)},
+		},
+		{
+			name:     "Redshift cluster",
+			instance: models.Instance{ID: "i-1", Name: "warehouse", Provider: "aws", ProviderID: models.RedshiftIDPrefix + "warehouse", Status: "available"},
+			previous: "stopped",
+			latest:   &models.Event{EventType: "sleep", CreatedAt: daysAgo(7)},
+		},
+		{
+			name:     "other provider",
+			instance: models.Instance{ID: "i-1", Name: "orders", Provider: "gcp", ProviderID: "orders", Status: "available"},
+			previous: "stopped",
+			latest:   &models.Event{EventType: "sleep", CreatedAt: daysAgo(7)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := &fakeEvents{latest: tt.latest, err: tt.err}
+			d := NewDiscoveryService(nil, nil, nil, events, true, 30, nil)
+
+			d.detectAutoRestart(context.Background(), tt.instance, tt.previous)
+
+			if got := len(events.created) == 1 && events.created[0].EventType == "aws_auto_restart"; got != tt.want || len(events.created) > 1 {
+				t.Fatalf("created %+v, want auto-restart = %v", events.created, tt.want)
+			}
+			if tt.want && events.created[0].Metadata == nil {
+				t.Error("auto-restart event has no stop metadata")
+			}
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"snoozeql/internal/models"
 	"snoozeql/internal/provider"
 	"snoozeql/internal/store"
+	"snoozeql/internal/tracker"
 )
 
 // CloudAccountProvider represents a cloud account with its provider
@@ -24,6 +25,7 @@ type CloudAccountProvider struct {
 // EventCreator interface for event operations needed by DiscoveryService
 type EventCreator interface {
 	CreateEvent(ctx context.Context, event *models.Event) error
+	LatestStateEvent(ctx context.Context, instanceID string) (*models.Event, error)
 }
 
 // DiscoveryService manages database instance discovery
@@ -37,6 +39,7 @@ type DiscoveryService struct {
 	instanceStore *store.InstanceStore
 	accountStore  *store.CloudAccountStore
 	eventStore    EventCreator
+	tracker       *tracker.Tracker
 	mu            sync.RWMutex
 }
 
@@ -53,6 +56,11 @@ func NewDiscoveryService(registry *provider.Registry, instanceStore *store.Insta
 	}
 }
 
+// SetTracker makes manual start/stop calls retry transient errors and record their outcome
+func (d *DiscoveryService) SetTracker(t *tracker.Tracker) {
+	d.tracker = t
+}
+
 // IsEnabled returns whether discovery is enabled
 func (d *DiscoveryService) IsEnabled() bool {
 	return d.enabled
@@ -160,6 +168,7 @@ func (d *DiscoveryService) Run(ctx context.Context) error {
 	syncCount := 0
 	var syncErrors []error
 	if d.instanceStore != nil {
+		previous := d.previousStatuses(ctx)
 		for _, instance := range instances {
 			fmt.Printf("DEBUG: Syncing instance: Name=%s, Provider=%s, ProviderName=%s, ProviderID=%s, Status=%s\n", instance.Name, instance.Provider, instance.ProviderName, instance.ProviderID, instance.Status)
 			if err := d.instanceStore.UpsertInstance(ctx, &instance); err != nil {
@@ -167,6 +176,7 @@ func (d *DiscoveryService) Run(ctx context.Context) error {
 				fmt.Printf("DEBUG: Failed to sync instance %s: %v\n", instance.Name, err)
 			} else {
 				syncCount++
+				d.detectAutoRestart(ctx, instance, previous[instanceKey(instance)])
 			}
 		}
 	}
@@ -229,7 +239,10 @@ func (d *DiscoveryService) StartDatabase(ctx context.Context, providerName strin
 	instance, getErr := d.instanceStore.GetInstanceByProviderID(ctx, "", id)
 	var prevStatus string
 	var instanceUUID string
-	if getErr == nil {
+	if getErr == nil && instance != nil {
+		if instance.ClusterID != "" {
+			return fmt.Errorf("%s is a member of cluster %s and can't be started on its own - start the cluster instead", instance.Name, instance.ClusterID)
+		}
 		prevStatus = instance.Status
 		instanceUUID = instance.ID
 	}
@@ -254,6 +267,14 @@ func (d *DiscoveryService) StartDatabase(ctx context.Context, providerName strin
 		log.Printf("DEBUG: No instance UUID found for %s, cannot create start event", id)
 	}
 
+	// Track the action when the instance is known so its outcome is recorded
+	if d.tracker != nil && instanceUUID != "" {
+		tracked := *instance
+		tracked.ProviderName = providerName
+		tracked.ProviderID = id
+		return d.tracker.Execute(context.WithoutCancel(ctx), tracked, "start", "manual", nil)
+	}
+
 	err = provider.StartDatabase(ctx, id)
 	return err
 }
@@ -269,7 +290,10 @@ func (d *DiscoveryService) StopDatabase(ctx context.Context, providerName string
 	instance, getErr := d.instanceStore.GetInstanceByProviderID(ctx, "", id)
 	var prevStatus string
 	var instanceUUID string
-	if getErr == nil {
+	if getErr == nil && instance != nil {
+		if instance.ClusterID != "" {
+			return fmt.Errorf("%s is a member of cluster %s and can't be stopped on its own - stop the cluster instead", instance.Name, instance.ClusterID)
+		}
 		prevStatus = instance.Status
 		instanceUUID = instance.ID
 	}
@@ -294,6 +318,14 @@ func (d *DiscoveryService) StopDatabase(ctx context.Context, providerName string
 		log.Printf("DEBUG: No instance UUID found for %s, cannot create stop event", id)
 	}
 
+	// Track the action when the instance is known so its outcome is recorded
+	if d.tracker != nil && instanceUUID != "" {
+		tracked := *instance
+		tracked.ProviderName = providerName
+		tracked.ProviderID = id
+		return d.tracker.Execute(context.WithoutCancel(ctx), tracked, "stop", "manual", nil)
+	}
+
 	err = provider.StopDatabase(ctx, id)
 	return err
 }
@@ -0,0 +1,135 @@
+package leader
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"snoozeql/internal/store"
+)
+
+// Elector runs background jobs only while this replica holds a Postgres lease.
+// Non-leaders poll for the lease every renew interval, so a dead leader is replaced
+// within roughly one TTL; a leader that shuts down cleanly releases it immediately.
+type Elector struct {
+	leaseStore    *store.LeaseStore
+	name          string
+	holder        string
+	ttl           time.Duration
+	renewInterval time.Duration
+}
+
+// NewElector creates an elector for the named lease
+// The lease is renewed every ttl/3, so a leader survives two failed renewals
+func NewElector(leaseStore *store.LeaseStore, name string, ttl time.Duration) *Elector {
+	return &Elector{
+		leaseStore:    leaseStore,
+		name:          name,
+		holder:        HolderID(),
+		ttl:           ttl,
+		renewInterval: ttl / 3,
+	}
+}
+
+// HolderID returns an identifier for this process (hostname, pid and a random suffix)
+func HolderID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(suffix))
+}
+
+// Run campaigns for the lease until ctx is done. Each time leadership is gained,
+// every job is started in its own goroutine with a context that is cancelled when
+// leadership is lost. Jobs must return when their context is cancelled; Run waits
+// for them before campaigning again so two copies never run in one process.
+func (e *Elector) Run(ctx context.Context, jobs ...func(ctx context.Context)) {
+	log.Printf("Leader election for %q starting (holder %s, ttl %s)", e.name, e.holder, e.ttl)
+
+	ticker := time.NewTicker(e.renewInterval)
+	defer ticker.Stop()
+
+	for {
+		if e.acquire(ctx) {
+			e.lead(ctx, ticker, jobs)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lead runs the jobs and renews the lease until it is lost or ctx is done
+func (e *Elector) lead(ctx context.Context, ticker *time.Ticker, jobs []func(ctx context.Context)) {
+	log.Printf("Acquired leadership for %q", e.name)
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func(job func(ctx context.Context)) {
+			defer wg.Done()
+			job(leaderCtx)
+		}(job)
+	}
+
+	// The lease stays valid for a full TTL after the last successful renewal, so a
+	// failed renewal (e.g. a database blip) only costs leadership once we can no
+	// longer be sure the lease is ours
+	deadline := time.Now().Add(e.ttl)
+	for leaderCtx.Err() == nil {
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-ticker.C:
+			held, err := e.leaseStore.TryAcquire(ctx, e.name, e.holder, e.ttl)
+			switch {
+			case err == nil && held:
+				deadline = time.Now().Add(e.ttl)
+			case err == nil:
+				log.Printf("Lost leadership for %q to another replica", e.name)
+				cancel()
+			case time.Now().After(deadline.Add(-e.renewInterval)):
+				log.Printf("Lost leadership for %q: lease renewal failing: %v", e.name, err)
+				cancel()
+			default:
+				log.Printf("Warning: Lease %q renewal failed: %v", e.name, err)
+			}
+		}
+	}
+
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		// Shutting down: hand the lease over right away
+		releaseCtx, releaseCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer releaseCancel()
+		if err := e.leaseStore.Release(releaseCtx, e.name, e.holder); err != nil {
+			log.Printf("Warning: Failed to release lease %q: %v", e.name, err)
+		}
+	}
+}
+
+// acquire tries to acquire or renew the lease, treating errors as not holding it
+func (e *Elector) acquire(ctx context.Context) bool {
+	ok, err := e.leaseStore.TryAcquire(ctx, e.name, e.holder, e.ttl)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Warning: Lease %q renewal failed: %v", e.name, err)
+		}
+		return false
+	}
+	return ok
+}
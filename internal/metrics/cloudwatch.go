@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -19,8 +20,9 @@ import (
 
 // CloudWatchClient wraps the AWS CloudWatch client for RDS metrics
 type CloudWatchClient struct {
-	client *cloudwatch.Client
-	region string
+	client    *cloudwatch.Client
+	region    string
+	namespace string // AWS/RDS, unless picked with ForEngine
 }
 
 // NewCloudWatchClient creates a new CloudWatch client with credentials
@@ -41,8 +43,9 @@ func NewCloudWatchClient(region, accessKey, secretKey string) (*CloudWatchClient
 	}
 
 	return &CloudWatchClient{
-		client: cloudwatch.NewFromConfig(cfg),
-		region: region,
+		client:    cloudwatch.NewFromConfig(cfg),
+		region:    region,
+		namespace: metricNamespace(""),
 	}, nil
 }
 
@@ -175,6 +178,49 @@ func (c *CloudWatchClient) getMetricWithRetry(ctx context.Context, dbInstanceID,
 	return nil, fmt.Errorf("failed after 3 retries: %w", lastErr)
 }
 
+// metricNamespace returns the CloudWatch namespace an engine publishes its metrics to
+func metricNamespace(engine string) string {
+	switch engine {
+	case "redshift":
+		return "AWS/Redshift"
+	case "docdb":
+		return "AWS/DocDB"
+	case "neptune":
+		return "AWS/Neptune"
+	default:
+		return "AWS/RDS"
+	}
+}
+
+// ForEngine returns a client reading the metrics of instances with the given engine
+// from its namespace, sharing the underlying CloudWatch client
+func (c *CloudWatchClient) ForEngine(engine string) *CloudWatchClient {
+	clone := *c
+	clone.namespace = metricNamespace(engine)
+	return &clone
+}
+
+// dbDimension returns the CloudWatch dimension of an instance, or of a cluster for
+// cluster provider IDs ("cluster:{identifier}", "redshift:{identifier}")
+func dbDimension(id string) types.Dimension {
+	if cluster, ok := strings.CutPrefix(id, models.RedshiftIDPrefix); ok {
+		return types.Dimension{
+			Name:  aws.String("ClusterIdentifier"),
+			Value: aws.String(cluster),
+		}
+	}
+	if cluster, ok := strings.CutPrefix(id, models.ClusterIDPrefix); ok {
+		return types.Dimension{
+			Name:  aws.String("DBClusterIdentifier"),
+			Value: aws.String(cluster),
+		}
+	}
+	return types.Dimension{
+		Name:  aws.String("DBInstanceIdentifier"),
+		Value: aws.String(id),
+	}
+}
+
 // getMetric fetches a single CloudWatch metric
 func (c *CloudWatchClient) getMetric(ctx context.Context, dbInstanceID, metricName string, start, end time.Time) (*MetricValue, error) {
 	// Write to file to verify function is called
@@ -187,17 +233,12 @@ func (c *CloudWatchClient) getMetric(ctx context.Context, dbInstanceID, metricNa
 	log.Printf("DEBUG: getMetric called for %s DBInstanceID: %s", metricName, dbInstanceID)
 
 	input := &cloudwatch.GetMetricStatisticsInput{
-		Namespace:  aws.String("AWS/RDS"),
+		Namespace:  aws.String(c.namespace),
 		MetricName: aws.String(metricName),
-		Dimensions: []types.Dimension{
-			{
-				Name:  aws.String("DBInstanceIdentifier"),
-				Value: aws.String(dbInstanceID),
-			},
-		},
-		StartTime: aws.Time(start),
-		EndTime:   aws.Time(end),
-		Period:    aws.Int32(3600), // 1 hour
+		Dimensions: []types.Dimension{dbDimension(dbInstanceID)},
+		StartTime:  aws.Time(start),
+		EndTime:    aws.Time(end),
+		Period:     aws.Int32(3600), // 1 hour
 		Statistics: []types.Statistic{
 			types.StatisticAverage,
 			types.StatisticMaximum,
@@ -246,17 +287,12 @@ type MetricValueWithTimestamp struct {
 // Returns all datapoints (not just the most recent) for use with 5-minute periods
 func (c *CloudWatchClient) getMetricMultiple(ctx context.Context, dbInstanceID, metricName string, start, end time.Time) ([]MetricValueWithTimestamp, error) {
 	input := &cloudwatch.GetMetricStatisticsInput{
-		Namespace:  aws.String("AWS/RDS"),
+		Namespace:  aws.String(c.namespace),
 		MetricName: aws.String(metricName),
-		Dimensions: []types.Dimension{
-			{
-				Name:  aws.String("DBInstanceIdentifier"),
-				Value: aws.String(dbInstanceID),
-			},
-		},
-		StartTime: aws.Time(start),
-		EndTime:   aws.Time(end),
-		Period:    aws.Int32(300), // 5 minutes
+		Dimensions: []types.Dimension{dbDimension(dbInstanceID)},
+		StartTime:  aws.Time(start),
+		EndTime:    aws.Time(end),
+		Period:     aws.Int32(300), // 5 minutes
 		Statistics: []types.Statistic{
 			types.StatisticAverage,
 			types.StatisticMaximum,
@@ -303,7 +303,8 @@ func (c *MetricsCollector) storeZeroMetrics(ctx context.Context, instance models
 	return nil
 }
 
-// getClient returns or creates a CloudWatch client for the instance's account/region
+// getClient returns or creates a CloudWatch client for the instance's account/region,
+// reading from the namespace of the instance's engine
 func (c *MetricsCollector) getClient(ctx context.Context, instance models.Instance) (*CloudWatchClient, error) {
 	// Only AWS instances are supported for active metric collection
 	if instance.Provider != "aws" {
@@ -318,7 +319,7 @@ func (c *MetricsCollector) getClient(ctx context.Context, instance models.Instan
 	c.clientsMu.RUnlock()
 
 	if exists {
-		return client, nil
+		return client.ForEngine(instance.Engine), nil
 	}
 
 	// Create new client - need to get credentials from account store
@@ -369,7 +370,7 @@ func (c *MetricsCollector) getClient(ctx context.Context, instance models.Instan
 	c.clients[key] = client
 	c.clientsMu.Unlock()
 
-	return client, nil
+	return client.ForEngine(instance.Engine), nil
 }
 
 // SetEnabled enables or disables the collector
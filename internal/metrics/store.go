@@ -2,6 +2,8 @@ package metrics
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
@@ -285,6 +287,36 @@ func (s *MetricsStore) GetMinuteMetricsByInstance(ctx context.Context, instanceI
 	return metrics, nil
 }
 
+// GetLatestMinuteMetrics returns the most recent 5-minute metric at or after since
+// for each of the given metric names, keyed by metric name. Metrics with no data in
+// that window are left out.
+func (s *MetricsStore) GetLatestMinuteMetrics(ctx context.Context, instanceID string, since time.Time, metricNames ...string) (map[string]models.MinuteMetric, error) {
+	latest := make(map[string]models.MinuteMetric)
+	for _, name := range metricNames {
+		query := `
+			SELECT id, instance_id, metric_name, minute, avg_value, max_value, min_value, sample_count, created_at, updated_at
+			FROM metrics_5min
+			WHERE instance_id = $1 AND metric_name = $2 AND minute >= $3
+			ORDER BY minute DESC
+			LIMIT 1`
+
+		var m models.MinuteMetric
+		err := s.db.QueryRow(ctx, query, instanceID, name, since).Scan(
+			&m.ID, &m.InstanceID, &m.MetricName, &m.Minute,
+			&m.AvgValue, &m.MaxValue, &m.MinValue, &m.SampleCount,
+			&m.CreatedAt, &m.UpdatedAt,
+		)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to query latest %s: %w", name, err)
+		}
+		latest[name] = m
+	}
+	return latest, nil
+}
+
 // GetInstanceIDs returns all instance UUIDs that have metrics data
 // Used by the analyzer to identify instances for recommendation generation
 func (s *MetricsStore) GetInstanceIDs(ctx context.Context) ([]string, error) {
@@ -4,16 +4,25 @@ import "time"
 
 // CloudAccount represents a configured cloud provider account
 type CloudAccount struct {
-	ID               string         `json:"id" db:"id"`
-	Name             string         `json:"name" db:"name"`
-	Provider         string         `json:"provider" db:"provider"`
-	Credentials      map[string]any `json:"-" db:"credentials"` // Not exposed in API
-	Regions          []string       `json:"regions" db:"regions"`
-	ConnectionStatus string         `json:"connection_status,omitempty" db:"connection_status"`
-	LastSyncAt       *time.Time     `json:"last_sync_at,omitempty" db:"last_sync_at"`
-	LastError        *string        `json:"last_error,omitempty" db:"last_error"`
-	DeletedAt        *time.Time     `json:"deleted_at,omitempty" db:"deleted_at"`
-	CreatedAt        time.Time      `json:"created_at" db:"created_at"`
+	ID               string           `json:"id" db:"id"`
+	Name             string           `json:"name" db:"name"`
+	Provider         string           `json:"provider" db:"provider"`
+	Credentials      map[string]any   `json:"-" db:"credentials"` // Not exposed in API
+	Regions          []string         `json:"regions" db:"regions"`
+	ManagedTags      []string         `json:"managed_tags,omitempty" db:"managed_tags"`           // Tag keys that opt instances in to being managed; empty manages all
+	DiscoveryFilters []ProviderFilter `json:"discovery_filters,omitempty" db:"discovery_filters"` // Server-side filters applied when listing instances
+	ConnectionStatus string           `json:"connection_status,omitempty" db:"connection_status"`
+	LastSyncAt       *time.Time       `json:"last_sync_at,omitempty" db:"last_sync_at"`
+	LastError        *string          `json:"last_error,omitempty" db:"last_error"`
+	DeletedAt        *time.Time       `json:"deleted_at,omitempty" db:"deleted_at"`
+	CreatedAt        time.Time        `json:"created_at" db:"created_at"`
+}
+
+// ProviderFilter is a server-side filter on the provider's list call, e.g. an RDS
+// DescribeDBInstances filter {"name": "engine", "values": ["postgres"]}
+type ProviderFilter struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
 }
 
 // Instance represents a discovered database instance
@@ -21,7 +30,7 @@ type Instance struct {
 	ID              string            `json:"id" db:"id"`
 	CloudAccountID  string            `json:"cloud_account_id" db:"cloud_account_id"`
 	AccountID       string            `json:"account_id" db:"account_id"`       // AWS account ID from provider (not stored in DB, used for mapping)
-	Provider        string            `json:"provider" db:"provider"`           // Cloud provider type: "aws" or "gcp"
+	Provider        string            `json:"provider" db:"provider"`           // Cloud provider type: "aws", "gcp", "azure" or "kubernetes"
 	ProviderName    string            `json:"provider_name" db:"provider_name"` // Full provider identifier: "aws_{accountID}_{region}"
 	ProviderID      string            `json:"provider_id" db:"provider_id"`
 	Name            string            `json:"name" db:"name"`
@@ -32,48 +41,160 @@ type Instance struct {
 	Managed         bool              `json:"managed" db:"managed"`
 	Tags            map[string]string `json:"tags" db:"tags"`
 	HourlyCostCents int               `json:"hourly_cost_cents" db:"hourly_cost_cents"`
-	CreatedAt       time.Time         `json:"created_at" db:"created_at"`
-	UpdatedAt       time.Time         `json:"updated_at" db:"updated_at"`
+	ClusterID       string            `json:"cluster_id,omitempty" db:"cluster_id"` // Provider ID of the cluster this instance is a member of; members are started/stopped with their cluster
+
+	// Provider maintenance, in UTC: backup "hh24:mi-hh24:mi", maintenance "ddd:hh24:mi-ddd:hh24:mi"
+	BackupWindow       string                     `json:"backup_window,omitempty" db:"backup_window"`
+	MaintenanceWindow  string                     `json:"maintenance_window,omitempty" db:"maintenance_window"`
+	PendingMaintenance []PendingMaintenanceAction `json:"pending_maintenance,omitempty" db:"pending_maintenance"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ClusterIDPrefix prefixes the provider ID of an instance that stands for a whole
+// cluster (e.g. an Aurora cluster), which is started and stopped at cluster level
+const ClusterIDPrefix = "cluster:"
+
+// RedshiftIDPrefix prefixes the provider ID of a Redshift cluster, which is paused
+// and resumed rather than stopped and started
+const RedshiftIDPrefix = "redshift:"
+
+// PendingMaintenanceAction is a maintenance action the provider has queued for an instance
+type PendingMaintenanceAction struct {
+	Action           string     `json:"action"` // e.g. "system-update", "db-upgrade"
+	Description      string     `json:"description,omitempty"`
+	AutoAppliedAfter *time.Time `json:"auto_applied_after,omitempty"`
+	ForcedApplyDate  *time.Time `json:"forced_apply_date,omitempty"`
+	CurrentApplyDate *time.Time `json:"current_apply_date,omitempty"` // When it will actually be applied, if scheduled
+	OptInStatus      string     `json:"opt_in_status,omitempty"`
 }
 
 // Schedule represents a sleep/wake schedule
 type Schedule struct {
-	ID          string     `json:"id" db:"id"`
-	Name        string     `json:"name" db:"name"`
-	Description string     `json:"description" db:"description"`
-	Selectors   []Selector `json:"selectors" db:"selectors"`
-	Timezone    string     `json:"timezone" db:"timezone"`
-	SleepCron   string     `json:"sleep_cron" db:"sleep_cron"`
-	WakeCron    string     `json:"wake_cron" db:"wake_cron"`
-	Enabled     bool       `json:"enabled" db:"enabled"`
-	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
-}
-
-// Selector defines matching criteria for dynamic schedule assignment
+	ID          string              `json:"id" db:"id"`
+	Name        string              `json:"name" db:"name"`
+	Description string              `json:"description" db:"description"`
+	Selectors   []Selector          `json:"selectors" db:"selectors"`
+	Operator    string              `json:"operator" db:"selector_operator"`               // How selectors combine: "or" (default) or "and"
+	Expression  string              `json:"expression,omitempty" db:"selector_expression"` // Selector expression, used instead of selectors when set
+	Exclusions  []Selector          `json:"exclusions" db:"exclusions"`                    // Instances matching any of these are never selected
+	Timezone    string              `json:"timezone" db:"timezone"`
+	SleepCron   string              `json:"sleep_cron" db:"sleep_cron"`
+	WakeCron    string              `json:"wake_cron" db:"wake_cron"`
+	Enabled     bool                `json:"enabled" db:"enabled"`
+	Exceptions  []ScheduleException `json:"exceptions" db:"exceptions"`
+	Ordering    *ScheduleOrdering   `json:"ordering,omitempty" db:"ordering"`
+	Budget      *ScheduleBudget     `json:"budget,omitempty" db:"budget"`
+	Priority    int                 `json:"priority" db:"priority"` // Higher wins when matching schedules disagree
+	CreatedAt   time.Time           `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at" db:"updated_at"`
+}
+
+// ScheduleException references an exception calendar from a schedule
+type ScheduleException struct {
+	CalendarID string `json:"calendar_id"`
+	Mode       string `json:"mode"` // "suppress_wake" or "force_sleep"
+}
+
+// Schedule exception modes
+const (
+	ExceptionSuppressWake = "suppress_wake" // Don't wake instances on calendar dates
+	ExceptionForceSleep   = "force_sleep"   // Keep instances asleep all day on calendar dates
+)
+
+// ScheduleOrdering orders a schedule's instances into tiers. Waking proceeds tier by
+// tier, waiting for each to become available; sleeping runs in reverse order.
+// Matching instances not selected by any tier form an implicit last tier.
+type ScheduleOrdering struct {
+	Tiers          []ScheduleTier `json:"tiers"`
+	TimeoutMinutes int            `json:"timeout_minutes,omitempty"` // Per-tier wait, default 15
+	FailurePolicy  string         `json:"failure_policy,omitempty"`  // "abort" (default) or "continue"
+}
+
+// ScheduleTier is one step of an ordered wake/sleep
+type ScheduleTier struct {
+	Name      string     `json:"name"`
+	Selectors []Selector `json:"selectors"`
+}
+
+// Tier failure policies
+const (
+	TierFailureAbort    = "abort"    // Skip the remaining tiers
+	TierFailureContinue = "continue" // Carry on with the next tier
+)
+
+// ScheduleBudget caps how long matching instances may be awake, whenever that is.
+// Time awake is counted from wake/sleep events in the schedule's timezone; once either
+// budget is used up the instance is stopped. A budget schedule needs no CRONs, but may
+// have them (e.g. to wake instances each morning).
+type ScheduleBudget struct {
+	DailyHours  float64 `json:"daily_hours,omitempty"`  // Per calendar day; 0 = no daily budget
+	WeeklyHours float64 `json:"weekly_hours,omitempty"` // Per Monday-Sunday week; 0 = no weekly budget
+}
+
+// ExceptionCalendar is a named set of dates (holidays, shutdown weeks)
+type ExceptionCalendar struct {
+	ID          string          `json:"id" db:"id"`
+	Name        string          `json:"name" db:"name"`
+	Description string          `json:"description" db:"description"`
+	Entries     []CalendarEntry `json:"entries" db:"entries"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// CalendarEntry is an inclusive range of calendar dates (YYYY-MM-DD)
+type CalendarEntry struct {
+	Name      string `json:"name,omitempty"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"` // Inclusive; defaults to start_date
+}
+
+// Selector defines matching criteria for dynamic schedule assignment.
+// All set criteria must match (AND within selector); Not inverts the result.
 type Selector struct {
-	Name     *Matcher            `json:"name,omitempty" db:"name"`
-	Provider *string             `json:"provider,omitempty" db:"provider"`
-	Region   *Matcher            `json:"region,omitempty" db:"region"`
-	Engine   *Matcher            `json:"engine,omitempty" db:"engine"`
-	Tags     map[string]*Matcher `json:"tags,omitempty" db:"tags"`
+	Name            *Matcher            `json:"name,omitempty" db:"name"`
+	Provider        *string             `json:"provider,omitempty" db:"provider"` // Cloud provider type, e.g. "aws" or "gcp"
+	Region          *Matcher            `json:"region,omitempty" db:"region"`
+	Engine          *Matcher            `json:"engine,omitempty" db:"engine"`
+	CloudAccount    *Matcher            `json:"cloud_account,omitempty" db:"cloud_account"` // Cloud account ID (or the provider's account ID when known)
+	Tags            map[string]*Matcher `json:"tags,omitempty" db:"tags"`
+	HourlyCostCents []NumberMatcher     `json:"hourly_cost_cents,omitempty" db:"hourly_cost_cents"` // All comparisons must hold
+	Not             bool                `json:"not,omitempty" db:"not"`
 }
 
 // Matcher defines how to match a string value
 type Matcher struct {
 	Pattern string    `json:"pattern" db:"pattern"`
 	Type    MatchType `json:"type" db:"type"`
+	Values  []string  `json:"values,omitempty" db:"values"` // Set for MatchIn
+	Not     bool      `json:"not,omitempty" db:"not"`       // Invert the match (a missing tag then matches)
 }
 
 // MatchType defines the matching strategy
 type MatchType string
 
 const (
-	MatchExact    MatchType = "exact"
-	MatchContains MatchType = "contains"
-	MatchPrefix   MatchType = "prefix"
-	MatchSuffix   MatchType = "suffix"
-	MatchRegex    MatchType = "regex"
+	MatchExact     MatchType = "exact"
+	MatchContains  MatchType = "contains"
+	MatchPrefix    MatchType = "prefix"
+	MatchSuffix    MatchType = "suffix"
+	MatchRegex     MatchType = "regex"
+	MatchIn        MatchType = "in"         // Equal to any of Values
+	MatchExists    MatchType = "exists"     // Tag is present (other fields: not empty)
+	MatchNotExists MatchType = "not_exists" // Tag is absent (other fields: empty)
+)
+
+// NumberMatcher compares a numeric value, e.g. {"op": "gte", "value": 100}
+type NumberMatcher struct {
+	Op    string `json:"op"` // "eq", "ne", "lt", "lte", "gt" or "gte"
+	Value int    `json:"value"`
+}
+
+// Selector operators: how a list of selectors combines
+const (
+	OperatorOr  = "or"  // Any selector must match
+	OperatorAnd = "and" // All selectors must match
 )
 
 // Recommendation represents a suggested schedule based on activity patterns
@@ -101,6 +222,62 @@ type Override struct {
 	Expired    bool       `json:"expired" db:"expired"`
 }
 
+// Override types
+const (
+	OverrideKeepAlive = "keep_alive" // Keep the instance awake until UntilTime
+	OverrideSkipNext  = "skip_next"  // Skip the next scheduled SkipAction
+)
+
+// IsActive reports whether the override is still in effect at the given time
+func (o *Override) IsActive(now time.Time) bool {
+	if o.Expired {
+		return false
+	}
+	return o.UntilTime == nil || o.UntilTime.After(now)
+}
+
+// ScheduledAction is a one-off start/stop at an absolute time, targeting a single
+// instance or every instance matching its selectors
+type ScheduledAction struct {
+	ID            string     `json:"id" db:"id"`
+	Name          string     `json:"name" db:"name"`
+	Action        string     `json:"action" db:"action"` // "start" or "stop"
+	ExecuteAt     time.Time  `json:"execute_at" db:"execute_at"`
+	InstanceID    *string    `json:"instance_id,omitempty" db:"instance_id"`
+	Selectors     []Selector `json:"selectors" db:"selectors"`
+	Status        string     `json:"status" db:"status"`
+	Reason        *string    `json:"reason,omitempty" db:"reason"`
+	CreatedBy     string     `json:"created_by" db:"created_by"`
+	ExecutedAt    *time.Time `json:"executed_at,omitempty" db:"executed_at"`
+	InstanceCount int        `json:"instance_count" db:"instance_count"` // Instances acted on
+	Error         *string    `json:"error,omitempty" db:"error"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Scheduled action statuses
+const (
+	ScheduledActionPending   = "pending"
+	ScheduledActionRunning   = "running"
+	ScheduledActionCompleted = "completed"
+	ScheduledActionFailed    = "failed"
+	ScheduledActionCancelled = "cancelled"
+)
+
+// IdlePolicy stops matching instances once they have been idle for a while,
+// independently of any schedule's CRONs
+type IdlePolicy struct {
+	ID             string     `json:"id" db:"id"`
+	Name           string     `json:"name" db:"name"`
+	Description    string     `json:"description" db:"description"`
+	Selectors      []Selector `json:"selectors" db:"selectors"`
+	MaxConnections float64    `json:"max_connections" db:"max_connections"` // Idle while peak connections stay at or below this
+	MaxCPUPercent  float64    `json:"max_cpu_percent" db:"max_cpu_percent"` // Idle while average CPU stays at or below this
+	IdleMinutes    int        `json:"idle_minutes" db:"idle_minutes"`       // How long the instance must be idle
+	Enabled        bool       `json:"enabled" db:"enabled"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
 // Event represents a start/stop event
 type Event struct {
 	ID             string    `json:"id" db:"id"`
@@ -0,0 +1,93 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"snoozeql/internal/models"
+)
+
+// Notifier sends user-facing notifications about upcoming scheduled actions
+type Notifier interface {
+	// NotifyPreStop warns that instances are about to be stopped by a schedule
+	NotifyPreStop(ctx context.Context, warning PreStopWarning) error
+}
+
+// PreStopWarning describes a scheduled sleep that is about to happen
+type PreStopWarning struct {
+	ScheduleID   string
+	ScheduleName string
+	StopTime     time.Time
+	Instances    []models.Instance
+}
+
+// extendLinkTTL is how long an extend link stays valid after it is sent
+const extendLinkTTL = 24 * time.Hour
+
+// ExtendPath is the (unauthenticated, signed) route extend links point at
+const ExtendPath = "/actions/extend"
+
+// LinkSigner builds and verifies signed "extend by an hour" links.
+// Links are opened from chat clients that can't send an API key, so they carry an
+// HMAC signature over the instance, the scheduled stop time and the link expiry instead.
+type LinkSigner struct {
+	baseURL string
+	secret  []byte
+}
+
+// NewLinkSigner creates a link signer for links rooted at baseURL (e.g. "https://snoozeql.example.com")
+func NewLinkSigner(baseURL, secret string) *LinkSigner {
+	return &LinkSigner{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		secret:  []byte(secret),
+	}
+}
+
+// ExtendURL returns a signed link that keeps the instance awake for an hour past stopAt
+func (l *LinkSigner) ExtendURL(instanceID string, stopAt time.Time, now time.Time) string {
+	expires := now.Add(extendLinkTTL).Unix()
+	at := stopAt.Unix()
+
+	query := url.Values{}
+	query.Set("instance_id", instanceID)
+	query.Set("at", strconv.FormatInt(at, 10))
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("sig", l.sign(instanceID, at, expires))
+	return l.baseURL + ExtendPath + "?" + query.Encode()
+}
+
+// VerifyExtend checks an extend link's query parameters
+// Returns the instance ID and the scheduled stop time the link was issued for
+func (l *LinkSigner) VerifyExtend(query url.Values, now time.Time) (string, time.Time, error) {
+	instanceID := query.Get("instance_id")
+	at, err := strconv.ParseInt(query.Get("at"), 10, 64)
+	if err != nil || instanceID == "" {
+		return "", time.Time{}, fmt.Errorf("malformed link")
+	}
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("malformed link")
+	}
+
+	expected := l.sign(instanceID, at, expires)
+	if !hmac.Equal([]byte(expected), []byte(query.Get("sig"))) {
+		return "", time.Time{}, fmt.Errorf("invalid signature")
+	}
+	if now.Unix() > expires {
+		return "", time.Time{}, fmt.Errorf("link has expired")
+	}
+	return instanceID, time.Unix(at, 0), nil
+}
+
+func (l *LinkSigner) sign(instanceID string, at, expires int64) string {
+	mac := hmac.New(sha256.New, l.secret)
+	fmt.Fprintf(mac, "extend|%s|%d|%d", instanceID, at, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
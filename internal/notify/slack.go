@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SlackNotifier posts notifications to a Slack incoming webhook.
+// Any HTTP endpoint that accepts the same JSON payload can stand in for Slack.
+type SlackNotifier struct {
+	webhookURL string
+	links      *LinkSigner
+	client     *http.Client
+}
+
+// NewSlackNotifier creates a Slack notifier
+// links may be nil, in which case warnings are sent without extend buttons
+func NewSlackNotifier(webhookURL string, links *LinkSigner) *SlackNotifier {
+	return &SlackNotifier{
+		webhookURL: webhookURL,
+		links:      links,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// slackMessage is the subset of the Slack webhook payload we use
+type slackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks,omitempty"`
+}
+
+type slackBlock struct {
+	Type      string        `json:"type"`
+	Text      *slackText    `json:"text,omitempty"`
+	Accessory *slackElement `json:"accessory,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackElement struct {
+	Type string     `json:"type"`
+	Text *slackText `json:"text,omitempty"`
+	URL  string     `json:"url,omitempty"`
+}
+
+// NotifyPreStop posts a warning listing the instances about to be stopped,
+// with an "Extend 1 hour" button per instance
+func (n *SlackNotifier) NotifyPreStop(ctx context.Context, warning PreStopWarning) error {
+	minutes := int(time.Until(warning.StopTime).Round(time.Minute).Minutes())
+	summary := fmt.Sprintf(":zzz: Schedule *%s* will stop %d database(s) in %d minutes (at %s)",
+		warning.ScheduleName, len(warning.Instances), minutes, warning.StopTime.Format("15:04 MST"))
+
+	msg := slackMessage{
+		Text: summary,
+		Blocks: []slackBlock{
+			{Type: "section", Text: &slackText{Type: "mrkdwn", Text: summary}},
+		},
+	}
+
+	now := time.Now()
+	for _, instance := range warning.Instances {
+		line := fmt.Sprintf("• *%s* (%s, %s)", instance.Name, instance.Provider, instance.Region)
+		block := slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: line}}
+		if n.links != nil {
+			block.Accessory = &slackElement{
+				Type: "button",
+				Text: &slackText{Type: "plain_text", Text: "Extend 1 hour"},
+				URL:  n.links.ExtendURL(instance.ID, warning.StopTime, now),
+			}
+		}
+		msg.Blocks = append(msg.Blocks, block)
+	}
+
+	return n.post(ctx, msg)
+}
+
+func (n *SlackNotifier) post(ctx context.Context, msg slackMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal slack message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create slack request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to slack: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("slack webhook returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
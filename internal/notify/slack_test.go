@@ -0,0 +1,121 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"snoozeql/internal/models"
+)
+
+func TestSlackNotifyPreStop(t *testing.T) {
+	var got slackMessage
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	signer := NewLinkSigner("https://snoozeql.example.com/", "secret")
+	notifier := NewSlackNotifier(server.URL, signer)
+	stopAt := time.Now().Add(10 * time.Minute).Truncate(time.Second)
+	warning := PreStopWarning{
+		ScheduleName: "Nights",
+		StopTime:     stopAt,
+		Instances: []models.Instance{
+			{ID: "i-1", Name: "orders-db", Provider: "aws", Region: "eu-west-1"},
+			{ID: "i-2", Name: "users-db", Provider: "aws", Region: "eu-west-1"},
+		},
+	}
+	if err := notifier.NotifyPreStop(context.Background(), warning); err != nil {
+		t.Fatalf("NotifyPreStop: %v", err)
+	}
+
+	if contentType != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", contentType)
+	}
+	if !strings.Contains(got.Text, "Nights") || !strings.Contains(got.Text, "2 database(s)") {
+		t.Errorf("summary = %q", got.Text)
+	}
+	if len(got.Blocks) != 3 {
+		t.Fatalf("got %d blocks, want summary + 2 instances", len(got.Blocks))
+	}
+
+	for i, id := range []string{"i-1", "i-2"} {
+		button := got.Blocks[i+1].Accessory
+		if button == nil || button.Type != "button" {
+			t.Fatalf("block %d has no button", i+1)
+		}
+		link, err := url.Parse(button.URL)
+		if err != nil {
+			t.Fatalf("button URL: %v", err)
+		}
+		if link.Host != "snoozeql.example.com" || link.Path != ExtendPath {
+			t.Errorf("button URL = %s", button.URL)
+		}
+		instanceID, at, err := signer.VerifyExtend(link.Query(), time.Now())
+		if err != nil {
+			t.Fatalf("VerifyExtend: %v", err)
+		}
+		if instanceID != id || !at.Equal(stopAt) {
+			t.Errorf("link for %s, %s; want %s, %s", instanceID, at, id, stopAt)
+		}
+	}
+}
+
+func TestSlackNotifyPreStopError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	notifier := NewSlackNotifier(server.URL, nil)
+	err := notifier.NotifyPreStop(context.Background(), PreStopWarning{ScheduleName: "Nights", StopTime: time.Now()})
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "invalid_token") {
+		t.Errorf("err = %v, want the webhook's status and body", err)
+	}
+}
+
+func TestVerifyExtend(t *testing.T) {
+	signer := NewLinkSigner("https://snoozeql.example.com", "secret")
+	now := time.Now()
+	link, _ := url.Parse(signer.ExtendURL("i-1", now.Add(time.Hour), now))
+	valid := link.Query()
+
+	tests := []struct {
+		name   string
+		modify func(url.Values)
+		signer *LinkSigner
+		at     time.Time
+		ok     bool
+	}{
+		{name: "valid", modify: func(url.Values) {}, signer: signer, at: now, ok: true},
+		{name: "other instance", modify: func(q url.Values) { q.Set("instance_id", "i-2") }, signer: signer, at: now},
+		{name: "moved stop time", modify: func(q url.Values) { q.Set("at", "1") }, signer: signer, at: now},
+		{name: "missing signature", modify: func(q url.Values) { q.Del("sig") }, signer: signer, at: now},
+		{name: "other secret", modify: func(url.Values) {}, signer: NewLinkSigner("", "other"), at: now},
+		{name: "expired", modify: func(url.Values) {}, signer: signer, at: now.Add(extendLinkTTL + time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{}
+			for key, values := range valid {
+				query[key] = append([]string(nil), values...)
+			}
+			tt.modify(query)
+			_, _, err := tt.signer.VerifyExtend(query, tt.at)
+			if (err == nil) != tt.ok {
+				t.Errorf("err = %v, want ok = %v", err, tt.ok)
+			}
+		})
+	}
+}
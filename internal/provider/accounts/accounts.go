@@ -0,0 +1,139 @@
+// Package accounts registers cloud providers for the cloud accounts stored in the database
+
+package accounts
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"snoozeql/internal/models"
+	"snoozeql/internal/provider"
+	awsprovider "snoozeql/internal/provider/aws"
+	azureprovider "snoozeql/internal/provider/azure"
+	gcpprovider "snoozeql/internal/provider/gcp"
+	k8sprovider "snoozeql/internal/provider/kubernetes"
+)
+
+// Register creates a provider for each cloud account (one per region for AWS) and
+// registers it under the key discovery and actions use: "aws_{accountID}_{region}",
+// "gcp_{accountID}", "azure_{accountID}" or "kubernetes_{accountID}". Accounts with
+// missing credentials are skipped.
+func Register(registry *provider.Registry, cloudAccounts []models.CloudAccount) {
+	for _, account := range cloudAccounts {
+		if account.Provider == "aws" {
+			accessKey := credential(account, "aws_access_key_id")
+			secretKey := credential(account, "aws_secret_access_key")
+
+			if accessKey == "" || secretKey == "" {
+				log.Printf("Warning: Skipping AWS account %s - missing credentials", account.Name)
+				continue
+			}
+
+			regions := account.Regions
+			if len(regions) == 0 {
+				regions = []string{"us-east-1"}
+			}
+
+			for _, region := range regions {
+				awsProvider, err := awsprovider.NewRDSProvider(region, "", account.ManagedTags, accessKey, secretKey)
+				if err == nil {
+					awsProvider.SetDiscoveryFilters(account.DiscoveryFilters)
+					providerKey := fmt.Sprintf("aws_%s_%s", account.ID, region)
+					registry.Register(providerKey, awsProvider)
+					log.Printf("✓ Registered AWS provider for account: %s (region: %s, key: %s)", account.Name, region, providerKey)
+				} else {
+					log.Printf("Warning: Failed to register AWS provider for %s in region %s: %v", account.Name, region, err)
+				}
+			}
+		} else if account.Provider == "gcp" {
+			projectID := credential(account, "gcp_project_id")
+			serviceAccountKey := credential(account, "gcp_service_account_key")
+
+			if projectID == "" {
+				log.Printf("Warning: Skipping GCP account %s - missing project ID", account.Name)
+				continue
+			}
+
+			gcpProvider, err := gcpprovider.NewCloudSQLProvider(projectID, "", account.ManagedTags, serviceAccountKey)
+			if err != nil {
+				log.Printf("Warning: Failed to create GCP provider for %s: %v", account.Name, err)
+				continue
+			}
+
+			providerKey := fmt.Sprintf("gcp_%s", account.ID)
+			registry.Register(providerKey, gcpProvider)
+			log.Printf("✓ Registered GCP provider for account: %s (project: %s, key: %s)", account.Name, projectID, providerKey)
+		} else if account.Provider == "azure" {
+			subscriptionID := credential(account, "azure_subscription_id")
+			tenantID := credential(account, "azure_tenant_id")
+			clientID := credential(account, "azure_client_id")
+			clientSecret := credential(account, "azure_client_secret")
+
+			if subscriptionID == "" || tenantID == "" || clientID == "" || clientSecret == "" {
+				log.Printf("Warning: Skipping Azure account %s - missing service principal credentials", account.Name)
+				continue
+			}
+
+			azureProvider, err := azureprovider.NewFlexibleServerProvider(subscriptionID, account.ManagedTags, tenantID, clientID, clientSecret)
+			if err != nil {
+				log.Printf("Warning: Failed to create Azure provider for %s: %v", account.Name, err)
+				continue
+			}
+			// Sovereign clouds (and local stand-ins) use other endpoints
+			azureProvider.SetEndpoints(credential(account, "azure_arm_endpoint"), credential(account, "azure_login_endpoint"))
+
+			providerKey := fmt.Sprintf("azure_%s", account.ID)
+			registry.Register(providerKey, azureProvider)
+			log.Printf("✓ Registered Azure provider for account: %s (subscription: %s, key: %s)", account.Name, subscriptionID, providerKey)
+		} else if account.Provider == "kubernetes" {
+			// Regions are the namespaces to discover in; an empty kubeconfig means in-cluster
+			k8sProvider, err := k8sprovider.NewKubernetesProvider(credential(account, "kubeconfig"), credential(account, "context"), account.Regions, credential(account, "label_selector"), account.ManagedTags)
+			if err != nil {
+				log.Printf("Warning: Failed to create Kubernetes provider for %s: %v", account.Name, err)
+				continue
+			}
+
+			providerKey := fmt.Sprintf("kubernetes_%s", account.ID)
+			registry.Register(providerKey, k8sProvider)
+			log.Printf("✓ Registered Kubernetes provider for account: %s (namespaces: %v, key: %s)", account.Name, account.Regions, providerKey)
+		} else {
+			log.Printf("Skipping %s provider (not supported yet): %s", account.Provider, account.Name)
+		}
+	}
+}
+
+// credential returns the account's string credential with the given key, or "" if it
+// is missing or not a string
+func credential(account models.CloudAccount, key string) string {
+	if cred, ok := account.Credentials[key]; ok {
+		if str, ok := cred.(string); ok {
+			return str
+		}
+	}
+	return ""
+}
+
+// ValidateDiscoveryConfig checks an account's discovery filters: only AWS supports them,
+// with the DescribeDBInstances filter names, and each needs at least one value.
+// Returns an error message if invalid, empty string if valid.
+func ValidateDiscoveryConfig(account models.CloudAccount) string {
+	for _, tag := range account.ManagedTags {
+		if strings.TrimSpace(tag) == "" {
+			return "Managed tags must not be empty"
+		}
+	}
+	if len(account.DiscoveryFilters) > 0 && account.Provider != "aws" {
+		return fmt.Sprintf("Discovery filters are not supported for %s accounts", account.Provider)
+	}
+	for _, filter := range account.DiscoveryFilters {
+		if !slices.Contains(awsprovider.RDSFilterNames, filter.Name) {
+			return fmt.Sprintf("Unknown discovery filter '%s' (use %s)", filter.Name, strings.Join(awsprovider.RDSFilterNames, ", "))
+		}
+		if len(filter.Values) == 0 {
+			return fmt.Sprintf("Discovery filter '%s' needs at least one value", filter.Name)
+		}
+	}
+	return ""
+}
@@ -4,6 +4,8 @@ package aws
 import (
 	"context"
 	"fmt"
+	"log"
+	"sort"
 	"strings"
 	"time"
 
@@ -12,16 +14,20 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/rds"
 	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/aws/aws-sdk-go-v2/service/redshift"
 
 	"snoozeql/internal/models"
 )
 
-// RDSProvider implements the Provider interface for AWS RDS
+// RDSProvider implements the Provider interface for AWS RDS, and for the Redshift
+// clusters of the same account and region
 type RDSProvider struct {
-	rdsClient   *rds.Client
-	region      string
-	accountID   string
-	managedTags []string
+	rdsClient      *rds.Client
+	redshiftClient *redshift.Client
+	region         string
+	accountID      string
+	managedTags    []string
+	filters        []types.Filter
 }
 
 // NewRDSProvider creates a new AWS RDS provider with static credentials
@@ -46,13 +52,30 @@ func NewRDSProvider(region string, accountID string, managedTags []string, acces
 	}
 
 	return &RDSProvider{
-		rdsClient:   rds.NewFromConfig(cfg),
-		region:      region,
-		accountID:   accountID,
-		managedTags: managedTags,
+		rdsClient:      rds.NewFromConfig(cfg),
+		redshiftClient: redshift.NewFromConfig(cfg),
+		region:         region,
+		accountID:      accountID,
+		managedTags:    managedTags,
 	}, nil
 }
 
+// RDSFilterNames are the DescribeDBInstances filters discovery can be narrowed with
+var RDSFilterNames = []string{"db-cluster-id", "db-instance-id", "dbi-resource-id", "domain", "engine"}
+
+// SetDiscoveryFilters makes ListDatabases only return instances matching the filters
+// (server-side DescribeDBInstances filters, see RDSFilterNames). Aurora, DocumentDB
+// and Neptune clusters are then only listed when one of their members is.
+func (p *RDSProvider) SetDiscoveryFilters(filters []models.ProviderFilter) {
+	p.filters = nil
+	for _, filter := range filters {
+		p.filters = append(p.filters, types.Filter{
+			Name:   aws.String(filter.Name),
+			Values: filter.Values,
+		})
+	}
+}
+
 // TestConnection tests if the AWS credentials are valid
 func (p *RDSProvider) TestConnection(ctx context.Context) error {
 	_, err := p.rdsClient.DescribeDBInstances(ctx, &rds.DescribeDBInstancesInput{
@@ -64,28 +87,201 @@ func (p *RDSProvider) TestConnection(ctx context.Context) error {
 	return nil
 }
 
-// ListDatabases returns all RDS instances
+// ListDatabases returns all RDS instances, plus one entry per Aurora, DocumentDB or
+// Neptune cluster. Cluster members can't be stopped on their own, so they are marked
+// with their cluster's ID and the cluster entry (provider ID "cluster:{identifier}")
+// is started and stopped.
+// Redshift clusters are listed too, see listRedshift.
 func (p *RDSProvider) ListDatabases(ctx context.Context) ([]models.Instance, error) {
 	var instances []models.Instance
 
-	result, err := p.rdsClient.DescribeDBInstances(ctx, &rds.DescribeDBInstancesInput{})
+	dbInstances, err := p.describeInstances(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to describe DB instances: %w", err)
+		return nil, err
+	}
+	discovered := make(map[string]bool)
+	for _, db := range dbInstances {
+		discovered[aws.ToString(db.DBInstanceIdentifier)] = true
 	}
 
-	for _, db := range result.DBInstances {
+	clusters, err := p.stoppableClusters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	memberOf := make(map[string]string)
+	var listed []types.DBCluster
+	for _, cluster := range clusters {
+		hasMember := false
+		for _, member := range cluster.DBClusterMembers {
+			memberOf[aws.ToString(member.DBInstanceIdentifier)] = models.ClusterIDPrefix + aws.ToString(cluster.DBClusterIdentifier)
+			hasMember = hasMember || discovered[aws.ToString(member.DBInstanceIdentifier)]
+		}
+		if hasMember || len(p.filters) == 0 {
+			listed = append(listed, cluster)
+		}
+	}
+
+	members, err := p.clusterMembers(ctx, listed, dbInstances)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := p.pendingMaintenance(ctx, nil)
+	for _, db := range dbInstances {
 		instance, err := p.dbInstanceToModel(db)
 		if err != nil {
 			return nil, err
 		}
+		instance.ClusterID = memberOf[aws.ToString(db.DBInstanceIdentifier)]
+		instance.PendingMaintenance = pending[aws.ToString(db.DBInstanceArn)]
+		instances = append(instances, instance)
+	}
+
+	for _, cluster := range listed {
+		instance := p.dbClusterToModel(cluster, members)
+		instance.PendingMaintenance = pending[aws.ToString(cluster.DBClusterArn)]
 		instances = append(instances, instance)
 	}
 
+	instances = append(instances, p.listRedshift(ctx)...)
+
 	return instances, nil
 }
 
-// StartDatabase starts a stopped RDS instance
+// describeInstances returns every DB instance matching the discovery filters, following
+// DescribeDBInstances' pagination markers
+func (p *RDSProvider) describeInstances(ctx context.Context) ([]types.DBInstance, error) {
+	var dbInstances []types.DBInstance
+	paginator := rds.NewDescribeDBInstancesPaginator(p.rdsClient, &rds.DescribeDBInstancesInput{
+		Filters: p.filters,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe DB instances: %w", err)
+		}
+		dbInstances = append(dbInstances, page.DBInstances...)
+	}
+	return dbInstances, nil
+}
+
+// clusterMembers returns the member instances of the clusters: the discovered instances,
+// plus the members of clusters that discovery filters left some members of out, so a
+// cluster's classes and cost always cover all of its members
+func (p *RDSProvider) clusterMembers(ctx context.Context, clusters []types.DBCluster, discovered []types.DBInstance) ([]types.DBInstance, error) {
+	known := make(map[string]bool)
+	for _, db := range discovered {
+		known[aws.ToString(db.DBInstanceIdentifier)] = true
+	}
+
+	var incomplete []string
+	for _, cluster := range clusters {
+		for _, member := range cluster.DBClusterMembers {
+			if !known[aws.ToString(member.DBInstanceIdentifier)] {
+				incomplete = append(incomplete, aws.ToString(cluster.DBClusterIdentifier))
+				break
+			}
+		}
+	}
+	if len(incomplete) == 0 {
+		return discovered, nil
+	}
+
+	members := append([]types.DBInstance(nil), discovered...)
+	paginator := rds.NewDescribeDBInstancesPaginator(p.rdsClient, &rds.DescribeDBInstancesInput{
+		Filters: []types.Filter{{Name: aws.String("db-cluster-id"), Values: incomplete}},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe DB cluster members: %w", err)
+		}
+		members = append(members, page.DBInstances...)
+	}
+	return members, nil
+}
+
+// stoppableClusters returns the region's provisioned Aurora, DocumentDB and Neptune
+// clusters. Aurora Serverless v1 clusters pause on their own and can't be stopped, so
+// they are left out.
+func (p *RDSProvider) stoppableClusters(ctx context.Context) ([]types.DBCluster, error) {
+	var clusters []types.DBCluster
+	paginator := rds.NewDescribeDBClustersPaginator(p.rdsClient, &rds.DescribeDBClustersInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe DB clusters: %w", err)
+		}
+		for _, cluster := range page.DBClusters {
+			if isStoppableCluster(cluster) {
+				clusters = append(clusters, cluster)
+			}
+		}
+	}
+	return clusters, nil
+}
+
+func isStoppableCluster(cluster types.DBCluster) bool {
+	return isClusterEngine(aws.ToString(cluster.Engine)) && aws.ToString(cluster.EngineMode) != "serverless"
+}
+
+// isClusterEngine reports whether instances of the engine are started and stopped
+// through their cluster: Aurora, DocumentDB ("docdb") and Neptune
+func isClusterEngine(engine string) bool {
+	return strings.HasPrefix(engine, "aurora") || engine == "docdb" || engine == "neptune"
+}
+
+// clusterIdentifier returns the cluster identifier of a cluster provider ID
+func clusterIdentifier(id string) (string, bool) {
+	return strings.CutPrefix(id, models.ClusterIDPrefix)
+}
+
+// pendingMaintenance returns the pending maintenance actions by resource ARN, for one
+// ARN or (if nil) every resource in the region. Failures are logged and yield no actions,
+// since maintenance awareness is best-effort.
+func (p *RDSProvider) pendingMaintenance(ctx context.Context, arn *string) map[string][]models.PendingMaintenanceAction {
+	pending := make(map[string][]models.PendingMaintenanceAction)
+	paginator := rds.NewDescribePendingMaintenanceActionsPaginator(p.rdsClient, &rds.DescribePendingMaintenanceActionsInput{
+		ResourceIdentifier: arn,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			log.Printf("Warning: Failed to describe pending maintenance actions in %s: %v", p.region, err)
+			return pending
+		}
+		for _, resource := range page.PendingMaintenanceActions {
+			for _, action := range resource.PendingMaintenanceActionDetails {
+				pending[aws.ToString(resource.ResourceIdentifier)] = append(pending[aws.ToString(resource.ResourceIdentifier)], models.PendingMaintenanceAction{
+					Action:           aws.ToString(action.Action),
+					Description:      aws.ToString(action.Description),
+					AutoAppliedAfter: action.AutoAppliedAfterDate,
+					ForcedApplyDate:  action.ForcedApplyDate,
+					CurrentApplyDate: action.CurrentApplyDate,
+					OptInStatus:      aws.ToString(action.OptInStatus),
+				})
+			}
+		}
+	}
+	return pending
+}
+
+// StartDatabase starts a stopped RDS instance or DB cluster, or resumes a paused
+// Redshift cluster
 func (p *RDSProvider) StartDatabase(ctx context.Context, id string) error {
+	if identifier, ok := redshiftIdentifier(id); ok {
+		return p.resumeRedshift(ctx, identifier)
+	}
+	if cluster, ok := clusterIdentifier(id); ok {
+		_, err := p.rdsClient.StartDBCluster(ctx, &rds.StartDBClusterInput{
+			DBClusterIdentifier: aws.String(cluster),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to start DB cluster %s: %w", cluster, err)
+		}
+		return nil
+	}
+
 	_, err := p.rdsClient.StartDBInstance(ctx, &rds.StartDBInstanceInput{
 		DBInstanceIdentifier: aws.String(id),
 	})
@@ -95,8 +291,22 @@ func (p *RDSProvider) StartDatabase(ctx context.Context, id string) error {
 	return nil
 }
 
-// StopDatabase stops a running RDS instance
+// StopDatabase stops a running RDS instance or DB cluster, or pauses a Redshift
+// cluster
 func (p *RDSProvider) StopDatabase(ctx context.Context, id string) error {
+	if identifier, ok := redshiftIdentifier(id); ok {
+		return p.pauseRedshift(ctx, identifier)
+	}
+	if cluster, ok := clusterIdentifier(id); ok {
+		_, err := p.rdsClient.StopDBCluster(ctx, &rds.StopDBClusterInput{
+			DBClusterIdentifier: aws.String(cluster),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to stop DB cluster %s: %w", cluster, err)
+		}
+		return nil
+	}
+
 	_, err := p.rdsClient.StopDBInstance(ctx, &rds.StopDBInstanceInput{
 		DBInstanceIdentifier: aws.String(id),
 	})
@@ -108,6 +318,21 @@ func (p *RDSProvider) StopDatabase(ctx context.Context, id string) error {
 
 // GetDatabaseStatus returns the current status of a database
 func (p *RDSProvider) GetDatabaseStatus(ctx context.Context, id string) (string, error) {
+	if identifier, ok := redshiftIdentifier(id); ok {
+		cluster, err := p.describeRedshift(ctx, identifier)
+		if err != nil {
+			return "", err
+		}
+		return redshiftStatus(aws.ToString(cluster.ClusterStatus)), nil
+	}
+	if cluster, ok := clusterIdentifier(id); ok {
+		db, err := p.describeCluster(ctx, cluster)
+		if err != nil {
+			return "", err
+		}
+		return aws.ToString(db.Status), nil
+	}
+
 	result, err := p.rdsClient.DescribeDBInstances(ctx, &rds.DescribeDBInstancesInput{
 		DBInstanceIdentifier: aws.String(id),
 	})
@@ -122,6 +347,22 @@ func (p *RDSProvider) GetDatabaseStatus(ctx context.Context, id string) (string,
 	return *result.DBInstances[0].DBInstanceStatus, nil
 }
 
+// describeCluster returns a single DB cluster
+func (p *RDSProvider) describeCluster(ctx context.Context, cluster string) (types.DBCluster, error) {
+	result, err := p.rdsClient.DescribeDBClusters(ctx, &rds.DescribeDBClustersInput{
+		DBClusterIdentifier: aws.String(cluster),
+	})
+	if err != nil {
+		return types.DBCluster{}, fmt.Errorf("failed to describe DB cluster %s: %w", cluster, err)
+	}
+
+	if len(result.DBClusters) == 0 {
+		return types.DBCluster{}, fmt.Errorf("DB cluster %s not found", cluster)
+	}
+
+	return result.DBClusters[0], nil
+}
+
 // GetMetrics returns activity metrics for a database
 func (p *RDSProvider) GetMetrics(ctx context.Context, providerName string, id string, period string) (map[string]any, error) {
 	metrics := make(map[string]any)
@@ -171,6 +412,18 @@ func (p *RDSProvider) GetMetrics(ctx context.Context, providerName string, id st
 
 // GetDatabaseByID returns a database by its ID
 func (p *RDSProvider) GetDatabaseByID(ctx context.Context, id string) (*models.Instance, error) {
+	if identifier, ok := redshiftIdentifier(id); ok {
+		cluster, err := p.describeRedshift(ctx, identifier)
+		if err != nil {
+			return nil, err
+		}
+		inst := p.redshiftToModel(cluster)
+		return &inst, nil
+	}
+	if cluster, ok := clusterIdentifier(id); ok {
+		return p.getClusterByID(ctx, cluster)
+	}
+
 	result, err := p.rdsClient.DescribeDBInstances(ctx, &rds.DescribeDBInstancesInput{
 		DBInstanceIdentifier: aws.String(id),
 	})
@@ -186,6 +439,33 @@ func (p *RDSProvider) GetDatabaseByID(ctx context.Context, id string) (*models.I
 	if err != nil {
 		return nil, err
 	}
+	if cluster := result.DBInstances[0].DBClusterIdentifier; cluster != nil && isClusterEngine(inst.Engine) {
+		inst.ClusterID = models.ClusterIDPrefix + *cluster
+	}
+	if arn := result.DBInstances[0].DBInstanceArn; arn != nil {
+		inst.PendingMaintenance = p.pendingMaintenance(ctx, arn)[*arn]
+	}
+	return &inst, nil
+}
+
+// getClusterByID returns a DB cluster with its members' classes and cost
+func (p *RDSProvider) getClusterByID(ctx context.Context, cluster string) (*models.Instance, error) {
+	db, err := p.describeCluster(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := p.rdsClient.DescribeDBInstances(ctx, &rds.DescribeDBInstancesInput{
+		Filters: []types.Filter{{Name: aws.String("db-cluster-id"), Values: []string{cluster}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe members of DB cluster %s: %w", cluster, err)
+	}
+
+	inst := p.dbClusterToModel(db, members.DBInstances)
+	if arn := db.DBClusterArn; arn != nil {
+		inst.PendingMaintenance = p.pendingMaintenance(ctx, arn)[*arn]
+	}
 	return &inst, nil
 }
 
@@ -221,7 +501,7 @@ func (p *RDSProvider) dbInstanceToModel(db types.DBInstance) (models.Instance, e
 	}
 
 	// Calculate hourly cost approx based on instance class
-	hourlyCostCents := p.getInstanceCost(instanceClass)
+	hourlyCostCents := p.getInstanceCost(engine, instanceClass)
 
 	return models.Instance{
 		Provider:        "aws",
@@ -235,9 +515,71 @@ func (p *RDSProvider) dbInstanceToModel(db types.DBInstance) (models.Instance, e
 		Managed:         p.isManaged(tags),
 		Tags:            tags,
 		HourlyCostCents: hourlyCostCents,
+
+		BackupWindow:      aws.ToString(db.PreferredBackupWindow),
+		MaintenanceWindow: aws.ToString(db.PreferredMaintenanceWindow),
 	}, nil
 }
 
+// dbClusterToModel converts a DB cluster into an instance entry. Its instance type
+// lists the member classes and its cost is the sum of the members' costs; instances
+// must hold all of the cluster's members (see clusterMembers).
+func (p *RDSProvider) dbClusterToModel(cluster types.DBCluster, instances []types.DBInstance) models.Instance {
+	tags := make(map[string]string)
+	for _, tag := range cluster.TagList {
+		if tag.Key != nil && tag.Value != nil {
+			tags[*tag.Key] = *tag.Value
+		}
+	}
+
+	classes := make(map[string]string)
+	for _, db := range instances {
+		if db.DBInstanceIdentifier != nil && db.DBInstanceClass != nil {
+			classes[*db.DBInstanceIdentifier] = *db.DBInstanceClass
+		}
+	}
+
+	var memberClasses []string
+	hourlyCostCents := 0
+	for _, member := range cluster.DBClusterMembers {
+		instanceClass, ok := classes[aws.ToString(member.DBInstanceIdentifier)]
+		if !ok {
+			continue
+		}
+		memberClasses = append(memberClasses, instanceClass)
+		hourlyCostCents += p.getInstanceCost(aws.ToString(cluster.Engine), instanceClass)
+	}
+	sort.Strings(memberClasses)
+
+	instanceType := "unknown"
+	if len(memberClasses) > 0 {
+		instanceType = strings.Join(memberClasses, ",")
+	}
+
+	status := "unknown"
+	if cluster.Status != nil {
+		status = *cluster.Status
+	}
+
+	identifier := aws.ToString(cluster.DBClusterIdentifier)
+	return models.Instance{
+		Provider:        "aws",
+		ID:              models.ClusterIDPrefix + identifier,
+		ProviderID:      models.ClusterIDPrefix + identifier,
+		Name:            identifier,
+		Region:          p.region,
+		InstanceType:    instanceType,
+		Engine:          strings.Split(aws.ToString(cluster.Engine), ".")[0],
+		Status:          status,
+		Managed:         p.isManaged(tags),
+		Tags:            tags,
+		HourlyCostCents: hourlyCostCents,
+
+		BackupWindow:      aws.ToString(cluster.PreferredBackupWindow),
+		MaintenanceWindow: aws.ToString(cluster.PreferredMaintenanceWindow),
+	}
+}
+
 func (p *RDSProvider) isManaged(tags map[string]string) bool {
 	if len(p.managedTags) == 0 {
 		return true
@@ -250,7 +592,16 @@ func (p *RDSProvider) isManaged(tags map[string]string) bool {
 	return false
 }
 
-func (p *RDSProvider) getInstanceCost(instanceClass string) int {
+// getInstanceCost returns the approximate hourly cost of an instance in cents.
+// DocumentDB and Neptune instances are priced differently from RDS ones of the same class.
+func (p *RDSProvider) getInstanceCost(engine, instanceClass string) int {
+	switch engine {
+	case "docdb":
+		return getDocDBCost(instanceClass)
+	case "neptune":
+		return getNeptuneCost(instanceClass)
+	}
+
 	instanceStr := instanceClass
 	switch {
 	case containsPrefix(instanceStr, "db.r5."):
@@ -266,6 +617,36 @@ func (p *RDSProvider) getInstanceCost(instanceClass string) int {
 	}
 }
 
+func getDocDBCost(instanceClass string) int {
+	switch {
+	case containsPrefix(instanceClass, "db.r5."):
+		return 28
+	case containsPrefix(instanceClass, "db.r6g."):
+		return 25
+	case containsPrefix(instanceClass, "db.t3."):
+		return 8
+	case containsPrefix(instanceClass, "db.t4g."):
+		return 7
+	default:
+		return 30
+	}
+}
+
+func getNeptuneCost(instanceClass string) int {
+	switch {
+	case containsPrefix(instanceClass, "db.r5."):
+		return 35
+	case containsPrefix(instanceClass, "db.r6g."):
+		return 31
+	case containsPrefix(instanceClass, "db.t3."):
+		return 10
+	case containsPrefix(instanceClass, "db.t4g."):
+		return 9
+	default:
+		return 35
+	}
+}
+
 func parsePeriod(period string) (time.Duration, error) {
 	switch period {
 	case "1h", "1 hour":
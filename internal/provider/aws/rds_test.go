@@ -0,0 +1,181 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+
+	"snoozeql/internal/models"
+)
+
+// rdsInstance is a DB instance served by the RDS stand-in
+type rdsInstance struct {
+	identifier, cluster, class, engine string
+}
+
+// rdsStandIn is a local stand-in for the RDS query API serving one Aurora cluster with
+// two members and a standalone instance
+type rdsStandIn struct {
+	*httptest.Server
+	instances []rdsInstance
+	filters   []string // "{name}={values}" of each DescribeDBInstances call
+}
+
+func newRDSStandIn(t *testing.T) *rdsStandIn {
+	t.Helper()
+	s := &rdsStandIn{instances: []rdsInstance{
+		{"orders-1", "orders", "db.r6g.large", "aurora-postgresql"},
+		{"orders-2", "orders", "db.r6g.xlarge", "aurora-postgresql"},
+		{"billing", "", "db.t3.medium", "postgres"},
+	}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *rdsStandIn) handle(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	action := r.Form.Get("Action")
+	var result string
+
+	switch action {
+	case "DescribeDBClusters":
+		result = `<DBClusters><DBCluster>
+			<DBClusterIdentifier>orders</DBClusterIdentifier>
+			<DBClusterArn>arn:aws:rds:eu-west-1:123:cluster:orders</DBClusterArn>
+			<Engine>aurora-postgresql</Engine>
+			<EngineMode>provisioned</EngineMode>
+			<Status>available</Status>
+			<DBClusterMembers>
+				<DBClusterMember><DBInstanceIdentifier>orders-1</DBInstanceIdentifier></DBClusterMember>
+				<DBClusterMember><DBInstanceIdentifier>orders-2</DBInstanceIdentifier></DBClusterMember>
+			</DBClusterMembers>
+		</DBCluster></DBClusters>`
+
+	case "DescribeDBInstances":
+		name := r.Form.Get("Filters.Filter.1.Name")
+		var values []string
+		for i := 1; r.Form.Has(fmt.Sprintf("Filters.Filter.1.Values.Value.%d", i)); i++ {
+			values = append(values, r.Form.Get(fmt.Sprintf("Filters.Filter.1.Values.Value.%d", i)))
+		}
+		s.filters = append(s.filters, name+"="+strings.Join(values, ","))
+
+		var items strings.Builder
+		for _, instance := range s.instances {
+			switch {
+			case name == "db-instance-id" && !slices.Contains(values, instance.identifier),
+				name == "db-cluster-id" && !slices.Contains(values, instance.cluster):
+				continue
+			}
+			fmt.Fprintf(&items, `<DBInstance>
+				<DBInstanceIdentifier>%s</DBInstanceIdentifier>
+				<DBClusterIdentifier>%s</DBClusterIdentifier>
+				<DBInstanceClass>%s</DBInstanceClass>
+				<Engine>%s</Engine>
+				<DBInstanceStatus>available</DBInstanceStatus>
+			</DBInstance>`, instance.identifier, instance.cluster, instance.class, instance.engine)
+		}
+		result = "<DBInstances>" + items.String() + "</DBInstances>"
+
+	case "DescribePendingMaintenanceActions":
+		result = "<PendingMaintenanceActions></PendingMaintenanceActions>"
+
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	fmt.Fprintf(w, `<%[1]sResponse xmlns="http://rds.amazonaws.com/doc/2014-10-31/"><%[1]sResult>%[2]s</%[1]sResult></%[1]sResponse>`, action, result)
+}
+
+func newTestRDSProvider(standIn *rdsStandIn) *RDSProvider {
+	return &RDSProvider{
+		rdsClient: rds.New(rds.Options{
+			Region:           "eu-west-1",
+			BaseEndpoint:     aws.String(standIn.URL),
+			Credentials:      aws.AnonymousCredentials{},
+			RetryMaxAttempts: 1,
+		}),
+		region: "eu-west-1",
+	}
+}
+
+func TestListDatabasesClusterCostCoversAllMembers(t *testing.T) {
+	tests := []struct {
+		name        string
+		filters     []models.ProviderFilter
+		wantCluster bool
+		wantFilters []string
+	}{
+		{
+			name:        "discovery filter selecting one member",
+			filters:     []models.ProviderFilter{{Name: "db-instance-id", Values: []string{"orders-1"}}},
+			wantCluster: true,
+			wantFilters: []string{"db-instance-id=orders-1", "db-cluster-id=orders"},
+		},
+		{
+			name:        "discovery filter selecting the cluster",
+			filters:     []models.ProviderFilter{{Name: "db-cluster-id", Values: []string{"orders"}}},
+			wantCluster: true,
+			wantFilters: []string{"db-cluster-id=orders"},
+		},
+		{
+			name:        "discovery filter selecting no member",
+			filters:     []models.ProviderFilter{{Name: "db-instance-id", Values: []string{"billing"}}},
+			wantFilters: []string{"db-instance-id=billing"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standIn := newRDSStandIn(t)
+			p := newTestRDSProvider(standIn)
+			p.SetDiscoveryFilters(tt.filters)
+
+			instances, err := p.ListDatabases(context.Background())
+			if err != nil {
+				t.Fatalf("ListDatabases: %v", err)
+			}
+
+			var cluster *models.Instance
+			for i := range instances {
+				if instances[i].ID == models.ClusterIDPrefix+"orders" {
+					cluster = &instances[i]
+				}
+			}
+			if (cluster != nil) != tt.wantCluster {
+				t.Fatalf("cluster listed = %v, want %v", cluster != nil, tt.wantCluster)
+			}
+			if cluster != nil {
+				wantCost := p.getInstanceCost("aurora-postgresql", "db.r6g.large") + p.getInstanceCost("aurora-postgresql", "db.r6g.xlarge")
+				if cluster.InstanceType != "db.r6g.large,db.r6g.xlarge" || cluster.HourlyCostCents != wantCost {
+					t.Errorf("cluster = %s at %d cents, want both members at %d cents", cluster.InstanceType, cluster.HourlyCostCents, wantCost)
+				}
+			}
+			if strings.Join(standIn.filters, " ") != strings.Join(tt.wantFilters, " ") {
+				t.Errorf("DescribeDBInstances filters = %v, want %v", standIn.filters, tt.wantFilters)
+			}
+		})
+	}
+}
+
+func TestGetClusterByID(t *testing.T) {
+	standIn := newRDSStandIn(t)
+	p := newTestRDSProvider(standIn)
+
+	cluster, err := p.GetDatabaseByID(context.Background(), models.ClusterIDPrefix+"orders")
+	if err != nil {
+		t.Fatalf("GetDatabaseByID: %v", err)
+	}
+	if cluster.InstanceType != "db.r6g.large,db.r6g.xlarge" || cluster.Status != "available" {
+		t.Errorf("cluster = %+v", cluster)
+	}
+}
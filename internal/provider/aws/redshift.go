@@ -0,0 +1,170 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/redshift"
+	redshifttypes "github.com/aws/aws-sdk-go-v2/service/redshift/types"
+
+	"snoozeql/internal/models"
+)
+
+// redshiftIdentifier returns the cluster identifier of a Redshift provider ID
+func redshiftIdentifier(id string) (string, bool) {
+	return strings.CutPrefix(id, models.RedshiftIDPrefix)
+}
+
+// listRedshift returns the region's Redshift clusters (provider ID
+// "redshift:{identifier}"). With discovery filters set, they are only listed when an
+// engine filter names "redshift", since the filters are RDS filters. Failures are
+// logged and yield no clusters, so accounts without Redshift permissions keep
+// discovering their RDS instances.
+func (p *RDSProvider) listRedshift(ctx context.Context) []models.Instance {
+	if !p.listsRedshift() {
+		return nil
+	}
+
+	var instances []models.Instance
+	paginator := redshift.NewDescribeClustersPaginator(p.redshiftClient, &redshift.DescribeClustersInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			log.Printf("Warning: Failed to describe Redshift clusters in %s: %v", p.region, err)
+			return instances
+		}
+		for _, cluster := range page.Clusters {
+			instances = append(instances, p.redshiftToModel(cluster))
+		}
+	}
+	return instances
+}
+
+// listsRedshift reports whether the discovery filters let Redshift clusters through
+func (p *RDSProvider) listsRedshift() bool {
+	if len(p.filters) == 0 {
+		return true
+	}
+	for _, filter := range p.filters {
+		if aws.ToString(filter.Name) == "engine" && slices.Contains(filter.Values, "redshift") {
+			return true
+		}
+	}
+	return false
+}
+
+// describeRedshift returns a single Redshift cluster
+func (p *RDSProvider) describeRedshift(ctx context.Context, identifier string) (redshifttypes.Cluster, error) {
+	result, err := p.redshiftClient.DescribeClusters(ctx, &redshift.DescribeClustersInput{
+		ClusterIdentifier: aws.String(identifier),
+	})
+	if err != nil {
+		return redshifttypes.Cluster{}, fmt.Errorf("failed to describe Redshift cluster %s: %w", identifier, err)
+	}
+
+	if len(result.Clusters) == 0 {
+		return redshifttypes.Cluster{}, fmt.Errorf("Redshift cluster %s not found", identifier)
+	}
+
+	return result.Clusters[0], nil
+}
+
+// resumeRedshift resumes a paused Redshift cluster
+func (p *RDSProvider) resumeRedshift(ctx context.Context, identifier string) error {
+	_, err := p.redshiftClient.ResumeCluster(ctx, &redshift.ResumeClusterInput{
+		ClusterIdentifier: aws.String(identifier),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to resume Redshift cluster %s: %w", identifier, err)
+	}
+	return nil
+}
+
+// pauseRedshift pauses an available Redshift cluster
+func (p *RDSProvider) pauseRedshift(ctx context.Context, identifier string) error {
+	_, err := p.redshiftClient.PauseCluster(ctx, &redshift.PauseClusterInput{
+		ClusterIdentifier: aws.String(identifier),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to pause Redshift cluster %s: %w", identifier, err)
+	}
+	return nil
+}
+
+// redshiftStatus maps a Redshift cluster status onto the RDS statuses schedules and
+// the rest of SnoozeQL work with: a paused cluster is stopped
+func redshiftStatus(status string) string {
+	switch status {
+	case "paused":
+		return "stopped"
+	case "pausing":
+		return "stopping"
+	case "resuming":
+		return "starting"
+	case "":
+		return "unknown"
+	default:
+		return status
+	}
+}
+
+// redshiftToModel converts a Redshift cluster into an instance entry. Its cost is the
+// node type's cost times the number of nodes.
+func (p *RDSProvider) redshiftToModel(cluster redshifttypes.Cluster) models.Instance {
+	tags := make(map[string]string)
+	for _, tag := range cluster.Tags {
+		if tag.Key != nil && tag.Value != nil {
+			tags[*tag.Key] = *tag.Value
+		}
+	}
+
+	nodeType := "unknown"
+	if cluster.NodeType != nil {
+		nodeType = *cluster.NodeType
+	}
+	nodes := int(aws.ToInt32(cluster.NumberOfNodes))
+	if nodes < 1 {
+		nodes = 1
+	}
+
+	identifier := aws.ToString(cluster.ClusterIdentifier)
+	return models.Instance{
+		Provider:        "aws",
+		ID:              models.RedshiftIDPrefix + identifier,
+		ProviderID:      models.RedshiftIDPrefix + identifier,
+		Name:            identifier,
+		Region:          p.region,
+		InstanceType:    nodeType,
+		Engine:          "redshift",
+		Status:          redshiftStatus(aws.ToString(cluster.ClusterStatus)),
+		Managed:         p.isManaged(tags),
+		Tags:            tags,
+		HourlyCostCents: getNodeCost(nodeType) * nodes,
+
+		MaintenanceWindow: aws.ToString(cluster.PreferredMaintenanceWindow),
+	}
+}
+
+// getNodeCost returns the approximate on-demand hourly cost of one Redshift node in cents
+func getNodeCost(nodeType string) int {
+	switch nodeType {
+	case "dc2.large":
+		return 25
+	case "dc2.8xlarge":
+		return 480
+	case "ra3.large":
+		return 54
+	case "ra3.xlplus":
+		return 109
+	case "ra3.4xlarge":
+		return 326
+	case "ra3.16xlarge":
+		return 1304
+	default:
+		return 100
+	}
+}
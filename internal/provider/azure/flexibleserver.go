@@ -0,0 +1,506 @@
+// Azure Database for PostgreSQL/MySQL Flexible Server provider, talking to the ARM REST API
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"snoozeql/internal/models"
+)
+
+const (
+	defaultARMEndpoint   = "https://management.azure.com"
+	defaultLoginEndpoint = "https://login.microsoftonline.com"
+
+	metricsAPIVersion = "2018-01-01"
+)
+
+// serverKind is a Flexible Server resource type and the API version used for it
+type serverKind struct {
+	resourceType string
+	apiVersion   string
+	engine       string
+}
+
+var serverKinds = []serverKind{
+	{resourceType: "Microsoft.DBforPostgreSQL/flexibleServers", apiVersion: "2022-12-01", engine: "postgres"},
+	{resourceType: "Microsoft.DBforMySQL/flexibleServers", apiVersion: "2023-12-30", engine: "mysql"},
+}
+
+// FlexibleServerProvider implements the Provider interface for Azure Database for
+// PostgreSQL and MySQL Flexible Servers in one subscription
+type FlexibleServerProvider struct {
+	httpClient     *http.Client
+	armEndpoint    string
+	loginEndpoint  string
+	tenantID       string
+	clientID       string
+	clientSecret   string
+	subscriptionID string
+	managedTags    []string
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// flexibleServer is the part of an ARM Flexible Server resource the provider uses
+type flexibleServer struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Location string            `json:"location"`
+	Tags     map[string]string `json:"tags"`
+	SKU      struct {
+		Name string `json:"name"` // e.g. "Standard_D2ds_v4"
+		Tier string `json:"tier"` // "Burstable", "GeneralPurpose" or "MemoryOptimized"
+	} `json:"sku"`
+	Properties struct {
+		State   string `json:"state"` // "Ready", "Stopped", "Starting", "Stopping", ...
+		Version string `json:"version"`
+	} `json:"properties"`
+}
+
+// NewFlexibleServerProvider creates a new Azure Flexible Server provider authenticating
+// as a service principal (client credentials)
+func NewFlexibleServerProvider(subscriptionID string, managedTags []string, tenantID, clientID, clientSecret string) (*FlexibleServerProvider, error) {
+	if subscriptionID == "" || tenantID == "" || clientID == "" || clientSecret == "" {
+		return nil, fmt.Errorf("subscription ID, tenant ID, client ID and client secret are required")
+	}
+
+	return &FlexibleServerProvider{
+		httpClient:     &http.Client{Timeout: 30 * time.Second},
+		armEndpoint:    defaultARMEndpoint,
+		loginEndpoint:  defaultLoginEndpoint,
+		tenantID:       tenantID,
+		clientID:       clientID,
+		clientSecret:   clientSecret,
+		subscriptionID: subscriptionID,
+		managedTags:    managedTags,
+	}, nil
+}
+
+// SetEndpoints points the provider at other Resource Manager and login endpoints, for
+// sovereign clouds or a local stand-in of the ARM API. Empty values keep the default.
+func (p *FlexibleServerProvider) SetEndpoints(armEndpoint, loginEndpoint string) {
+	if armEndpoint != "" {
+		p.armEndpoint = strings.TrimSuffix(armEndpoint, "/")
+	}
+	if loginEndpoint != "" {
+		p.loginEndpoint = strings.TrimSuffix(loginEndpoint, "/")
+	}
+}
+
+// TestConnection tests if the service principal can list servers in the subscription
+func (p *FlexibleServerProvider) TestConnection(ctx context.Context) error {
+	kind := serverKinds[0]
+	var page struct {
+		Value []flexibleServer `json:"value"`
+	}
+	if err := p.do(ctx, http.MethodGet, p.listURL(kind), &page); err != nil {
+		return fmt.Errorf("failed to test Azure connection: %w", err)
+	}
+	return nil
+}
+
+// ListDatabases returns all PostgreSQL and MySQL Flexible Servers in the subscription
+func (p *FlexibleServerProvider) ListDatabases(ctx context.Context) ([]models.Instance, error) {
+	var instances []models.Instance
+
+	for _, kind := range serverKinds {
+		next := p.listURL(kind)
+		for next != "" {
+			var page struct {
+				Value    []flexibleServer `json:"value"`
+				NextLink string           `json:"nextLink"`
+			}
+			if err := p.do(ctx, http.MethodGet, next, &page); err != nil {
+				return nil, fmt.Errorf("failed to list %s: %w", kind.resourceType, err)
+			}
+			for _, server := range page.Value {
+				instances = append(instances, p.serverToModel(server, kind))
+			}
+			next = page.NextLink
+		}
+	}
+
+	return instances, nil
+}
+
+// StartDatabase starts a stopped Flexible Server; id is its ARM resource ID
+func (p *FlexibleServerProvider) StartDatabase(ctx context.Context, id string) error {
+	kind, err := kindOf(id)
+	if err != nil {
+		return err
+	}
+	if err := p.do(ctx, http.MethodPost, p.resourceURL(id, "/start", kind.apiVersion), nil); err != nil {
+		return fmt.Errorf("failed to start flexible server %s: %w", id, err)
+	}
+	return nil
+}
+
+// StopDatabase stops a running Flexible Server; id is its ARM resource ID
+func (p *FlexibleServerProvider) StopDatabase(ctx context.Context, id string) error {
+	kind, err := kindOf(id)
+	if err != nil {
+		return err
+	}
+	if err := p.do(ctx, http.MethodPost, p.resourceURL(id, "/stop", kind.apiVersion), nil); err != nil {
+		return fmt.Errorf("failed to stop flexible server %s: %w", id, err)
+	}
+	return nil
+}
+
+// GetDatabaseStatus returns the current status of a database
+func (p *FlexibleServerProvider) GetDatabaseStatus(ctx context.Context, id string) (string, error) {
+	server, _, err := p.getServer(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return serverStatus(server.Properties.State), nil
+}
+
+// GetMetrics returns activity metrics for a database from Azure Monitor
+func (p *FlexibleServerProvider) GetMetrics(ctx context.Context, providerName string, id string, period string) (map[string]any, error) {
+	metrics := make(map[string]any)
+
+	duration, err := parsePeriod(period)
+	if err != nil {
+		return nil, fmt.Errorf("invalid period: %w", err)
+	}
+
+	endTime := time.Now().UTC()
+	startTime := endTime.Add(-duration)
+
+	series := map[string]string{
+		"cpu":         "cpu_percent",
+		"connections": "active_connections",
+	}
+	for key, metricName := range series {
+		values, err := p.getMetric(ctx, id, metricName, startTime, endTime, duration)
+		if err != nil {
+			metrics[key+"_error"] = err.Error()
+		} else {
+			metrics[key] = values
+		}
+	}
+
+	return metrics, nil
+}
+
+// GetDatabaseByID returns a database by its ARM resource ID
+func (p *FlexibleServerProvider) GetDatabaseByID(ctx context.Context, id string) (*models.Instance, error) {
+	server, kind, err := p.getServer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	inst := p.serverToModel(server, kind)
+	return &inst, nil
+}
+
+func (p *FlexibleServerProvider) getServer(ctx context.Context, id string) (flexibleServer, serverKind, error) {
+	kind, err := kindOf(id)
+	if err != nil {
+		return flexibleServer{}, serverKind{}, err
+	}
+	var server flexibleServer
+	if err := p.do(ctx, http.MethodGet, p.resourceURL(id, "", kind.apiVersion), &server); err != nil {
+		return flexibleServer{}, serverKind{}, fmt.Errorf("failed to get flexible server %s: %w", id, err)
+	}
+	return server, kind, nil
+}
+
+// getMetric returns the average, maximum and minimum of an Azure Monitor metric over
+// start..end
+func (p *FlexibleServerProvider) getMetric(ctx context.Context, id, metricName string, start, end time.Time, duration time.Duration) (map[string]float64, error) {
+	interval := "PT1H"
+	if duration <= time.Hour {
+		interval = "PT5M"
+	}
+	query := url.Values{
+		"api-version": {metricsAPIVersion},
+		"metricnames": {metricName},
+		"timespan":    {start.Format(time.RFC3339) + "/" + end.Format(time.RFC3339)},
+		"interval":    {interval},
+		"aggregation": {"Average,Maximum,Minimum"},
+	}
+
+	var result struct {
+		Value []struct {
+			Timeseries []struct {
+				Data []struct {
+					Average *float64 `json:"average"`
+					Maximum *float64 `json:"maximum"`
+					Minimum *float64 `json:"minimum"`
+				} `json:"data"`
+			} `json:"timeseries"`
+		} `json:"value"`
+	}
+	if err := p.do(ctx, http.MethodGet, p.armEndpoint+id+"/providers/Microsoft.Insights/metrics?"+query.Encode(), &result); err != nil {
+		return nil, fmt.Errorf("failed to get metric %s: %w", metricName, err)
+	}
+
+	var averages, maximums, minimums []float64
+	for _, metric := range result.Value {
+		for _, series := range metric.Timeseries {
+			for _, point := range series.Data {
+				if point.Average != nil {
+					averages = append(averages, *point.Average)
+				}
+				if point.Maximum != nil {
+					maximums = append(maximums, *point.Maximum)
+				}
+				if point.Minimum != nil {
+					minimums = append(minimums, *point.Minimum)
+				}
+			}
+		}
+	}
+	if len(averages) == 0 {
+		return nil, fmt.Errorf("no datapoints for %s", metricName)
+	}
+
+	values := map[string]float64{"avg": average(averages)}
+	if len(maximums) > 0 {
+		values["max"] = slices.Max(maximums)
+	}
+	if len(minimums) > 0 {
+		values["min"] = slices.Min(minimums)
+	}
+	return values, nil
+}
+
+// do sends an authenticated ARM request and decodes the JSON response into out (if not nil)
+func (p *FlexibleServerProvider) do(ctx context.Context, method, requestURL string, out any) error {
+	token, err := p.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return armError(resp.StatusCode, body)
+	}
+	if out == nil || len(body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// accessToken returns a Resource Manager token for the service principal, fetching a
+// new one shortly before the cached one expires
+func (p *FlexibleServerProvider) accessToken(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token != "" && time.Now().Before(p.tokenExpiry.Add(-time.Minute)) {
+		return p.token, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {p.clientID},
+		"client_secret": {p.clientSecret},
+		"scope":         {p.armEndpoint + "/.default"},
+	}
+	tokenURL := fmt.Sprintf("%s/%s/oauth2/v2.0/token", p.loginEndpoint, url.PathEscape(p.tenantID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int    `json:"expires_in"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to decode token response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || token.AccessToken == "" {
+		return "", fmt.Errorf("failed to get Azure token (status %d): %s", resp.StatusCode, token.ErrorDescription)
+	}
+
+	p.token = token.AccessToken
+	p.tokenExpiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return p.token, nil
+}
+
+// armError turns an ARM error response ({"error": {"code", "message"}}) into an error
+func armError(status int, body []byte) error {
+	var payload struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil && payload.Error.Code != "" {
+		return fmt.Errorf("status %d: %s: %s", status, payload.Error.Code, payload.Error.Message)
+	}
+	return fmt.Errorf("status %d", status)
+}
+
+func (p *FlexibleServerProvider) listURL(kind serverKind) string {
+	return fmt.Sprintf("%s/subscriptions/%s/providers/%s?api-version=%s",
+		p.armEndpoint, url.PathEscape(p.subscriptionID), kind.resourceType, kind.apiVersion)
+}
+
+func (p *FlexibleServerProvider) resourceURL(id, action, apiVersion string) string {
+	return p.armEndpoint + id + action + "?api-version=" + apiVersion
+}
+
+// kindOf returns the server kind of an ARM resource ID
+// ("/subscriptions/.../providers/Microsoft.DBforPostgreSQL/flexibleServers/{name}")
+func kindOf(id string) (serverKind, error) {
+	lower := strings.ToLower(id)
+	for _, kind := range serverKinds {
+		if strings.Contains(lower, "/providers/"+strings.ToLower(kind.resourceType)+"/") {
+			return kind, nil
+		}
+	}
+	return serverKind{}, fmt.Errorf("%s is not a flexible server resource ID", id)
+}
+
+func (p *FlexibleServerProvider) serverToModel(server flexibleServer, kind serverKind) models.Instance {
+	tags := server.Tags
+	if tags == nil {
+		tags = make(map[string]string)
+	}
+
+	instanceType := server.SKU.Name
+	if instanceType == "" {
+		instanceType = "unknown"
+	}
+
+	return models.Instance{
+		Provider:        "azure",
+		ID:              server.Name,
+		ProviderID:      server.ID, // ARM resource ID - start/stop and metrics address the server by it
+		Name:            server.Name,
+		Region:          server.Location,
+		InstanceType:    instanceType,
+		Engine:          kind.engine,
+		Status:          serverStatus(server.Properties.State),
+		Managed:         p.isManaged(tags),
+		Tags:            tags,
+		HourlyCostCents: serverCost(server.SKU.Tier, server.SKU.Name),
+	}
+}
+
+// serverStatus maps a Flexible Server state onto the statuses the scheduler knows
+func serverStatus(state string) string {
+	switch state {
+	case "Ready":
+		return "available"
+	case "Stopped":
+		return "stopped"
+	case "Starting":
+		return "starting"
+	case "Stopping":
+		return "stopping"
+	case "":
+		return "unknown"
+	default:
+		return strings.ToLower(state)
+	}
+}
+
+func (p *FlexibleServerProvider) isManaged(tags map[string]string) bool {
+	if len(p.managedTags) == 0 {
+		return true
+	}
+	for _, tag := range p.managedTags {
+		if _, exists := tags[tag]; exists {
+			return true
+		}
+	}
+	return false
+}
+
+// serverCost approximates the hourly compute cost from the SKU tier and its vCores
+// (the number in the SKU name, e.g. 4 for "Standard_D4ds_v4")
+func serverCost(tier, skuName string) int {
+	var perVCore int
+	switch tier {
+	case "Burstable":
+		perVCore = 3
+	case "GeneralPurpose":
+		perVCore = 9
+	case "MemoryOptimized":
+		perVCore = 13
+	default:
+		return 50
+	}
+
+	size := strings.TrimLeft(strings.TrimPrefix(skuName, "Standard_"), "ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	vCores := 0
+	for _, r := range size {
+		if r < '0' || r > '9' {
+			break
+		}
+		vCores = vCores*10 + int(r-'0')
+	}
+	if vCores == 0 {
+		vCores = 1
+	}
+	return perVCore * vCores
+}
+
+func parsePeriod(period string) (time.Duration, error) {
+	switch period {
+	case "1h", "1 hour":
+		return time.Hour, nil
+	case "24h", "1d", "1 day":
+		return 24 * time.Hour, nil
+	case "7d", "7 day", "7 days":
+		return 7 * 24 * time.Hour, nil
+	case "30d", "30 day", "30 days":
+		return 30 * 24 * time.Hour, nil
+	default:
+		return time.Hour, fmt.Errorf("unknown period: %s", period)
+	}
+}
+
+func average(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
@@ -0,0 +1,258 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	testSubscription = "sub-1"
+	testTenant       = "tenant-1"
+	pgServerID       = "/subscriptions/sub-1/resourceGroups/rg/providers/Microsoft.DBforPostgreSQL/flexibleServers/orders"
+	mysqlServerID    = "/subscriptions/sub-1/resourceGroups/rg/providers/Microsoft.DBforMySQL/flexibleServers/users"
+)
+
+// armStandIn is a local stand-in for the Azure login and Resource Manager endpoints
+type armStandIn struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	tokenCalls  int
+	expiresIn   int
+	actions     []string // "POST /path" of start/stop calls
+	state       string   // State reported for the PostgreSQL server
+	unauthCalls int
+}
+
+func newARMStandIn(t *testing.T) *armStandIn {
+	t.Helper()
+	s := &armStandIn{expiresIn: 3600, state: "Ready"}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *armStandIn) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.URL.Path == "/"+testTenant+"/oauth2/v2.0/token" {
+		r.ParseForm()
+		if r.Form.Get("grant_type") != "client_credentials" || r.Form.Get("client_secret") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error_description": "bad client credentials"})
+			return
+		}
+		s.tokenCalls++
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": fmt.Sprintf("token-%d", s.tokenCalls),
+			"expires_in":   s.expiresIn,
+		})
+		return
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer token-") {
+		s.unauthCalls++
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	server := func(id, name, sku, tier, state string) map[string]any {
+		return map[string]any{
+			"id": id, "name": name, "location": "westeurope",
+			"tags":       map[string]string{"env": "dev"},
+			"sku":        map[string]string{"name": sku, "tier": tier},
+			"properties": map[string]string{"state": state},
+		}
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/subscriptions/sub-1/providers/Microsoft.DBforPostgreSQL/flexibleServers":
+		if r.URL.Query().Get("page") == "2" {
+			json.NewEncoder(w).Encode(map[string]any{"value": []any{
+				server(strings.Replace(pgServerID, "orders", "billing", 1), "billing", "Standard_B1ms", "Burstable", "Stopped"),
+			}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"value":    []any{server(pgServerID, "orders", "Standard_D4ds_v4", "GeneralPurpose", s.state)},
+			"nextLink": s.URL + r.URL.Path + "?api-version=2022-12-01&page=2",
+		})
+	case r.Method == http.MethodGet && r.URL.Path == "/subscriptions/sub-1/providers/Microsoft.DBforMySQL/flexibleServers":
+		json.NewEncoder(w).Encode(map[string]any{"value": []any{
+			server(mysqlServerID, "users", "Standard_E2ds_v4", "MemoryOptimized", "Starting"),
+		}})
+	case r.Method == http.MethodGet && r.URL.Path == pgServerID:
+		json.NewEncoder(w).Encode(server(pgServerID, "orders", "Standard_D4ds_v4", "GeneralPurpose", s.state))
+	case r.Method == http.MethodPost && (strings.HasSuffix(r.URL.Path, "/start") || strings.HasSuffix(r.URL.Path, "/stop")):
+		if r.URL.Query().Get("api-version") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.actions = append(s.actions, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]any{"error": map[string]string{"code": "ResourceNotFound", "message": "not found"}})
+	}
+}
+
+func newTestProvider(t *testing.T, standIn *armStandIn, managedTags []string) *FlexibleServerProvider {
+	t.Helper()
+	p, err := NewFlexibleServerProvider(testSubscription, managedTags, testTenant, "client", "secret")
+	if err != nil {
+		t.Fatalf("NewFlexibleServerProvider: %v", err)
+	}
+	p.SetEndpoints(standIn.URL, standIn.URL+"/")
+	return p
+}
+
+func TestListDatabasesFollowsNextLink(t *testing.T) {
+	standIn := newARMStandIn(t)
+	p := newTestProvider(t, standIn, nil)
+
+	instances, err := p.ListDatabases(context.Background())
+	if err != nil {
+		t.Fatalf("ListDatabases: %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, instance := range instances {
+		got[instance.Name] = instance.Engine + "/" + instance.Status
+		if instance.Provider != "azure" || instance.Region != "westeurope" || instance.Tags["env"] != "dev" {
+			t.Errorf("%s: unexpected instance %+v", instance.Name, instance)
+		}
+	}
+	want := map[string]string{
+		"orders":  "postgres/available",
+		"billing": "postgres/stopped",
+		"users":   "mysql/starting",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("%s = %q, want %q", name, got[name], value)
+		}
+	}
+	if instances[0].ProviderID != pgServerID {
+		t.Errorf("ProviderID = %q, want the ARM resource ID", instances[0].ProviderID)
+	}
+	if instances[0].HourlyCostCents != 36 {
+		t.Errorf("cost of a 4 vCore GeneralPurpose server = %d, want 36", instances[0].HourlyCostCents)
+	}
+}
+
+func TestAccessTokenIsCached(t *testing.T) {
+	standIn := newARMStandIn(t)
+	p := newTestProvider(t, standIn, nil)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := p.GetDatabaseStatus(ctx, pgServerID); err != nil {
+			t.Fatalf("GetDatabaseStatus: %v", err)
+		}
+	}
+	if standIn.tokenCalls != 1 {
+		t.Errorf("fetched %d tokens for 3 requests, want 1", standIn.tokenCalls)
+	}
+
+	// A token about to expire is replaced
+	standIn.expiresIn = 30
+	p.token = ""
+	for i := 0; i < 2; i++ {
+		if _, err := p.GetDatabaseStatus(ctx, pgServerID); err != nil {
+			t.Fatalf("GetDatabaseStatus: %v", err)
+		}
+	}
+	if standIn.tokenCalls != 3 {
+		t.Errorf("fetched %d tokens in total, want 3 (short-lived tokens aren't reused)", standIn.tokenCalls)
+	}
+	if standIn.unauthCalls != 0 {
+		t.Errorf("%d requests without a bearer token", standIn.unauthCalls)
+	}
+}
+
+func TestAccessTokenError(t *testing.T) {
+	standIn := newARMStandIn(t)
+	p := newTestProvider(t, standIn, nil)
+	p.clientSecret = "wrong"
+
+	_, err := p.ListDatabases(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "bad client credentials") {
+		t.Errorf("err = %v, want the login error", err)
+	}
+}
+
+func TestStartStop(t *testing.T) {
+	standIn := newARMStandIn(t)
+	p := newTestProvider(t, standIn, nil)
+	ctx := context.Background()
+
+	if err := p.StopDatabase(ctx, pgServerID); err != nil {
+		t.Fatalf("StopDatabase: %v", err)
+	}
+	if err := p.StartDatabase(ctx, mysqlServerID); err != nil {
+		t.Fatalf("StartDatabase: %v", err)
+	}
+	want := []string{"POST " + pgServerID + "/stop", "POST " + mysqlServerID + "/start"}
+	if strings.Join(standIn.actions, ",") != strings.Join(want, ",") {
+		t.Errorf("actions = %v, want %v", standIn.actions, want)
+	}
+
+	if err := p.StopDatabase(ctx, "/subscriptions/sub-1/resourceGroups/rg/providers/Microsoft.Sql/servers/x"); err == nil {
+		t.Error("stopping a non-flexible-server resource should fail")
+	}
+}
+
+func TestGetDatabaseStatus(t *testing.T) {
+	standIn := newARMStandIn(t)
+	p := newTestProvider(t, standIn, nil)
+
+	tests := []struct {
+		state string
+		want  string
+	}{
+		{"Ready", "available"},
+		{"Stopped", "stopped"},
+		{"Starting", "starting"},
+		{"Stopping", "stopping"},
+		{"Updating", "updating"},
+		{"", "unknown"},
+	}
+	for _, tt := range tests {
+		standIn.state = tt.state
+		status, err := p.GetDatabaseStatus(context.Background(), pgServerID)
+		if err != nil {
+			t.Fatalf("GetDatabaseStatus: %v", err)
+		}
+		if status != tt.want {
+			t.Errorf("state %q = %q, want %q", tt.state, status, tt.want)
+		}
+	}
+
+	_, err := p.GetDatabaseStatus(context.Background(), strings.Replace(pgServerID, "orders", "missing", 1))
+	if err == nil || !strings.Contains(err.Error(), "ResourceNotFound") {
+		t.Errorf("err = %v, want the ARM error code", err)
+	}
+}
+
+func TestManagedTags(t *testing.T) {
+	standIn := newARMStandIn(t)
+	p := newTestProvider(t, standIn, []string{"snoozeql"})
+
+	instance, err := p.GetDatabaseByID(context.Background(), pgServerID)
+	if err != nil {
+		t.Fatalf("GetDatabaseByID: %v", err)
+	}
+	if instance.Managed {
+		t.Error("server without a managed tag is marked managed")
+	}
+}
@@ -0,0 +1,275 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// serviceAccountDir holds the in-cluster service account files, used when no kubeconfig is given
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// kubeconfig is the part of a kubeconfig file the provider uses. It may be YAML or
+// JSON, and must carry its certificates inline (`kubectl config view --raw --flatten`).
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string   `yaml:"name"`
+		User kubeUser `yaml:"user"`
+	} `yaml:"users"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster string `yaml:"cluster"`
+			User    string `yaml:"user"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+// kubeUser is a kubeconfig user entry. Only tokens, client certificates and basic auth
+// are supported; exec plugins and auth providers (the EKS/GKE/AKS defaults) need their
+// CLIs, so such users are rejected.
+type kubeUser struct {
+	Token                 string         `yaml:"token"`
+	TokenFile             string         `yaml:"tokenFile"`
+	ClientCertificateData string         `yaml:"client-certificate-data"`
+	ClientKeyData         string         `yaml:"client-key-data"`
+	ClientCertificate     string         `yaml:"client-certificate"`
+	ClientKey             string         `yaml:"client-key"`
+	Username              string         `yaml:"username"`
+	Password              string         `yaml:"password"`
+	Exec                  map[string]any `yaml:"exec"`
+	AuthProvider          map[string]any `yaml:"auth-provider"`
+}
+
+// validate checks that the user authenticates in a way the provider supports
+func (u kubeUser) validate(name string) error {
+	switch {
+	case u.Exec != nil:
+		return fmt.Errorf("user %q authenticates with an exec plugin, which is not supported; use a service account token (kubectl create token) or a client certificate", name)
+	case u.AuthProvider != nil:
+		return fmt.Errorf("user %q authenticates with an auth provider, which is not supported; use a service account token (kubectl create token) or a client certificate", name)
+	case u.TokenFile != "" || u.ClientCertificate != "" || u.ClientKey != "":
+		return fmt.Errorf("user %q refers to credential files; inline them with kubectl config view --raw --flatten", name)
+	case u.Token == "" && u.ClientCertificateData == "" && u.Username == "":
+		return fmt.Errorf("user %q has no token, client certificate or username", name)
+	}
+	return nil
+}
+
+// apiClient talks to the Kubernetes API server of one kubeconfig context
+type apiClient struct {
+	httpClient *http.Client
+	server     string
+	token      string
+	username   string
+	password   string
+}
+
+// newAPIClient builds a client for the context (or the current context, if empty) of
+// a YAML or JSON kubeconfig. An empty kubeconfig means the in-cluster service account.
+func newAPIClient(kubeconfigData, contextName string) (*apiClient, error) {
+	if strings.TrimSpace(kubeconfigData) == "" {
+		return inClusterClient()
+	}
+
+	// JSON is YAML too
+	var config kubeconfig
+	if err := yaml.Unmarshal([]byte(kubeconfigData), &config); err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig: %w", err)
+	}
+
+	if contextName == "" {
+		contextName = config.CurrentContext
+	}
+	var clusterName, userName string
+	found := false
+	for _, c := range config.Contexts {
+		if c.Name == contextName {
+			clusterName, userName, found = c.Context.Cluster, c.Context.User, true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("context %q not found in kubeconfig", contextName)
+	}
+
+	client := &apiClient{}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	found = false
+	for _, c := range config.Clusters {
+		if c.Name != clusterName {
+			continue
+		}
+		found = true
+		client.server = strings.TrimSuffix(c.Cluster.Server, "/")
+		tlsConfig.InsecureSkipVerify = c.Cluster.InsecureSkipTLSVerify
+		if c.Cluster.CertificateAuthorityData == "" && c.Cluster.CertificateAuthority != "" {
+			return nil, fmt.Errorf("cluster %q refers to a certificate-authority file; inline it with kubectl config view --raw --flatten", clusterName)
+		}
+		if c.Cluster.CertificateAuthorityData != "" {
+			ca, err := base64.StdEncoding.DecodeString(c.Cluster.CertificateAuthorityData)
+			if err != nil {
+				return nil, fmt.Errorf("invalid certificate-authority-data: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(ca) {
+				return nil, fmt.Errorf("no certificates in certificate-authority-data")
+			}
+			tlsConfig.RootCAs = pool
+		}
+	}
+	if !found || client.server == "" {
+		return nil, fmt.Errorf("cluster %q not found in kubeconfig", clusterName)
+	}
+
+	found = false
+	for _, u := range config.Users {
+		if u.Name != userName {
+			continue
+		}
+		found = true
+		if err := u.User.validate(userName); err != nil {
+			return nil, err
+		}
+		client.token = u.User.Token
+		client.username = u.User.Username
+		client.password = u.User.Password
+		if u.User.ClientCertificateData != "" {
+			certPEM, errCert := base64.StdEncoding.DecodeString(u.User.ClientCertificateData)
+			keyPEM, errKey := base64.StdEncoding.DecodeString(u.User.ClientKeyData)
+			if errCert != nil || errKey != nil {
+				return nil, fmt.Errorf("invalid client certificate data")
+			}
+			cert, err := tls.X509KeyPair(certPEM, keyPEM)
+			if err != nil {
+				return nil, fmt.Errorf("invalid client certificate: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		break
+	}
+	if !found {
+		return nil, fmt.Errorf("user %q of context %q not found in kubeconfig", userName, contextName)
+	}
+
+	client.httpClient = &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}
+	return client, nil
+}
+
+// inClusterClient builds a client from the pod's service account
+func inClusterClient() (*apiClient, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("no kubeconfig given and not running in a cluster")
+	}
+	token, err := os.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %w", err)
+	}
+	ca, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(ca)
+
+	return &apiClient{
+		httpClient: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: pool}},
+		},
+		server: "https://" + host + ":" + port,
+		token:  strings.TrimSpace(string(token)),
+	}, nil
+}
+
+// apiError is a non-2xx response from the API server
+type apiError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("status %d: %s", e.StatusCode, e.Message)
+}
+
+// do sends a request to the API server and decodes the JSON response into out (if not
+// nil). A non-nil patch is sent as a JSON merge patch.
+func (c *apiClient) do(ctx context.Context, method, path string, patch any, out any) error {
+	var body io.Reader
+	if patch != nil {
+		data, err := json.Marshal(patch)
+		if err != nil {
+			return fmt.Errorf("failed to marshal patch: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.server+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if patch != nil {
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+	}
+	switch {
+	case c.token != "":
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	case c.username != "":
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// The API server answers with a Status object
+		var status struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &status) != nil || status.Message == "" {
+			status.Message = http.StatusText(resp.StatusCode)
+		}
+		return &apiError{StatusCode: resp.StatusCode, Message: status.Message}
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewAPIClient(t *testing.T) {
+	const jsonConfig = `{
+    "apiVersion": "v1",
+    "kind": "Config",
+    "current-context": "dev",
+    "clusters": [
+        {"name": "dev", "cluster": {"server": "https://dev.example.com:6443/", "insecure-skip-tls-verify": true}},
+        {"name": "prod", "cluster": {"server": "https://prod.example.com:6443"}}
+    ],
+    "contexts": [
+        {"name": "dev", "context": {"cluster": "dev", "user": "dev-admin"}},
+        {"name": "prod", "context": {"cluster": "prod", "user": "prod-admin"}}
+    ],
+    "users": [
+        {"name": "dev-admin", "user": {"token": "dev-token"}},
+        {"name": "prod-admin", "user": {"username": "admin", "password": "secret"}}
+    ]
+}`
+
+	// yamlConfig returns a single-context YAML kubeconfig with the given user entry
+	yamlConfig := func(user string) string {
+		return `apiVersion: v1
+kind: Config
+current-context: eks
+clusters:
+- name: eks
+  cluster:
+    server: https://eks.example.com
+contexts:
+- name: eks
+  context:
+    cluster: eks
+    user: eks-user
+users:
+- name: eks-user
+  user:
+` + user
+	}
+
+	tests := []struct {
+		name       string
+		kubeconfig string
+		context    string
+		wantServer string
+		wantToken  string
+		wantUser   string
+		wantErr    string
+	}{
+		{name: "JSON, current context", kubeconfig: jsonConfig, wantServer: "https://dev.example.com:6443", wantToken: "dev-token"},
+		{name: "JSON, named context", kubeconfig: jsonConfig, context: "prod", wantServer: "https://prod.example.com:6443", wantUser: "admin"},
+		{name: "YAML token", kubeconfig: yamlConfig("    token: eks-token\n"), wantServer: "https://eks.example.com", wantToken: "eks-token"},
+		{name: "missing context", kubeconfig: jsonConfig, context: "staging", wantErr: `context "staging" not found`},
+		{
+			name:       "missing user",
+			kubeconfig: strings.Replace(jsonConfig, `"name": "dev-admin"`, `"name": "someone-else"`, 1),
+			wantErr:    `user "dev-admin" of context "dev" not found`,
+		},
+		{
+			name:       "exec plugin",
+			kubeconfig: yamlConfig("    exec:\n      apiVersion: client.authentication.k8s.io/v1beta1\n      command: aws\n      args: [eks, get-token, --cluster-name, eks]\n"),
+			wantErr:    "exec plugin, which is not supported",
+		},
+		{
+			name:       "auth provider",
+			kubeconfig: yamlConfig("    auth-provider:\n      name: gcp\n"),
+			wantErr:    "auth provider, which is not supported",
+		},
+		{name: "credential files", kubeconfig: yamlConfig("    client-certificate: /home/me/.kube/client.crt\n    client-key: /home/me/.kube/client.key\n"), wantErr: "--flatten"},
+		{name: "no credentials", kubeconfig: yamlConfig("    {}\n"), wantErr: "no token, client certificate or username"},
+		{name: "not a kubeconfig", kubeconfig: "clusters: [", wantErr: "invalid kubeconfig"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := newAPIClient(tt.kubeconfig, tt.context)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newAPIClient: %v", err)
+			}
+			if client.server != tt.wantServer || client.token != tt.wantToken || client.username != tt.wantUser {
+				t.Errorf("client = %s, token %q, user %q; want %s, token %q, user %q",
+					client.server, client.token, client.username, tt.wantServer, tt.wantToken, tt.wantUser)
+			}
+		})
+	}
+}
@@ -0,0 +1,436 @@
+// Package kubernetes provides a provider for databases running in Kubernetes: plain
+// StatefulSets, which sleep by scaling to zero replicas, and CloudNativePG clusters,
+// which sleep through declarative hibernation.
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"snoozeql/internal/models"
+)
+
+const (
+	// DefaultLabelSelector selects the workloads discovered when none is configured
+	DefaultLabelSelector = "snoozeql.io/discover=true"
+
+	// previousReplicasAnnotation remembers a StatefulSet's replica count while it is scaled to zero
+	previousReplicasAnnotation = "snoozeql.io/previous-replicas"
+	// costAnnotation gives a workload's hourly cost in cents, since it can't be derived
+	costAnnotation = "snoozeql.io/hourly-cost-cents"
+	// engineLabel names a StatefulSet's engine when its image doesn't tell
+	engineLabel = "snoozeql.io/engine"
+
+	cnpgHibernationAnnotation = "cnpg.io/hibernation"
+)
+
+// Workload kinds, the first segment of a provider ID ("{kind}/{namespace}/{name}")
+const (
+	kindStatefulSet = "statefulset"
+	kindCNPG        = "cnpg"
+)
+
+// KubernetesProvider implements the Provider interface for database workloads in one
+// Kubernetes cluster
+type KubernetesProvider struct {
+	client        *apiClient
+	namespaces    []string
+	labelSelector string
+	managedTags   []string
+}
+
+// objectMeta is the metadata the provider reads from workloads
+type objectMeta struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+type statefulSet struct {
+	Metadata objectMeta `json:"metadata"`
+	Spec     struct {
+		Replicas *int `json:"replicas"`
+		Template struct {
+			Spec struct {
+				Containers []struct {
+					Image string `json:"image"`
+				} `json:"containers"`
+			} `json:"spec"`
+		} `json:"template"`
+	} `json:"spec"`
+	Status struct {
+		Replicas      int `json:"replicas"`
+		ReadyReplicas int `json:"readyReplicas"`
+	} `json:"status"`
+}
+
+type cnpgCluster struct {
+	Metadata objectMeta `json:"metadata"`
+	Spec     struct {
+		Instances int `json:"instances"`
+	} `json:"spec"`
+}
+
+type pod struct {
+	Status struct {
+		Conditions []struct {
+			Type   string `json:"type"`
+			Status string `json:"status"`
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+// NewKubernetesProvider creates a provider for the context (empty for the current one)
+// of a YAML or JSON kubeconfig, or for the cluster it runs in if kubeconfig is empty. Workloads
+// matching labelSelector (DefaultLabelSelector if empty) in namespaces (all if empty)
+// are discovered.
+func NewKubernetesProvider(kubeconfig, contextName string, namespaces []string, labelSelector string, managedTags []string) (*KubernetesProvider, error) {
+	client, err := newAPIClient(kubeconfig, contextName)
+	if err != nil {
+		return nil, err
+	}
+	if labelSelector == "" {
+		labelSelector = DefaultLabelSelector
+	}
+	return &KubernetesProvider{
+		client:        client,
+		namespaces:    namespaces,
+		labelSelector: labelSelector,
+		managedTags:   managedTags,
+	}, nil
+}
+
+// TestConnection tests if the workloads can be listed
+func (p *KubernetesProvider) TestConnection(ctx context.Context) error {
+	var list struct{}
+	if err := p.client.do(ctx, http.MethodGet, p.listPath("/apis/apps/v1", "statefulsets", p.firstNamespace())+"&limit=1", nil, &list); err != nil {
+		return fmt.Errorf("failed to test Kubernetes connection: %w", err)
+	}
+	return nil
+}
+
+// ListDatabases returns the matching StatefulSets and CloudNativePG clusters
+func (p *KubernetesProvider) ListDatabases(ctx context.Context) ([]models.Instance, error) {
+	var instances []models.Instance
+
+	namespaces := p.namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{""} // All namespaces
+	}
+
+	for _, namespace := range namespaces {
+		var sets struct {
+			Items []statefulSet `json:"items"`
+		}
+		if err := p.client.do(ctx, http.MethodGet, p.listPath("/apis/apps/v1", "statefulsets", namespace), nil, &sets); err != nil {
+			return nil, fmt.Errorf("failed to list statefulsets: %w", err)
+		}
+		for _, set := range sets.Items {
+			instances = append(instances, p.statefulSetToModel(set))
+		}
+
+		var clusters struct {
+			Items []cnpgCluster `json:"items"`
+		}
+		err := p.client.do(ctx, http.MethodGet, p.listPath("/apis/postgresql.cnpg.io/v1", "clusters", namespace), nil, &clusters)
+		if isNotFound(err) {
+			continue // CloudNativePG isn't installed
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list CloudNativePG clusters: %w", err)
+		}
+		for _, cluster := range clusters.Items {
+			ready, total, err := p.podReadiness(ctx, cluster.Metadata.Namespace, cluster.Metadata.Name)
+			if err != nil {
+				return nil, err
+			}
+			instances = append(instances, p.cnpgClusterToModel(cluster, ready, total))
+		}
+	}
+
+	return instances, nil
+}
+
+// StartDatabase scales a StatefulSet back to the replica count it had before it was
+// stopped, or ends the hibernation of a CloudNativePG cluster
+func (p *KubernetesProvider) StartDatabase(ctx context.Context, id string) error {
+	kind, namespace, name, err := parseID(id)
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case kindStatefulSet:
+		var set statefulSet
+		if err := p.client.do(ctx, http.MethodGet, objectPath("/apis/apps/v1", "statefulsets", namespace, name), nil, &set); err != nil {
+			return fmt.Errorf("failed to get statefulset %s: %w", id, err)
+		}
+		replicas := 1
+		if previous, err := strconv.Atoi(set.Metadata.Annotations[previousReplicasAnnotation]); err == nil && previous > 0 {
+			replicas = previous
+		}
+		patch := map[string]any{
+			"metadata": map[string]any{"annotations": map[string]any{previousReplicasAnnotation: nil}},
+			"spec":     map[string]any{"replicas": replicas},
+		}
+		if err := p.client.do(ctx, http.MethodPatch, objectPath("/apis/apps/v1", "statefulsets", namespace, name), patch, nil); err != nil {
+			return fmt.Errorf("failed to scale up statefulset %s: %w", id, err)
+		}
+	case kindCNPG:
+		if err := p.hibernate(ctx, namespace, name, "off"); err != nil {
+			return fmt.Errorf("failed to start CloudNativePG cluster %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// StopDatabase scales a StatefulSet to zero replicas, remembering its replica count, or
+// hibernates a CloudNativePG cluster
+func (p *KubernetesProvider) StopDatabase(ctx context.Context, id string) error {
+	kind, namespace, name, err := parseID(id)
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case kindStatefulSet:
+		var set statefulSet
+		if err := p.client.do(ctx, http.MethodGet, objectPath("/apis/apps/v1", "statefulsets", namespace, name), nil, &set); err != nil {
+			return fmt.Errorf("failed to get statefulset %s: %w", id, err)
+		}
+		if set.Spec.Replicas != nil && *set.Spec.Replicas == 0 {
+			return nil // Already scaled down; keep the remembered count
+		}
+		replicas := 1
+		if set.Spec.Replicas != nil {
+			replicas = *set.Spec.Replicas
+		}
+		patch := map[string]any{
+			"metadata": map[string]any{"annotations": map[string]any{previousReplicasAnnotation: strconv.Itoa(replicas)}},
+			"spec":     map[string]any{"replicas": 0},
+		}
+		if err := p.client.do(ctx, http.MethodPatch, objectPath("/apis/apps/v1", "statefulsets", namespace, name), patch, nil); err != nil {
+			return fmt.Errorf("failed to scale down statefulset %s: %w", id, err)
+		}
+	case kindCNPG:
+		if err := p.hibernate(ctx, namespace, name, "on"); err != nil {
+			return fmt.Errorf("failed to stop CloudNativePG cluster %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// GetDatabaseStatus returns the current status of a database
+func (p *KubernetesProvider) GetDatabaseStatus(ctx context.Context, id string) (string, error) {
+	instance, err := p.GetDatabaseByID(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return instance.Status, nil
+}
+
+// GetMetrics returns activity metrics for a database; Kubernetes workloads have none
+func (p *KubernetesProvider) GetMetrics(ctx context.Context, providerName string, id string, period string) (map[string]any, error) {
+	return map[string]any{
+		"cpu_error":         "metrics are not collected for Kubernetes databases",
+		"connections_error": "metrics are not collected for Kubernetes databases",
+	}, nil
+}
+
+// GetDatabaseByID returns a database by its provider ID
+func (p *KubernetesProvider) GetDatabaseByID(ctx context.Context, id string) (*models.Instance, error) {
+	kind, namespace, name, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var instance models.Instance
+	switch kind {
+	case kindStatefulSet:
+		var set statefulSet
+		if err := p.client.do(ctx, http.MethodGet, objectPath("/apis/apps/v1", "statefulsets", namespace, name), nil, &set); err != nil {
+			return nil, fmt.Errorf("failed to get statefulset %s: %w", id, err)
+		}
+		instance = p.statefulSetToModel(set)
+	case kindCNPG:
+		var cluster cnpgCluster
+		if err := p.client.do(ctx, http.MethodGet, objectPath("/apis/postgresql.cnpg.io/v1", "clusters", namespace, name), nil, &cluster); err != nil {
+			return nil, fmt.Errorf("failed to get CloudNativePG cluster %s: %w", id, err)
+		}
+		ready, total, err := p.podReadiness(ctx, namespace, name)
+		if err != nil {
+			return nil, err
+		}
+		instance = p.cnpgClusterToModel(cluster, ready, total)
+	}
+	return &instance, nil
+}
+
+// hibernate sets a CloudNativePG cluster's hibernation annotation ("on" or "off")
+func (p *KubernetesProvider) hibernate(ctx context.Context, namespace, name, state string) error {
+	patch := map[string]any{
+		"metadata": map[string]any{"annotations": map[string]any{cnpgHibernationAnnotation: state}},
+	}
+	return p.client.do(ctx, http.MethodPatch, objectPath("/apis/postgresql.cnpg.io/v1", "clusters", namespace, name), patch, nil)
+}
+
+// podReadiness counts the ready and all pods of a CloudNativePG cluster
+func (p *KubernetesProvider) podReadiness(ctx context.Context, namespace, name string) (int, int, error) {
+	var pods struct {
+		Items []pod `json:"items"`
+	}
+	path := fmt.Sprintf("/api/v1/namespaces/%s/pods?labelSelector=%s", url.PathEscape(namespace), url.QueryEscape("cnpg.io/cluster="+name))
+	if err := p.client.do(ctx, http.MethodGet, path, nil, &pods); err != nil {
+		return 0, 0, fmt.Errorf("failed to list pods of CloudNativePG cluster %s/%s: %w", namespace, name, err)
+	}
+
+	ready := 0
+	for _, pod := range pods.Items {
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == "Ready" && condition.Status == "True" {
+				ready++
+				break
+			}
+		}
+	}
+	return ready, len(pods.Items), nil
+}
+
+func (p *KubernetesProvider) statefulSetToModel(set statefulSet) models.Instance {
+	desired := 1
+	if set.Spec.Replicas != nil {
+		desired = *set.Spec.Replicas
+	}
+
+	var status string
+	switch {
+	case desired == 0 && set.Status.Replicas == 0:
+		status = "stopped"
+	case desired == 0:
+		status = "stopping"
+	case set.Status.ReadyReplicas >= desired:
+		status = "available"
+	default:
+		status = "starting"
+	}
+
+	engine := set.Metadata.Labels[engineLabel]
+	if engine == "" {
+		engine = "unknown"
+		for _, container := range set.Spec.Template.Spec.Containers {
+			if e := engineFromImage(container.Image); e != "" {
+				engine = e
+				break
+			}
+		}
+	}
+
+	return p.workloadToModel(kindStatefulSet, set.Metadata, engine, status)
+}
+
+func (p *KubernetesProvider) cnpgClusterToModel(cluster cnpgCluster, ready, total int) models.Instance {
+	var status string
+	hibernated := cluster.Metadata.Annotations[cnpgHibernationAnnotation] == "on"
+	switch {
+	case hibernated && total == 0:
+		status = "stopped"
+	case hibernated:
+		status = "stopping"
+	case ready > 0 && ready >= cluster.Spec.Instances:
+		status = "available"
+	default:
+		status = "starting"
+	}
+
+	return p.workloadToModel(kindCNPG, cluster.Metadata, "postgres", status)
+}
+
+// workloadToModel builds the instance of a workload. Its namespace stands in for the
+// region; labels become tags.
+func (p *KubernetesProvider) workloadToModel(kind string, meta objectMeta, engine, status string) models.Instance {
+	tags := meta.Labels
+	if tags == nil {
+		tags = make(map[string]string)
+	}
+
+	cost, _ := strconv.Atoi(meta.Annotations[costAnnotation])
+
+	return models.Instance{
+		Provider:        "kubernetes",
+		ID:              meta.Namespace + "/" + meta.Name,
+		ProviderID:      kind + "/" + meta.Namespace + "/" + meta.Name,
+		Name:            meta.Name,
+		Region:          meta.Namespace,
+		InstanceType:    kind,
+		Engine:          engine,
+		Status:          status,
+		Managed:         p.isManaged(tags),
+		Tags:            tags,
+		HourlyCostCents: cost,
+	}
+}
+
+func (p *KubernetesProvider) isManaged(tags map[string]string) bool {
+	if len(p.managedTags) == 0 {
+		return true
+	}
+	for _, tag := range p.managedTags {
+		if _, exists := tags[tag]; exists {
+			return true
+		}
+	}
+	return false
+}
+
+// engineFromImage guesses the database engine from a container image name
+func engineFromImage(image string) string {
+	repository, _, _ := strings.Cut(image[strings.LastIndex(image, "/")+1:], ":")
+	switch {
+	case strings.Contains(repository, "postgres"):
+		return "postgres"
+	case strings.Contains(repository, "mysql"), strings.Contains(repository, "mariadb"):
+		return "mysql"
+	default:
+		return ""
+	}
+}
+
+// parseID splits a provider ID ("{kind}/{namespace}/{name}")
+func parseID(id string) (string, string, string, error) {
+	parts := strings.Split(id, "/")
+	if len(parts) != 3 || (parts[0] != kindStatefulSet && parts[0] != kindCNPG) || parts[1] == "" || parts[2] == "" {
+		return "", "", "", fmt.Errorf("invalid Kubernetes database ID %q (want statefulset|cnpg/{namespace}/{name})", id)
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+func (p *KubernetesProvider) firstNamespace() string {
+	if len(p.namespaces) > 0 {
+		return p.namespaces[0]
+	}
+	return ""
+}
+
+// listPath returns the path listing the matching resources of a namespace (all if empty)
+func (p *KubernetesProvider) listPath(group, resource, namespace string) string {
+	path := group
+	if namespace != "" {
+		path += "/namespaces/" + url.PathEscape(namespace)
+	}
+	return path + "/" + resource + "?labelSelector=" + url.QueryEscape(p.labelSelector)
+}
+
+func objectPath(group, resource, namespace, name string) string {
+	return fmt.Sprintf("%s/namespaces/%s/%s/%s", group, url.PathEscape(namespace), resource, url.PathEscape(name))
+}
+
+func isNotFound(err error) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,521 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testToken = "test-token"
+
+// apiObject is a workload held by the stand-in API server
+type apiObject struct {
+	group, resource, namespace string
+	body                       map[string]any
+}
+
+// apiStandIn is a local stand-in for a Kubernetes API server. It serves the
+// StatefulSets and CloudNativePG clusters it holds, applies merge patches to them and
+// lists the pods of CloudNativePG clusters.
+type apiStandIn struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	objects map[string]*apiObject       // By object path
+	pods    map[string][]map[string]any // By "{namespace}/{cluster}"
+	patches []string                    // Paths of merge patches, in order
+	noCNPG  bool                        // Whether the CloudNativePG CRD is missing
+}
+
+func newAPIStandIn(t *testing.T) *apiStandIn {
+	t.Helper()
+	s := &apiStandIn{objects: make(map[string]*apiObject), pods: make(map[string][]map[string]any)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *apiStandIn) addStatefulSet(namespace, name string, labels, annotations map[string]any, replicas, ready int, image string) {
+	s.objects[objectPath("/apis/apps/v1", "statefulsets", namespace, name)] = &apiObject{
+		group: "/apis/apps/v1", resource: "statefulsets", namespace: namespace,
+		body: map[string]any{
+			"metadata": map[string]any{"name": name, "namespace": namespace, "labels": labels, "annotations": annotations},
+			"spec": map[string]any{
+				"replicas": replicas,
+				"template": map[string]any{"spec": map[string]any{"containers": []any{map[string]any{"image": image}}}},
+			},
+			"status": map[string]any{"replicas": replicas, "readyReplicas": ready},
+		},
+	}
+}
+
+func (s *apiStandIn) addCNPGCluster(namespace, name string, labels, annotations map[string]any, instances int) {
+	s.objects[objectPath("/apis/postgresql.cnpg.io/v1", "clusters", namespace, name)] = &apiObject{
+		group: "/apis/postgresql.cnpg.io/v1", resource: "clusters", namespace: namespace,
+		body: map[string]any{
+			"metadata": map[string]any{"name": name, "namespace": namespace, "labels": labels, "annotations": annotations},
+			"spec":     map[string]any{"instances": instances},
+		},
+	}
+}
+
+// setPods replaces the pods of a CloudNativePG cluster with ready and unready ones
+func (s *apiStandIn) setPods(namespace, cluster string, ready, unready int) {
+	var pods []map[string]any
+	for i := 0; i < ready+unready; i++ {
+		status := "True"
+		if i >= ready {
+			status = "False"
+		}
+		pods = append(pods, map[string]any{"status": map[string]any{"conditions": []any{
+			map[string]any{"type": "Initialized", "status": "True"},
+			map[string]any{"type": "Ready", "status": status},
+		}}})
+	}
+	s.pods[namespace+"/"+cluster] = pods
+}
+
+// object returns the stored body of a workload
+func (s *apiStandIn) object(group, resource, namespace, name string) map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.objects[objectPath(group, resource, namespace, name)].body
+}
+
+func (s *apiStandIn) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fail := func(status int, message string) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]any{"kind": "Status", "message": message})
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+testToken {
+		fail(http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	path := r.URL.EscapedPath()
+	switch {
+	case r.Method == http.MethodPatch:
+		object, ok := s.objects[path]
+		if !ok {
+			fail(http.StatusNotFound, "not found")
+			return
+		}
+		if r.Header.Get("Content-Type") != "application/merge-patch+json" {
+			fail(http.StatusUnsupportedMediaType, "unsupported patch type")
+			return
+		}
+		var patch map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		mergePatch(object.body, patch)
+		s.patches = append(s.patches, path)
+		json.NewEncoder(w).Encode(object.body)
+
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/api/v1/namespaces/") && strings.HasSuffix(path, "/pods"):
+		namespace := strings.TrimSuffix(strings.TrimPrefix(path, "/api/v1/namespaces/"), "/pods")
+		cluster, ok := strings.CutPrefix(r.URL.Query().Get("labelSelector"), "cnpg.io/cluster=")
+		if !ok {
+			fail(http.StatusBadRequest, "expected a cnpg.io/cluster selector")
+			return
+		}
+		items := s.pods[namespace+"/"+cluster]
+		if items == nil {
+			items = []map[string]any{}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"items": items})
+
+	case r.Method == http.MethodGet && s.objects[path] != nil:
+		if s.noCNPG && strings.HasPrefix(path, "/apis/postgresql.cnpg.io/") {
+			fail(http.StatusNotFound, "the server could not find the requested resource")
+			return
+		}
+		json.NewEncoder(w).Encode(s.objects[path].body)
+
+	case r.Method == http.MethodGet && r.URL.Query().Has("labelSelector"):
+		group, namespace, resource, ok := parseListPath(path)
+		if !ok {
+			fail(http.StatusNotFound, "not found")
+			return
+		}
+		if s.noCNPG && group == "/apis/postgresql.cnpg.io/v1" {
+			fail(http.StatusNotFound, "the server could not find the requested resource")
+			return
+		}
+		key, value, _ := strings.Cut(r.URL.Query().Get("labelSelector"), "=")
+		items := []any{}
+		for _, object := range s.objects {
+			if object.group != group || object.resource != resource || (namespace != "" && object.namespace != namespace) {
+				continue
+			}
+			labels, _ := object.body["metadata"].(map[string]any)["labels"].(map[string]any)
+			if labels[key] == value {
+				items = append(items, object.body)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"items": items})
+
+	default:
+		fail(http.StatusNotFound, "not found")
+	}
+}
+
+// parseListPath splits a list path ("{group}[/namespaces/{namespace}]/{resource}")
+func parseListPath(path string) (string, string, string, bool) {
+	for _, group := range []string{"/apis/apps/v1", "/apis/postgresql.cnpg.io/v1"} {
+		rest, ok := strings.CutPrefix(path, group+"/")
+		if !ok {
+			continue
+		}
+		if namespaced, ok := strings.CutPrefix(rest, "namespaces/"); ok {
+			namespace, resource, _ := strings.Cut(namespaced, "/")
+			namespace, _ = url.PathUnescape(namespace)
+			return group, namespace, resource, true
+		}
+		return group, "", rest, true
+	}
+	return "", "", "", false
+}
+
+// mergePatch applies a JSON merge patch (RFC 7386) to target
+func mergePatch(target, patch map[string]any) {
+	for key, value := range patch {
+		switch value := value.(type) {
+		case nil:
+			delete(target, key)
+		case map[string]any:
+			child, _ := target[key].(map[string]any)
+			if child == nil {
+				child = make(map[string]any)
+				target[key] = child
+			}
+			mergePatch(child, value)
+		default:
+			target[key] = value
+		}
+	}
+}
+
+func testKubeconfig(server string) string {
+	return fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: test
+clusters:
+- name: test
+  cluster:
+    server: %s
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: snoozeql
+users:
+- name: snoozeql
+  user:
+    token: %s
+`, server, testToken)
+}
+
+func newTestProvider(t *testing.T, standIn *apiStandIn, namespaces []string, managedTags []string) *KubernetesProvider {
+	t.Helper()
+	p, err := NewKubernetesProvider(testKubeconfig(standIn.URL), "", namespaces, "", managedTags)
+	if err != nil {
+		t.Fatalf("NewKubernetesProvider: %v", err)
+	}
+	return p
+}
+
+var discoverLabels = map[string]any{"snoozeql.io/discover": "true", "team": "orders"}
+
+func TestStatefulSetScalesToZeroAndBack(t *testing.T) {
+	standIn := newAPIStandIn(t)
+	standIn.addStatefulSet("db", "orders", discoverLabels, nil, 3, 3, "postgres:16")
+	p := newTestProvider(t, standIn, nil, nil)
+	ctx := context.Background()
+	id := "statefulset/db/orders"
+
+	if err := p.StopDatabase(ctx, id); err != nil {
+		t.Fatalf("StopDatabase: %v", err)
+	}
+	set := standIn.object("/apis/apps/v1", "statefulsets", "db", "orders")
+	if replicas := set["spec"].(map[string]any)["replicas"]; replicas != float64(0) {
+		t.Errorf("replicas after stop = %v, want 0", replicas)
+	}
+	annotations := set["metadata"].(map[string]any)["annotations"].(map[string]any)
+	if annotations[previousReplicasAnnotation] != "3" {
+		t.Errorf("%s = %v, want \"3\"", previousReplicasAnnotation, annotations[previousReplicasAnnotation])
+	}
+
+	// Stopping again keeps the remembered count instead of overwriting it with 0
+	if err := p.StopDatabase(ctx, id); err != nil {
+		t.Fatalf("StopDatabase: %v", err)
+	}
+	if len(standIn.patches) != 1 {
+		t.Errorf("sent %d patches, want 1 (a stopped statefulset isn't patched again)", len(standIn.patches))
+	}
+
+	if err := p.StartDatabase(ctx, id); err != nil {
+		t.Fatalf("StartDatabase: %v", err)
+	}
+	set = standIn.object("/apis/apps/v1", "statefulsets", "db", "orders")
+	if replicas := set["spec"].(map[string]any)["replicas"]; replicas != float64(3) {
+		t.Errorf("replicas after start = %v, want 3", replicas)
+	}
+	annotations = set["metadata"].(map[string]any)["annotations"].(map[string]any)
+	if _, ok := annotations[previousReplicasAnnotation]; ok {
+		t.Errorf("%s is kept after start", previousReplicasAnnotation)
+	}
+}
+
+func TestStatefulSetStartsWithoutAnnotation(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]any
+		want        float64
+	}{
+		{name: "no annotation", want: 1},
+		{name: "invalid annotation", annotations: map[string]any{previousReplicasAnnotation: "many"}, want: 1},
+		{name: "zero annotation", annotations: map[string]any{previousReplicasAnnotation: "0"}, want: 1},
+		{name: "remembered count", annotations: map[string]any{previousReplicasAnnotation: "2"}, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standIn := newAPIStandIn(t)
+			standIn.addStatefulSet("db", "orders", discoverLabels, tt.annotations, 0, 0, "postgres:16")
+			p := newTestProvider(t, standIn, nil, nil)
+
+			if err := p.StartDatabase(context.Background(), "statefulset/db/orders"); err != nil {
+				t.Fatalf("StartDatabase: %v", err)
+			}
+			set := standIn.object("/apis/apps/v1", "statefulsets", "db", "orders")
+			if replicas := set["spec"].(map[string]any)["replicas"]; replicas != tt.want {
+				t.Errorf("replicas = %v, want %v", replicas, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatefulSetStatus(t *testing.T) {
+	tests := []struct {
+		name          string
+		desired       int
+		replicas      int
+		readyReplicas int
+		want          string
+	}{
+		{name: "all replicas ready", desired: 2, replicas: 2, readyReplicas: 2, want: "available"},
+		{name: "replicas coming up", desired: 2, replicas: 2, readyReplicas: 1, want: "starting"},
+		{name: "scaled to zero", desired: 0, replicas: 0, want: "stopped"},
+		{name: "pods still terminating", desired: 0, replicas: 1, readyReplicas: 1, want: "stopping"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standIn := newAPIStandIn(t)
+			standIn.addStatefulSet("db", "orders", discoverLabels, nil, tt.desired, tt.readyReplicas, "postgres:16")
+			status := standIn.objects[objectPath("/apis/apps/v1", "statefulsets", "db", "orders")].body["status"].(map[string]any)
+			status["replicas"] = tt.replicas
+			p := newTestProvider(t, standIn, nil, nil)
+
+			got, err := p.GetDatabaseStatus(context.Background(), "statefulset/db/orders")
+			if err != nil {
+				t.Fatalf("GetDatabaseStatus: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("status = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCNPGHibernation(t *testing.T) {
+	standIn := newAPIStandIn(t)
+	standIn.addCNPGCluster("db", "billing", discoverLabels, nil, 2)
+	p := newTestProvider(t, standIn, nil, nil)
+	ctx := context.Background()
+	id := "cnpg/db/billing"
+
+	hibernation := func() any {
+		cluster := standIn.object("/apis/postgresql.cnpg.io/v1", "clusters", "db", "billing")
+		annotations, _ := cluster["metadata"].(map[string]any)["annotations"].(map[string]any)
+		return annotations[cnpgHibernationAnnotation]
+	}
+
+	if err := p.StopDatabase(ctx, id); err != nil {
+		t.Fatalf("StopDatabase: %v", err)
+	}
+	if got := hibernation(); got != "on" {
+		t.Errorf("%s after stop = %v, want on", cnpgHibernationAnnotation, got)
+	}
+
+	if err := p.StartDatabase(ctx, id); err != nil {
+		t.Fatalf("StartDatabase: %v", err)
+	}
+	if got := hibernation(); got != "off" {
+		t.Errorf("%s after start = %v, want off", cnpgHibernationAnnotation, got)
+	}
+
+	if err := p.StopDatabase(ctx, "cnpg/db/missing"); err == nil || !strings.Contains(err.Error(), "status 404") {
+		t.Errorf("err = %v, want the API server's 404", err)
+	}
+}
+
+func TestCNPGStatusFromPodReadiness(t *testing.T) {
+	tests := []struct {
+		name       string
+		hibernated bool
+		ready      int
+		unready    int
+		want       string
+	}{
+		{name: "all instances ready", ready: 2, want: "available"},
+		{name: "more pods than instances", ready: 3, want: "available"},
+		{name: "instance not ready", ready: 1, unready: 1, want: "starting"},
+		{name: "no pods yet", want: "starting"},
+		{name: "hibernated", hibernated: true, want: "stopped"},
+		{name: "hibernating", hibernated: true, ready: 1, unready: 1, want: "stopping"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standIn := newAPIStandIn(t)
+			annotations := map[string]any{}
+			if tt.hibernated {
+				annotations[cnpgHibernationAnnotation] = "on"
+			}
+			standIn.addCNPGCluster("db", "billing", discoverLabels, annotations, 2)
+			standIn.setPods("db", "billing", tt.ready, tt.unready)
+			standIn.setPods("db", "other", 2, 0)
+			p := newTestProvider(t, standIn, nil, nil)
+
+			got, err := p.GetDatabaseStatus(context.Background(), "cnpg/db/billing")
+			if err != nil {
+				t.Fatalf("GetDatabaseStatus: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("status = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListDatabases(t *testing.T) {
+	standIn := newAPIStandIn(t)
+	standIn.addStatefulSet("db", "orders", discoverLabels, map[string]any{costAnnotation: "42"}, 1, 1, "registry.example.com/library/postgres:16")
+	standIn.addStatefulSet("db", "users", map[string]any{"snoozeql.io/discover": "true", engineLabel: "mysql"}, nil, 1, 1, "custom:1")
+	standIn.addStatefulSet("other", "cache", discoverLabels, nil, 1, 1, "mariadb:11")
+	standIn.addStatefulSet("db", "web", map[string]any{"app": "web"}, nil, 1, 1, "nginx")
+	standIn.addCNPGCluster("db", "billing", discoverLabels, nil, 1)
+	standIn.setPods("db", "billing", 1, 0)
+
+	tests := []struct {
+		name       string
+		namespaces []string
+		noCNPG     bool
+		want       map[string]string // ProviderID to engine/status
+	}{
+		{
+			name: "all namespaces",
+			want: map[string]string{
+				"statefulset/db/orders":   "postgres/available",
+				"statefulset/db/users":    "mysql/available",
+				"statefulset/other/cache": "mysql/available",
+				"cnpg/db/billing":         "postgres/available",
+			},
+		},
+		{
+			name:       "one namespace",
+			namespaces: []string{"other"},
+			want:       map[string]string{"statefulset/other/cache": "mysql/available"},
+		},
+		{
+			name:   "without CloudNativePG",
+			noCNPG: true,
+			want: map[string]string{
+				"statefulset/db/orders":   "postgres/available",
+				"statefulset/db/users":    "mysql/available",
+				"statefulset/other/cache": "mysql/available",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standIn.noCNPG = tt.noCNPG
+			p := newTestProvider(t, standIn, tt.namespaces, []string{"team"})
+
+			instances, err := p.ListDatabases(context.Background())
+			if err != nil {
+				t.Fatalf("ListDatabases: %v", err)
+			}
+			got := make(map[string]string)
+			for _, instance := range instances {
+				got[instance.ProviderID] = instance.Engine + "/" + instance.Status
+				if instance.Provider != "kubernetes" || instance.Region != strings.Split(instance.ProviderID, "/")[1] {
+					t.Errorf("%s: unexpected instance %+v", instance.ProviderID, instance)
+				}
+				if instance.Managed != (instance.Tags["team"] != "") {
+					t.Errorf("%s: managed = %v with tags %v", instance.ProviderID, instance.Managed, instance.Tags)
+				}
+				if instance.ProviderID == "statefulset/db/orders" && instance.HourlyCostCents != 42 {
+					t.Errorf("cost = %d, want the annotated 42", instance.HourlyCostCents)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for id, value := range tt.want {
+				if got[id] != value {
+					t.Errorf("%s = %q, want %q", id, got[id], value)
+				}
+			}
+		})
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	standIn := newAPIStandIn(t)
+	kubeconfig := strings.Replace(testKubeconfig(standIn.URL), testToken, "expired", 1)
+	p, err := NewKubernetesProvider(kubeconfig, "", nil, "", nil)
+	if err != nil {
+		t.Fatalf("NewKubernetesProvider: %v", err)
+	}
+
+	err = p.TestConnection(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "status 401: Unauthorized") {
+		t.Errorf("err = %v, want the API server's status", err)
+	}
+}
+
+func TestParseID(t *testing.T) {
+	tests := []struct {
+		id   string
+		ok   bool
+		want string
+	}{
+		{id: "statefulset/db/orders", ok: true, want: "statefulset db orders"},
+		{id: "cnpg/db/billing", ok: true, want: "cnpg db billing"},
+		{id: "deployment/db/orders"},
+		{id: "statefulset/db"},
+		{id: "statefulset//orders"},
+		{id: "statefulset/db/orders/extra"},
+	}
+	for _, tt := range tests {
+		kind, namespace, name, err := parseID(tt.id)
+		if (err == nil) != tt.ok {
+			t.Errorf("parseID(%q) err = %v, want ok = %v", tt.id, err, tt.ok)
+			continue
+		}
+		if got := strings.Join([]string{kind, namespace, name}, " "); tt.ok && got != tt.want {
+			t.Errorf("parseID(%q) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
@@ -100,6 +100,15 @@ func (r *Registry) StopDatabase(ctx context.Context, providerName string, id str
 	return provider.StopDatabase(ctx, id)
 }
 
+// GetDatabaseStatus returns the current provider status of a database by provider-specific ID
+func (r *Registry) GetDatabaseStatus(ctx context.Context, providerName string, id string) (string, error) {
+	provider, err := r.Get(providerName)
+	if err != nil {
+		return "", err
+	}
+	return provider.GetDatabaseStatus(ctx, id)
+}
+
 // GetProvider returns the provider that manages the given instance
 func (r *Registry) GetProvider(instance models.Instance) (Provider, error) {
 	return r.Get(instance.Provider)
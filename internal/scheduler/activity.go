@@ -0,0 +1,236 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"snoozeql/internal/metrics"
+	"snoozeql/internal/models"
+)
+
+// activityFreshness is how recent the latest metrics must be to count as current.
+// Older (or missing) metrics never defer a stop.
+const activityFreshness = 20 * time.Minute
+
+// ActivityGuard defers scheduled stops while an instance is in use
+type ActivityGuard struct {
+	MaxConnections  float64       // Defer while DatabaseConnections is above this
+	MaxCPUPercent   float64       // Defer while CPUUtilization is above this
+	RecheckInterval time.Duration // How often a deferred stop re-checks activity
+	MaxDelay        time.Duration // Stop anyway once a stop has been deferred this long
+}
+
+// deferredStop is a stop held back by the activity guard
+type deferredStop struct {
+	instance    models.Instance
+	triggeredBy string
+	metadata    []byte
+	reason      string
+	deferredAt  time.Time
+	nextCheck   time.Time
+}
+
+// activity is the latest observed usage of an instance
+type activity struct {
+	Connections float64
+	CPUPercent  float64
+	At          time.Time
+}
+
+// SetActivityGuard makes the scheduler defer stops of instances whose latest
+// 5-minute metrics show connections or CPU above the guard's thresholds
+func (s *Scheduler) SetActivityGuard(metricsStore *metrics.MetricsStore, guard ActivityGuard) {
+	s.metricsStore = metricsStore
+	s.activityGuard = &guard
+}
+
+// latestActivity returns the instance's most recent connections and CPU, and whether
+// there are current metrics for both
+func (s *Scheduler) latestActivity(ctx context.Context, instance models.Instance, now time.Time) (activity, bool) {
+	latest, err := s.metricsStore.GetLatestMinuteMetrics(ctx, instance.ID, now.Add(-activityFreshness),
+		models.MetricDatabaseConnections, models.MetricCPUUtilization)
+	if err != nil {
+		log.Printf("Warning: Failed to read activity metrics for %s: %v", instance.Name, err)
+		return activity{}, false
+	}
+
+	connections, okConnections := latest[models.MetricDatabaseConnections]
+	cpu, okCPU := latest[models.MetricCPUUtilization]
+	if !okConnections || !okCPU {
+		return activity{}, false
+	}
+
+	at := connections.Minute
+	if cpu.Minute.After(at) {
+		at = cpu.Minute
+	}
+	// Peak connections so a short session inside the period still counts
+	return activity{Connections: connections.MaxValue, CPUPercent: cpu.AvgValue, At: at}, true
+}
+
+// isActive reports whether observed activity is above the guard's thresholds
+func (g *ActivityGuard) isActive(a activity) bool {
+	return a.Connections > g.MaxConnections || a.CPUPercent > g.MaxCPUPercent
+}
+
+// deferStop checks whether a stop should be held back because the instance is in use.
+// If so, the stop is queued for re-checking, a sleep_deferred event is recorded and
+// true is returned. A stop that is already deferred stays deferred.
+func (s *Scheduler) deferStop(ctx context.Context, instance models.Instance, triggeredBy string, metadata []byte, reason string) bool {
+	if s.activityGuard == nil || s.metricsStore == nil {
+		return false
+	}
+
+	s.mu.Lock()
+	_, pending := s.deferredStops[instance.ID]
+	s.mu.Unlock()
+	if pending {
+		log.Printf("Stop of %s already deferred by activity guard", instance.Name)
+		return true
+	}
+
+	now := time.Now()
+	observed, ok := s.latestActivity(ctx, instance, now)
+	if !ok || !s.activityGuard.isActive(observed) {
+		return false
+	}
+
+	s.mu.Lock()
+	s.deferredStops[instance.ID] = &deferredStop{
+		instance:    instance,
+		triggeredBy: triggeredBy,
+		metadata:    metadata,
+		reason:      reason,
+		deferredAt:  now,
+		nextCheck:   now.Add(s.activityGuard.RecheckInterval),
+	}
+	s.mu.Unlock()
+
+	log.Printf("Deferring stop of %s - in use (%.0f connections, %.1f%% CPU)", instance.Name, observed.Connections, observed.CPUPercent)
+	s.recordDeferral(ctx, instance, triggeredBy, metadata, observed)
+	return true
+}
+
+// cancelDeferredStop drops a deferred stop, e.g. because the instance is being woken
+func (s *Scheduler) cancelDeferredStop(instance models.Instance) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.deferredStops[instance.ID]; ok {
+		delete(s.deferredStops, instance.ID)
+		log.Printf("Cancelled deferred stop of %s", instance.Name)
+	}
+}
+
+// runDeferredStops re-checks deferred stops that are due. A stop goes ahead once the
+// instance is out of its backup/maintenance windows and idle (or the guard's maximum
+// delay has passed), and is dropped if the instance is no longer running or a
+// keep-alive override now covers it.
+func (s *Scheduler) runDeferredStops(ctx context.Context, overrides map[string][]models.Override, now time.Time) {
+	s.mu.Lock()
+	var due []*deferredStop
+	for _, stop := range s.deferredStops {
+		if !now.Before(stop.nextCheck) {
+			due = append(due, stop)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, stop := range due {
+		instance := stop.instance
+		if s.instanceStore != nil {
+			if current, err := s.instanceStore.GetInstanceByID(ctx, instance.ID); err == nil {
+				instance.Status = current.Status
+				instance.BackupWindow = current.BackupWindow
+				instance.MaintenanceWindow = current.MaintenanceWindow
+			}
+		}
+
+		if instance.Status != "available" && instance.Status != "running" {
+			log.Printf("Dropping deferred stop of %s - now %s", instance.Name, instance.Status)
+			s.cancelDeferredStop(instance)
+			continue
+		}
+		if hasKeepAlive(instance, overrides, now) {
+			log.Printf("Dropping deferred stop of %s - keep-alive override active", instance.Name)
+			s.cancelDeferredStop(instance)
+			continue
+		}
+
+		if window, inWindow := collidingWindow(instance, now); inWindow {
+			s.mu.Lock()
+			stop.nextCheck = window.End
+			s.mu.Unlock()
+			log.Printf("Stop of %s still delayed - %s window until %s", instance.Name, window.Kind, window.End.Format(time.RFC3339))
+			continue
+		}
+
+		deferredFor := now.Sub(stop.deferredAt)
+		var observed activity
+		var ok, timedOut bool
+		if s.activityGuard != nil && s.metricsStore != nil {
+			observed, ok = s.latestActivity(ctx, instance, now)
+			timedOut = deferredFor >= s.activityGuard.MaxDelay
+			if ok && s.activityGuard.isActive(observed) && !timedOut {
+				s.mu.Lock()
+				stop.nextCheck = now.Add(s.activityGuard.RecheckInterval)
+				s.mu.Unlock()
+				log.Printf("Stop of %s still deferred - in use (%.0f connections, %.1f%% CPU)", instance.Name, observed.Connections, observed.CPUPercent)
+				continue
+			}
+		}
+
+		s.mu.Lock()
+		delete(s.deferredStops, instance.ID)
+		s.mu.Unlock()
+
+		fields := map[string]any{}
+		if len(stop.metadata) > 0 {
+			if err := json.Unmarshal(stop.metadata, &fields); err != nil {
+				fields = map[string]any{}
+			}
+		}
+		fields["deferred_minutes"] = int(deferredFor.Minutes())
+		if timedOut && ok && s.activityGuard.isActive(observed) {
+			fields["activity_guard"] = "max_delay_reached"
+			log.Printf("Stopping %s after max activity delay (%s) despite activity", instance.Name, s.activityGuard.MaxDelay)
+		}
+		metadata, _ := json.Marshal(fields)
+		s.performAction(ctx, instance, "stop", stop.triggeredBy, metadata, stop.reason+" (deferred)")
+	}
+}
+
+// recordDeferral logs a sleep_deferred event with the activity that held the stop back
+func (s *Scheduler) recordDeferral(ctx context.Context, instance models.Instance, triggeredBy string, metadata []byte, observed activity) {
+	if s.eventStore == nil {
+		return
+	}
+
+	fields := map[string]any{}
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &fields); err != nil {
+			fields = map[string]any{}
+		}
+	}
+	fields["connections"] = observed.Connections
+	fields["cpu_percent"] = observed.CPUPercent
+	fields["observed_at"] = observed.At
+	fields["max_connections"] = s.activityGuard.MaxConnections
+	fields["max_cpu_percent"] = s.activityGuard.MaxCPUPercent
+	fields["recheck_minutes"] = int(s.activityGuard.RecheckInterval.Minutes())
+	fields["max_delay_minutes"] = int(s.activityGuard.MaxDelay.Minutes())
+	eventMetadata, _ := json.Marshal(fields)
+
+	event := &models.Event{
+		InstanceID:     instance.ID,
+		EventType:      "sleep_deferred",
+		TriggeredBy:    triggeredBy,
+		PreviousStatus: instance.Status,
+		NewStatus:      instance.Status,
+		Metadata:       eventMetadata,
+	}
+	if err := s.eventStore.CreateEvent(ctx, event); err != nil {
+		log.Printf("Warning: Failed to create sleep_deferred event for %s: %v", instance.Name, err)
+	}
+}
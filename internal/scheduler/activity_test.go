@@ -0,0 +1,389 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"snoozeql/internal/models"
+	"snoozeql/internal/provider"
+	"snoozeql/internal/tracker"
+)
+
+// fakeMetrics serves 5-minute metrics from memory
+type fakeMetrics struct {
+	rows []models.MinuteMetric
+	err  error
+}
+
+func (f *fakeMetrics) GetLatestMinuteMetrics(ctx context.Context, instanceID string, since time.Time, metricNames ...string) (map[string]models.MinuteMetric, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	latest := make(map[string]models.MinuteMetric)
+	for _, row := range f.rows {
+		for _, name := range metricNames {
+			if row.InstanceID == instanceID && row.MetricName == name && !row.Minute.Before(since) && row.Minute.After(latest[name].Minute) {
+				latest[name] = row
+			}
+		}
+	}
+	return latest, nil
+}
+
+func (f *fakeMetrics) GetMinuteMetricsByInstance(ctx context.Context, instanceID string, start, end time.Time) ([]models.MinuteMetric, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	var rows []models.MinuteMetric
+	for _, row := range f.rows {
+		if row.InstanceID == instanceID && !row.Minute.Before(start) && !row.Minute.After(end) {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// add stores a connections (max) and CPU (avg) bucket of the instance
+func (f *fakeMetrics) add(instanceID string, minute time.Time, connections, cpu float64) {
+	f.rows = append(f.rows,
+		models.MinuteMetric{InstanceID: instanceID, MetricName: models.MetricDatabaseConnections, Minute: minute, MaxValue: connections, AvgValue: connections},
+		models.MinuteMetric{InstanceID: instanceID, MetricName: models.MetricCPUUtilization, Minute: minute, MaxValue: cpu, AvgValue: cpu},
+	)
+}
+
+// recordingProvider records the start/stop calls it receives. Instances are available
+// until stopped, and reach the target state of an action as soon as it is requested.
+type recordingProvider struct {
+	mu       sync.Mutex
+	actions  []string          // "{action} {id}"
+	status   map[string]string // Provider ID -> status
+	failures map[string]error  // Provider ID -> error its actions fail with
+	after    map[string]string // Provider ID -> status it ends up in instead of the action's target
+}
+
+func (p *recordingProvider) record(action, id string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.actions = append(p.actions, action+" "+id)
+	if err := p.failures[id]; err != nil {
+		return err
+	}
+	if p.status == nil {
+		p.status = make(map[string]string)
+	}
+	p.status[id] = tracker.TargetStatus(action)
+	if status, ok := p.after[id]; ok {
+		p.status[id] = status
+	}
+	return nil
+}
+
+func (p *recordingProvider) recorded() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.actions...)
+}
+
+func (p *recordingProvider) StartDatabase(ctx context.Context, id string) error {
+	return p.record("start", id)
+}
+func (p *recordingProvider) StopDatabase(ctx context.Context, id string) error {
+	return p.record("stop", id)
+}
+func (p *recordingProvider) GetDatabaseStatus(ctx context.Context, id string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if status, ok := p.status[id]; ok {
+		return status, nil
+	}
+	return "available", nil
+}
+func (p *recordingProvider) ListDatabases(context.Context) ([]models.Instance, error) {
+	return nil, nil
+}
+func (p *recordingProvider) GetMetrics(context.Context, string, string, string) (map[string]any, error) {
+	return nil, nil
+}
+func (p *recordingProvider) GetDatabaseByID(context.Context, string) (*models.Instance, error) {
+	return nil, nil
+}
+
+// newTestScheduler returns a scheduler without stores that sends actions to the
+// returned provider and reads metrics from the returned fake
+func newTestScheduler() (*Scheduler, *recordingProvider, *fakeMetrics) {
+	registry := provider.NewRegistry()
+	recorder := &recordingProvider{}
+	registry.Register("aws_1_eu-west-1", recorder)
+	s := NewScheduler(nil, registry, nil, nil, nil, nil, nil)
+	fake := &fakeMetrics{}
+	s.metricsStore = fake
+	return s, recorder, fake
+}
+
+var testGuard = ActivityGuard{
+	MaxConnections:  0,
+	MaxCPUPercent:   10,
+	RecheckInterval: 5 * time.Minute,
+	MaxDelay:        time.Hour,
+}
+
+func testInstance(status string) models.Instance {
+	return models.Instance{ID: "i-1", Name: "orders", ProviderName: "aws_1_eu-west-1", ProviderID: "orders", Status: status}
+}
+
+func TestDeferStop(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		guard   *ActivityGuard
+		metrics func(*fakeMetrics)
+		want    bool
+	}{
+		{
+			name:    "open connections",
+			guard:   &testGuard,
+			metrics: func(f *fakeMetrics) { f.add("i-1", now.Add(-5*time.Minute), 2, 1) },
+			want:    true,
+		},
+		{
+			name:    "busy CPU",
+			guard:   &testGuard,
+			metrics: func(f *fakeMetrics) { f.add("i-1", now.Add(-5*time.Minute), 0, 35) },
+			want:    true,
+		},
+		{
+			name:    "idle",
+			guard:   &testGuard,
+			metrics: func(f *fakeMetrics) { f.add("i-1", now.Add(-5*time.Minute), 0, 10) },
+		},
+		{
+			name:  "latest bucket decides",
+			guard: &testGuard,
+			metrics: func(f *fakeMetrics) {
+				f.add("i-1", now.Add(-15*time.Minute), 8, 80)
+				f.add("i-1", now.Add(-5*time.Minute), 0, 2)
+			},
+		},
+		{
+			name:    "stale metrics",
+			guard:   &testGuard,
+			metrics: func(f *fakeMetrics) { f.add("i-1", now.Add(-activityFreshness-5*time.Minute), 5, 50) },
+		},
+		{
+			name:  "missing CPU",
+			guard: &testGuard,
+			metrics: func(f *fakeMetrics) {
+				f.add("i-1", now.Add(-5*time.Minute), 5, 50)
+				f.rows = f.rows[:1]
+			},
+		},
+		{
+			name:    "other instance",
+			guard:   &testGuard,
+			metrics: func(f *fakeMetrics) { f.add("i-2", now.Add(-5*time.Minute), 5, 50) },
+		},
+		{
+			name:    "metrics error",
+			guard:   &testGuard,
+			metrics: func(f *fakeMetrics) { f.err = errors.New("connection refused") },
+		},
+		{
+			name:    "no guard",
+			metrics: func(f *fakeMetrics) { f.add("i-1", now.Add(-5*time.Minute), 5, 50) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, recorder, metrics := newTestScheduler()
+			s.activityGuard = tt.guard
+			tt.metrics(metrics)
+
+			got := s.deferStop(context.Background(), testInstance("available"), "schedule", nil, "schedule: Nights")
+			if got != tt.want {
+				t.Errorf("deferStop = %v, want %v", got, tt.want)
+			}
+			if _, pending := s.deferredStops["i-1"]; pending != tt.want {
+				t.Errorf("pending = %v, want %v", pending, tt.want)
+			}
+			if len(recorder.actions) != 0 {
+				t.Errorf("actions = %v, deferStop must not act", recorder.actions)
+			}
+		})
+	}
+}
+
+func TestDeferStopKeepsPendingStop(t *testing.T) {
+	s, _, metrics := newTestScheduler()
+	s.activityGuard = &testGuard
+	metrics.add("i-1", time.Now().Add(-5*time.Minute), 3, 50)
+
+	ctx := context.Background()
+	if !s.deferStop(ctx, testInstance("available"), "schedule", nil, "schedule: Nights") {
+		t.Fatal("busy instance not deferred")
+	}
+	deferredAt := s.deferredStops["i-1"].deferredAt
+
+	// Now idle, but the pending stop stays until it is re-checked
+	metrics.rows = nil
+	if !s.deferStop(ctx, testInstance("available"), "schedule", nil, "schedule: Nights") {
+		t.Error("second stop went ahead while a deferred stop is pending")
+	}
+	if !s.deferredStops["i-1"].deferredAt.Equal(deferredAt) {
+		t.Error("pending stop was replaced")
+	}
+
+	s.cancelDeferredStop(testInstance("available"))
+	if len(s.deferredStops) != 0 {
+		t.Error("cancelDeferredStop kept the stop")
+	}
+}
+
+func TestRunDeferredStops(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name       string
+		instance   models.Instance
+		deferredAt time.Time
+		nextCheck  time.Time
+		activity   []float64 // Connections and CPU of a fresh bucket, if any
+		overrides  []models.Override
+		wantStop   bool
+		wantKept   bool
+		wantNext   time.Time
+	}{
+		{
+			name:       "idle now",
+			instance:   testInstance("available"),
+			deferredAt: now.Add(-10 * time.Minute),
+			nextCheck:  now,
+			activity:   []float64{0, 3},
+			wantStop:   true,
+		},
+		{
+			name:       "no current metrics",
+			instance:   testInstance("available"),
+			deferredAt: now.Add(-10 * time.Minute),
+			nextCheck:  now,
+			wantStop:   true,
+		},
+		{
+			name:       "still in use",
+			instance:   testInstance("available"),
+			deferredAt: now.Add(-10 * time.Minute),
+			nextCheck:  now.Add(-time.Minute),
+			activity:   []float64{4, 3},
+			wantKept:   true,
+			wantNext:   now.Add(testGuard.RecheckInterval),
+		},
+		{
+			name:       "max delay reached",
+			instance:   testInstance("available"),
+			deferredAt: now.Add(-testGuard.MaxDelay),
+			nextCheck:  now,
+			activity:   []float64{4, 3},
+			wantStop:   true,
+		},
+		{
+			name:       "not due",
+			instance:   testInstance("available"),
+			deferredAt: now.Add(-time.Minute),
+			nextCheck:  now.Add(time.Minute),
+			wantKept:   true,
+			wantNext:   now.Add(time.Minute),
+		},
+		{
+			name:       "no longer running",
+			instance:   testInstance("stopped"),
+			deferredAt: now.Add(-10 * time.Minute),
+			nextCheck:  now,
+		},
+		{
+			name:       "keep-alive override",
+			instance:   testInstance("available"),
+			deferredAt: now.Add(-10 * time.Minute),
+			nextCheck:  now,
+			overrides:  []models.Override{{ID: "k", InstanceID: "i-1", Type: models.OverrideKeepAlive}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, recorder, metrics := newTestScheduler()
+			s.activityGuard = &testGuard
+			if tt.activity != nil {
+				metrics.add("i-1", now.Add(-5*time.Minute), tt.activity[0], tt.activity[1])
+			}
+			s.deferredStops["i-1"] = &deferredStop{
+				instance:    tt.instance,
+				triggeredBy: "schedule",
+				reason:      "schedule: Nights",
+				deferredAt:  tt.deferredAt,
+				nextCheck:   tt.nextCheck,
+			}
+
+			s.runDeferredStops(context.Background(), GroupOverrides(tt.overrides), now)
+
+			stopped := len(recorder.actions) == 1 && recorder.actions[0] == "stop orders"
+			if stopped != tt.wantStop || (!tt.wantStop && len(recorder.actions) != 0) {
+				t.Errorf("actions = %v, want stop = %v", recorder.actions, tt.wantStop)
+			}
+			stop, kept := s.deferredStops["i-1"]
+			if kept != tt.wantKept {
+				t.Fatalf("kept = %v, want %v", kept, tt.wantKept)
+			}
+			if kept && !stop.nextCheck.Equal(tt.wantNext) {
+				t.Errorf("next check = %s, want %s", stop.nextCheck, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestRunDeferredStopsWaitsForBackupWindow(t *testing.T) {
+	s, recorder, _ := newTestScheduler()
+	s.activityGuard = &testGuard
+
+	now := time.Date(2026, 6, 1, 2, 50, 0, 0, time.UTC)
+	instance := testInstance("available")
+	instance.BackupWindow = "03:00-03:30"
+	s.deferredStops["i-1"] = &deferredStop{instance: instance, deferredAt: now.Add(-time.Hour), nextCheck: now}
+
+	s.runDeferredStops(context.Background(), nil, now)
+
+	if len(recorder.actions) != 0 {
+		t.Errorf("stopped %v right before the backup window", recorder.actions)
+	}
+	if next := s.deferredStops["i-1"].nextCheck; !next.Equal(time.Date(2026, 6, 1, 3, 30, 0, 0, time.UTC)) {
+		t.Errorf("next check = %s, want the end of the backup window", next)
+	}
+}
+
+func TestHoldForWindowKeepsPendingStop(t *testing.T) {
+	s, recorder, _ := newTestScheduler()
+	now := time.Date(2026, 6, 1, 3, 10, 0, 0, time.UTC)
+	instance := testInstance("available")
+	instance.BackupWindow = "03:00-03:30"
+
+	ctx := context.Background()
+	if !s.holdForWindow(ctx, instance, "schedule", nil, "schedule: Nights", now) {
+		t.Fatal("stop during the backup window not held")
+	}
+	// Re-stops every minute through the window keep the first hold
+	if !s.holdForWindow(ctx, instance, "budget", nil, "budget", now.Add(time.Minute)) {
+		t.Error("second stop went ahead during the backup window")
+	}
+	if stop := s.deferredStops["i-1"]; stop.triggeredBy != "schedule" || !stop.deferredAt.Equal(now) {
+		t.Errorf("pending stop was replaced: %+v", stop)
+	}
+	if s.holdForWindow(ctx, instance, "schedule", nil, "schedule: Nights", now.Add(time.Hour)) {
+		t.Error("stop held outside the backup window")
+	}
+	if len(recorder.actions) != 0 {
+		t.Errorf("actions = %v, holdForWindow must not act", recorder.actions)
+	}
+}
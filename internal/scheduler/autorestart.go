@@ -0,0 +1,62 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"snoozeql/internal/models"
+)
+
+// autoRestartLookback is how far back aws_auto_restart events are picked up
+const autoRestartLookback = 24 * time.Hour
+
+// restopAutoRestarts stops instances that AWS started on its own (recorded by discovery
+// as aws_auto_restart events) while their schedules want them asleep. An auto-restart is
+// left alone once anything has started or stopped the instance since, while it is still
+// starting, while a keep-alive override covers it, or if its schedules want it awake.
+func (s *Scheduler) restopAutoRestarts(ctx context.Context, schedules []models.Schedule, calendars Calendars, overrides map[string][]models.Override, now time.Time) {
+	if s.eventStore == nil {
+		return
+	}
+
+	events, err := s.eventStore.ListEventsByTypeSince(ctx, "aws_auto_restart", now.Add(-autoRestartLookback))
+	if err != nil {
+		log.Printf("Warning: Failed to list auto-restart events: %v", err)
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, event := range events {
+		// Events are most recent first, so only the latest per instance counts
+		if seen[event.InstanceID] {
+			continue
+		}
+		seen[event.InstanceID] = true
+
+		handled, err := s.eventStore.HasStateEventSince(ctx, event.InstanceID, event.CreatedAt)
+		if err != nil || handled {
+			continue
+		}
+
+		instance, err := s.instanceStore.GetInstanceByID(ctx, event.InstanceID)
+		if err != nil || instance.Status != "available" {
+			continue
+		}
+
+		target, ok := instanceTarget(*instance, schedules, calendars, now)
+		if !ok || target.Action != "stop" || hasKeepAlive(*instance, overrides, now) {
+			continue
+		}
+
+		log.Printf("Re-stopping %s after AWS auto-restart - schedule '%s' wants it asleep", instance.Name, target.Schedule.Name)
+		metadata, _ := json.Marshal(map[string]any{
+			"auto_restart_event_id": event.ID,
+			"auto_restarted_at":     event.CreatedAt,
+			"schedule_id":           target.Schedule.ID,
+			"schedule_name":         target.Schedule.Name,
+		})
+		s.executeAction(ctx, *instance, "stop", "aws_auto_restart", metadata, "AWS auto-restart: "+target.Schedule.Name)
+	}
+}
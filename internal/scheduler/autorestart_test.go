@@ -0,0 +1,157 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"snoozeql/internal/models"
+)
+
+// fakeEvents keeps events in memory
+type fakeEvents struct {
+	mu     sync.Mutex
+	events []models.Event
+}
+
+func (f *fakeEvents) CreateEvent(ctx context.Context, event *models.Event) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	f.events = append(f.events, *event)
+	return nil
+}
+
+// matching returns the events of the instance ("" for all) with one of the types since a time, oldest first
+func (f *fakeEvents) matching(instanceID string, since time.Time, types ...string) []models.Event {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var events []models.Event
+	for _, event := range f.events {
+		if (instanceID == "" || event.InstanceID == instanceID) && !event.CreatedAt.Before(since) && slices.Contains(types, event.EventType) {
+			events = append(events, event)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].CreatedAt.Before(events[j].CreatedAt) })
+	return events
+}
+
+func (f *fakeEvents) HasStateEventSince(ctx context.Context, instanceID string, since time.Time) (bool, error) {
+	return len(f.matching(instanceID, since, "wake", "sleep", "start", "stop", "wake_skipped", "sleep_skipped")) > 0, nil
+}
+
+func (f *fakeEvents) LatestStateEvent(ctx context.Context, instanceID string) (*models.Event, error) {
+	events := f.matching(instanceID, time.Time{}, "wake", "sleep", "start", "stop", "aws_auto_restart")
+	if len(events) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &events[len(events)-1], nil
+}
+
+func (f *fakeEvents) ListStateEventsSince(ctx context.Context, instanceID string, since time.Time) ([]models.Event, error) {
+	return f.matching(instanceID, since, "wake", "sleep", "start", "stop", "aws_auto_restart", "action_completed", "action_failed"), nil
+}
+
+func (f *fakeEvents) ListEventsByTypeSince(ctx context.Context, eventType string, since time.Time) ([]models.Event, error) {
+	events := f.matching("", since, eventType)
+	slices.Reverse(events)
+	return events, nil
+}
+
+// fakeInstances serves instances from memory
+type fakeInstances []models.Instance
+
+func (f fakeInstances) ListInstances(ctx context.Context) ([]models.Instance, error) {
+	return f, nil
+}
+
+func (f fakeInstances) GetInstanceByID(ctx context.Context, id string) (*models.Instance, error) {
+	for i := range f {
+		if f[i].ID == id {
+			return &f[i], nil
+		}
+	}
+	return nil, fmt.Errorf("instance %s not found", id)
+}
+
+func TestRestopAutoRestarts(t *testing.T) {
+	night := time.Date(2026, 6, 1, 23, 0, 0, 0, time.UTC)
+	nights := models.Schedule{
+		ID:        "s",
+		Name:      "Nights",
+		Enabled:   true,
+		Timezone:  "UTC",
+		WakeCron:  "0 7 * * *",
+		SleepCron: "0 19 * * *",
+		Selectors: []models.Selector{{}},
+	}
+	restart := models.Event{ID: "e-1", InstanceID: "i-1", EventType: "aws_auto_restart", CreatedAt: night.Add(-30 * time.Minute)}
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		status    string
+		events    []models.Event
+		overrides []models.Override
+		want      bool
+	}{
+		{name: "asleep per schedule", now: night, status: "available", events: []models.Event{restart}, want: true},
+		{name: "schedule wants it awake", now: night.Add(10 * time.Hour), status: "available", events: []models.Event{restart}},
+		{name: "still starting", now: night, status: "starting", events: []models.Event{restart}},
+		{
+			name:   "started since",
+			now:    night,
+			status: "available",
+			events: []models.Event{restart, {InstanceID: "i-1", EventType: "start", CreatedAt: night.Add(-10 * time.Minute)}},
+		},
+		{
+			name:      "keep-alive override",
+			now:       night,
+			status:    "available",
+			events:    []models.Event{restart},
+			overrides: []models.Override{{ID: "k", InstanceID: "i-1", Type: models.OverrideKeepAlive}},
+		},
+		{
+			name:   "restart too long ago",
+			now:    night,
+			status: "available",
+			events: []models.Event{{InstanceID: "i-1", EventType: "aws_auto_restart", CreatedAt: night.Add(-autoRestartLookback - time.Minute)}},
+		},
+		{
+			name:   "only the latest restart counts",
+			now:    night,
+			status: "available",
+			events: []models.Event{{InstanceID: "i-1", EventType: "aws_auto_restart", CreatedAt: night.Add(-2 * time.Hour)}, restart},
+			want:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, recorder, _ := newTestScheduler()
+			events := &fakeEvents{events: tt.events}
+			s.eventStore = events
+			s.instanceStore = fakeInstances{testInstance(tt.status)}
+
+			s.restopAutoRestarts(context.Background(), []models.Schedule{nights}, nil, GroupOverrides(tt.overrides), tt.now)
+
+			stopped := slices.Equal(recorder.recorded(), []string{"stop orders"})
+			if stopped != tt.want || (!tt.want && len(recorder.recorded()) != 0) {
+				t.Fatalf("actions = %v, want stop = %v", recorder.recorded(), tt.want)
+			}
+			if tt.want {
+				sleeps := events.matching("i-1", time.Time{}, "sleep")
+				if len(sleeps) != 1 || sleeps[0].TriggeredBy != "aws_auto_restart" {
+					t.Errorf("sleep events = %+v, want one triggered by aws_auto_restart", sleeps)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"snoozeql/internal/models"
+	"snoozeql/internal/notify"
+	"snoozeql/internal/tracker"
+)
+
+// budgetStopRetry is how long after a stop was requested an instance that still shows
+// as running is left alone, so the stop isn't repeated while it is stopping
+const budgetStopRetry = 10 * time.Minute
+
+// budgetUsage is how long an instance has been awake in the current budget periods
+type budgetUsage struct {
+	Day       time.Duration
+	Week      time.Duration
+	Remaining time.Duration // Awake time left before the tightest budget is used up
+}
+
+// eventState reports whether a state event leaves the instance awake. An action_failed
+// event puts the instance back in the state it was in before the action (awake after a
+// failed stop); ok is false for outcome events without an action, which don't change
+// the state.
+func eventState(event models.Event) (awake, ok bool) {
+	switch event.EventType {
+	case "wake", "start", "aws_auto_restart":
+		return true, true
+	case tracker.EventActionCompleted, tracker.EventActionFailed:
+		var outcome struct {
+			Action string `json:"action"`
+		}
+		if json.Unmarshal(event.Metadata, &outcome) != nil || (outcome.Action != "start" && outcome.Action != "stop") {
+			return false, false
+		}
+		started := outcome.Action == "start"
+		if event.EventType == tracker.EventActionFailed {
+			return !started, true
+		}
+		return started, true
+	}
+	return false, true
+}
+
+// budgetPeriods returns the start of the current day and of the current week (Monday) in loc
+func budgetPeriods(now time.Time, loc *time.Location) (time.Time, time.Time) {
+	local := now.In(loc)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	daysSinceMonday := (int(day.Weekday()) + 6) % 7
+	week := day.AddDate(0, 0, -daysSinceMonday)
+	return day, week
+}
+
+// settleFailedActions returns the events with each action's wake/sleep event that was
+// followed by an action_failed event replaced by the failure, since the wake/sleep event
+// is written before the provider call: a failed stop counts as awake all along
+func settleFailedActions(events []models.Event) []models.Event {
+	settled := make([]models.Event, 0, len(events))
+	requested := -1 // Index in settled of the latest wake/sleep/start/stop event
+	for _, event := range events {
+		switch event.EventType {
+		case "wake", "start", "sleep", "stop":
+			requested = len(settled)
+		case tracker.EventActionFailed:
+			state, ok := eventState(event)
+			if ok && requested >= 0 {
+				if requestAwake, _ := eventState(settled[requested]); requestAwake != state {
+					failure := event
+					failure.CreatedAt = settled[requested].CreatedAt
+					settled[requested] = failure
+					requested = -1
+					continue
+				}
+			}
+		}
+		settled = append(settled, event)
+	}
+	return settled
+}
+
+// awakeTime returns how long the instance was awake between from and now, given its state
+// events (oldest first, starting with the latest one before from). Without an event
+// before from, the state at from is inferred from the first event after it (a sleep
+// means it was awake), or from the instance's current status if there are none.
+func awakeTime(events []models.Event, status string, from, now time.Time) time.Duration {
+	events = settleFailedActions(events)
+	awake := status == "available" || status == "running"
+	for _, event := range events {
+		if state, ok := eventState(event); ok {
+			if !event.CreatedAt.Before(from) {
+				// A failed action leaves the state it found
+				awake = state == (event.EventType == tracker.EventActionFailed)
+			}
+			break
+		}
+	}
+	for _, event := range events {
+		if !event.CreatedAt.Before(from) {
+			break
+		}
+		if state, ok := eventState(event); ok {
+			awake = state
+		}
+	}
+
+	var total time.Duration
+	since := from
+	for _, event := range events {
+		state, ok := eventState(event)
+		if event.CreatedAt.Before(from) || !ok {
+			continue
+		}
+		if awake {
+			total += event.CreatedAt.Sub(since)
+		}
+		awake = state
+		since = event.CreatedAt
+	}
+	if awake && now.After(since) {
+		total += now.Sub(since)
+	}
+	return total
+}
+
+// usage computes the instance's awake time today and this week against the budget
+func usage(budget models.ScheduleBudget, events []models.Event, status string, day, week, now time.Time) budgetUsage {
+	u := budgetUsage{
+		Day:       awakeTime(events, status, day, now),
+		Week:      awakeTime(events, status, week, now),
+		Remaining: time.Duration(math.MaxInt64),
+	}
+	if budget.DailyHours > 0 {
+		u.Remaining = min(u.Remaining, hoursDuration(budget.DailyHours)-u.Day)
+	}
+	if budget.WeeklyHours > 0 {
+		u.Remaining = min(u.Remaining, hoursDuration(budget.WeeklyHours)-u.Week)
+	}
+	return u
+}
+
+func hoursDuration(hours float64) time.Duration {
+	return time.Duration(hours * float64(time.Hour))
+}
+
+// runBudgets stops running instances that have used up the awake-hours budget of a
+// schedule matching them, unless an override keeps them up, and warns (through the
+// pre-stop notifier) when a budget is about to run out
+func (s *Scheduler) runBudgets(ctx context.Context, schedules []models.Schedule, overrides map[string][]models.Override, now time.Time) {
+	if s.eventStore == nil {
+		return
+	}
+
+	var instances []models.Instance
+	loaded := false
+	for _, schedule := range schedules {
+		if !schedule.Enabled || schedule.Budget == nil {
+			continue
+		}
+		if !loaded {
+			var err error
+			if instances, err = s.instanceStore.ListInstances(ctx); err != nil {
+				log.Printf("Warning: Failed to list instances for awake-hours budgets: %v", err)
+				return
+			}
+			loaded = true
+		}
+
+		loc, err := time.LoadLocation(schedule.Timezone)
+		if err != nil {
+			loc = time.UTC
+		}
+		day, week := budgetPeriods(now, loc)
+
+		for _, instance := range instances {
+			if instance.Status != "available" && instance.Status != "running" {
+				continue
+			}
+			if !MatchesSchedule(instance, schedule) || s.hasDeferredStop(instance.ID) {
+				continue
+			}
+
+			events, err := s.eventStore.ListStateEventsSince(ctx, instance.ID, week)
+			if err != nil {
+				log.Printf("Warning: Failed to load events of %s for budget '%s': %v", instance.Name, schedule.Name, err)
+				continue
+			}
+			u := usage(*schedule.Budget, events, instance.Status, day, week, now)
+
+			if u.Remaining > 0 {
+				s.warnBudget(ctx, schedule, instance, overrides, now.Add(u.Remaining), now)
+				continue
+			}
+
+			if overrideWouldBlock(instance, "stop", overrides, now) {
+				continue
+			}
+			// Stop already requested and still in progress
+			if n := len(events); n > 0 && now.Sub(events[n-1].CreatedAt) < budgetStopRetry {
+				if awake, ok := eventState(events[n-1]); ok && !awake {
+					continue
+				}
+			}
+
+			log.Printf("%s has used up the awake-hours budget of schedule '%s' (%s today, %s this week)",
+				instance.Name, schedule.Name, u.Day.Round(time.Minute), u.Week.Round(time.Minute))
+			metadata, _ := json.Marshal(map[string]any{
+				"schedule_id":         schedule.ID,
+				"schedule_name":       schedule.Name,
+				"awake_hours_today":   math.Round(u.Day.Hours()*100) / 100,
+				"awake_hours_week":    math.Round(u.Week.Hours()*100) / 100,
+				"daily_budget_hours":  schedule.Budget.DailyHours,
+				"weekly_budget_hours": schedule.Budget.WeeklyHours,
+			})
+			s.executeAction(ctx, instance, "stop", "budget", metadata, "awake-hours budget used up: "+schedule.Name)
+		}
+	}
+}
+
+// warnBudget sends a pre-stop warning once the instance's budget will run out within
+// the pre-stop lead time, at most once per lead time per instance
+func (s *Scheduler) warnBudget(ctx context.Context, schedule models.Schedule, instance models.Instance, overrides map[string][]models.Override, stopTime, now time.Time) {
+	if s.notifier == nil || s.preStopLead <= 0 || stopTime.Sub(now) > s.preStopLead {
+		return
+	}
+	if overrideWouldBlock(instance, "stop", overrides, stopTime) {
+		return
+	}
+	if !s.shouldWarnBudget(instance.ID, now) {
+		return
+	}
+
+	warning := notify.PreStopWarning{
+		ScheduleID:   schedule.ID,
+		ScheduleName: fmt.Sprintf("%s (awake-hours budget)", schedule.Name),
+		StopTime:     stopTime,
+		Instances:    []models.Instance{instance},
+	}
+	if err := s.notifier.NotifyPreStop(ctx, warning); err != nil {
+		log.Printf("Warning: Failed to send budget warning for %s: %v", instance.Name, err)
+		return
+	}
+	log.Printf("Sent budget warning for %s (schedule '%s', stop at %s)", instance.Name, schedule.Name, stopTime.Format("15:04"))
+}
+
+// shouldWarnBudget records a budget warning for the instance, unless one was sent
+// within the last pre-stop lead time
+func (s *Scheduler) shouldWarnBudget(instanceID string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if last, ok := s.budgetWarned[instanceID]; ok && now.Sub(last) < s.preStopLead {
+		return false
+	}
+	s.budgetWarned[instanceID] = now
+	return true
+}
+
+// hasDeferredStop reports whether a stop of the instance is already waiting
+func (s *Scheduler) hasDeferredStop(instanceID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, pending := s.deferredStops[instanceID]
+	return pending
+}
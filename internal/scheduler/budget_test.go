@@ -0,0 +1,177 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"snoozeql/internal/models"
+)
+
+// stateEvents builds state events from alternating event types and times
+func stateEvents(pairs ...any) []models.Event {
+	var events []models.Event
+	for i := 0; i < len(pairs); i += 2 {
+		events = append(events, models.Event{EventType: pairs[i].(string), CreatedAt: pairs[i+1].(time.Time)})
+	}
+	return events
+}
+
+// outcomeEvent builds the tracker's outcome event of an action
+func outcomeEvent(eventType, action string, at time.Time) models.Event {
+	return models.Event{EventType: eventType, Metadata: []byte(`{"action":"` + action + `"}`), CreatedAt: at}
+}
+
+func TestAwakeTime(t *testing.T) {
+	from := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	now := from.Add(12 * time.Hour)
+	at := func(hours float64) time.Time { return from.Add(time.Duration(hours * float64(time.Hour))) }
+
+	tests := []struct {
+		name   string
+		events []models.Event
+		status string
+		want   time.Duration
+	}{
+		{name: "running without events", status: "available", want: 12 * time.Hour},
+		{name: "stopped without events", status: "stopped"},
+		{name: "awake before the period", events: stateEvents("wake", at(-2), "sleep", at(8)), status: "stopped", want: 8 * time.Hour},
+		{name: "asleep before the period", events: stateEvents("sleep", at(-2), "wake", at(9)), status: "available", want: 3 * time.Hour},
+		{name: "first event is a sleep", events: stateEvents("sleep", at(6)), status: "stopped", want: 6 * time.Hour},
+		{name: "first event is a wake", events: stateEvents("wake", at(10)), status: "available", want: 2 * time.Hour},
+		{
+			name:   "several cycles",
+			events: stateEvents("sleep", at(-5), "wake", at(1), "sleep", at(3), "start", at(5), "stop", at(6.5), "wake", at(11)),
+			status: "available",
+			want:   4*time.Hour + 30*time.Minute,
+		},
+		{name: "AWS restart of a stopped instance", events: stateEvents("sleep", at(-24), "aws_auto_restart", at(11)), status: "available", want: time.Hour},
+		{name: "event at the start of the period", events: stateEvents("wake", at(0)), status: "running", want: 12 * time.Hour},
+		{
+			name:   "failed stop keeps counting",
+			events: append(stateEvents("wake", at(-1), "sleep", at(4)), outcomeEvent("action_failed", "stop", at(4.5))),
+			status: "available",
+			want:   12 * time.Hour,
+		},
+		{
+			name:   "failed stop requested before the period",
+			events: append(stateEvents("sleep", at(-0.5)), outcomeEvent("action_failed", "stop", at(1))),
+			status: "available",
+			want:   12 * time.Hour,
+		},
+		{
+			name:   "failed start stays asleep",
+			events: append(stateEvents("sleep", at(-1), "wake", at(4)), outcomeEvent("action_failed", "start", at(4))),
+			status: "stopped",
+		},
+		{
+			name:   "completed stop",
+			events: append(stateEvents("wake", at(-1), "sleep", at(4)), outcomeEvent("action_completed", "stop", at(4.2))),
+			status: "stopped",
+			want:   4 * time.Hour,
+		},
+		{
+			name:   "failed stop before the period",
+			events: []models.Event{outcomeEvent("action_failed", "stop", at(-1)), {EventType: "sleep", CreatedAt: at(3)}},
+			status: "stopped",
+			want:   3 * time.Hour,
+		},
+		{
+			name:   "first event is a failed stop",
+			events: []models.Event{outcomeEvent("action_failed", "stop", at(2))},
+			status: "available",
+			want:   12 * time.Hour,
+		},
+		{
+			name:   "outcome without an action is ignored",
+			events: []models.Event{{EventType: "action_failed", CreatedAt: at(2)}, {EventType: "sleep", CreatedAt: at(5)}},
+			status: "stopped",
+			want:   5 * time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := awakeTime(tt.events, tt.status, from, now); got != tt.want {
+				t.Errorf("awakeTime = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBudgetPeriods(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no timezone data: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		loc      *time.Location
+		wantDay  time.Time
+		wantWeek time.Time
+	}{
+		{
+			name:     "friday",
+			now:      time.Date(2026, 10, 16, 15, 0, 0, 0, time.UTC),
+			loc:      time.UTC,
+			wantDay:  time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC),
+			wantWeek: time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "sunday belongs to the week before",
+			now:      time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC),
+			loc:      time.UTC,
+			wantDay:  time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC),
+			wantWeek: time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "already monday in the schedule's timezone",
+			now:      time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC),
+			loc:      berlin,
+			wantDay:  time.Date(2026, 10, 19, 0, 0, 0, 0, berlin),
+			wantWeek: time.Date(2026, 10, 19, 0, 0, 0, 0, berlin),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			day, week := budgetPeriods(tt.now, tt.loc)
+			if !day.Equal(tt.wantDay) || !week.Equal(tt.wantWeek) {
+				t.Errorf("periods = %s, %s; want %s, %s", day, week, tt.wantDay, tt.wantWeek)
+			}
+		})
+	}
+}
+
+func TestUsage(t *testing.T) {
+	week := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC) // Monday
+	day := week.AddDate(0, 0, 4)                          // Friday
+	now := day.Add(10 * time.Hour)
+	// Awake 9h on each of Monday..Thursday (36h), and since 06:00 today (4h)
+	var events []models.Event
+	for d := 0; d < 4; d++ {
+		events = append(events, stateEvents("wake", week.AddDate(0, 0, d).Add(8*time.Hour), "sleep", week.AddDate(0, 0, d).Add(17*time.Hour))...)
+	}
+	events = append(events, stateEvents("wake", day.Add(6*time.Hour))...)
+
+	tests := []struct {
+		name   string
+		budget models.ScheduleBudget
+		want   time.Duration
+	}{
+		{name: "daily", budget: models.ScheduleBudget{DailyHours: 8}, want: 4 * time.Hour},
+		{name: "weekly", budget: models.ScheduleBudget{WeeklyHours: 50}, want: 10 * time.Hour},
+		{name: "tightest wins", budget: models.ScheduleBudget{DailyHours: 8, WeeklyHours: 42}, want: 2 * time.Hour},
+		{name: "used up", budget: models.ScheduleBudget{WeeklyHours: 39.5}, want: -30 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := usage(tt.budget, events, "available", day, week, now)
+			if u.Day != 4*time.Hour || u.Week != 40*time.Hour {
+				t.Errorf("usage = %s today, %s this week; want 4h, 40h", u.Day, u.Week)
+			}
+			if u.Remaining != tt.want {
+				t.Errorf("remaining = %s, want %s", u.Remaining, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package scheduler
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gorhill/cronexpr"
+	"snoozeql/internal/models"
+)
+
+// DefaultConflictHorizon is how far ahead schedules are compared for conflicts.
+// A week covers every combination of weekday CRONs.
+const DefaultConflictHorizon = 7 * 24 * time.Hour
+
+// ScheduleConflict is a pair of enabled schedules that match the same instances and
+// want them in different states during some windows of the analysis horizon
+type ScheduleConflict struct {
+	ScheduleA ConflictSchedule   `json:"schedule_a"`
+	ScheduleB ConflictSchedule   `json:"schedule_b"`
+	WinnerID  string             `json:"winner_id"` // The schedule the scheduler follows while they disagree
+	Instances []ConflictInstance `json:"instances"`
+	Windows   []ConflictWindow   `json:"windows"`
+}
+
+// ConflictSchedule identifies one side of a conflict
+type ConflictSchedule struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Priority int    `json:"priority"`
+}
+
+// ConflictInstance is an instance matched by both schedules of a conflict
+type ConflictInstance struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// ConflictWindow is a period in which the two schedules want different states
+type ConflictWindow struct {
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	StateA string    `json:"state_a"` // "awake" or "asleep"
+	StateB string    `json:"state_b"`
+}
+
+// outranks reports whether schedule a takes precedence over b for an instance both match.
+// Higher priority wins; ties go to the older schedule and then the lower ID, so every
+// run (and every replica) resolves a conflict the same way.
+func outranks(a, b models.Schedule) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
+
+// priorityResolver decides which schedule controls an instance when several enabled
+// schedules match it. Desired states are worked out lazily and cached for one run.
+type priorityResolver struct {
+	schedules []models.Schedule
+	calendars Calendars
+	now       time.Time
+	desired   map[string]string
+}
+
+// newPriorityResolver creates a resolver for the enabled schedules at now
+func newPriorityResolver(schedules []models.Schedule, calendars Calendars, now time.Time) *priorityResolver {
+	enabled := make([]models.Schedule, 0, len(schedules))
+	for _, schedule := range schedules {
+		if schedule.Enabled {
+			enabled = append(enabled, schedule)
+		}
+	}
+	sort.SliceStable(enabled, func(i, j int) bool {
+		return outranks(enabled[i], enabled[j])
+	})
+	return &priorityResolver{
+		schedules: enabled,
+		calendars: calendars,
+		now:       now,
+		desired:   make(map[string]string),
+	}
+}
+
+// blockedBy returns the highest-ranked schedule that outranks schedule, matches the
+// instance and currently wants it in the opposite state of action, if there is one
+func (p *priorityResolver) blockedBy(schedule models.Schedule, instance models.Instance, action string) (models.Schedule, bool) {
+	for _, other := range p.schedules {
+		if other.ID == schedule.ID || !outranks(other, schedule) {
+			continue
+		}
+		if !MatchesSchedule(instance, other) {
+			continue
+		}
+
+		desired, ok := p.desired[other.ID]
+		if !ok {
+			desired, _ = desiredAction(other, p.now, p.calendars)
+			p.desired[other.ID] = desired
+		}
+		if desired != "none" && desired != action {
+			return other, true
+		}
+	}
+	return models.Schedule{}, false
+}
+
+// stateChange is the moment a schedule moves its instances into a new state
+type stateChange struct {
+	At    time.Time
+	State string // "start" (awake), "stop" (asleep) or "none" (schedule has never fired)
+}
+
+// stateTimeline returns the schedule's desired state at from followed by each change
+// up to until, using the same per-minute evaluation as the scheduler
+func stateTimeline(schedule models.Schedule, calendars Calendars, from, until time.Time) []stateChange {
+	loc := scheduleLocation(schedule)
+	from = from.In(loc)
+	until = until.In(loc)
+
+	var wakeCron, sleepCron *cronexpr.Expression
+	if schedule.WakeCron != "" {
+		wakeCron, _ = cronexpr.Parse(schedule.WakeCron)
+	}
+	if schedule.SleepCron != "" {
+		sleepCron, _ = cronexpr.Parse(schedule.SleepCron)
+	}
+
+	initial, _ := desiredAction(schedule, from, calendars)
+	timeline := []stateChange{{At: from, State: initial}}
+	for _, minute := range candidateMinutes(schedule, wakeCron, sleepCron, from, until) {
+		fire := evaluateFire(schedule, loc, wakeCron, sleepCron, minute, calendars)
+		if fire.Action == "none" || fire.Action == timeline[len(timeline)-1].State {
+			continue
+		}
+		timeline = append(timeline, stateChange{At: minute, State: fire.Action})
+	}
+	return timeline
+}
+
+// stateAt returns the timeline's state at t
+func stateAt(timeline []stateChange, t time.Time) string {
+	state := "none"
+	for _, change := range timeline {
+		if change.At.After(t) {
+			break
+		}
+		state = change.State
+	}
+	return state
+}
+
+// disagreements returns the windows between from and until in which two timelines
+// both have a state and the states differ
+func disagreements(a, b []stateChange, from, until time.Time) []ConflictWindow {
+	var boundaries []time.Time
+	for _, change := range append(append([]stateChange{}, a...), b...) {
+		boundaries = append(boundaries, change.At)
+	}
+	boundaries = append(boundaries, from, until)
+	sort.Slice(boundaries, func(i, j int) bool {
+		return boundaries[i].Before(boundaries[j])
+	})
+
+	windows := []ConflictWindow{}
+	for i := 0; i < len(boundaries)-1; i++ {
+		start, end := boundaries[i], boundaries[i+1]
+		if !start.Before(end) || start.Before(from) || end.After(until) {
+			continue
+		}
+		stateA, stateB := stateAt(a, start), stateAt(b, start)
+		if stateA == "none" || stateB == "none" || stateA == stateB {
+			continue
+		}
+
+		window := ConflictWindow{Start: start, End: end, StateA: stateLabel(stateA), StateB: stateLabel(stateB)}
+		if n := len(windows); n > 0 && windows[n-1].End.Equal(start) && windows[n-1].StateA == window.StateA {
+			windows[n-1].End = end
+			continue
+		}
+		windows = append(windows, window)
+	}
+	return windows
+}
+
+// stateLabel names a desired state for API output
+func stateLabel(action string) string {
+	if action == "start" {
+		return "awake"
+	}
+	return "asleep"
+}
+
+// FindConflicts compares every pair of enabled schedules that match at least one common
+// instance and returns the pairs that want those instances in different states at some
+// point between from and until. The winner of each conflict is the schedule the
+// scheduler and reconciler follow while the two disagree.
+func FindConflicts(schedules []models.Schedule, instances []models.Instance, calendars Calendars, from, until time.Time) []ScheduleConflict {
+	var enabled []models.Schedule
+	for _, schedule := range schedules {
+		if schedule.Enabled {
+			enabled = append(enabled, schedule)
+		}
+	}
+
+	// Which instances each schedule matches
+	matched := make([]map[string]models.Instance, len(enabled))
+	for i, schedule := range enabled {
+		matched[i] = make(map[string]models.Instance)
+		for _, instance := range instances {
+			if MatchesSchedule(instance, schedule) {
+				matched[i][instance.ID] = instance
+			}
+		}
+	}
+
+	timelines := make(map[int][]stateChange)
+	timeline := func(i int) []stateChange {
+		if _, ok := timelines[i]; !ok {
+			timelines[i] = stateTimeline(enabled[i], calendars, from, until)
+		}
+		return timelines[i]
+	}
+
+	conflicts := []ScheduleConflict{}
+	for i := range enabled {
+		for j := i + 1; j < len(enabled); j++ {
+			var shared []ConflictInstance
+			for id, instance := range matched[i] {
+				if _, ok := matched[j][id]; ok {
+					shared = append(shared, ConflictInstance{ID: instance.ID, Name: instance.Name})
+				}
+			}
+			if len(shared) == 0 {
+				continue
+			}
+
+			windows := disagreements(timeline(i), timeline(j), from, until)
+			if len(windows) == 0 {
+				continue
+			}
+
+			sort.Slice(shared, func(a, b int) bool {
+				return shared[a].Name < shared[b].Name
+			})
+			winner := enabled[i]
+			if outranks(enabled[j], enabled[i]) {
+				winner = enabled[j]
+			}
+			conflicts = append(conflicts, ScheduleConflict{
+				ScheduleA: ConflictSchedule{ID: enabled[i].ID, Name: enabled[i].Name, Priority: enabled[i].Priority},
+				ScheduleB: ConflictSchedule{ID: enabled[j].ID, Name: enabled[j].Name, Priority: enabled[j].Priority},
+				WinnerID:  winner.ID,
+				Instances: shared,
+				Windows:   windows,
+			})
+		}
+	}
+	return conflicts
+}
@@ -0,0 +1,36 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/gorhill/cronexpr"
+)
+
+// lastFireWindows are the look-back windows tried (in order) when searching for the
+// most recent fire time of a CRON expression. cronexpr can only iterate forward, so we
+// start a window before t and walk forward until we pass it.
+var lastFireWindows = []time.Duration{
+	time.Hour,
+	24 * time.Hour,
+	8 * 24 * time.Hour,
+	32 * 24 * time.Hour,
+	367 * 24 * time.Hour,
+}
+
+// lastFire returns the most recent time at or before t that expr fires
+// The search is done in t's location. Returns the zero time if expr has not fired
+// within the last year.
+func lastFire(expr *cronexpr.Expression, t time.Time) time.Time {
+	for _, window := range lastFireWindows {
+		var last time.Time
+		next := expr.Next(t.Add(-window))
+		for !next.IsZero() && !next.After(t) {
+			last = next
+			next = expr.Next(next)
+		}
+		if !last.IsZero() {
+			return last
+		}
+	}
+	return time.Time{}
+}
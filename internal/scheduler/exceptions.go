@@ -0,0 +1,77 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"snoozeql/internal/calendar"
+	"snoozeql/internal/models"
+)
+
+// Calendars holds exception calendars by ID
+type Calendars map[string]models.ExceptionCalendar
+
+// loadCalendars returns all exception calendars by ID (nil if there is no calendar store)
+func (s *Scheduler) loadCalendars(ctx context.Context) Calendars {
+	if s.calendarStore == nil {
+		return nil
+	}
+
+	list, err := s.calendarStore.ListCalendars(ctx)
+	if err != nil {
+		log.Printf("Warning: Failed to list exception calendars: %v", err)
+		return nil
+	}
+
+	return NewCalendars(list)
+}
+
+// NewCalendars indexes exception calendars by ID
+func NewCalendars(list []models.ExceptionCalendar) Calendars {
+	calendars := make(Calendars, len(list))
+	for _, cal := range list {
+		calendars[cal.ID] = cal
+	}
+	return calendars
+}
+
+// Exception returns the calendar and entry that puts t (in the schedule's timezone) under
+// an exception of the given mode. A force_sleep exception also counts as suppress_wake.
+func (c Calendars) Exception(schedule models.Schedule, mode string, t time.Time) (models.ExceptionCalendar, models.CalendarEntry, bool) {
+	for _, exception := range schedule.Exceptions {
+		if exception.Mode != mode && !(mode == models.ExceptionSuppressWake && exception.Mode == models.ExceptionForceSleep) {
+			continue
+		}
+		cal, ok := c[exception.CalendarID]
+		if !ok {
+			continue
+		}
+		if entry, ok := calendar.EntryOn(cal, t); ok {
+			return cal, entry, true
+		}
+	}
+	return models.ExceptionCalendar{}, models.CalendarEntry{}, false
+}
+
+// scheduleLocation returns the schedule's timezone, falling back to UTC
+func scheduleLocation(schedule models.Schedule) *time.Location {
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// startOfDay returns local midnight of t's calendar date
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// exceptionLabel describes a calendar entry for logs and API output
+func exceptionLabel(cal models.ExceptionCalendar, entry models.CalendarEntry) string {
+	if entry.Name == "" {
+		return cal.Name
+	}
+	return entry.Name + " (" + cal.Name + ")"
+}
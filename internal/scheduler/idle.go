@@ -0,0 +1,160 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"snoozeql/internal/metrics"
+	"snoozeql/internal/models"
+	"snoozeql/internal/selector"
+	"snoozeql/internal/store"
+)
+
+// idleWindow is what an instance's 5-minute metrics show over an idle policy's window
+type idleWindow struct {
+	PeakConnections float64
+	PeakCPUPercent  float64
+}
+
+// SetIdlePolicies makes the scheduler evaluate idle policies every interval and stop
+// matching instances whose 5-minute metrics show them idle for the policy's duration.
+// A zero interval disables idle policies.
+func (s *Scheduler) SetIdlePolicies(idlePolicyStore *store.IdlePolicyStore, metricsStore *metrics.MetricsStore, interval time.Duration) {
+	s.idlePolicyStore = idlePolicyStore
+	s.metricsStore = metricsStore
+	s.idleInterval = interval
+}
+
+// RunIdlePolicies stops running instances that have been idle for as long as an
+// enabled idle policy matching them requires. Stops go through the same path as
+// scheduled ones and are recorded with triggered_by "idle_policy".
+func (s *Scheduler) RunIdlePolicies(ctx context.Context) error {
+	if s.idlePolicyStore == nil || s.metricsStore == nil {
+		return nil
+	}
+
+	policies, err := s.idlePolicyStore.ListIdlePolicies(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list idle policies: %w", err)
+	}
+
+	var enabled []models.IdlePolicy
+	for _, policy := range policies {
+		if policy.Enabled {
+			enabled = append(enabled, policy)
+		}
+	}
+	if len(enabled) == 0 {
+		return nil
+	}
+
+	instances, err := s.instanceStore.ListInstances(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list instances: %w", err)
+	}
+
+	now := time.Now()
+	overrides := s.loadOverrides(ctx, now)
+
+	for _, instance := range instances {
+		if instance.Status != "available" && instance.Status != "running" {
+			continue
+		}
+
+		// The first matching policy (by name) decides
+		for _, policy := range enabled {
+			if !selector.MatchAny(instance, policy.Selectors) {
+				continue
+			}
+			s.applyIdlePolicy(ctx, instance, policy, overrides, now)
+			break
+		}
+	}
+	return nil
+}
+
+// applyIdlePolicy stops the instance if it has been idle for the policy's whole window
+func (s *Scheduler) applyIdlePolicy(ctx context.Context, instance models.Instance, policy models.IdlePolicy, overrides map[string][]models.Override, now time.Time) {
+	if hasKeepAlive(instance, overrides, now) {
+		return
+	}
+
+	windowStart := now.Add(-time.Duration(policy.IdleMinutes) * time.Minute)
+
+	// Metrics stored while the instance was asleep are zero, so a recent wake (or any
+	// other start/stop) means the window doesn't show real idleness yet
+	if s.eventStore != nil {
+		recent, err := s.eventStore.HasStateEventSince(ctx, instance.ID, windowStart)
+		if err != nil {
+			log.Printf("Warning: Failed to check events for %s: %v", instance.Name, err)
+			return
+		}
+		if recent {
+			return
+		}
+	}
+
+	window, ok := s.idleWindow(ctx, instance, windowStart, now)
+	if !ok || window.PeakConnections > policy.MaxConnections || window.PeakCPUPercent > policy.MaxCPUPercent {
+		return
+	}
+
+	log.Printf("Idle policy '%s' stopping %s - idle for %d minutes (peak %.0f connections, %.1f%% CPU)",
+		policy.Name, instance.Name, policy.IdleMinutes, window.PeakConnections, window.PeakCPUPercent)
+
+	metadata, _ := json.Marshal(map[string]any{
+		"policy_id":        policy.ID,
+		"policy_name":      policy.Name,
+		"idle_minutes":     policy.IdleMinutes,
+		"max_connections":  policy.MaxConnections,
+		"max_cpu_percent":  policy.MaxCPUPercent,
+		"peak_connections": window.PeakConnections,
+		"peak_cpu_percent": window.PeakCPUPercent,
+	})
+	if err := s.executeAction(ctx, instance, "stop", "idle_policy", metadata, "idle policy: "+policy.Name); err != nil {
+		log.Printf("Warning: Idle policy '%s' failed to stop %s: %v", policy.Name, instance.Name, err)
+	}
+}
+
+// idleWindow reads the instance's 5-minute connection and CPU metrics between start
+// and now. It returns false unless both metrics cover the window: data from its first
+// buckets and data no older than activityFreshness.
+func (s *Scheduler) idleWindow(ctx context.Context, instance models.Instance, start, now time.Time) (idleWindow, bool) {
+	rows, err := s.metricsStore.GetMinuteMetricsByInstance(ctx, instance.ID, start, now)
+	if err != nil {
+		log.Printf("Warning: Failed to read idle metrics for %s: %v", instance.Name, err)
+		return idleWindow{}, false
+	}
+
+	var window idleWindow
+	first := make(map[string]time.Time)
+	last := make(map[string]time.Time)
+	for _, row := range rows {
+		switch row.MetricName {
+		case models.MetricDatabaseConnections:
+			// Peak connections so a short session inside a bucket still counts
+			window.PeakConnections = max(window.PeakConnections, row.MaxValue)
+		case models.MetricCPUUtilization:
+			window.PeakCPUPercent = max(window.PeakCPUPercent, row.AvgValue)
+		default:
+			continue
+		}
+		if f, ok := first[row.MetricName]; !ok || row.Minute.Before(f) {
+			first[row.MetricName] = row.Minute
+		}
+		if row.Minute.After(last[row.MetricName]) {
+			last[row.MetricName] = row.Minute
+		}
+	}
+
+	for _, name := range []string{models.MetricDatabaseConnections, models.MetricCPUUtilization} {
+		f, ok := first[name]
+		if !ok || f.After(start.Add(2*metrics.MetricPeriod)) || now.Sub(last[name]) > activityFreshness {
+			return idleWindow{}, false
+		}
+	}
+	return window, true
+}
@@ -0,0 +1,169 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"snoozeql/internal/models"
+)
+
+// fill stores buckets of the instance every 5 minutes from first to last
+func (f *fakeMetrics) fill(instanceID string, first, last time.Time, connections, cpu float64) {
+	for minute := first; !minute.After(last); minute = minute.Add(5 * time.Minute) {
+		f.add(instanceID, minute, connections, cpu)
+	}
+}
+
+func TestIdleWindow(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	start := now.Add(-time.Hour)
+
+	tests := []struct {
+		name            string
+		metrics         func(*fakeMetrics)
+		wantOK          bool
+		wantConnections float64
+		wantCPU         float64
+	}{
+		{
+			name:    "covered and idle",
+			metrics: func(f *fakeMetrics) { f.fill("i-1", start, now.Add(-5*time.Minute), 0, 1.5) },
+			wantOK:  true, wantCPU: 1.5,
+		},
+		{
+			name: "peaks over the window",
+			metrics: func(f *fakeMetrics) {
+				f.fill("i-1", start, now.Add(-5*time.Minute), 0, 1)
+				f.add("i-1", start.Add(20*time.Minute), 3, 40)
+			},
+			wantOK: true, wantConnections: 3, wantCPU: 40,
+		},
+		{
+			name:    "first buckets may be missing",
+			metrics: func(f *fakeMetrics) { f.fill("i-1", start.Add(10*time.Minute), now.Add(-5*time.Minute), 0, 1) },
+			wantOK:  true, wantCPU: 1,
+		},
+		{
+			name:    "data starts too late",
+			metrics: func(f *fakeMetrics) { f.fill("i-1", start.Add(15*time.Minute), now.Add(-5*time.Minute), 0, 1) },
+		},
+		{
+			name:    "data stopped arriving",
+			metrics: func(f *fakeMetrics) { f.fill("i-1", start, now.Add(-activityFreshness-5*time.Minute), 0, 1) },
+		},
+		{
+			name: "connections only",
+			metrics: func(f *fakeMetrics) {
+				f.fill("i-1", start, now.Add(-5*time.Minute), 0, 1)
+				var connections []models.MinuteMetric
+				for _, row := range f.rows {
+					if row.MetricName == models.MetricDatabaseConnections {
+						connections = append(connections, row)
+					}
+				}
+				f.rows = connections
+			},
+		},
+		{
+			name: "other metrics are ignored",
+			metrics: func(f *fakeMetrics) {
+				f.fill("i-1", start, now.Add(-5*time.Minute), 0, 1)
+				f.rows = append(f.rows, models.MinuteMetric{InstanceID: "i-1", MetricName: "FreeableMemory", Minute: start, MaxValue: 1e9, AvgValue: 1e9})
+			},
+			wantOK: true, wantCPU: 1,
+		},
+		{
+			name:    "no data",
+			metrics: func(f *fakeMetrics) {},
+		},
+		{
+			name:    "metrics error",
+			metrics: func(f *fakeMetrics) { f.err = errors.New("connection refused") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, metrics := newTestScheduler()
+			tt.metrics(metrics)
+
+			window, ok := s.idleWindow(context.Background(), testInstance("available"), start, now)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if window.PeakConnections != tt.wantConnections || window.PeakCPUPercent != tt.wantCPU {
+				t.Errorf("peaks = %v connections, %v%% CPU; want %v, %v%%", window.PeakConnections, window.PeakCPUPercent, tt.wantConnections, tt.wantCPU)
+			}
+		})
+	}
+}
+
+func TestApplyIdlePolicy(t *testing.T) {
+	now := time.Now().Truncate(5 * time.Minute)
+	policy := models.IdlePolicy{ID: "p", Name: "Idle dev", MaxConnections: 0, MaxCPUPercent: 5, IdleMinutes: 60}
+	start := now.Add(-time.Hour)
+
+	tests := []struct {
+		name      string
+		instance  models.Instance
+		metrics   func(*fakeMetrics)
+		overrides []models.Override
+		wantStop  bool
+	}{
+		{
+			name:     "idle for the whole window",
+			instance: testInstance("available"),
+			metrics:  func(f *fakeMetrics) { f.fill("i-1", start, now.Add(-5*time.Minute), 0, 2) },
+			wantStop: true,
+		},
+		{
+			name:     "CPU above the threshold",
+			instance: testInstance("available"),
+			metrics:  func(f *fakeMetrics) { f.fill("i-1", start, now.Add(-5*time.Minute), 0, 6) },
+		},
+		{
+			name:     "one connection",
+			instance: testInstance("available"),
+			metrics: func(f *fakeMetrics) {
+				f.fill("i-1", start, now.Add(-5*time.Minute), 0, 2)
+				f.add("i-1", now.Add(-30*time.Minute), 1, 2)
+			},
+		},
+		{
+			name:     "idle too briefly",
+			instance: testInstance("available"),
+			metrics:  func(f *fakeMetrics) { f.fill("i-1", now.Add(-30*time.Minute), now.Add(-5*time.Minute), 0, 2) },
+		},
+		{
+			name:      "keep-alive override",
+			instance:  testInstance("available"),
+			metrics:   func(f *fakeMetrics) { f.fill("i-1", start, now.Add(-5*time.Minute), 0, 2) },
+			overrides: []models.Override{{ID: "k", InstanceID: "i-1", Type: models.OverrideKeepAlive}},
+		},
+		{
+			name: "cluster member",
+			instance: func() models.Instance {
+				instance := testInstance("available")
+				instance.ClusterID = "cluster-1"
+				return instance
+			}(),
+			metrics: func(f *fakeMetrics) { f.fill("i-1", start, now.Add(-5*time.Minute), 0, 2) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, recorder, metrics := newTestScheduler()
+			tt.metrics(metrics)
+
+			s.applyIdlePolicy(context.Background(), tt.instance, policy, GroupOverrides(tt.overrides), now)
+
+			stopped := len(recorder.actions) == 1 && recorder.actions[0] == "stop orders"
+			if stopped != tt.wantStop || (!tt.wantStop && len(recorder.actions) != 0) {
+				t.Errorf("actions = %v, want stop = %v", recorder.actions, tt.wantStop)
+			}
+		})
+	}
+}
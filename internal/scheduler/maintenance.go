@@ -0,0 +1,280 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"snoozeql/internal/models"
+)
+
+// windowMargin is how long before a backup/maintenance window stops are already held
+// back, so a stop doesn't run into the window
+const windowMargin = 15 * time.Minute
+
+// maintenanceWindow is one occurrence of an instance's backup or maintenance window
+type maintenanceWindow struct {
+	Kind  string // "backup" or "maintenance"
+	Start time.Time
+	End   time.Time
+}
+
+var windowWeekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// SetMaintenanceWake makes the scheduler wake stopped instances lead before a maintenance
+// window in which pending maintenance will be applied, and put them back to sleep after
+// the window if their schedule wants them asleep. A zero lead disables it.
+func (s *Scheduler) SetMaintenanceWake(lead time.Duration) {
+	s.maintenanceWakeLead = lead
+}
+
+// parseClock parses "hh24:mi" into an offset from midnight
+func parseClock(value string) (time.Duration, error) {
+	hours, minutes, ok := strings.Cut(value, ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	h, errH := strconv.Atoi(hours)
+	m, errM := strconv.Atoi(minutes)
+	if errH != nil || errM != nil || h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// parseWeeklyClock parses "ddd:hh24:mi" into an offset from Sunday midnight
+func parseWeeklyClock(value string) (time.Duration, error) {
+	day, clock, ok := strings.Cut(value, ":")
+	weekday, known := windowWeekdays[strings.ToLower(day)]
+	if !ok || !known {
+		return 0, fmt.Errorf("invalid weekly time %q", value)
+	}
+	offset, err := parseClock(clock)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(weekday)*24*time.Hour + offset, nil
+}
+
+// windowOccurrences returns the occurrences of the instance's backup window
+// ("hh24:mi-hh24:mi", daily) and maintenance window ("ddd:hh24:mi-ddd:hh24:mi",
+// weekly) that overlap from..until. Windows are in UTC; unparseable ones are ignored.
+func windowOccurrences(instance models.Instance, from, until time.Time) []maintenanceWindow {
+	var windows []maintenanceWindow
+
+	if start, end, ok := strings.Cut(instance.BackupWindow, "-"); ok {
+		startOffset, errStart := parseClock(start)
+		endOffset, errEnd := parseClock(end)
+		if errStart == nil && errEnd == nil {
+			if endOffset <= startOffset {
+				endOffset += 24 * time.Hour
+			}
+			for day := from.UTC().Truncate(24*time.Hour).AddDate(0, 0, -1); day.Before(until); day = day.AddDate(0, 0, 1) {
+				windows = appendOverlapping(windows, maintenanceWindow{Kind: "backup", Start: day.Add(startOffset), End: day.Add(endOffset)}, from, until)
+			}
+		}
+	}
+
+	if start, end, ok := strings.Cut(instance.MaintenanceWindow, "-"); ok {
+		startOffset, errStart := parseWeeklyClock(start)
+		endOffset, errEnd := parseWeeklyClock(end)
+		if errStart == nil && errEnd == nil {
+			if endOffset <= startOffset {
+				endOffset += 7 * 24 * time.Hour
+			}
+			day := from.UTC().Truncate(24 * time.Hour)
+			week := day.AddDate(0, 0, -int(day.Weekday())-7) // Sunday midnight, a week early
+			for ; week.Before(until); week = week.AddDate(0, 0, 7) {
+				windows = appendOverlapping(windows, maintenanceWindow{Kind: "maintenance", Start: week.Add(startOffset), End: week.Add(endOffset)}, from, until)
+			}
+		}
+	}
+	return windows
+}
+
+// appendOverlapping appends the window if it overlaps from..until
+func appendOverlapping(windows []maintenanceWindow, window maintenanceWindow, from, until time.Time) []maintenanceWindow {
+	if window.End.After(from) && window.Start.Before(until) {
+		windows = append(windows, window)
+	}
+	return windows
+}
+
+// collidingWindow returns the backup or maintenance window a stop at now would run into:
+// one that is in progress or starts within windowMargin
+func collidingWindow(instance models.Instance, now time.Time) (maintenanceWindow, bool) {
+	windows := windowOccurrences(instance, now, now.Add(windowMargin))
+	if len(windows) == 0 {
+		return maintenanceWindow{}, false
+	}
+	// Hold the stop until the last of overlapping windows ends
+	colliding := windows[0]
+	for _, window := range windows[1:] {
+		if window.End.After(colliding.End) {
+			colliding = window
+		}
+	}
+	return colliding, true
+}
+
+// nextMaintenanceWindow returns the instance's next (or current) maintenance window
+func nextMaintenanceWindow(instance models.Instance, now time.Time) (maintenanceWindow, bool) {
+	for _, window := range windowOccurrences(instance, now, now.Add(8*24*time.Hour)) {
+		if window.Kind == "maintenance" {
+			return window, true
+		}
+	}
+	return maintenanceWindow{}, false
+}
+
+// dueMaintenance returns the pending maintenance actions the provider will apply in the window
+func dueMaintenance(actions []models.PendingMaintenanceAction, window maintenanceWindow) []string {
+	var due []string
+	for _, action := range actions {
+		var applies bool
+		switch {
+		case action.CurrentApplyDate != nil:
+			applies = !action.CurrentApplyDate.After(window.End)
+		case action.AutoAppliedAfter != nil:
+			applies = !action.AutoAppliedAfter.After(window.Start)
+		case action.ForcedApplyDate != nil:
+			applies = !action.ForcedApplyDate.After(window.End)
+		}
+		if applies {
+			due = append(due, action.Action)
+		}
+	}
+	return due
+}
+
+// holdForWindow delays a stop that would collide with the instance's backup or
+// maintenance window until the window is over. The stop is queued with the deferred
+// stops and a sleep_deferred event is recorded. Returns true if the stop was held back.
+// A stop that is already deferred stays deferred, without another event.
+func (s *Scheduler) holdForWindow(ctx context.Context, instance models.Instance, triggeredBy string, metadata []byte, reason string, now time.Time) bool {
+	window, ok := collidingWindow(instance, now)
+	if !ok {
+		return false
+	}
+
+	s.mu.Lock()
+	if _, pending := s.deferredStops[instance.ID]; pending {
+		s.mu.Unlock()
+		return true
+	}
+	s.deferredStops[instance.ID] = &deferredStop{
+		instance:    instance,
+		triggeredBy: triggeredBy,
+		metadata:    metadata,
+		reason:      reason,
+		deferredAt:  now,
+		nextCheck:   window.End,
+	}
+	s.mu.Unlock()
+
+	log.Printf("Delaying stop of %s until its %s window ends at %s", instance.Name, window.Kind, window.End.Format(time.RFC3339))
+	if s.eventStore == nil {
+		return true
+	}
+
+	fields := map[string]any{}
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &fields); err != nil {
+			fields = map[string]any{}
+		}
+	}
+	fields["window"] = window.Kind
+	fields["window_start"] = window.Start
+	fields["window_end"] = window.End
+	eventMetadata, _ := json.Marshal(fields)
+
+	event := &models.Event{
+		InstanceID:     instance.ID,
+		EventType:      "sleep_deferred",
+		TriggeredBy:    triggeredBy,
+		PreviousStatus: instance.Status,
+		NewStatus:      instance.Status,
+		Metadata:       eventMetadata,
+	}
+	if err := s.eventStore.CreateEvent(ctx, event); err != nil {
+		log.Printf("Warning: Failed to create sleep_deferred event for %s: %v", instance.Name, err)
+	}
+	return true
+}
+
+// runMaintenanceWakes wakes stopped instances shortly before a maintenance window in
+// which pending maintenance will be applied (AWS only patches running instances), and
+// stops them again once the window is over if their schedule wants them asleep
+func (s *Scheduler) runMaintenanceWakes(ctx context.Context, schedules []models.Schedule, calendars Calendars, overrides map[string][]models.Override, now time.Time) {
+	if s.maintenanceWakeLead <= 0 || s.eventStore == nil {
+		return
+	}
+
+	instances, err := s.instanceStore.ListInstances(ctx)
+	if err != nil {
+		log.Printf("Warning: Failed to list instances for maintenance wakes: %v", err)
+		return
+	}
+
+	for _, instance := range instances {
+		// Cluster members are woken for maintenance with their cluster
+		if instance.ClusterID != "" {
+			continue
+		}
+		switch instance.Status {
+		case "stopped":
+			window, ok := nextMaintenanceWindow(instance, now)
+			if !ok || now.Before(window.Start.Add(-s.maintenanceWakeLead)) || !now.Before(window.Start) {
+				continue
+			}
+			due := dueMaintenance(instance.PendingMaintenance, window)
+			if len(due) == 0 {
+				continue
+			}
+			// Anything that already started or stopped it in the lead time wins
+			if acted, err := s.eventStore.HasStateEventSince(ctx, instance.ID, window.Start.Add(-s.maintenanceWakeLead)); err != nil || acted {
+				continue
+			}
+
+			log.Printf("Waking %s for pending maintenance (%s) in window starting %s", instance.Name, strings.Join(due, ", "), window.Start.Format(time.RFC3339))
+			metadata, _ := json.Marshal(map[string]any{
+				"window_start":        window.Start,
+				"window_end":          window.End,
+				"maintenance_actions": due,
+			})
+			s.executeAction(ctx, instance, "start", "maintenance_window", metadata, "pending maintenance")
+
+		case "available", "running":
+			latest, err := s.eventStore.LatestStateEvent(ctx, instance.ID)
+			if err != nil || latest.EventType != "wake" || latest.TriggeredBy != "maintenance_window" {
+				continue
+			}
+			var woke struct {
+				WindowEnd time.Time `json:"window_end"`
+			}
+			if err := json.Unmarshal(latest.Metadata, &woke); err != nil || now.Before(woke.WindowEnd) {
+				continue
+			}
+
+			target, ok := instanceTarget(instance, schedules, calendars, now)
+			if !ok || target.Action != "stop" || hasKeepAlive(instance, overrides, now) {
+				continue
+			}
+
+			log.Printf("Maintenance window of %s is over - schedule '%s' wants it asleep", instance.Name, target.Schedule.Name)
+			metadata, _ := json.Marshal(map[string]any{
+				"window_end":    woke.WindowEnd,
+				"schedule_id":   target.Schedule.ID,
+				"schedule_name": target.Schedule.Name,
+			})
+			s.executeAction(ctx, instance, "stop", "maintenance_window", metadata, "maintenance window over: "+target.Schedule.Name)
+		}
+	}
+}
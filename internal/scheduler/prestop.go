@@ -0,0 +1,118 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gorhill/cronexpr"
+	"snoozeql/internal/models"
+	"snoozeql/internal/notify"
+)
+
+// SetNotifier enables pre-stop warnings, sent lead time before each scheduled sleep
+func (s *Scheduler) SetNotifier(notifier notify.Notifier, lead time.Duration) {
+	s.notifier = notifier
+	s.preStopLead = lead
+}
+
+// upcomingStop returns the sleep fire time if the schedule's sleep CRON fires exactly
+// lead from now (to the minute), i.e. now is when the pre-stop warning is due
+func upcomingStop(schedule models.Schedule, now time.Time, lead time.Duration) (time.Time, bool) {
+	if schedule.SleepCron == "" {
+		return time.Time{}, false
+	}
+	sleepCron, err := cronexpr.Parse(schedule.SleepCron)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	// Same approach as determineAction, shifted forward by the lead time
+	target := now.In(loc).Add(lead).Truncate(time.Minute)
+	next := sleepCron.Next(target.Add(-1 * time.Minute))
+	if !next.Truncate(time.Minute).Equal(target) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// warnPreStop sends a pre-stop warning for the schedule if one is due now.
+// Instances that won't actually be stopped (already asleep, kept awake by a
+// higher-priority schedule, or covered by a keep-alive/skip-next override) are
+// left out of the warning. resolver must be evaluated at the stop time.
+func (s *Scheduler) warnPreStop(ctx context.Context, schedule models.Schedule, overrides map[string][]models.Override, resolver *priorityResolver, now time.Time) {
+	if s.notifier == nil || s.preStopLead <= 0 {
+		return
+	}
+
+	stopTime, due := upcomingStop(schedule, now, s.preStopLead)
+	if !due || !s.shouldExecute(schedule.ID, "warn", now) {
+		return
+	}
+
+	instances, err := s.instanceStore.ListInstances(ctx)
+	if err != nil {
+		log.Printf("Warning: Failed to list instances for pre-stop warning %s: %v", schedule.Name, err)
+		return
+	}
+
+	var stopping []models.Instance
+	for _, instance := range instances {
+		if !MatchesSchedule(instance, schedule) {
+			continue
+		}
+		if instance.Status != "available" && instance.Status != "running" {
+			continue
+		}
+		if _, blocked := resolver.blockedBy(schedule, instance, "stop"); blocked {
+			continue
+		}
+		if overrideWouldBlock(instance, "stop", overrides, stopTime) {
+			continue
+		}
+		stopping = append(stopping, instance)
+	}
+
+	if len(stopping) == 0 {
+		return
+	}
+
+	warning := notify.PreStopWarning{
+		ScheduleID:   schedule.ID,
+		ScheduleName: schedule.Name,
+		StopTime:     stopTime,
+		Instances:    stopping,
+	}
+	if err := s.notifier.NotifyPreStop(ctx, warning); err != nil {
+		log.Printf("Warning: Failed to send pre-stop warning for schedule %s: %v", schedule.Name, err)
+		return
+	}
+	log.Printf("Sent pre-stop warning for schedule '%s' (%d instances, stop at %s)",
+		schedule.Name, len(stopping), stopTime.Format("15:04"))
+}
+
+// overrideWouldBlock reports whether an override will block the action at the given
+// time, without consuming skip-next overrides
+func overrideWouldBlock(instance models.Instance, action string, overrides map[string][]models.Override, at time.Time) bool {
+	for _, override := range overrides[instance.ID] {
+		if !override.IsActive(at) {
+			continue
+		}
+		switch override.Type {
+		case models.OverrideKeepAlive:
+			if action == "stop" {
+				return true
+			}
+		case models.OverrideSkipNext:
+			if override.SkipAction != nil && *override.SkipAction == action {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,223 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gorhill/cronexpr"
+	"snoozeql/internal/models"
+)
+
+// desiredState is the state a schedule wants an instance to be in right now
+type desiredState struct {
+	Action   string    // "start" (awake) or "stop" (asleep)
+	FireTime time.Time // The CRON fire that put the schedule in this state
+	Schedule models.Schedule
+}
+
+// desiredAction works out whether a schedule wants its instances awake or asleep at now
+// by comparing the most recent sleep fire with the most recent wake fire.
+// Returns "none" if neither CRON has fired within the last year. Wake wins ties,
+// matching determineAction. On force_sleep dates the schedule wants instances asleep
+// from midnight, and a wake that fired on a suppressed date doesn't count.
+func desiredAction(schedule models.Schedule, now time.Time, calendars Calendars) (string, time.Time) {
+	nowInScheduleLoc := now.In(scheduleLocation(schedule))
+
+	if _, _, ok := calendars.Exception(schedule, models.ExceptionForceSleep, nowInScheduleLoc); ok {
+		return "stop", startOfDay(nowInScheduleLoc)
+	}
+
+	var wakeLast, sleepLast time.Time
+	if schedule.WakeCron != "" {
+		if wakeCron, err := cronexpr.Parse(schedule.WakeCron); err == nil {
+			wakeLast = lastFire(wakeCron, nowInScheduleLoc)
+		}
+	}
+	if schedule.SleepCron != "" {
+		if sleepCron, err := cronexpr.Parse(schedule.SleepCron); err == nil {
+			sleepLast = lastFire(sleepCron, nowInScheduleLoc)
+		}
+	}
+
+	// A suppressed wake leaves the schedule in the state of the sleep before it
+	if !wakeLast.IsZero() {
+		if _, _, ok := calendars.Exception(schedule, models.ExceptionSuppressWake, wakeLast); ok {
+			wakeLast = time.Time{}
+		}
+	}
+
+	switch {
+	case wakeLast.IsZero() && sleepLast.IsZero():
+		return "none", time.Time{}
+	case sleepLast.After(wakeLast):
+		return "stop", sleepLast
+	default:
+		return "start", wakeLast
+	}
+}
+
+// Reconcile converges instances to the state their schedules want them in.
+// It catches up on CRON fires that were missed (e.g. while the server was down).
+// When matching schedules disagree, the highest-ranked one (see outranks) decides.
+// An instance is left alone if it is not in a steady state, if an override blocks the
+// action, or if a wake/sleep/start/stop event has been recorded for it since the
+// deciding fire (so manual actions are respected).
+func (s *Scheduler) Reconcile(ctx context.Context) error {
+	schedules, err := s.store.ListSchedules()
+	if err != nil {
+		return fmt.Errorf("failed to list schedules: %w", err)
+	}
+
+	instances, err := s.instanceStore.ListInstances(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list instances: %w", err)
+	}
+
+	now := time.Now()
+	overrides := s.loadOverrides(ctx, now)
+	calendars := s.loadCalendars(ctx)
+
+	// Work out each instance's desired state across all matching schedules
+	desired := make(map[string][]desiredState)
+	for _, schedule := range schedules {
+		if !schedule.Enabled {
+			continue
+		}
+
+		action, fireTime := desiredAction(schedule, now, calendars)
+		if action == "none" {
+			continue
+		}
+
+		for _, instance := range instances {
+			if MatchesSchedule(instance, schedule) {
+				desired[instance.ID] = append(desired[instance.ID], desiredState{
+					Action:   action,
+					FireTime: fireTime,
+					Schedule: schedule,
+				})
+			}
+		}
+	}
+
+	// Ordered schedules converge tier by tier, so collect their instances first
+	type orderedConverge struct {
+		target    desiredState
+		instances []models.Instance
+	}
+	ordered := make(map[string]*orderedConverge)
+
+	converged := 0
+	for _, instance := range instances {
+		states := desired[instance.ID]
+		if len(states) == 0 {
+			continue
+		}
+
+		target := pickTarget(states)
+		for _, state := range states {
+			if state.Action != target.Action {
+				log.Printf("Reconcile: %s - schedule '%s' outranks '%s', wants %s",
+					instance.Name, target.Schedule.Name, state.Schedule.Name, target.Action)
+			}
+		}
+
+		// Only act on instances in a steady state that differs from the target
+		if target.Action == "stop" && instance.Status != "available" && instance.Status != "running" {
+			continue
+		}
+		if target.Action == "start" && instance.Status != "stopped" {
+			continue
+		}
+
+		// Anything that happened since the fire (including a manual action) wins
+		if s.eventStore != nil {
+			handled, err := s.eventStore.HasStateEventSince(ctx, instance.ID, target.FireTime)
+			if err != nil {
+				log.Printf("Warning: Failed to check events for %s: %v", instance.Name, err)
+				continue
+			}
+			if handled {
+				continue
+			}
+		}
+
+		if s.overrideBlocks(ctx, instance, target.Action, overrides, now) {
+			continue
+		}
+
+		log.Printf("Reconcile: %s is %s but schedule '%s' wants %s since %s",
+			instance.Name, instance.Status, target.Schedule.Name, target.Action, target.FireTime.Format(time.RFC3339))
+
+		converged++
+		if isOrdered(target.Schedule) {
+			key := target.Schedule.ID + "_" + target.Action
+			if ordered[key] == nil {
+				ordered[key] = &orderedConverge{target: target}
+			}
+			ordered[key].instances = append(ordered[key].instances, instance)
+			continue
+		}
+
+		metadata, _ := json.Marshal(reconcileMetadata(target))
+		s.executeAction(ctx, instance, target.Action, "reconcile", metadata, "reconcile: "+target.Schedule.Name)
+	}
+
+	for _, group := range ordered {
+		s.startOrdered(ctx, group.target.Schedule, group.target.Action, group.instances, nil, "reconcile", reconcileMetadata(group.target))
+	}
+
+	if converged > 0 {
+		log.Printf("Reconcile: converged %d instances", converged)
+	}
+	return nil
+}
+
+// pickTarget chooses the desired state among an instance's matching schedules. The
+// highest-ranked schedule decides the state; among schedules that agree with it, the
+// most recent fire decides when the state was entered.
+func pickTarget(states []desiredState) desiredState {
+	winner := states[0]
+	for _, state := range states[1:] {
+		if outranks(state.Schedule, winner.Schedule) {
+			winner = state
+		}
+	}
+	target := winner
+	for _, state := range states {
+		if state.Action == winner.Action && state.FireTime.After(target.FireTime) {
+			target = state
+		}
+	}
+	return target
+}
+
+// instanceTarget returns the state the instance's enabled matching schedules want it
+// in at now, or false if none of them has fired
+func instanceTarget(instance models.Instance, schedules []models.Schedule, calendars Calendars, now time.Time) (desiredState, bool) {
+	var states []desiredState
+	for _, schedule := range schedules {
+		if !schedule.Enabled || !MatchesSchedule(instance, schedule) {
+			continue
+		}
+		if action, fireTime := desiredAction(schedule, now, calendars); action != "none" {
+			states = append(states, desiredState{Action: action, FireTime: fireTime, Schedule: schedule})
+		}
+	}
+	if len(states) == 0 {
+		return desiredState{}, false
+	}
+	return pickTarget(states), true
+}
+
+// reconcileMetadata is the event metadata recorded for a reconcile action
+func reconcileMetadata(target desiredState) map[string]any {
+	return map[string]any{
+		"schedule_id":   target.Schedule.ID,
+		"schedule_name": target.Schedule.Name,
+		"fire_time":     target.FireTime,
+	}
+}
@@ -0,0 +1,140 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"snoozeql/internal/models"
+	"snoozeql/internal/selector"
+)
+
+// scheduledActionClaimTimeout is how long a claimed action may stay running before it is
+// taken to be abandoned and claimed again. Executing an action only requests the start or
+// stop, so this is far longer than a run takes; running it again skips the instances
+// already in the target state.
+const scheduledActionClaimTimeout = 10 * time.Minute
+
+// runScheduledActions claims the one-off actions that are due, or were abandoned while
+// running, and executes them
+func (s *Scheduler) runScheduledActions(ctx context.Context, overrides map[string][]models.Override, now time.Time) {
+	if s.actionStore == nil {
+		return
+	}
+
+	due, err := s.actionStore.ClaimDueActions(ctx, now, now.Add(-scheduledActionClaimTimeout))
+	if err != nil {
+		log.Printf("Warning: Failed to claim scheduled actions: %v", err)
+		return
+	}
+
+	for _, action := range due {
+		s.runScheduledAction(ctx, action, overrides, now)
+	}
+}
+
+// runScheduledAction executes a single claimed action against its target instances
+// and records the outcome. Instances already in the target state are skipped, and
+// keep-alive overrides still block stops.
+func (s *Scheduler) runScheduledAction(ctx context.Context, action models.ScheduledAction, overrides map[string][]models.Override, now time.Time) {
+	log.Printf("Scheduled action '%s' (%s) due at %s", action.Name, action.Action, action.ExecuteAt.Format(time.RFC3339))
+
+	targets, err := s.scheduledActionTargets(ctx, action)
+	if err != nil {
+		s.finishScheduledAction(ctx, action, models.ScheduledActionFailed, 0, err.Error())
+		return
+	}
+
+	metadata, _ := json.Marshal(map[string]any{
+		"scheduled_action_id":   action.ID,
+		"scheduled_action_name": action.Name,
+		"scheduled_action_url":  "/api/v1/scheduled-actions/" + action.ID,
+		"execute_at":            action.ExecuteAt,
+	})
+
+	acted := 0
+	var failures []string
+	for _, instance := range targets {
+		if action.Action == "start" && (instance.Status == "available" || instance.Status == "starting" || instance.Status == "running") {
+			log.Printf("Skipping start for %s - already %s", instance.Name, instance.Status)
+			continue
+		}
+		if action.Action == "stop" && (instance.Status == "stopped" || instance.Status == "stopping") {
+			log.Printf("Skipping stop for %s - already %s", instance.Name, instance.Status)
+			continue
+		}
+		if action.Action == "stop" && hasKeepAlive(instance, overrides, now) {
+			log.Printf("Skipping stop for %s - keep-alive override active", instance.Name)
+			continue
+		}
+
+		if err := s.executeAction(ctx, instance, action.Action, "scheduled_action", metadata, "scheduled action: "+action.Name); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", instance.Name, err))
+			continue
+		}
+		acted++
+	}
+
+	if len(failures) > 0 {
+		s.finishScheduledAction(ctx, action, models.ScheduledActionFailed, acted, strings.Join(failures, "; "))
+		return
+	}
+	s.finishScheduledAction(ctx, action, models.ScheduledActionCompleted, acted, "")
+}
+
+// scheduledActionTargets returns the instances a scheduled action applies to
+func (s *Scheduler) scheduledActionTargets(ctx context.Context, action models.ScheduledAction) ([]models.Instance, error) {
+	if action.InstanceID != nil {
+		instance, err := s.instanceStore.GetInstanceByID(ctx, *action.InstanceID)
+		if err != nil {
+			return nil, fmt.Errorf("instance %s not found", *action.InstanceID)
+		}
+		return []models.Instance{*instance}, nil
+	}
+
+	// An action without selectors must never match the whole fleet
+	if len(action.Selectors) == 0 {
+		return nil, fmt.Errorf("action has no instance or selectors")
+	}
+
+	instances, err := s.instanceStore.ListInstances(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list instances: %w", err)
+	}
+
+	var targets []models.Instance
+	for _, instance := range instances {
+		if selector.MatchAny(instance, action.Selectors) {
+			targets = append(targets, instance)
+		}
+	}
+	return targets, nil
+}
+
+// finishScheduledAction records the outcome of a scheduled action
+func (s *Scheduler) finishScheduledAction(ctx context.Context, action models.ScheduledAction, status string, instanceCount int, errMsg string) {
+	var errPtr *string
+	if errMsg != "" {
+		errPtr = &errMsg
+		log.Printf("Scheduled action '%s' %s: %s", action.Name, status, errMsg)
+	} else {
+		log.Printf("Scheduled action '%s' %s (%d instances)", action.Name, status, instanceCount)
+	}
+
+	if err := s.actionStore.FinishScheduledAction(ctx, action.ID, status, instanceCount, errPtr); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// hasKeepAlive reports whether the instance has an active keep-alive override
+func hasKeepAlive(instance models.Instance, overrides map[string][]models.Override, now time.Time) bool {
+	for _, override := range overrides[instance.ID] {
+		if override.Type == models.OverrideKeepAlive && override.IsActive(now) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,26 +2,58 @@ package scheduler
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
-	"regexp"
 	"sync"
 	"time"
 
 	"github.com/gorhill/cronexpr"
 	"snoozeql/internal/models"
+	"snoozeql/internal/notify"
 	"snoozeql/internal/provider"
+	"snoozeql/internal/selector"
 	"snoozeql/internal/store"
+	"snoozeql/internal/tracker"
 )
 
 // Scheduler manages database schedules
 type Scheduler struct {
 	store         Store
 	registry      *provider.Registry
-	instanceStore *store.InstanceStore
-	eventStore    *store.EventStore
+	instanceStore InstanceReader
+	eventStore    EventRecorder
+	overrideStore *store.OverrideStore
+	calendarStore *store.CalendarStore
+	actionStore   *store.ScheduledActionStore
 	lastExecuted  map[string]time.Time // "scheduleID_wake" or "scheduleID_sleep" -> last execution time
+	orderedRuns   map[string]bool      // scheduleID -> ordered (tiered) run in progress
 	mu            sync.Mutex
+
+	// Reconciliation converges instances to their desired state (0 disables it)
+	reconcileInterval time.Duration
+
+	// Pre-stop warnings are sent preStopLead before each scheduled sleep
+	notifier    notify.Notifier
+	preStopLead time.Duration
+
+	// Tracks start/stop calls until the target state is reached (nil = fire-and-forget)
+	tracker *tracker.Tracker
+
+	// Stops of instances in use are deferred (nil guard = stop right away)
+	metricsStore  metricsReader
+	activityGuard *ActivityGuard
+	deferredStops map[string]*deferredStop // instanceID -> stop waiting for the instance to go idle
+
+	// Idle policies stop inactive instances every idleInterval (0 disables them)
+	idlePolicyStore *store.IdlePolicyStore
+	idleInterval    time.Duration
+
+	// Stopped instances with pending maintenance are woken this long before the window (0 disables it)
+	maintenanceWakeLead time.Duration
+
+	// instanceID -> when the last awake-hours budget warning was sent
+	budgetWarned map[string]time.Time
 }
 
 // Store interface for schedule persistence
@@ -34,18 +66,59 @@ type Store interface {
 	GetMatchingSchedules(instance models.Instance) ([]models.Schedule, error)
 }
 
+// metricsReader reads the stored 5-minute metrics activity and idle checks are based
+// on; *metrics.MetricsStore in production
+type metricsReader interface {
+	GetLatestMinuteMetrics(ctx context.Context, instanceID string, since time.Time, metricNames ...string) (map[string]models.MinuteMetric, error)
+	GetMinuteMetricsByInstance(ctx context.Context, instanceID string, start, end time.Time) ([]models.MinuteMetric, error)
+}
+
+// InstanceReader reads the stored instances; *store.InstanceStore in production
+type InstanceReader interface {
+	ListInstances(ctx context.Context) ([]models.Instance, error)
+	GetInstanceByID(ctx context.Context, id string) (*models.Instance, error)
+}
+
+// EventRecorder records and looks up instance events; *store.EventStore in production
+type EventRecorder interface {
+	CreateEvent(ctx context.Context, event *models.Event) error
+	HasStateEventSince(ctx context.Context, instanceID string, since time.Time) (bool, error)
+	LatestStateEvent(ctx context.Context, instanceID string) (*models.Event, error)
+	ListStateEventsSince(ctx context.Context, instanceID string, since time.Time) ([]models.Event, error)
+	ListEventsByTypeSince(ctx context.Context, eventType string, since time.Time) ([]models.Event, error)
+}
+
 // NewScheduler creates a new scheduler
-func NewScheduler(store Store, registry *provider.Registry, instanceStore *store.InstanceStore, eventStore *store.EventStore) *Scheduler {
+func NewScheduler(store Store, registry *provider.Registry, instanceStore InstanceReader, eventStore EventRecorder, overrideStore *store.OverrideStore, calendarStore *store.CalendarStore, actionStore *store.ScheduledActionStore) *Scheduler {
 	return &Scheduler{
 		store:         store,
 		registry:      registry,
 		instanceStore: instanceStore,
 		eventStore:    eventStore,
+		overrideStore: overrideStore,
+		calendarStore: calendarStore,
+		actionStore:   actionStore,
 		lastExecuted:  make(map[string]time.Time),
+		orderedRuns:   make(map[string]bool),
+		deferredStops: make(map[string]*deferredStop),
+		budgetWarned:  make(map[string]time.Time),
 	}
 }
 
+// SetReconcileInterval enables periodic reconciliation at the given interval
+// A zero interval disables reconciliation
+func (s *Scheduler) SetReconcileInterval(interval time.Duration) {
+	s.reconcileInterval = interval
+}
+
+// SetTracker makes start/stop actions retry transient errors and record their outcome
+func (s *Scheduler) SetTracker(t *tracker.Tracker) {
+	s.tracker = t
+}
+
 // RunContinuous runs the scheduler evaluation on a 1-minute interval
+// When reconciliation is enabled it also runs on startup and every reconcile interval,
+// and idle policies are evaluated every idle interval when enabled
 func (s *Scheduler) RunContinuous(ctx context.Context) {
 	log.Printf("Scheduler daemon starting (1-minute interval)")
 
@@ -54,6 +127,25 @@ func (s *Scheduler) RunContinuous(ctx context.Context) {
 		log.Printf("Initial scheduler run error: %v", err)
 	}
 
+	// Converge instances whose schedule fired while the scheduler was down
+	var reconcileC <-chan time.Time
+	if s.reconcileInterval > 0 {
+		if err := s.Reconcile(ctx); err != nil {
+			log.Printf("Initial reconcile error: %v", err)
+		}
+		reconcileTicker := time.NewTicker(s.reconcileInterval)
+		defer reconcileTicker.Stop()
+		reconcileC = reconcileTicker.C
+	}
+
+	// Stop instances that idle policies consider inactive
+	var idleC <-chan time.Time
+	if s.idleInterval > 0 {
+		idleTicker := time.NewTicker(s.idleInterval)
+		defer idleTicker.Stop()
+		idleC = idleTicker.C
+	}
+
 	ticker := time.NewTicker(1 * time.Minute)
 	defer ticker.Stop()
 
@@ -66,6 +158,14 @@ func (s *Scheduler) RunContinuous(ctx context.Context) {
 			if err := s.Run(ctx); err != nil {
 				log.Printf("Scheduler run error: %v", err)
 			}
+		case <-reconcileC:
+			if err := s.Reconcile(ctx); err != nil {
+				log.Printf("Reconcile error: %v", err)
+			}
+		case <-idleC:
+			if err := s.RunIdlePolicies(ctx); err != nil {
+				log.Printf("Idle policy error: %v", err)
+			}
 		}
 	}
 }
@@ -99,12 +199,35 @@ func (s *Scheduler) Run(ctx context.Context) error {
 
 	now := time.Now()
 
+	// Load active overrides once per run (expired ones are marked first)
+	overrides := s.loadOverrides(ctx, now)
+	calendars := s.loadCalendars(ctx)
+	resolver := newPriorityResolver(schedules, calendars, now)
+	warnResolver := newPriorityResolver(schedules, calendars, now.Add(s.preStopLead))
+
+	// One-off scheduled actions that are due
+	s.runScheduledActions(ctx, overrides, now)
+
+	// Stops held back by the activity guard that are due for a re-check
+	s.runDeferredStops(ctx, overrides, now)
+
+	// Instances AWS started after seven days stopped, while their schedule wants them asleep
+	s.restopAutoRestarts(ctx, schedules, calendars, overrides, now)
+
+	// Wake instances for pending maintenance, and put them back to sleep afterwards
+	s.runMaintenanceWakes(ctx, schedules, calendars, overrides, now)
+
+	// Instances that have used up an awake-hours budget
+	s.runBudgets(ctx, schedules, overrides, now)
+
 	for _, schedule := range schedules {
 		if !schedule.Enabled {
 			continue
 		}
 
-		action := s.determineAction(schedule, now)
+		s.warnPreStop(ctx, schedule, overrides, warnResolver, now)
+
+		action := s.determineAction(schedule, now, calendars)
 		if action == "none" {
 			continue
 		}
@@ -126,7 +249,7 @@ func (s *Scheduler) Run(ctx context.Context) error {
 		// Filter to matching instances
 		var matchingInstances []models.Instance
 		for _, instance := range instances {
-			if matchesSelector(instance, schedule.Selectors) {
+			if MatchesSchedule(instance, schedule) {
 				matchingInstances = append(matchingInstances, instance)
 			}
 		}
@@ -138,151 +261,176 @@ func (s *Scheduler) Run(ctx context.Context) error {
 
 		log.Printf("Schedule '%s': Found %d matching instances", schedule.Name, len(matchingInstances))
 
+		var toAct, pending []models.Instance
 		for _, instance := range matchingInstances {
 			// Skip if instance is already in target state
 			if action == "start" && (instance.Status == "available" || instance.Status == "starting" || instance.Status == "running") {
 				log.Printf("Skipping start for %s - already %s", instance.Name, instance.Status)
+				if instance.Status == "starting" {
+					pending = append(pending, instance)
+				}
 				continue
 			}
 			if action == "stop" && (instance.Status == "stopped" || instance.Status == "stopping") {
 				log.Printf("Skipping stop for %s - already %s", instance.Name, instance.Status)
+				if instance.Status == "stopping" {
+					pending = append(pending, instance)
+				}
 				continue
 			}
 
-			// Check for active override
-			if hasActiveOverride(instance) {
-				log.Printf("Skipping %s - active override exists", instance.Name)
+			// A higher-priority schedule that wants the opposite state wins
+			if winner, blocked := resolver.blockedBy(schedule, instance, action); blocked {
+				log.Printf("Skipping %s for %s - higher-priority schedule '%s' wants the opposite", action, instance.Name, winner.Name)
 				continue
 			}
 
-			// Determine new status for event logging
-			var newStatus string
-			var eventType string
-			if action == "start" {
-				newStatus = "starting"
-				eventType = "wake"
-			} else {
-				newStatus = "stopping"
-				eventType = "sleep"
+			// Check for active override
+			if s.overrideBlocks(ctx, instance, action, overrides, now) {
+				continue
 			}
 
-			// Log event BEFORE attempting action
-			if s.eventStore != nil {
-				event := &models.Event{
-					InstanceID:     instance.ID,
-					EventType:      eventType,
-					TriggeredBy:    "schedule",
-					PreviousStatus: instance.Status,
-					NewStatus:      newStatus,
-				}
-				if err := s.eventStore.CreateEvent(ctx, event); err != nil {
-					log.Printf("Warning: Failed to create event for %s: %v", instance.Name, err)
-				}
-			}
+			toAct = append(toAct, instance)
+		}
 
-			// Execute action using ProviderName (e.g., "aws_uuid_us-west-2")
-			switch action {
-			case "stop":
-				if err := s.registry.StopDatabase(ctx, instance.ProviderName, instance.ProviderID); err != nil {
-					log.Printf("Failed to stop %s: %v", instance.Name, err)
-				} else {
-					log.Printf("Stopped %s (schedule: %s)", instance.Name, schedule.Name)
-				}
-			case "start":
-				if err := s.registry.StartDatabase(ctx, instance.ProviderName, instance.ProviderID); err != nil {
-					log.Printf("Failed to start %s: %v", instance.Name, err)
-				} else {
-					log.Printf("Started %s (schedule: %s)", instance.Name, schedule.Name)
-				}
-			}
+		// Ordered schedules go tier by tier in the background
+		if isOrdered(schedule) {
+			s.startOrdered(ctx, schedule, action, toAct, pending, "schedule", nil)
+			continue
+		}
+
+		for _, instance := range toAct {
+			s.executeAction(ctx, instance, action, "schedule", nil, "schedule: "+schedule.Name)
 		}
 	}
 
 	return nil
 }
 
-func matchesSelector(instance models.Instance, selectors []models.Selector) bool {
-	if len(selectors) == 0 {
-		return true
-	}
+// executeAction starts or stops the instance, unless a stop is delayed because it would
+// collide with the instance's backup/maintenance window or the activity guard defers it
+// because the instance is in use. A start cancels any deferred stop of the instance.
+// Cluster members are refused, since only their cluster can be started or stopped.
+// triggeredBy and metadata are recorded on the event; reason is only used for logging
+func (s *Scheduler) executeAction(ctx context.Context, instance models.Instance, action, triggeredBy string, metadata []byte, reason string) error {
+	_, err := s.requestAction(ctx, instance, action, triggeredBy, metadata, reason)
+	return err
+}
 
-	for _, selector := range selectors {
-		if selectorMatchesInstance(instance, selector) {
-			return true
-		}
+// requestAction is executeAction, also reporting whether a stop was held back (queued
+// with the deferred stops) instead of being requested
+func (s *Scheduler) requestAction(ctx context.Context, instance models.Instance, action, triggeredBy string, metadata []byte, reason string) (held bool, err error) {
+	if instance.ClusterID != "" {
+		log.Printf("Skipping %s of %s: it is a member of cluster %s", action, instance.Name, instance.ClusterID)
+		return false, fmt.Errorf("%s is a member of cluster %s - %s the cluster instead", instance.Name, instance.ClusterID, action)
 	}
-
-	return false
+	if action == "stop" && s.holdForWindow(ctx, instance, triggeredBy, metadata, reason, time.Now()) {
+		return true, nil
+	}
+	if action == "stop" && s.deferStop(ctx, instance, triggeredBy, metadata, reason) {
+		return true, nil
+	}
+	if action == "start" {
+		s.cancelDeferredStop(instance)
+	}
+	return false, s.performAction(ctx, instance, action, triggeredBy, metadata, reason)
 }
 
-func selectorMatchesInstance(instance models.Instance, selector models.Selector) bool {
-	if selector.Name != nil {
-		if !matchesMatcher(instance.Name, selector.Name) {
-			return false
-		}
+// performAction logs a wake/sleep event for the instance and then starts or stops it
+func (s *Scheduler) performAction(ctx context.Context, instance models.Instance, action, triggeredBy string, metadata []byte, reason string) error {
+	// Determine new status for event logging
+	var newStatus string
+	var eventType string
+	if action == "start" {
+		newStatus = "starting"
+		eventType = "wake"
+	} else {
+		newStatus = "stopping"
+		eventType = "sleep"
 	}
 
-	if selector.Provider != nil {
-		if instance.Provider != *selector.Provider {
-			return false
+	// Log event BEFORE attempting action
+	if s.eventStore != nil {
+		event := &models.Event{
+			InstanceID:     instance.ID,
+			EventType:      eventType,
+			TriggeredBy:    triggeredBy,
+			PreviousStatus: instance.Status,
+			NewStatus:      newStatus,
+			Metadata:       metadata,
 		}
-	}
-
-	if selector.Region != nil {
-		if !matchesMatcher(instance.Region, selector.Region) {
-			return false
+		if err := s.eventStore.CreateEvent(ctx, event); err != nil {
+			log.Printf("Warning: Failed to create event for %s: %v", instance.Name, err)
 		}
 	}
 
-	if selector.Engine != nil {
-		if !matchesMatcher(instance.Engine, selector.Engine) {
-			return false
+	// Execute action using ProviderName (e.g., "aws_uuid_us-west-2")
+	if s.tracker != nil {
+		if err := s.tracker.Execute(ctx, instance, action, triggeredBy, metadata); err != nil {
+			log.Printf("Failed to %s %s: %v", action, instance.Name, err)
+			return err
 		}
+		log.Printf("Requested %s of %s (%s)", action, instance.Name, reason)
+		return nil
 	}
 
-	if selector.Tags != nil {
-		for key, matcher := range selector.Tags {
-			tagValue, exists := instance.Tags[key]
-			if !exists {
-				return false
-			}
-			if !matchesMatcher(tagValue, matcher) {
-				return false
-			}
+	switch action {
+	case "stop":
+		if err := s.registry.StopDatabase(ctx, instance.ProviderName, instance.ProviderID); err != nil {
+			log.Printf("Failed to stop %s: %v", instance.Name, err)
+			s.recordActionFailed(ctx, instance, action, triggeredBy, metadata, err)
+			return err
+		}
+		log.Printf("Stopped %s (%s)", instance.Name, reason)
+	case "start":
+		if err := s.registry.StartDatabase(ctx, instance.ProviderName, instance.ProviderID); err != nil {
+			log.Printf("Failed to start %s: %v", instance.Name, err)
+			s.recordActionFailed(ctx, instance, action, triggeredBy, metadata, err)
+			return err
 		}
+		log.Printf("Started %s (%s)", instance.Name, reason)
 	}
-
-	return true
+	return nil
 }
 
-func matchesMatcher(value string, matcher *models.Matcher) bool {
-	if matcher == nil {
-		return true
+// recordActionFailed writes the action_failed event the tracker would record for a
+// provider call that failed, so the wake/sleep event written before it isn't taken
+// as the instance's state
+func (s *Scheduler) recordActionFailed(ctx context.Context, instance models.Instance, action, triggeredBy string, metadata []byte, actionErr error) {
+	if s.eventStore == nil {
+		return
 	}
-
-	pattern := matcher.Pattern
-
-	switch models.MatchType(matcher.Type) {
-	case models.MatchExact:
-		return value == pattern
-	case models.MatchContains:
-		return contains(value, pattern)
-	case models.MatchPrefix:
-		return startsWith(value, pattern)
-	case models.MatchSuffix:
-		return endsWith(value, pattern)
-	case models.MatchRegex:
-		re, err := regexp.Compile(pattern)
-		if err != nil {
-			return false
+	fields := map[string]any{}
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &fields); err != nil {
+			fields = map[string]any{}
 		}
-		return re.MatchString(value)
-	default:
-		return contains(value, pattern)
 	}
+	fields["action"] = action
+	fields["error"] = actionErr.Error()
+	eventMetadata, _ := json.Marshal(fields)
+	event := &models.Event{
+		InstanceID:     instance.ID,
+		EventType:      tracker.EventActionFailed,
+		TriggeredBy:    triggeredBy,
+		PreviousStatus: instance.Status,
+		NewStatus:      instance.Status,
+		Metadata:       eventMetadata,
+	}
+	if err := s.eventStore.CreateEvent(ctx, event); err != nil {
+		log.Printf("Warning: Failed to create %s event for %s: %v", event.EventType, instance.Name, err)
+	}
+}
+
+// MatchesSchedule reports whether the schedule's selectors select the instance
+func MatchesSchedule(instance models.Instance, schedule models.Schedule) bool {
+	return selector.MatchSchedule(instance, schedule)
 }
 
-func (s *Scheduler) determineAction(schedule models.Schedule, now time.Time) string {
+// determineAction returns the action ("start", "stop" or "none") the schedule fires in
+// the current minute. Exception calendars suppress wakes on their dates, and force_sleep
+// calendars also fire a stop at the start of each of their dates.
+func (s *Scheduler) determineAction(schedule models.Schedule, now time.Time, calendars Calendars) string {
 	// Load timezone
 	loc, err := time.LoadLocation(schedule.Timezone)
 	if err != nil {
@@ -290,6 +438,55 @@ func (s *Scheduler) determineAction(schedule models.Schedule, now time.Time) str
 		loc = time.UTC
 	}
 
+	var wakeCron, sleepCron *cronexpr.Expression
+	if schedule.WakeCron != "" {
+		if wakeCron, err = cronexpr.Parse(schedule.WakeCron); err != nil {
+			log.Printf("Warning: Invalid wake_cron '%s' for schedule %s: %v", schedule.WakeCron, schedule.Name, err)
+		}
+	}
+	if schedule.SleepCron != "" {
+		if sleepCron, err = cronexpr.Parse(schedule.SleepCron); err != nil {
+			log.Printf("Warning: Invalid sleep_cron '%s' for schedule %s: %v", schedule.SleepCron, schedule.Name, err)
+		}
+	}
+
+	fire := evaluateFire(schedule, loc, wakeCron, sleepCron, now, calendars)
+	if fire.SuppressedBy != "" {
+		log.Printf("Schedule '%s': wake suppressed for %s", schedule.Name, fire.SuppressedBy)
+	}
+	switch fire.Trigger {
+	case TriggerForceSleep:
+		log.Printf("Schedule '%s': forcing sleep for %s", schedule.Name, fire.ForcedBy)
+	case TriggerWakeCron:
+		log.Printf("DEBUG: Schedule '%s' triggered wake at %s", schedule.Name, schedule.WakeCron)
+	case TriggerSleepCron:
+		log.Printf("DEBUG: Schedule '%s' triggered sleep at %s", schedule.Name, schedule.SleepCron)
+	}
+	return fire.Action
+}
+
+// Triggers that make a schedule fire
+const (
+	TriggerWakeCron   = "wake_cron"
+	TriggerSleepCron  = "sleep_cron"
+	TriggerForceSleep = "force_sleep"
+)
+
+// scheduleFire is what a schedule does in a given minute
+type scheduleFire struct {
+	Action  string // "start", "stop" or "none"
+	Trigger string // What fired the action (empty for "none")
+
+	// Exception (entry and calendar name) that forced a sleep, or suppressed a wake fire this minute
+	ForcedBy     string
+	SuppressedBy string
+}
+
+// evaluateFire works out what the schedule fires in now's minute (in loc).
+// A force_sleep calendar fires a stop at midnight; otherwise the wake CRON wins over
+// the sleep CRON when both fire, unless an exception calendar suppresses the wake.
+// Nil expressions (empty or invalid CRONs) never fire.
+func evaluateFire(schedule models.Schedule, loc *time.Location, wakeCron, sleepCron *cronexpr.Expression, now time.Time, calendars Calendars) scheduleFire {
 	// Convert now to the schedule's timezone for all comparisons
 	nowInScheduleLoc := now.In(loc)
 	currentMinute := nowInScheduleLoc.Truncate(time.Minute)
@@ -297,75 +494,128 @@ func (s *Scheduler) determineAction(schedule models.Schedule, now time.Time) str
 	// Get the minute that was the current minute when the CRON should have fired
 	// We check by going back 1 minute and seeing when the CRON fired after that
 	checkTime := nowInScheduleLoc.Add(-1 * time.Minute)
+	firesNow := func(expr *cronexpr.Expression) bool {
+		return expr != nil && expr.Next(checkTime).In(loc).Truncate(time.Minute).Equal(currentMinute)
+	}
 
-	// Parse wake CRON expression
-	if schedule.WakeCron != "" {
-		wakeCron, err := cronexpr.Parse(schedule.WakeCron)
-		if err == nil {
-			// Get the next fire time after checkTime
-			wakeLast := wakeCron.Next(checkTime)
-			wakeLastInScheduleLoc := wakeLast.In(loc)
-			wakeLastMinute := wakeLastInScheduleLoc.Truncate(time.Minute)
-
-			log.Printf("DEBUG: Schedule '%s' wake_cron=%s (in %s), now=%s, last fire minute=%s (schedule time)",
-				schedule.Name, schedule.WakeCron, schedule.Timezone,
-				nowInScheduleLoc.Format("15:04:05"),
-				wakeLastMinute.In(loc).Format("15:04:05"))
-
-			// Check if the last fire was in the current minute
-			if wakeLastMinute.Equal(currentMinute) {
-				log.Printf("DEBUG: Schedule '%s' triggered wake at %s", schedule.Name, schedule.WakeCron)
-				return "start"
-			}
-		} else {
-			log.Printf("Warning: Invalid wake_cron '%s' for schedule %s: %v", schedule.WakeCron, schedule.Name, err)
+	// Force sleep at the start of every day covered by a force_sleep calendar
+	if currentMinute.Equal(startOfDay(nowInScheduleLoc)) {
+		if cal, entry, ok := calendars.Exception(schedule, models.ExceptionForceSleep, nowInScheduleLoc); ok {
+			return scheduleFire{Action: "stop", Trigger: TriggerForceSleep, ForcedBy: exceptionLabel(cal, entry)}
 		}
 	}
 
-	// Parse sleep CRON expression
-	if schedule.SleepCron != "" {
-		sleepCron, err := cronexpr.Parse(schedule.SleepCron)
-		if err == nil {
-			// Get the next fire time after checkTime
-			sleepLast := sleepCron.Next(checkTime)
-			sleepLastInScheduleLoc := sleepLast.In(loc)
-			sleepLastMinute := sleepLastInScheduleLoc.Truncate(time.Minute)
-
-			log.Printf("DEBUG: Schedule '%s' sleep_cron=%s (in %s), now=%s, last fire minute=%s (schedule time)",
-				schedule.Name, schedule.SleepCron, schedule.Timezone,
-				nowInScheduleLoc.Format("15:04:05"),
-				sleepLastMinute.In(loc).Format("15:04:05"))
-
-			// Check if the last fire was in the current minute
-			if sleepLastMinute.Equal(currentMinute) {
-				log.Printf("DEBUG: Schedule '%s' triggered sleep at %s", schedule.Name, schedule.SleepCron)
-				return "stop"
-			}
+	var fire scheduleFire
+	if firesNow(wakeCron) {
+		if cal, entry, ok := calendars.Exception(schedule, models.ExceptionSuppressWake, nowInScheduleLoc); ok {
+			fire.SuppressedBy = exceptionLabel(cal, entry)
 		} else {
-			log.Printf("Warning: Invalid sleep_cron '%s' for schedule %s: %v", schedule.SleepCron, schedule.Name, err)
+			fire.Action = "start"
+			fire.Trigger = TriggerWakeCron
+			return fire
 		}
 	}
 
-	return "none"
+	if firesNow(sleepCron) {
+		fire.Action = "stop"
+		fire.Trigger = TriggerSleepCron
+		return fire
+	}
+
+	fire.Action = "none"
+	return fire
 }
 
-func hasActiveOverride(instance models.Instance) bool {
-	return false
+// loadOverrides expires stale overrides and returns the active ones grouped by instance ID
+func (s *Scheduler) loadOverrides(ctx context.Context, now time.Time) map[string][]models.Override {
+	if s.overrideStore == nil {
+		return nil
+	}
+
+	if expired, err := s.overrideStore.ExpireOverrides(ctx, now); err != nil {
+		log.Printf("Warning: Failed to expire overrides: %v", err)
+	} else if expired > 0 {
+		log.Printf("Expired %d overrides", expired)
+	}
+
+	active, err := s.overrideStore.ListActiveOverrides(ctx)
+	if err != nil {
+		log.Printf("Warning: Failed to list active overrides: %v", err)
+		return nil
+	}
+
+	return GroupOverrides(active)
+}
+
+// GroupOverrides groups overrides by instance ID
+func GroupOverrides(overrides []models.Override) map[string][]models.Override {
+	byInstance := make(map[string][]models.Override)
+	for _, override := range overrides {
+		byInstance[override.InstanceID] = append(byInstance[override.InstanceID], override)
+	}
+	return byInstance
 }
 
-func contains(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
+// overrideBlocks checks whether an active override prevents the action on the instance.
+// keep_alive blocks stops until its until_time; skip_next blocks the next matching
+// action once and is consumed (marked expired) when it does.
+func (s *Scheduler) overrideBlocks(ctx context.Context, instance models.Instance, action string, overrides map[string][]models.Override, now time.Time) bool {
+	instanceOverrides := overrides[instance.ID]
+	for i := range instanceOverrides {
+		override := &instanceOverrides[i]
+		if !override.IsActive(now) {
+			continue
+		}
+
+		switch override.Type {
+		case models.OverrideKeepAlive:
+			if action == "stop" {
+				log.Printf("Skipping stop for %s - keep-alive override %s active", instance.Name, override.ID)
+				return true
+			}
+		case models.OverrideSkipNext:
+			if override.SkipAction == nil || *override.SkipAction != action {
+				continue
+			}
+			log.Printf("Skipping %s for %s - skip-next override %s consumed", action, instance.Name, override.ID)
+			// Mark consumed locally so a second schedule in the same run doesn't reuse it
+			override.Expired = true
+			if s.overrideStore != nil {
+				if err := s.overrideStore.ExpireOverride(ctx, override.ID); err != nil {
+					log.Printf("Warning: Failed to consume override %s: %v", override.ID, err)
+				}
+			}
+			s.recordSkip(ctx, instance, action, override)
 			return true
 		}
 	}
 	return false
 }
 
-func startsWith(s, prefix string) bool {
-	return len(s) >= len(prefix) && s[:len(prefix)] == prefix
-}
+// recordSkip logs a wake_skipped/sleep_skipped event for a consumed skip-next override
+// so the reconciler treats the scheduled action as handled
+func (s *Scheduler) recordSkip(ctx context.Context, instance models.Instance, action string, override *models.Override) {
+	if s.eventStore == nil {
+		return
+	}
 
-func endsWith(s, suffix string) bool {
-	return len(s) >= len(suffix) && s[len(s)-len(suffix):] == suffix
+	eventType := "sleep_skipped"
+	if action == "start" {
+		eventType = "wake_skipped"
+	}
+	metadata, _ := json.Marshal(map[string]any{
+		"override_id": override.ID,
+	})
+
+	event := &models.Event{
+		InstanceID:     instance.ID,
+		EventType:      eventType,
+		TriggeredBy:    "override",
+		PreviousStatus: instance.Status,
+		NewStatus:      instance.Status,
+		Metadata:       metadata,
+	}
+	if err := s.eventStore.CreateEvent(ctx, event); err != nil {
+		log.Printf("Warning: Failed to create skip event for %s: %v", instance.Name, err)
+	}
 }
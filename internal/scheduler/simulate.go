@@ -0,0 +1,256 @@
+package scheduler
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gorhill/cronexpr"
+	"snoozeql/internal/models"
+)
+
+// maxSimulatedFires caps how many schedule fires a simulation returns, so an
+// every-minute CRON over a long horizon doesn't produce an enormous response
+const maxSimulatedFires = 1000
+
+// dstScanStep is the granularity used to find UTC offset changes in a horizon
+const dstScanStep = 15 * time.Minute
+
+// Simulation is what a schedule would do over a future horizon
+type Simulation struct {
+	ScheduleID     string               `json:"schedule_id,omitempty"`
+	ScheduleName   string               `json:"schedule_name"`
+	Timezone       string               `json:"timezone"`
+	From           time.Time            `json:"from"`
+	Until          time.Time            `json:"until"`
+	Actions        []SimulatedAction    `json:"actions"`   // Everything the schedule fires, in order
+	Instances      []InstanceSimulation `json:"instances"` // The same fires as they apply to each matching instance
+	DSTTransitions []DSTTransition      `json:"dst_transitions"`
+	Truncated      bool                 `json:"truncated"` // More than maxSimulatedFires fires in the horizon
+}
+
+// SimulatedAction is one start/stop fired by a schedule
+type SimulatedAction struct {
+	Time          time.Time `json:"time"` // In the schedule's timezone
+	Action        string    `json:"action"`
+	Trigger       string    `json:"trigger"` // "wake_cron", "sleep_cron" or "force_sleep"
+	Skipped       bool      `json:"skipped"`
+	SkipReason    string    `json:"skip_reason,omitempty"`
+	DSTTransition bool      `json:"dst_transition,omitempty"` // The UTC offset changes on this day
+}
+
+// InstanceSimulation lists a schedule's actions for one matching instance
+type InstanceSimulation struct {
+	InstanceID   string            `json:"instance_id"`
+	InstanceName string            `json:"instance_name"`
+	Status       string            `json:"status"` // Current status the simulation starts from
+	Actions      []SimulatedAction `json:"actions"`
+}
+
+// DSTTransition is a change of the schedule timezone's UTC offset
+type DSTTransition struct {
+	Time         time.Time `json:"time"`
+	OffsetBefore string    `json:"offset_before"`
+	OffsetAfter  string    `json:"offset_after"`
+}
+
+// Simulate works out the start/stop actions the schedule fires after from, up to until,
+// using the same per-minute evaluation as the scheduler. Wakes suppressed by exception
+// calendars are listed as skipped. For each instance matching the schedule's selectors,
+// actions are also marked skipped when the instance would already be in the target state
+// or an override (given by instance ID) would block them.
+func Simulate(schedule models.Schedule, instances []models.Instance, calendars Calendars, overrides map[string][]models.Override, from, until time.Time) Simulation {
+	loc := scheduleLocation(schedule)
+	from = from.In(loc)
+	until = until.In(loc)
+
+	sim := Simulation{
+		ScheduleID:     schedule.ID,
+		ScheduleName:   schedule.Name,
+		Timezone:       loc.String(),
+		From:           from,
+		Until:          until,
+		Actions:        []SimulatedAction{},
+		Instances:      []InstanceSimulation{},
+		DSTTransitions: dstTransitions(loc, from, until),
+	}
+
+	var wakeCron, sleepCron *cronexpr.Expression
+	if schedule.WakeCron != "" {
+		wakeCron, _ = cronexpr.Parse(schedule.WakeCron)
+	}
+	if schedule.SleepCron != "" {
+		sleepCron, _ = cronexpr.Parse(schedule.SleepCron)
+	}
+
+	transitionDays := make(map[string]bool)
+	for _, transition := range sim.DSTTransitions {
+		transitionDays[transition.Time.Format("2006-01-02")] = true
+	}
+
+	for _, minute := range candidateMinutes(schedule, wakeCron, sleepCron, from, until) {
+		if len(sim.Actions) >= maxSimulatedFires {
+			sim.Truncated = true
+			break
+		}
+
+		fire := evaluateFire(schedule, loc, wakeCron, sleepCron, minute, calendars)
+		dst := transitionDays[minute.Format("2006-01-02")]
+
+		// A suppressed wake is reported even if the sleep CRON fires in the same minute
+		if fire.SuppressedBy != "" {
+			sim.Actions = append(sim.Actions, SimulatedAction{
+				Time:          minute,
+				Action:        "start",
+				Trigger:       TriggerWakeCron,
+				Skipped:       true,
+				SkipReason:    "exception calendar: " + fire.SuppressedBy,
+				DSTTransition: dst,
+			})
+		}
+		if fire.Action == "none" {
+			continue
+		}
+		sim.Actions = append(sim.Actions, SimulatedAction{
+			Time:          minute,
+			Action:        fire.Action,
+			Trigger:       fire.Trigger,
+			DSTTransition: dst,
+		})
+	}
+
+	for _, instance := range instances {
+		if !MatchesSchedule(instance, schedule) {
+			continue
+		}
+		sim.Instances = append(sim.Instances, simulateInstance(instance, sim.Actions, overrides[instance.ID]))
+	}
+
+	return sim
+}
+
+// simulateInstance applies the schedule's actions to one instance, tracking whether it
+// would be awake or asleep after each action. Skip-next overrides are consumed once.
+func simulateInstance(instance models.Instance, actions []SimulatedAction, overrides []models.Override) InstanceSimulation {
+	result := InstanceSimulation{
+		InstanceID:   instance.ID,
+		InstanceName: instance.Name,
+		Status:       instance.Status,
+		Actions:      make([]SimulatedAction, 0, len(actions)),
+	}
+
+	state := steadyState(instance.Status)
+	consumed := make(map[string]bool)
+	for _, action := range actions {
+		if !action.Skipped {
+			action.SkipReason = instanceSkipReason(action, state, overrides, consumed)
+			action.Skipped = action.SkipReason != ""
+		}
+		if !action.Skipped {
+			state = action.Action
+		}
+		result.Actions = append(result.Actions, action)
+	}
+	return result
+}
+
+// steadyState returns the state an instance status is in or heading to: "start"
+// (awake), "stop" (asleep) or "" if it is neither
+func steadyState(status string) string {
+	switch status {
+	case "available", "running", "starting":
+		return "start"
+	case "stopped", "stopping":
+		return "stop"
+	}
+	return ""
+}
+
+// instanceSkipReason returns why the instance would skip the action, or "" if it
+// would be acted on. Checks follow the scheduler: target state first, then overrides.
+func instanceSkipReason(action SimulatedAction, state string, overrides []models.Override, consumed map[string]bool) string {
+	if state == action.Action {
+		if action.Action == "start" {
+			return "already awake"
+		}
+		return "already asleep"
+	}
+
+	for _, override := range overrides {
+		if consumed[override.ID] || !override.IsActive(action.Time) {
+			continue
+		}
+		switch override.Type {
+		case models.OverrideKeepAlive:
+			if action.Action == "stop" {
+				return "keep-alive override " + override.ID
+			}
+		case models.OverrideSkipNext:
+			if override.SkipAction != nil && *override.SkipAction == action.Action {
+				consumed[override.ID] = true
+				return "skip-next override " + override.ID
+			}
+		}
+	}
+	return ""
+}
+
+// candidateMinutes returns the minutes after from, up to until, in which the schedule
+// may fire: every wake/sleep CRON fire, plus midnights when a force_sleep calendar is used
+func candidateMinutes(schedule models.Schedule, wakeCron, sleepCron *cronexpr.Expression, from, until time.Time) []time.Time {
+	seen := make(map[int64]time.Time)
+	add := func(t time.Time) {
+		minute := t.Truncate(time.Minute)
+		seen[minute.Unix()] = minute
+	}
+
+	for _, expr := range []*cronexpr.Expression{wakeCron, sleepCron} {
+		if expr == nil {
+			continue
+		}
+		count := 0
+		for next := expr.Next(from); !next.IsZero() && !next.After(until) && count <= maxSimulatedFires; next = expr.Next(next) {
+			add(next)
+			count++
+		}
+	}
+
+	for _, exception := range schedule.Exceptions {
+		if exception.Mode != models.ExceptionForceSleep {
+			continue
+		}
+		for day := startOfDay(from).AddDate(0, 0, 1); !day.After(until); day = day.AddDate(0, 0, 1) {
+			add(day)
+		}
+		break
+	}
+
+	minutes := make([]time.Time, 0, len(seen))
+	for _, minute := range seen {
+		minutes = append(minutes, minute)
+	}
+	sort.Slice(minutes, func(i, j int) bool {
+		return minutes[i].Before(minutes[j])
+	})
+	return minutes
+}
+
+// dstTransitions returns the UTC offset changes of loc between from and until
+func dstTransitions(loc *time.Location, from, until time.Time) []DSTTransition {
+	transitions := []DSTTransition{}
+
+	t := from.UTC().Truncate(dstScanStep).In(loc)
+	_, offset := t.Zone()
+	for t.Before(until) {
+		next := t.Add(dstScanStep)
+		if _, nextOffset := next.Zone(); nextOffset != offset {
+			transitions = append(transitions, DSTTransition{
+				Time:         next,
+				OffsetBefore: t.Format("-07:00"),
+				OffsetAfter:  next.Format("-07:00"),
+			})
+			offset = nextOffset
+		}
+		t = next
+	}
+	return transitions
+}
@@ -0,0 +1,219 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"snoozeql/internal/models"
+)
+
+// describeActions renders actions as "{local time} {action}[ skipped: {reason}][ dst]"
+func describeActions(actions []SimulatedAction) []string {
+	var lines []string
+	for _, action := range actions {
+		line := action.Time.Format("01-02 15:04") + " " + action.Action
+		if action.Skipped {
+			line += " skipped: " + action.SkipReason
+		}
+		if action.DSTTransition {
+			line += " dst"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestSimulate(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no timezone data: %v", err)
+	}
+	nights := models.Schedule{
+		Name:      "Nights",
+		Timezone:  "Europe/Berlin",
+		WakeCron:  "0 7 * * *",
+		SleepCron: "0 19 * * *",
+		Selectors: []models.Selector{{}},
+	}
+	withExceptions := func(mode string) models.Schedule {
+		schedule := nights
+		schedule.Exceptions = []models.ScheduleException{{CalendarID: "holidays", Mode: mode}}
+		return schedule
+	}
+	calendars := NewCalendars([]models.ExceptionCalendar{{
+		ID:      "holidays",
+		Name:    "Holidays",
+		Entries: []models.CalendarEntry{{Name: "Easter Monday", StartDate: "2026-04-06"}},
+	}})
+
+	tests := []struct {
+		name     string
+		schedule models.Schedule
+		from     time.Time
+		until    time.Time
+		want     []string
+		wantDST  []string // "{UTC time} {offset before}>{offset after}"
+	}{
+		{
+			name:     "spring forward flags the day",
+			schedule: nights,
+			from:     time.Date(2026, 3, 28, 12, 0, 0, 0, berlin),
+			until:    time.Date(2026, 3, 30, 12, 0, 0, 0, berlin),
+			want:     []string{"03-28 19:00 stop", "03-29 07:00 start dst", "03-29 19:00 stop dst", "03-30 07:00 start"},
+			wantDST:  []string{"2026-03-29T01:00:00Z +01:00>+02:00"},
+		},
+		{
+			name:     "fall back flags the day",
+			schedule: nights,
+			from:     time.Date(2026, 10, 24, 20, 0, 0, 0, berlin),
+			until:    time.Date(2026, 10, 26, 8, 0, 0, 0, berlin),
+			want:     []string{"10-25 07:00 start dst", "10-25 19:00 stop dst", "10-26 07:00 start"},
+			wantDST:  []string{"2026-10-25T01:00:00Z +02:00>+01:00"},
+		},
+		{
+			name:     "no transition",
+			schedule: nights,
+			from:     time.Date(2026, 6, 1, 0, 0, 0, 0, berlin),
+			until:    time.Date(2026, 6, 1, 23, 0, 0, 0, berlin),
+			want:     []string{"06-01 07:00 start", "06-01 19:00 stop"},
+		},
+		{
+			name:     "suppressed wake is listed as skipped",
+			schedule: withExceptions(models.ExceptionSuppressWake),
+			from:     time.Date(2026, 4, 6, 0, 0, 0, 0, berlin),
+			until:    time.Date(2026, 4, 7, 8, 0, 0, 0, berlin),
+			want: []string{
+				"04-06 07:00 start skipped: exception calendar: Easter Monday (Holidays)",
+				"04-06 19:00 stop",
+				"04-07 07:00 start",
+			},
+		},
+		{
+			name:     "force sleep stops at midnight",
+			schedule: withExceptions(models.ExceptionForceSleep),
+			from:     time.Date(2026, 4, 5, 12, 0, 0, 0, berlin),
+			until:    time.Date(2026, 4, 6, 8, 0, 0, 0, berlin),
+			want: []string{
+				"04-05 19:00 stop",
+				"04-06 00:00 stop",
+				"04-06 07:00 start skipped: exception calendar: Easter Monday (Holidays)",
+			},
+		},
+		{
+			name:     "from is exclusive and until inclusive",
+			schedule: nights,
+			from:     time.Date(2026, 6, 1, 7, 0, 0, 0, berlin),
+			until:    time.Date(2026, 6, 1, 19, 0, 0, 0, berlin),
+			want:     []string{"06-01 19:00 stop"},
+		},
+		{
+			name:     "times are in the schedule's timezone",
+			schedule: nights,
+			from:     time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC),
+			until:    time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC),
+			want:     []string{"06-01 07:00 start"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := Simulate(tt.schedule, nil, calendars, nil, tt.from, tt.until)
+
+			if got := describeActions(sim.Actions); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("actions:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			var transitions []string
+			for _, transition := range sim.DSTTransitions {
+				transitions = append(transitions, fmt.Sprintf("%s %s>%s", transition.Time.UTC().Format(time.RFC3339), transition.OffsetBefore, transition.OffsetAfter))
+			}
+			if strings.Join(transitions, ",") != strings.Join(tt.wantDST, ",") {
+				t.Errorf("transitions = %v, want %v", transitions, tt.wantDST)
+			}
+			if sim.Timezone != "Europe/Berlin" || sim.Truncated {
+				t.Errorf("timezone = %s, truncated = %v", sim.Timezone, sim.Truncated)
+			}
+		})
+	}
+}
+
+func TestSimulateInstances(t *testing.T) {
+	schedule := models.Schedule{
+		Timezone:  "UTC",
+		WakeCron:  "0 7 * * *",
+		SleepCron: "0 19 * * *",
+		Selectors: []models.Selector{{Name: &models.Matcher{Pattern: "dev-", Type: models.MatchPrefix}}},
+	}
+	from := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2026, 6, 2, 23, 0, 0, 0, time.UTC)
+	start, stop := "start", "stop"
+	tomorrow := time.Date(2026, 6, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		instance  models.Instance
+		overrides []models.Override
+		want      []string
+	}{
+		{
+			name:     "stopped instance skips nothing",
+			instance: models.Instance{ID: "1", Name: "dev-orders", Status: "stopped"},
+			want:     []string{"06-01 07:00 start", "06-01 19:00 stop", "06-02 07:00 start", "06-02 19:00 stop"},
+		},
+		{
+			name:     "available instance is already awake",
+			instance: models.Instance{ID: "1", Name: "dev-orders", Status: "available"},
+			want:     []string{"06-01 07:00 start skipped: already awake", "06-01 19:00 stop", "06-02 07:00 start", "06-02 19:00 stop"},
+		},
+		{
+			name:      "keep-alive blocks stops until it ends",
+			instance:  models.Instance{ID: "1", Name: "dev-orders", Status: "stopped"},
+			overrides: []models.Override{{ID: "k", Type: models.OverrideKeepAlive, UntilTime: &tomorrow}},
+			want:      []string{"06-01 07:00 start", "06-01 19:00 stop skipped: keep-alive override k", "06-02 07:00 start skipped: already awake", "06-02 19:00 stop"},
+		},
+		{
+			name:     "skip-next is consumed once",
+			instance: models.Instance{ID: "1", Name: "dev-orders", Status: "available"},
+			overrides: []models.Override{
+				{ID: "s", Type: models.OverrideSkipNext, SkipAction: &stop},
+				{ID: "w", Type: models.OverrideSkipNext, SkipAction: &start, Expired: true},
+			},
+			want: []string{
+				"06-01 07:00 start skipped: already awake",
+				"06-01 19:00 stop skipped: skip-next override s",
+				"06-02 07:00 start skipped: already awake",
+				"06-02 19:00 stop",
+			},
+		},
+		{
+			name:     "unknown status acts on everything",
+			instance: models.Instance{ID: "1", Name: "dev-orders", Status: "modifying"},
+			want:     []string{"06-01 07:00 start", "06-01 19:00 stop", "06-02 07:00 start", "06-02 19:00 stop"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instances := []models.Instance{tt.instance, {ID: "2", Name: "prod-orders", Status: "available"}}
+			sim := Simulate(schedule, instances, nil, map[string][]models.Override{tt.instance.ID: tt.overrides}, from, until)
+
+			if len(sim.Instances) != 1 || sim.Instances[0].InstanceID != tt.instance.ID {
+				t.Fatalf("instances = %+v, want only the matching one", sim.Instances)
+			}
+			if got := describeActions(sim.Instances[0].Actions); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("actions:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestSimulateTruncates(t *testing.T) {
+	schedule := models.Schedule{Timezone: "UTC", SleepCron: "* * * * *"}
+	from := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	sim := Simulate(schedule, nil, nil, nil, from, from.Add(24*time.Hour))
+	if !sim.Truncated || len(sim.Actions) != maxSimulatedFires {
+		t.Errorf("got %d actions (truncated %v), want %d and truncated", len(sim.Actions), sim.Truncated, maxSimulatedFires)
+	}
+}
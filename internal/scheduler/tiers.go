@@ -0,0 +1,252 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"snoozeql/internal/models"
+	"snoozeql/internal/selector"
+	"snoozeql/internal/tracker"
+)
+
+const defaultTierTimeout = 15 * time.Minute
+
+// tierPollInterval is how often a tier's instances are checked; a variable so tests can
+// shorten it
+var tierPollInterval = 15 * time.Second
+
+// tierGroup is the set of instances in one tier of an ordered schedule
+type tierGroup struct {
+	Name      string
+	Instances []models.Instance
+}
+
+// isOrdered reports whether the schedule wakes and sleeps its instances in tiers
+func isOrdered(schedule models.Schedule) bool {
+	return schedule.Ordering != nil && len(schedule.Ordering.Tiers) > 0
+}
+
+// buildTiers splits instances into the schedule's tiers, in wake order.
+// Each instance goes into the first tier whose selectors match it; the rest form
+// an implicit last tier. Empty tiers are dropped.
+func buildTiers(ordering *models.ScheduleOrdering, instances []models.Instance) []tierGroup {
+	groups := make([]tierGroup, len(ordering.Tiers)+1)
+	for i, tier := range ordering.Tiers {
+		groups[i].Name = tier.Name
+		if groups[i].Name == "" {
+			groups[i].Name = fmt.Sprintf("tier %d", i+1)
+		}
+	}
+	groups[len(ordering.Tiers)].Name = "unassigned"
+
+	for _, instance := range instances {
+		placed := false
+		for i, tier := range ordering.Tiers {
+			if selector.MatchAny(instance, tier.Selectors) {
+				groups[i].Instances = append(groups[i].Instances, instance)
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			groups[len(ordering.Tiers)].Instances = append(groups[len(ordering.Tiers)].Instances, instance)
+		}
+	}
+
+	var nonEmpty []tierGroup
+	for _, group := range groups {
+		if len(group.Instances) > 0 {
+			nonEmpty = append(nonEmpty, group)
+		}
+	}
+	return nonEmpty
+}
+
+// startOrdered runs an ordered wake/sleep for the schedule in the background.
+// toAct are the instances to start/stop; pending are instances already moving toward
+// the target state, which later tiers still wait for. Only one ordered run per
+// schedule is in flight at a time.
+func (s *Scheduler) startOrdered(ctx context.Context, schedule models.Schedule, action string, toAct, pending []models.Instance, triggeredBy string, metadata map[string]any) {
+	if len(toAct) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	if s.orderedRuns[schedule.ID] {
+		s.mu.Unlock()
+		log.Printf("Schedule '%s': ordered run already in progress, skipping %s", schedule.Name, action)
+		return
+	}
+	s.orderedRuns[schedule.ID] = true
+	s.mu.Unlock()
+
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			delete(s.orderedRuns, schedule.ID)
+			s.mu.Unlock()
+		}()
+		s.runOrdered(ctx, schedule, action, toAct, pending, triggeredBy, metadata)
+	}()
+}
+
+// runOrdered starts/stops instances tier by tier. Waking goes in tier order and sleeping
+// in reverse; after acting on a tier it waits (up to the ordering's timeout) until every
+// instance in it reaches the target state. Stops held back by a backup/maintenance window
+// or the activity guard aren't waited for: they run later from the deferred stops. If a
+// tier fails, the failure policy decides whether the remaining tiers are skipped (abort)
+// or still run (continue).
+func (s *Scheduler) runOrdered(ctx context.Context, schedule models.Schedule, action string, toAct, pending []models.Instance, triggeredBy string, metadata map[string]any) {
+	ordering := schedule.Ordering
+	timeout := defaultTierTimeout
+	if ordering.TimeoutMinutes > 0 {
+		timeout = time.Duration(ordering.TimeoutMinutes) * time.Minute
+	}
+
+	actIDs := make(map[string]bool, len(toAct))
+	for _, instance := range toAct {
+		actIDs[instance.ID] = true
+	}
+
+	tiers := buildTiers(ordering, append(append([]models.Instance{}, toAct...), pending...))
+	if action == "stop" {
+		for i, j := 0, len(tiers)-1; i < j; i, j = i+1, j-1 {
+			tiers[i], tiers[j] = tiers[j], tiers[i]
+		}
+	}
+
+	for i, tier := range tiers {
+		log.Printf("Schedule '%s': %s tier '%s' (%d/%d, %d instances)", schedule.Name, action, tier.Name, i+1, len(tiers), len(tier.Instances))
+
+		tierMetadata := map[string]any{"tier": tier.Name, "tier_index": i + 1}
+		for k, v := range metadata {
+			tierMetadata[k] = v
+		}
+		eventMetadata, _ := json.Marshal(tierMetadata)
+
+		var failures []string
+		var waitFor []models.Instance
+		for _, instance := range tier.Instances {
+			if !actIDs[instance.ID] {
+				waitFor = append(waitFor, instance)
+				continue
+			}
+			held, err := s.requestAction(ctx, instance, action, triggeredBy, eventMetadata, "schedule: "+schedule.Name+", tier: "+tier.Name)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", instance.Name, err))
+				continue
+			}
+			if held {
+				log.Printf("Schedule '%s': not waiting for %s in tier '%s' - its stop is deferred", schedule.Name, instance.Name, tier.Name)
+				continue
+			}
+			waitFor = append(waitFor, instance)
+		}
+
+		if err := s.waitForTier(ctx, waitFor, action, timeout); err != nil {
+			failures = append(failures, err.Error())
+		}
+
+		if len(failures) == 0 {
+			continue
+		}
+
+		log.Printf("Schedule '%s': tier '%s' failed: %s", schedule.Name, tier.Name, strings.Join(failures, "; "))
+		if ordering.FailurePolicy == models.TierFailureContinue {
+			continue
+		}
+
+		// Abort: record the instances we are not going to touch
+		for _, remaining := range tiers[i+1:] {
+			for _, instance := range remaining.Instances {
+				if actIDs[instance.ID] {
+					s.recordTierSkip(ctx, instance, action, triggeredBy, schedule, tier.Name, remaining.Name)
+				}
+			}
+		}
+		log.Printf("Schedule '%s': aborting remaining %d tiers", schedule.Name, len(tiers)-i-1)
+		return
+	}
+}
+
+// waitForTier polls provider status until every instance is in the action's target state
+func (s *Scheduler) waitForTier(ctx context.Context, instances []models.Instance, action string, timeout time.Duration) error {
+	if len(instances) == 0 {
+		return nil
+	}
+
+	deadline := time.Now().Add(timeout)
+	remaining := instances
+	for {
+		var notReady []models.Instance
+		for _, instance := range remaining {
+			status, err := s.registry.GetDatabaseStatus(ctx, instance.ProviderName, instance.ProviderID)
+			if err != nil {
+				log.Printf("Warning: Failed to get status of %s: %v", instance.Name, err)
+				notReady = append(notReady, instance)
+				continue
+			}
+			if strings.EqualFold(status, "failed") {
+				return fmt.Errorf("%s entered status %s", instance.Name, status)
+			}
+			if !tracker.ReachedTarget(status, action) {
+				notReady = append(notReady, instance)
+			}
+		}
+
+		if len(notReady) == 0 {
+			return nil
+		}
+		remaining = notReady
+
+		if time.Now().After(deadline) {
+			names := make([]string, len(remaining))
+			for i, instance := range remaining {
+				names[i] = instance.Name
+			}
+			return fmt.Errorf("timed out after %s waiting for %s", timeout, strings.Join(names, ", "))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(tierPollInterval):
+		}
+	}
+}
+
+// recordTierSkip logs a wake_skipped/sleep_skipped event for an instance left untouched
+// because an earlier tier failed, so the reconciler doesn't converge it out of order
+func (s *Scheduler) recordTierSkip(ctx context.Context, instance models.Instance, action, triggeredBy string, schedule models.Schedule, failedTier, tier string) {
+	if s.eventStore == nil {
+		return
+	}
+
+	eventType := "sleep_skipped"
+	if action == "start" {
+		eventType = "wake_skipped"
+	}
+	metadata, _ := json.Marshal(map[string]any{
+		"schedule_id":   schedule.ID,
+		"schedule_name": schedule.Name,
+		"tier":          tier,
+		"failed_tier":   failedTier,
+		"reason":        "tier_failed",
+	})
+
+	event := &models.Event{
+		InstanceID:     instance.ID,
+		EventType:      eventType,
+		TriggeredBy:    triggeredBy,
+		PreviousStatus: instance.Status,
+		NewStatus:      instance.Status,
+		Metadata:       metadata,
+	}
+	if err := s.eventStore.CreateEvent(ctx, event); err != nil {
+		log.Printf("Warning: Failed to create skip event for %s: %v", instance.Name, err)
+	}
+}
@@ -0,0 +1,271 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"snoozeql/internal/models"
+)
+
+// tierInstance returns a running instance of the test provider
+func tierInstance(name string) models.Instance {
+	return models.Instance{ID: name, Name: name, ProviderName: "aws_1_eu-west-1", ProviderID: name, Status: "available"}
+}
+
+// orderedSchedule wakes the "db" instance before all others
+func orderedSchedule(policy string) models.Schedule {
+	return models.Schedule{
+		ID:   "s",
+		Name: "Ordered",
+		Ordering: &models.ScheduleOrdering{
+			Tiers:          []models.ScheduleTier{{Name: "databases", Selectors: []models.Selector{{Name: &models.Matcher{Type: models.MatchExact, Pattern: "db"}}}}},
+			FailurePolicy:  policy,
+			TimeoutMinutes: 1,
+		},
+	}
+}
+
+// fastTiers shortens the tier polling interval for the test
+func fastTiers(t *testing.T) {
+	saved := tierPollInterval
+	tierPollInterval = time.Millisecond
+	t.Cleanup(func() { tierPollInterval = saved })
+}
+
+func TestOrderedStopSkipsDeferredMembers(t *testing.T) {
+	fastTiers(t)
+	s, recorder, metrics := newTestScheduler()
+	s.activityGuard = &testGuard
+	metrics.add("api", time.Now().Add(-5*time.Minute), 3, 1) // In use
+
+	done := make(chan struct{})
+	go func() {
+		s.runOrdered(context.Background(), orderedSchedule(models.TierFailureAbort), "stop",
+			[]models.Instance{tierInstance("db"), tierInstance("api")}, nil, "schedule", nil)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ordered stop waited for the deferred stop")
+	}
+
+	if got := recorder.recorded(); !slices.Equal(got, []string{"stop db"}) {
+		t.Errorf("actions = %v, want [stop db]", got)
+	}
+	if !s.hasDeferredStop("api") {
+		t.Error("stop of api not deferred")
+	}
+}
+
+func TestOrderedStopSkipsMembersHeldForWindows(t *testing.T) {
+	fastTiers(t)
+	s, recorder, _ := newTestScheduler()
+	api := tierInstance("api")
+	api.BackupWindow = time.Now().UTC().Add(-5*time.Minute).Format("15:04") + "-" + time.Now().UTC().Add(30*time.Minute).Format("15:04")
+
+	done := make(chan struct{})
+	go func() {
+		s.runOrdered(context.Background(), orderedSchedule(models.TierFailureAbort), "stop",
+			[]models.Instance{tierInstance("db"), api}, nil, "schedule", nil)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ordered stop waited for the held stop")
+	}
+
+	if got := recorder.recorded(); !slices.Equal(got, []string{"stop db"}) {
+		t.Errorf("actions = %v, want [stop db]", got)
+	}
+	if !s.hasDeferredStop("api") {
+		t.Error("stop of api not held")
+	}
+}
+
+func TestBuildTiers(t *testing.T) {
+	exact := func(name string) []models.Selector {
+		return []models.Selector{{Name: &models.Matcher{Type: models.MatchExact, Pattern: name}}}
+	}
+	prefix := func(name string) []models.Selector {
+		return []models.Selector{{Name: &models.Matcher{Type: models.MatchPrefix, Pattern: name}}}
+	}
+
+	tests := []struct {
+		name      string
+		tiers     []models.ScheduleTier
+		instances []string
+		want      []string // "{tier}: {instances}" in wake order
+	}{
+		{
+			name:      "instances in tier order, the rest last",
+			tiers:     []models.ScheduleTier{{Name: "databases", Selectors: prefix("db")}, {Name: "apps", Selectors: prefix("api")}},
+			instances: []string{"web", "api-1", "db-2", "db-1"},
+			want:      []string{"databases: db-2, db-1", "apps: api-1", "unassigned: web"},
+		},
+		{
+			name:      "instance matching several tiers goes into the first",
+			tiers:     []models.ScheduleTier{{Name: "primary", Selectors: exact("db-1")}, {Name: "databases", Selectors: prefix("db")}},
+			instances: []string{"db-2", "db-1"},
+			want:      []string{"primary: db-1", "databases: db-2"},
+		},
+		{
+			name:      "empty tiers dropped and unnamed tiers numbered",
+			tiers:     []models.ScheduleTier{{Name: "caches", Selectors: prefix("redis")}, {Selectors: prefix("db")}},
+			instances: []string{"db-1"},
+			want:      []string{"tier 2: db-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var instances []models.Instance
+			for _, name := range tt.instances {
+				instances = append(instances, tierInstance(name))
+			}
+
+			var got []string
+			for _, group := range buildTiers(&models.ScheduleOrdering{Tiers: tt.tiers}, instances) {
+				var names []string
+				for _, instance := range group.Instances {
+					names = append(names, instance.Name)
+				}
+				got = append(got, fmt.Sprintf("%s: %s", group.Name, strings.Join(names, ", ")))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("tiers = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunOrdered(t *testing.T) {
+	errDenied := errors.New("access denied")
+
+	tests := []struct {
+		name        string
+		action      string
+		policy      string
+		failures    map[string]error
+		after       map[string]string
+		wantActions []string
+		wantSkipped []string // "{event type} {instance}"
+	}{
+		{
+			name:        "wake in tier order",
+			action:      "start",
+			policy:      models.TierFailureAbort,
+			wantActions: []string{"start db", "start api"},
+		},
+		{
+			name:        "sleep in reverse tier order",
+			action:      "stop",
+			policy:      models.TierFailureAbort,
+			wantActions: []string{"stop api", "stop db"},
+		},
+		{
+			name:        "abort after a failed start",
+			action:      "start",
+			policy:      models.TierFailureAbort,
+			failures:    map[string]error{"db": errDenied},
+			wantActions: []string{"start db"},
+			wantSkipped: []string{"wake_skipped api"},
+		},
+		{
+			name:        "abort after a failed stop",
+			action:      "stop",
+			policy:      models.TierFailureAbort,
+			failures:    map[string]error{"api": errDenied},
+			wantActions: []string{"stop api"},
+			wantSkipped: []string{"sleep_skipped db"},
+		},
+		{
+			name:        "abort when an instance enters status failed",
+			action:      "start",
+			policy:      models.TierFailureAbort,
+			after:       map[string]string{"db": "failed"},
+			wantActions: []string{"start db"},
+			wantSkipped: []string{"wake_skipped api"},
+		},
+		{
+			name:        "continue after a failed start",
+			action:      "start",
+			policy:      models.TierFailureContinue,
+			failures:    map[string]error{"db": errDenied},
+			wantActions: []string{"start db", "start api"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fastTiers(t)
+			s, recorder, _ := newTestScheduler()
+			recorder.failures = tt.failures
+			recorder.after = tt.after
+			events := &fakeEvents{}
+			s.eventStore = events
+
+			s.runOrdered(context.Background(), orderedSchedule(tt.policy), tt.action,
+				[]models.Instance{tierInstance("api"), tierInstance("db")}, nil, "schedule", nil)
+
+			if got := recorder.recorded(); !slices.Equal(got, tt.wantActions) {
+				t.Errorf("actions = %v, want %v", got, tt.wantActions)
+			}
+			var skipped []string
+			for _, event := range events.matching("", time.Time{}, "wake_skipped", "sleep_skipped") {
+				skipped = append(skipped, event.EventType+" "+event.InstanceID)
+			}
+			if !slices.Equal(skipped, tt.wantSkipped) {
+				t.Errorf("skip events = %v, want %v", skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestWaitForTier(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  map[string]string
+		wantErr string
+	}{
+		{
+			name:   "all instances reach the target",
+			status: map[string]string{"db": "available", "api": "available"},
+		},
+		{
+			name:    "instance enters status failed",
+			status:  map[string]string{"db": "available", "api": "failed"},
+			wantErr: "api entered status failed",
+		},
+		{
+			name:    "timeout names the instances not ready",
+			status:  map[string]string{"db": "available", "api": "starting"},
+			wantErr: "timed out after 20ms waiting for api",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fastTiers(t)
+			s, recorder, _ := newTestScheduler()
+			recorder.status = tt.status
+
+			err := s.waitForTier(context.Background(), []models.Instance{tierInstance("db"), tierInstance("api")}, "start", 20*time.Millisecond)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("waitForTier: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("waitForTier error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package scheduler
+
+import (
+	"sort"
+	"time"
+
+	"snoozeql/internal/models"
+)
+
+// UpcomingHorizon is how far ahead upcoming actions are looked for
+const UpcomingHorizon = 31 * 24 * time.Hour
+
+// UpcomingAction is a start/stop an instance will go through, and the schedule behind it
+type UpcomingAction struct {
+	SimulatedAction
+	ScheduleID   string `json:"schedule_id"`
+	ScheduleName string `json:"schedule_name"`
+}
+
+// Upcoming works out the actions the instance goes through between from and until
+// across every enabled schedule that matches it, in time order. An action is marked
+// skipped when an exception calendar suppresses it, a higher-ranked schedule wants the
+// instance in the opposite state, the instance would already be in the target state,
+// or one of the instance's overrides blocks it.
+func Upcoming(instance models.Instance, schedules []models.Schedule, calendars Calendars, overrides []models.Override, from, until time.Time) []UpcomingAction {
+	var matching []models.Schedule
+	for _, schedule := range schedules {
+		if schedule.Enabled && MatchesSchedule(instance, schedule) {
+			matching = append(matching, schedule)
+		}
+	}
+
+	var actions []UpcomingAction
+	for _, schedule := range matching {
+		sim := Simulate(schedule, nil, calendars, nil, from, until)
+		for _, action := range sim.Actions {
+			actions = append(actions, UpcomingAction{
+				SimulatedAction: action,
+				ScheduleID:      schedule.ID,
+				ScheduleName:    schedule.Name,
+			})
+		}
+	}
+	sort.SliceStable(actions, func(i, j int) bool {
+		return actions[i].Time.Before(actions[j].Time)
+	})
+
+	// Desired-state timelines of the matching schedules, for priority checks
+	timelines := make(map[string][]stateChange)
+	timeline := func(schedule models.Schedule) []stateChange {
+		if _, ok := timelines[schedule.ID]; !ok {
+			timelines[schedule.ID] = stateTimeline(schedule, calendars, from, until)
+		}
+		return timelines[schedule.ID]
+	}
+
+	state := steadyState(instance.Status)
+	consumed := make(map[string]bool)
+	for i := range actions {
+		action := &actions[i]
+		if !action.Skipped {
+			for _, other := range matching {
+				if other.ID == action.ScheduleID || !outranks(other, scheduleByID(matching, action.ScheduleID)) {
+					continue
+				}
+				if desired := stateAt(timeline(other), action.Time); desired != "none" && desired != action.Action {
+					action.Skipped = true
+					action.SkipReason = "higher-priority schedule: " + other.Name
+					break
+				}
+			}
+		}
+		if !action.Skipped {
+			action.SkipReason = instanceSkipReason(action.SimulatedAction, state, overrides, consumed)
+			action.Skipped = action.SkipReason != ""
+		}
+		if !action.Skipped {
+			state = action.Action
+		}
+	}
+	return actions
+}
+
+// scheduleByID returns the schedule with the given ID from the list
+func scheduleByID(schedules []models.Schedule, id string) models.Schedule {
+	for _, schedule := range schedules {
+		if schedule.ID == id {
+			return schedule
+		}
+	}
+	return models.Schedule{}
+}
+
+// NextActions returns the first limit actions, leaving out skipped ones unless includeSkipped
+func NextActions(actions []UpcomingAction, limit int, includeSkipped bool) []UpcomingAction {
+	next := []UpcomingAction{}
+	for _, action := range actions {
+		if len(next) >= limit {
+			break
+		}
+		if action.Skipped && !includeSkipped {
+			continue
+		}
+		next = append(next, action)
+	}
+	return next
+}
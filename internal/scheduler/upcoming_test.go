@@ -0,0 +1,114 @@
+package scheduler
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"snoozeql/internal/models"
+)
+
+// describeUpcoming renders actions as "{hh:mm} {action} {schedule}[ skipped: {reason}]"
+func describeUpcoming(actions []UpcomingAction) []string {
+	var lines []string
+	for _, action := range actions {
+		line := action.Time.Format("15:04") + " " + action.Action + " " + action.ScheduleName
+		if action.Skipped {
+			line += " skipped: " + action.SkipReason
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestUpcoming(t *testing.T) {
+	from := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC) // Monday
+	until := from.Add(24 * time.Hour)
+	nights := models.Schedule{ID: "a", Name: "Nights", Enabled: true, Timezone: "UTC", WakeCron: "0 7 * * *", SleepCron: "0 19 * * *", Selectors: []models.Selector{{}}}
+	late := models.Schedule{ID: "b", Name: "Late", Enabled: true, Timezone: "UTC", WakeCron: "0 8 * * *", SleepCron: "0 22 * * *", Selectors: []models.Selector{{}}, Priority: 10}
+	stop, start := "stop", "start"
+
+	tests := []struct {
+		name      string
+		status    string
+		schedules []models.Schedule
+		overrides []models.Override
+		want      []string
+	}{
+		{
+			name:      "single schedule",
+			status:    "stopped",
+			schedules: []models.Schedule{nights},
+			want:      []string{"07:00 start Nights", "19:00 stop Nights"},
+		},
+		{
+			name:      "already in the target state",
+			status:    "available",
+			schedules: []models.Schedule{nights},
+			want:      []string{"07:00 start Nights skipped: already awake", "19:00 stop Nights"},
+		},
+		{
+			name:      "higher priority wins",
+			status:    "stopped",
+			schedules: []models.Schedule{nights, late},
+			want: []string{
+				"07:00 start Nights skipped: higher-priority schedule: Late",
+				"08:00 start Late",
+				"19:00 stop Nights skipped: higher-priority schedule: Late",
+				"22:00 stop Late",
+			},
+		},
+		{
+			name:      "disabled and non-matching schedules are ignored",
+			status:    "stopped",
+			schedules: []models.Schedule{nights, func() models.Schedule { s := late; s.Enabled = false; return s }(), {ID: "c", Name: "None", Enabled: true, Timezone: "UTC", WakeCron: "0 6 * * *", SleepCron: "0 20 * * *"}},
+			want:      []string{"07:00 start Nights", "19:00 stop Nights"},
+		},
+		{
+			name:      "keep-alive blocks stops",
+			status:    "stopped",
+			schedules: []models.Schedule{nights},
+			overrides: []models.Override{{ID: "k", Type: models.OverrideKeepAlive}},
+			want:      []string{"07:00 start Nights", "19:00 stop Nights skipped: keep-alive override k"},
+		},
+		{
+			name:      "skip-next is used once",
+			status:    "stopped",
+			schedules: []models.Schedule{nights, func() models.Schedule { s := late; s.Priority = -1; return s }()},
+			overrides: []models.Override{{ID: "n", Type: models.OverrideSkipNext, SkipAction: &stop}, {ID: "w", Type: models.OverrideSkipNext, SkipAction: &start}},
+			want: []string{
+				"07:00 start Nights skipped: skip-next override w",
+				"08:00 start Late",
+				"19:00 stop Nights skipped: skip-next override n",
+				"22:00 stop Late", // Nights wants it asleep too, and the skip-next is used up
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := models.Instance{ID: "i-1", Name: "orders", Status: tt.status}
+			got := describeUpcoming(Upcoming(instance, tt.schedules, nil, tt.overrides, from, until))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("upcoming =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextActions(t *testing.T) {
+	actions := []UpcomingAction{
+		{SimulatedAction: SimulatedAction{Action: "start", Skipped: true}},
+		{SimulatedAction: SimulatedAction{Action: "stop"}},
+		{SimulatedAction: SimulatedAction{Action: "start"}},
+	}
+	if got := NextActions(actions, 1, false); len(got) != 1 || got[0].Action != "stop" {
+		t.Errorf("NextActions without skipped = %+v", got)
+	}
+	if got := NextActions(actions, 2, true); len(got) != 2 || !got[0].Skipped {
+		t.Errorf("NextActions with skipped = %+v", got)
+	}
+	if got := NextActions(nil, 5, false); got == nil || len(got) != 0 {
+		t.Errorf("NextActions(nil) = %#v, want an empty list", got)
+	}
+}
@@ -0,0 +1,61 @@
+package selector
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lruCache is a fixed-size cache that evicts the least recently used entry. Patterns
+// and expressions come from API input (including the filter preview), so the caches
+// must stay bounded however many distinct ones are sent.
+type lruCache[V any] struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // Most recently used first; values are *lruEntry[V]
+	entries map[string]*list.Element
+}
+
+type lruEntry[V any] struct {
+	key   string
+	value V
+}
+
+func newLRUCache[V any](size int) *lruCache[V] {
+	return &lruCache[V]{size: size, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// get returns the cached value of key and marks it as recently used
+func (c *lruCache[V]) get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		return element.Value.(*lruEntry[V]).value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// add caches the value of key, evicting the least recently used entry when full
+func (c *lruCache[V]) add(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*lruEntry[V]).value = value
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry[V]{key: key, value: value})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry[V]).key)
+	}
+}
+
+// len returns the number of cached entries
+func (c *lruCache[V]) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
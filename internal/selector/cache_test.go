@@ -0,0 +1,50 @@
+package selector
+
+import (
+	"fmt"
+	"testing"
+
+	"snoozeql/internal/models"
+)
+
+func TestLRUCache(t *testing.T) {
+	c := newLRUCache[int](2)
+	c.add("a", 1)
+	c.add("b", 2)
+	c.get("a") // "b" is now the least recently used
+	c.add("c", 3)
+
+	if _, ok := c.get("b"); ok {
+		t.Error("least recently used entry not evicted")
+	}
+	if v, ok := c.get("a"); !ok || v != 1 {
+		t.Errorf("get(a) = %v, %v; want 1, true", v, ok)
+	}
+	c.add("c", 4)
+	if v, _ := c.get("c"); v != 4 || c.len() != 2 {
+		t.Errorf("get(c) = %v with %d entries, want 4 with 2", v, c.len())
+	}
+}
+
+func TestRegexCacheIsBounded(t *testing.T) {
+	for i := 0; i < regexCache.size+100; i++ {
+		sel := models.Selector{Name: matcher(models.MatchRegex, fmt.Sprintf("^orders-%d$", i))}
+		MatchSelector(testInstance, sel)
+	}
+	if n := regexCache.len(); n != regexCache.size {
+		t.Errorf("regex cache holds %d patterns, want %d", n, regexCache.size)
+	}
+}
+
+func TestValidationDoesNotCacheRegexes(t *testing.T) {
+	pattern := "^validated-only$"
+	if Validate([]models.Selector{{Name: matcher(models.MatchRegex, pattern)}}) != "" {
+		t.Fatal("valid selector rejected")
+	}
+	if _, err := ParseExpression(`name ~ "` + pattern + `"`); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := regexCache.get(pattern); ok {
+		t.Error("validated pattern was cached")
+	}
+}
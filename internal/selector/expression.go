@@ -0,0 +1,575 @@
+package selector
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"snoozeql/internal/models"
+)
+
+// Selector expressions are a compact text alternative to selector JSON, e.g.
+//
+//	tag.env in ("dev", "qa") && engine == "postgres" && !name ~ "^keep-"
+//
+// Grammar (! binds tighter than &&, which binds tighter than ||):
+//
+//	expr       = and { "||" and }
+//	and        = unary { "&&" unary }
+//	unary      = "!" unary | "(" expr ")" | comparison
+//	comparison = field ( "==" | "!=" | "~" | "!~" | "contains" | "startswith" | "endswith" ) string
+//	           | field [ "not" ] "in" "(" string { "," string } ")"
+//	           | field [ "not" ] "exists"
+//	           | "hourly_cost_cents" ( "==" | "!=" | "<" | "<=" | ">" | ">=" ) number
+//	field      = "name" | "provider" | "region" | "engine" | "cloud_account" | "tag." key | "tag." string
+//
+// "~" is a regex match. Comparisons compile to the same matchers as selector JSON.
+
+// Expression is a parsed selector expression
+type Expression struct {
+	root node
+}
+
+// Match reports whether the expression selects the instance. Like selectors, it never
+// selects cluster members.
+func (e *Expression) Match(instance models.Instance) bool {
+	return instance.ClusterID == "" && e.root.match(instance)
+}
+
+// ParseError is a syntax error in a selector expression, with its 1-based position
+type ParseError struct {
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+// expressionCache holds the parsed expressions of schedules, keyed by source
+var expressionCache = newLRUCache[*Expression](256)
+
+// Limits on expressions, which come from API input: recursion in the parser and in
+// matching must stay far from the goroutine stack limit
+const (
+	maxExpressionLength = 4096 // Bytes of source
+	maxExpressionDepth  = 64   // Nested "!" and parentheses
+)
+
+// ParseExpression parses a selector expression. Errors are *ParseError.
+func ParseExpression(src string) (*Expression, error) {
+	if len(src) > maxExpressionLength {
+		// Point at the first character past the limit
+		over := lexer{src: src, line: 1, col: 1}
+		for over.pos < maxExpressionLength {
+			over.read()
+		}
+		return nil, &ParseError{Line: over.line, Column: over.col, Msg: fmt.Sprintf("expression is longer than %d bytes", maxExpressionLength)}
+	}
+
+	p := &parser{lex: lexer{src: src, line: 1, col: 1}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokEOF {
+		return nil, p.errorf("expression is empty")
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+
+	return &Expression{root: root}, nil
+}
+
+// cachedExpression parses a stored expression, reusing earlier parses
+func cachedExpression(src string) (*Expression, error) {
+	if cached, ok := expressionCache.get(src); ok {
+		return cached, nil
+	}
+	expr, err := ParseExpression(src)
+	if err != nil {
+		return nil, err
+	}
+	expressionCache.add(src, expr)
+	return expr, nil
+}
+
+// node is an evaluable expression node
+type node interface {
+	match(instance models.Instance) bool
+}
+
+type andNode struct{ left, right node }
+type orNode struct{ left, right node }
+type notNode struct{ operand node }
+
+// fieldNode compares a string field or tag with a Matcher
+type fieldNode struct {
+	field   string // "name", "provider", "region", "engine", "cloud_account" or "tag"
+	tag     string
+	matcher *models.Matcher
+}
+
+// costNode compares the hourly cost with a NumberMatcher
+type costNode struct {
+	comparison models.NumberMatcher
+}
+
+func (n andNode) match(instance models.Instance) bool {
+	return n.left.match(instance) && n.right.match(instance)
+}
+
+func (n orNode) match(instance models.Instance) bool {
+	return n.left.match(instance) || n.right.match(instance)
+}
+
+func (n notNode) match(instance models.Instance) bool {
+	return !n.operand.match(instance)
+}
+
+func (n fieldNode) match(instance models.Instance) bool {
+	switch n.field {
+	case "name":
+		return matchField(instance.Name, n.matcher)
+	case "provider":
+		return matchField(strings.ToLower(providerType(instance)), n.matcher)
+	case "region":
+		return matchField(instance.Region, n.matcher)
+	case "engine":
+		return matchField(instance.Engine, n.matcher)
+	case "cloud_account":
+		return matchField(instance.CloudAccountID, n.matcher) ||
+			(instance.AccountID != "" && matchField(instance.AccountID, n.matcher))
+	case "tag":
+		value, present := instance.Tags[n.tag]
+		return matchString(value, present, n.matcher)
+	default:
+		return false
+	}
+}
+
+func (n costNode) match(instance models.Instance) bool {
+	return matchNumber(instance.HourlyCostCents, n.comparison)
+}
+
+// Parser
+
+type parser struct {
+	lex   lexer
+	tok   token
+	depth int // Current nesting of "!" and parentheses
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return &ParseError{Line: p.tok.line, Column: p.tok.col, Msg: fmt.Sprintf(format, args...)}
+}
+
+// expect consumes a token of the given kind
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	tok := p.tok
+	if tok.kind != kind {
+		return tok, p.errorf("expected %s, found %s", what, tok)
+	}
+	return tok, p.advance()
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOr {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokAnd {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.tok.kind == tokNot || p.tok.kind == tokLParen {
+		if p.depth == maxExpressionDepth {
+			return nil, p.errorf("expression is nested more than %d levels deep", maxExpressionDepth)
+		}
+		p.depth++
+		defer func() { p.depth-- }()
+	}
+
+	switch p.tok.kind {
+	case tokNot:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	case tokLParen:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRParen, `")"`); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	case tokIdent:
+		return p.parseComparison()
+	default:
+		return nil, p.errorf("expected a field, \"!\" or \"(\", found %s", p.tok)
+	}
+}
+
+func (p *parser) parseComparison() (node, error) {
+	fieldTok := p.tok
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if fieldTok.text == "hourly_cost_cents" {
+		return p.parseCost(fieldTok)
+	}
+
+	field := fieldNode{field: fieldTok.text}
+	switch {
+	case fieldTok.text == "name", fieldTok.text == "provider", fieldTok.text == "region",
+		fieldTok.text == "engine", fieldTok.text == "cloud_account":
+	case fieldTok.text == "tag.":
+		// tag."key with spaces"
+		keyTok, err := p.expect(tokString, "a tag key")
+		if err != nil {
+			return nil, err
+		}
+		field.field, field.tag = "tag", keyTok.text
+	case strings.HasPrefix(fieldTok.text, "tag."):
+		field.field, field.tag = "tag", strings.TrimPrefix(fieldTok.text, "tag.")
+	default:
+		return nil, &ParseError{Line: fieldTok.line, Column: fieldTok.col,
+			Msg: fmt.Sprintf("unknown field %q (use name, provider, region, engine, cloud_account, hourly_cost_cents or tag.<key>)", fieldTok.text)}
+	}
+
+	opTok := p.tok
+	negate := false
+	if opTok.kind == tokIdent && opTok.text == "not" {
+		negate = true
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokIdent || (p.tok.text != "in" && p.tok.text != "exists") {
+			return nil, p.errorf(`expected "in" or "exists" after "not", found %s`, p.tok)
+		}
+		opTok = p.tok
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case opTok.kind == tokIdent && opTok.text == "exists":
+		field.matcher = &models.Matcher{Type: models.MatchExists, Not: negate}
+		return field, nil
+
+	case opTok.kind == tokIdent && opTok.text == "in":
+		values, err := p.parseSet()
+		if err != nil {
+			return nil, err
+		}
+		field.matcher = &models.Matcher{Type: models.MatchIn, Values: values, Not: negate}
+		return field, nil
+	}
+
+	var matcher models.Matcher
+	switch {
+	case opTok.kind == tokEq:
+		matcher.Type = models.MatchExact
+	case opTok.kind == tokNe:
+		matcher.Type, matcher.Not = models.MatchExact, true
+	case opTok.kind == tokMatch:
+		matcher.Type = models.MatchRegex
+	case opTok.kind == tokNotMatch:
+		matcher.Type, matcher.Not = models.MatchRegex, true
+	case opTok.kind == tokIdent && opTok.text == "contains":
+		matcher.Type = models.MatchContains
+	case opTok.kind == tokIdent && opTok.text == "startswith":
+		matcher.Type = models.MatchPrefix
+	case opTok.kind == tokIdent && opTok.text == "endswith":
+		matcher.Type = models.MatchSuffix
+	default:
+		return nil, &ParseError{Line: opTok.line, Column: opTok.col,
+			Msg: fmt.Sprintf("expected an operator (==, !=, ~, !~, contains, startswith, endswith, in, exists) after %s, found %s", fieldTok.text, opTok)}
+	}
+
+	valueTok, err := p.expect(tokString, "a quoted string")
+	if err != nil {
+		return nil, err
+	}
+	matcher.Pattern = valueTok.text
+	if matcher.Type == models.MatchRegex {
+		// Compiled without caching: parsing API input shouldn't fill the regex cache
+		if _, err := regexp.Compile(matcher.Pattern); err != nil {
+			return nil, &ParseError{Line: valueTok.line, Column: valueTok.col, Msg: "invalid regex: " + err.Error()}
+		}
+	}
+	field.matcher = &matcher
+	return field, nil
+}
+
+// parseSet parses ( "a", "b", ... )
+func (p *parser) parseSet() ([]string, error) {
+	if _, err := p.expect(tokLParen, `"(" after "in"`); err != nil {
+		return nil, err
+	}
+	var values []string
+	for {
+		valueTok, err := p.expect(tokString, "a quoted string")
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, valueTok.text)
+		if p.tok.kind != tokComma {
+			break
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.expect(tokRParen, `"," or ")"`); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// parseCost parses the operator and number of an hourly_cost_cents comparison
+func (p *parser) parseCost(fieldTok token) (node, error) {
+	ops := map[tokenKind]string{tokEq: "eq", tokNe: "ne", tokLt: "lt", tokLe: "lte", tokGt: "gt", tokGe: "gte"}
+	op, ok := ops[p.tok.kind]
+	if !ok {
+		return nil, p.errorf("expected ==, !=, <, <=, > or >= after %s, found %s", fieldTok.text, p.tok)
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	numberTok, err := p.expect(tokNumber, "a number")
+	if err != nil {
+		return nil, err
+	}
+	value, err := strconv.Atoi(numberTok.text)
+	if err != nil {
+		return nil, &ParseError{Line: numberTok.line, Column: numberTok.col, Msg: fmt.Sprintf("invalid number %q", numberTok.text)}
+	}
+	return costNode{models.NumberMatcher{Op: op, Value: value}}, nil
+}
+
+// Lexer
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokAnd      // &&
+	tokOr       // ||
+	tokNot      // !
+	tokLParen   // (
+	tokRParen   // )
+	tokComma    // ,
+	tokEq       // ==
+	tokNe       // !=
+	tokMatch    // ~
+	tokNotMatch // !~
+	tokLt       // <
+	tokLe       // <=
+	tokGt       // >
+	tokGe       // >=
+)
+
+type token struct {
+	kind      tokenKind
+	text      string // Identifier, unquoted string or number
+	line, col int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokIdent:
+		return strconv.Quote(t.text)
+	case tokString:
+		return "string " + strconv.Quote(t.text)
+	case tokNumber:
+		return "number " + t.text
+	default:
+		return `"` + t.text + `"`
+	}
+}
+
+type lexer struct {
+	src       string
+	pos       int
+	line, col int
+}
+
+// peek returns the rune at the current position (utf8.RuneError at the end)
+func (l *lexer) peek() rune {
+	if l.pos >= len(l.src) {
+		return utf8.RuneError
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	return r
+}
+
+// read consumes one rune, tracking line and column
+func (l *lexer) read() rune {
+	r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+	l.pos += size
+	if r == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+	return r
+}
+
+func isIdentStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_'
+}
+
+// isIdentPart allows the characters found in tag keys, e.g. tag.aws:cloudformation:stack-name
+func isIdentPart(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_.-:/@", r)
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) && unicode.IsSpace(l.peek()) {
+		l.read()
+	}
+	tok := token{line: l.line, col: l.col}
+	if l.pos >= len(l.src) {
+		tok.kind = tokEOF
+		return tok, nil
+	}
+
+	start := l.pos
+	r := l.read()
+	switch {
+	case isIdentStart(r):
+		for l.pos < len(l.src) && isIdentPart(l.peek()) {
+			l.read()
+		}
+		tok.kind, tok.text = tokIdent, l.src[start:l.pos]
+		return tok, nil
+
+	case unicode.IsDigit(r):
+		for l.pos < len(l.src) && unicode.IsDigit(l.peek()) {
+			l.read()
+		}
+		tok.kind, tok.text = tokNumber, l.src[start:l.pos]
+		return tok, nil
+
+	case r == '"':
+		var value strings.Builder
+		for {
+			if l.pos >= len(l.src) || l.peek() == '\n' {
+				return tok, &ParseError{Line: tok.line, Column: tok.col, Msg: "unterminated string"}
+			}
+			c := l.read()
+			if c == '"' {
+				break
+			}
+			if c == '\\' {
+				escLine, escCol := l.line, l.col-1
+				if l.pos >= len(l.src) {
+					return tok, &ParseError{Line: tok.line, Column: tok.col, Msg: "unterminated string"}
+				}
+				switch esc := l.read(); esc {
+				case '"', '\\':
+					c = esc
+				case 'n':
+					c = '\n'
+				case 't':
+					c = '\t'
+				default:
+					return tok, &ParseError{Line: escLine, Column: escCol, Msg: fmt.Sprintf("unknown escape \\%c", esc)}
+				}
+			}
+			value.WriteRune(c)
+		}
+		tok.kind, tok.text = tokString, value.String()
+		return tok, nil
+	}
+
+	// Operators, longest first
+	two := ""
+	if l.pos < len(l.src) {
+		two = string(r) + string(l.peek())
+	}
+	twoChar := map[string]tokenKind{"&&": tokAnd, "||": tokOr, "==": tokEq, "!=": tokNe, "!~": tokNotMatch, "<=": tokLe, ">=": tokGe}
+	if kind, ok := twoChar[two]; ok {
+		l.read()
+		tok.kind, tok.text = kind, two
+		return tok, nil
+	}
+	oneChar := map[rune]tokenKind{'!': tokNot, '(': tokLParen, ')': tokRParen, ',': tokComma, '~': tokMatch, '<': tokLt, '>': tokGt}
+	if kind, ok := oneChar[r]; ok {
+		tok.kind, tok.text = kind, string(r)
+		return tok, nil
+	}
+
+	switch r {
+	case '&', '|':
+		return tok, &ParseError{Line: tok.line, Column: tok.col, Msg: fmt.Sprintf("unexpected %q (did you mean %q?)", r, strings.Repeat(string(r), 2))}
+	case '=':
+		return tok, &ParseError{Line: tok.line, Column: tok.col, Msg: `unexpected "=" (did you mean "=="?)`}
+	case '\'':
+		return tok, &ParseError{Line: tok.line, Column: tok.col, Msg: "strings use double quotes"}
+	}
+	return tok, &ParseError{Line: tok.line, Column: tok.col, Msg: fmt.Sprintf("unexpected character %q", r)}
+}
@@ -0,0 +1,105 @@
+package selector
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExpressionMatch(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{`tag.env in ("dev", "qa") && engine == "postgres" && !name ~ "^keep-"`, true},
+		{`engine == "mysql" || tag.team == "orders"`, true},
+		{`engine == "mysql" || tag.team == "orders" && tag.env == "prod"`, false}, // && binds tighter
+		{`(engine == "mysql" || tag.team == "orders") && tag.env == "dev"`, true},
+		{`!engine == "mysql" && !tag.env == "prod"`, true}, // ! binds tighter than &&
+		{`!(engine == "postgres" && tag.env == "dev")`, false},
+		{`name != "orders-dev"`, false},
+		{`name !~ "prod$"`, true},
+		{`name startswith "orders" && name endswith "dev" && name contains "rs-d"`, true},
+		{`provider == "aws" && region == "eu-west-1" && cloud_account == "123456789012"`, true},
+		{`tag.env exists && tag.owner not exists`, true},
+		{`tag.env not in ("prod", "staging")`, true},
+		{`tag."team" == "orders"`, true},
+		{`tag.aws:cloudformation:stack-name exists`, false},
+		{`hourly_cost_cents >= 40 && hourly_cost_cents < 41 && hourly_cost_cents != 0`, true},
+		{`hourly_cost_cents > 40 || hourly_cost_cents <= 39 || hourly_cost_cents == 41`, false},
+		{`name == "a\"b\\c"`, false},
+		{"engine == \"postgres\"\n\t&& tag.env == \"dev\"\n", true},
+	}
+	for _, tt := range tests {
+		expr, err := ParseExpression(tt.src)
+		if err != nil {
+			t.Errorf("ParseExpression(%q): %v", tt.src, err)
+			continue
+		}
+		if got := expr.Match(testInstance); got != tt.want {
+			t.Errorf("%s: Match = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestExpressionNeverMatchesClusterMembers(t *testing.T) {
+	expr, err := ParseExpression(`engine == "postgres"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	member := testInstance
+	member.ClusterID = "orders"
+	if expr.Match(member) {
+		t.Error("cluster member matched")
+	}
+}
+
+func TestParseExpressionErrors(t *testing.T) {
+	tests := []struct {
+		src    string
+		line   int
+		column int
+		msg    string
+	}{
+		{"", 1, 1, "expression is empty"},
+		{"   \n  ", 2, 3, "expression is empty"},
+		{`engine = "postgres"`, 1, 8, `unexpected "=" (did you mean "=="?)`},
+		{`engine == postgres`, 1, 11, `expected a quoted string, found "postgres"`},
+		{`color == "red"`, 1, 1, `unknown field "color"`},
+		{`name == "a" &&`, 1, 15, `expected a field, "!" or "(", found end of expression`},
+		{"engine == \"postgres\"\n  && name ~ \"(\"", 2, 13, "invalid regex"},
+		{`name == "abc`, 1, 9, "unterminated string"},
+		{"name == \"abc\n\"", 1, 9, "unterminated string"},
+		{`name == "a\q"`, 1, 11, `unknown escape \q`},
+		{`tag.env in "dev"`, 1, 12, `expected "(" after "in"`},
+		{`tag.env in ("dev" "qa")`, 1, 19, `expected "," or ")"`},
+		{`tag.env in ()`, 1, 13, "expected a quoted string"},
+		{`hourly_cost_cents ~ 5`, 1, 19, "expected ==, !=, <, <=, > or >= after hourly_cost_cents"},
+		{`hourly_cost_cents > "5"`, 1, 21, "expected a number"},
+		{`hourly_cost_cents > 99999999999999999999`, 1, 21, "invalid number"},
+		{`name not == "x"`, 1, 10, `expected "in" or "exists" after "not"`},
+		{`name is "x"`, 1, 6, "expected an operator"},
+		{`(name == "a"`, 1, 13, `expected ")", found end of expression`},
+		{`name == 'a'`, 1, 9, "strings use double quotes"},
+		{`name == "a" & engine == "b"`, 1, 13, `unexpected '&' (did you mean "&&"?)`},
+		{`name == "a" engine == "b"`, 1, 13, `unexpected "engine"`},
+		{`name == "a" #`, 1, 13, "unexpected character '#'"},
+		{`tag.env == "dév" && x == "1"`, 1, 21, `unknown field "x"`},
+		{strings.Repeat("!", 64) + `x == "1"`, 1, 65, `unknown field "x"`},
+		{strings.Repeat("!", 65) + `name == "a"`, 1, 65, "nested more than 64 levels deep"},
+		{strings.Repeat("(", 5000) + `name == "a"`, 1, 4097, "longer than 4096 bytes"},
+		{"name == \"a\" &&\n" + strings.Repeat("(", 70) + `name == "a"`, 2, 65, "nested more than 64 levels deep"},
+		{`name == "a" || ` + strings.Repeat(`name == "a" || `, 300) + `name == "a"`, 1, 4097, "longer than 4096 bytes"},
+	}
+	for _, tt := range tests {
+		_, err := ParseExpression(tt.src)
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("%q: err = %v, want a *ParseError", tt.src, err)
+			continue
+		}
+		if parseErr.Line != tt.line || parseErr.Column != tt.column || !strings.Contains(parseErr.Msg, tt.msg) {
+			t.Errorf("%q: got %v, want line %d, column %d: %s", tt.src, err, tt.line, tt.column, tt.msg)
+		}
+	}
+}
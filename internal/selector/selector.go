@@ -0,0 +1,227 @@
+// Package selector decides which instances a list of selectors selects. It is the one
+// matching implementation behind schedules, tiers, idle policies, scheduled actions and
+// the filter preview.
+
+package selector
+
+import (
+	"regexp"
+	"strings"
+
+	"snoozeql/internal/models"
+)
+
+// regexCache holds compiled regex patterns, since the same selectors are matched
+// against every instance on every scheduler run
+var regexCache = newLRUCache[*regexp.Regexp](1024)
+
+// Match reports whether the selectors select the instance: any of them (operator "or",
+// the default) or all of them ("and"), and none of the exclusions. An empty selector
+// list selects nothing, so a schedule never applies to the whole fleet by accident.
+// Cluster members are never selected: their cluster is.
+func Match(instance models.Instance, selectors []models.Selector, operator string, exclusions []models.Selector) bool {
+	if len(selectors) == 0 || instance.ClusterID != "" {
+		return false
+	}
+
+	var matched bool
+	if operator == models.OperatorAnd {
+		matched = true
+		for _, sel := range selectors {
+			if !MatchSelector(instance, sel) {
+				matched = false
+				break
+			}
+		}
+	} else {
+		for _, sel := range selectors {
+			if MatchSelector(instance, sel) {
+				matched = true
+				break
+			}
+		}
+	}
+	return matched && !Excluded(instance, exclusions)
+}
+
+// Operator returns the operator selectors combine with: the given one, or "or" if it
+// is empty. Stored schedules, previews and matching all use this default.
+func Operator(operator string) string {
+	if operator == "" {
+		return models.OperatorOr
+	}
+	return operator
+}
+
+// MatchAny reports whether any of the selectors selects the instance
+func MatchAny(instance models.Instance, selectors []models.Selector) bool {
+	return Match(instance, selectors, models.OperatorOr, nil)
+}
+
+// MatchSchedule reports whether the schedule selects the instance: its expression if it
+// has one (an invalid expression selects nothing), otherwise its selectors and operator,
+// minus its exclusions
+func MatchSchedule(instance models.Instance, schedule models.Schedule) bool {
+	if schedule.Expression != "" {
+		expr, err := cachedExpression(schedule.Expression)
+		if err != nil || !expr.Match(instance) {
+			return false
+		}
+		return !Excluded(instance, schedule.Exclusions)
+	}
+	return Match(instance, schedule.Selectors, schedule.Operator, schedule.Exclusions)
+}
+
+// Excluded reports whether any of the exclusions matches the instance
+func Excluded(instance models.Instance, exclusions []models.Selector) bool {
+	for _, exclusion := range exclusions {
+		if MatchSelector(instance, exclusion) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchSelector reports whether a single selector matches the instance.
+// All set criteria must match; Not inverts the result.
+func MatchSelector(instance models.Instance, sel models.Selector) bool {
+	return matchCriteria(instance, sel) != sel.Not
+}
+
+func matchCriteria(instance models.Instance, sel models.Selector) bool {
+	if sel.Name != nil && !matchField(instance.Name, sel.Name) {
+		return false
+	}
+
+	if sel.Provider != nil && !strings.EqualFold(providerType(instance), *sel.Provider) {
+		return false
+	}
+
+	if sel.Region != nil && !matchField(instance.Region, sel.Region) {
+		return false
+	}
+
+	if sel.Engine != nil && !matchField(instance.Engine, sel.Engine) {
+		return false
+	}
+
+	if sel.CloudAccount != nil {
+		// The provider's own account ID is only known for freshly discovered instances
+		if !matchField(instance.CloudAccountID, sel.CloudAccount) &&
+			(instance.AccountID == "" || !matchField(instance.AccountID, sel.CloudAccount)) {
+			return false
+		}
+	}
+
+	for key, matcher := range sel.Tags {
+		value, present := instance.Tags[key]
+		if !matchString(value, present, matcher) {
+			return false
+		}
+	}
+
+	for _, comparison := range sel.HourlyCostCents {
+		if !matchNumber(instance.HourlyCostCents, comparison) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// providerType returns the instance's cloud provider type ("aws", "gcp", ...), falling
+// back to the prefix of its provider name ("aws_{accountID}_{region}")
+func providerType(instance models.Instance) string {
+	provider := instance.Provider
+	if provider == "" {
+		provider = instance.ProviderName
+	}
+	provider, _, _ = strings.Cut(provider, "_")
+	return provider
+}
+
+// matchField applies a Matcher to an instance field; an empty field counts as absent
+func matchField(value string, matcher *models.Matcher) bool {
+	return matchString(value, value != "", matcher)
+}
+
+// matchString applies a Matcher to a value. present is false for a missing tag, which
+// only not_exists (or a negated matcher) matches.
+func matchString(value string, present bool, matcher *models.Matcher) bool {
+	if matcher == nil {
+		return true
+	}
+
+	var matched bool
+	switch matcher.Type {
+	case models.MatchExists:
+		matched = present
+	case models.MatchNotExists:
+		matched = !present
+	default:
+		matched = present && matchValue(value, matcher)
+	}
+	return matched != matcher.Not
+}
+
+// matchValue applies a pattern-based Matcher to a present value
+func matchValue(value string, matcher *models.Matcher) bool {
+	switch matcher.Type {
+	case models.MatchExact:
+		return value == matcher.Pattern
+	case models.MatchContains, "": // Matchers saved without a type have always meant contains
+		return strings.Contains(value, matcher.Pattern)
+	case models.MatchPrefix:
+		return strings.HasPrefix(value, matcher.Pattern)
+	case models.MatchSuffix:
+		return strings.HasSuffix(value, matcher.Pattern)
+	case models.MatchRegex:
+		re, err := compileRegex(matcher.Pattern)
+		if err != nil {
+			return false // Invalid regex doesn't match
+		}
+		return re.MatchString(value)
+	case models.MatchIn:
+		for _, candidate := range matcher.Values {
+			if value == candidate {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// matchNumber applies a numeric comparison
+func matchNumber(value int, comparison models.NumberMatcher) bool {
+	switch comparison.Op {
+	case "eq":
+		return value == comparison.Value
+	case "ne":
+		return value != comparison.Value
+	case "lt":
+		return value < comparison.Value
+	case "lte":
+		return value <= comparison.Value
+	case "gt":
+		return value > comparison.Value
+	case "gte":
+		return value >= comparison.Value
+	default:
+		return false
+	}
+}
+
+// compileRegex compiles a pattern, reusing earlier compilations
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := regexCache.get(pattern); ok {
+		return cached, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexCache.add(pattern, re)
+	return re, nil
+}
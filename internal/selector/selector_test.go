@@ -0,0 +1,199 @@
+package selector
+
+import (
+	"strings"
+	"testing"
+
+	"snoozeql/internal/models"
+)
+
+var testInstance = models.Instance{
+	Name:            "orders-dev",
+	Provider:        "aws",
+	ProviderName:    "aws_123_eu-west-1",
+	Region:          "eu-west-1",
+	Engine:          "postgres",
+	CloudAccountID:  "acc-1",
+	AccountID:       "123456789012",
+	HourlyCostCents: 40,
+	Tags:            map[string]string{"env": "dev", "team": "orders", "empty": ""},
+}
+
+func matcher(matchType models.MatchType, pattern string) *models.Matcher {
+	return &models.Matcher{Type: matchType, Pattern: pattern}
+}
+
+func TestMatchSelector(t *testing.T) {
+	aws, gcp := "aws", "GCP"
+
+	tests := []struct {
+		name string
+		sel  models.Selector
+		want bool
+	}{
+		{name: "empty selector matches everything", sel: models.Selector{}, want: true},
+		{name: "exact", sel: models.Selector{Name: matcher(models.MatchExact, "orders-dev")}, want: true},
+		{name: "exact mismatch", sel: models.Selector{Name: matcher(models.MatchExact, "orders")}},
+		{name: "contains", sel: models.Selector{Name: matcher(models.MatchContains, "ders")}, want: true},
+		{name: "untyped matcher means contains", sel: models.Selector{Name: matcher("", "ders")}, want: true},
+		{name: "prefix", sel: models.Selector{Region: matcher(models.MatchPrefix, "eu-")}, want: true},
+		{name: "suffix", sel: models.Selector{Name: matcher(models.MatchSuffix, "-prod")}},
+		{name: "regex", sel: models.Selector{Name: matcher(models.MatchRegex, `^orders-(dev|qa)$`)}, want: true},
+		{name: "invalid regex matches nothing", sel: models.Selector{Name: matcher(models.MatchRegex, `(`)}},
+		{name: "unknown type matches nothing", sel: models.Selector{Name: matcher("glob", "*")}},
+		{name: "in", sel: models.Selector{Engine: &models.Matcher{Type: models.MatchIn, Values: []string{"mysql", "postgres"}}}, want: true},
+		{name: "in mismatch", sel: models.Selector{Engine: &models.Matcher{Type: models.MatchIn, Values: []string{"mysql"}}}},
+		{name: "provider type is case-insensitive", sel: models.Selector{Provider: &aws}, want: true},
+		{name: "other provider", sel: models.Selector{Provider: &gcp}},
+		{name: "cloud account", sel: models.Selector{CloudAccount: matcher(models.MatchExact, "acc-1")}, want: true},
+		{name: "provider account ID", sel: models.Selector{CloudAccount: matcher(models.MatchExact, "123456789012")}, want: true},
+		{name: "tag", sel: models.Selector{Tags: map[string]*models.Matcher{"env": matcher(models.MatchExact, "dev")}}, want: true},
+		{name: "all criteria must match", sel: models.Selector{
+			Engine: matcher(models.MatchExact, "postgres"),
+			Tags:   map[string]*models.Matcher{"env": matcher(models.MatchExact, "prod")},
+		}},
+		{name: "missing tag doesn't match", sel: models.Selector{Tags: map[string]*models.Matcher{"owner": matcher(models.MatchContains, "")}}},
+		{name: "negated matcher matches missing tag", sel: models.Selector{Tags: map[string]*models.Matcher{"owner": {Type: models.MatchExact, Pattern: "bob", Not: true}}}, want: true},
+		{name: "negated matcher", sel: models.Selector{Tags: map[string]*models.Matcher{"env": {Type: models.MatchExact, Pattern: "dev", Not: true}}}},
+		{name: "exists with empty value", sel: models.Selector{Tags: map[string]*models.Matcher{"empty": {Type: models.MatchExists}}}, want: true},
+		{name: "exists missing", sel: models.Selector{Tags: map[string]*models.Matcher{"owner": {Type: models.MatchExists}}}},
+		{name: "not_exists", sel: models.Selector{Tags: map[string]*models.Matcher{"owner": {Type: models.MatchNotExists}}}, want: true},
+		{name: "empty field counts as absent", sel: models.Selector{Name: &models.Matcher{Type: models.MatchExists}, Region: &models.Matcher{Type: models.MatchNotExists}}},
+		{name: "negated selector", sel: models.Selector{Tags: map[string]*models.Matcher{"env": matcher(models.MatchExact, "dev")}, Not: true}},
+		{name: "negated selector without match", sel: models.Selector{Tags: map[string]*models.Matcher{"env": matcher(models.MatchExact, "prod")}, Not: true}, want: true},
+		{name: "cost range", sel: models.Selector{HourlyCostCents: []models.NumberMatcher{{Op: "gte", Value: 40}, {Op: "lt", Value: 100}}}, want: true},
+		{name: "cost above", sel: models.Selector{HourlyCostCents: []models.NumberMatcher{{Op: "gt", Value: 40}}}},
+		{name: "cost ne", sel: models.Selector{HourlyCostCents: []models.NumberMatcher{{Op: "ne", Value: 40}}}},
+		{name: "cost unknown op", sel: models.Selector{HourlyCostCents: []models.NumberMatcher{{Op: "between", Value: 40}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchSelector(testInstance, tt.sel); got != tt.want {
+				t.Errorf("MatchSelector = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProviderTypeFallsBackToProviderName(t *testing.T) {
+	instance := testInstance
+	instance.Provider = ""
+	aws := "aws"
+	if !MatchSelector(instance, models.Selector{Provider: &aws}) {
+		t.Error("provider type not derived from the provider name")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	dev := models.Selector{Tags: map[string]*models.Matcher{"env": matcher(models.MatchExact, "dev")}}
+	mysql := models.Selector{Engine: matcher(models.MatchExact, "mysql")}
+	postgres := models.Selector{Engine: matcher(models.MatchExact, "postgres")}
+	orders := models.Selector{Tags: map[string]*models.Matcher{"team": matcher(models.MatchExact, "orders")}}
+
+	tests := []struct {
+		name       string
+		instance   models.Instance
+		selectors  []models.Selector
+		operator   string
+		exclusions []models.Selector
+		want       bool
+	}{
+		{name: "no selectors select nothing", instance: testInstance},
+		{name: "select all", instance: testInstance, selectors: []models.Selector{{}}, want: true},
+		{name: "or", instance: testInstance, selectors: []models.Selector{mysql, dev}, want: true},
+		{name: "default operator is or", instance: testInstance, selectors: []models.Selector{mysql, dev}, operator: "", want: true},
+		{name: "and", instance: testInstance, selectors: []models.Selector{postgres, dev}, operator: models.OperatorAnd, want: true},
+		{name: "and mismatch", instance: testInstance, selectors: []models.Selector{mysql, dev}, operator: models.OperatorAnd},
+		{name: "excluded", instance: testInstance, selectors: []models.Selector{dev}, exclusions: []models.Selector{mysql, orders}},
+		{name: "exclusions not matching", instance: testInstance, selectors: []models.Selector{dev}, exclusions: []models.Selector{mysql}, want: true},
+		{
+			name:      "cluster members are never selected",
+			instance:  models.Instance{Name: "orders-1", ClusterID: "orders", Tags: map[string]string{"env": "dev"}},
+			selectors: []models.Selector{{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Match(tt.instance, tt.selectors, tt.operator, tt.exclusions); got != tt.want {
+				t.Errorf("Match = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchSchedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule models.Schedule
+		want     bool
+	}{
+		{
+			name:     "selectors",
+			schedule: models.Schedule{Selectors: []models.Selector{{Engine: matcher(models.MatchExact, "postgres")}}},
+			want:     true,
+		},
+		{
+			name: "expression wins over selectors",
+			schedule: models.Schedule{
+				Selectors:  []models.Selector{{}},
+				Expression: `engine == "mysql"`,
+			},
+		},
+		{
+			name:     "expression",
+			schedule: models.Schedule{Expression: `tag.env == "dev" && hourly_cost_cents < 100`},
+			want:     true,
+		},
+		{
+			name: "expression with exclusions",
+			schedule: models.Schedule{
+				Expression: `tag.env == "dev"`,
+				Exclusions: []models.Selector{{Tags: map[string]*models.Matcher{"team": matcher(models.MatchExact, "orders")}}},
+			},
+		},
+		{name: "invalid expression selects nothing", schedule: models.Schedule{Expression: `engine = "postgres"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchSchedule(testInstance, tt.schedule); got != tt.want {
+				t.Errorf("MatchSchedule = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		selectors []models.Selector
+		want      string // Substring of the error; empty if valid
+	}{
+		{name: "valid", selectors: []models.Selector{{
+			Name:            matcher(models.MatchRegex, "^orders"),
+			Tags:            map[string]*models.Matcher{"env": {Type: models.MatchIn, Values: []string{"dev"}}},
+			HourlyCostCents: []models.NumberMatcher{{Op: "lte", Value: 10}},
+		}}},
+		{name: "untyped matcher", selectors: []models.Selector{{Name: matcher("", "orders")}}},
+		{name: "invalid regex", selectors: []models.Selector{{}, {Region: matcher(models.MatchRegex, "(")}}, want: "Selector 2 region: invalid regex"},
+		{name: "empty in", selectors: []models.Selector{{Tags: map[string]*models.Matcher{"env": {Type: models.MatchIn}}}}, want: "tag 'env': 'in' requires at least one value"},
+		{name: "unknown type", selectors: []models.Selector{{Engine: matcher("glob", "*")}}, want: "unknown match type 'glob'"},
+		{name: "unknown op", selectors: []models.Selector{{HourlyCostCents: []models.NumberMatcher{{Op: "<"}}}}, want: "unknown op '<'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Validate(tt.selectors)
+			if (got == "") != (tt.want == "") || !strings.Contains(got, tt.want) {
+				t.Errorf("Validate = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOperator(t *testing.T) {
+	if Operator("") != models.OperatorOr || Operator(models.OperatorAnd) != models.OperatorAnd {
+		t.Error("Operator doesn't default to or")
+	}
+	if ValidateOperator("xor") == "" || ValidateOperator("") != "" || ValidateOperator("and") != "" {
+		t.Error("ValidateOperator accepts the wrong operators")
+	}
+}
@@ -0,0 +1,72 @@
+package selector
+
+import (
+	"fmt"
+	"regexp"
+
+	"snoozeql/internal/models"
+)
+
+// Validate checks that every selector uses known match types and comparison operators,
+// valid regex patterns and non-empty "in" sets.
+// Returns an error message if invalid, empty string if valid.
+func Validate(selectors []models.Selector) string {
+	for i, sel := range selectors {
+		fields := []struct {
+			name    string
+			matcher *models.Matcher
+		}{
+			{"name", sel.Name},
+			{"region", sel.Region},
+			{"engine", sel.Engine},
+			{"cloud_account", sel.CloudAccount},
+		}
+		for _, field := range fields {
+			if errMsg := validateMatcher(field.matcher); errMsg != "" {
+				return fmt.Sprintf("Selector %d %s: %s", i+1, field.name, errMsg)
+			}
+		}
+		for key, matcher := range sel.Tags {
+			if errMsg := validateMatcher(matcher); errMsg != "" {
+				return fmt.Sprintf("Selector %d tag '%s': %s", i+1, key, errMsg)
+			}
+		}
+		for _, comparison := range sel.HourlyCostCents {
+			switch comparison.Op {
+			case "eq", "ne", "lt", "lte", "gt", "gte":
+			default:
+				return fmt.Sprintf("Selector %d hourly_cost_cents: unknown op '%s' (use eq, ne, lt, lte, gt or gte)", i+1, comparison.Op)
+			}
+		}
+	}
+	return ""
+}
+
+// ValidateOperator checks a selector operator; empty means the default ("or")
+func ValidateOperator(operator string) string {
+	if operator != "" && operator != models.OperatorAnd && operator != models.OperatorOr {
+		return "Operator must be 'and' or 'or'"
+	}
+	return ""
+}
+
+func validateMatcher(matcher *models.Matcher) string {
+	if matcher == nil {
+		return ""
+	}
+	switch matcher.Type {
+	case models.MatchExact, models.MatchContains, models.MatchPrefix, models.MatchSuffix,
+		models.MatchExists, models.MatchNotExists, "":
+	case models.MatchRegex:
+		if _, err := regexp.Compile(matcher.Pattern); err != nil {
+			return "invalid regex: " + err.Error()
+		}
+	case models.MatchIn:
+		if len(matcher.Values) == 0 {
+			return "'in' requires at least one value"
+		}
+	default:
+		return fmt.Sprintf("unknown match type '%s'", matcher.Type)
+	}
+	return ""
+}
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"snoozeql/internal/models"
+)
+
+// CalendarStore provides exception calendar CRUD operations
+type CalendarStore struct {
+	db *Postgres
+}
+
+// NewCalendarStore creates a new calendar store
+func NewCalendarStore(db *Postgres) *CalendarStore {
+	return &CalendarStore{db: db}
+}
+
+const calendarColumns = `id, name, COALESCE(description, ''), entries, created_at, updated_at`
+
+// ListCalendars returns all exception calendars ordered by name
+func (s *CalendarStore) ListCalendars(ctx context.Context) ([]models.ExceptionCalendar, error) {
+	rows, err := s.db.Query(ctx, `SELECT `+calendarColumns+` FROM exception_calendars ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query calendars: %w", err)
+	}
+	defer rows.Close()
+
+	calendars := []models.ExceptionCalendar{} // Initialize as empty slice, not nil
+	for rows.Next() {
+		calendar, err := scanCalendar(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan calendar: %w", err)
+		}
+		calendars = append(calendars, *calendar)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return calendars, nil
+}
+
+// GetCalendar retrieves an exception calendar by ID
+func (s *CalendarStore) GetCalendar(ctx context.Context, id string) (*models.ExceptionCalendar, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+calendarColumns+` FROM exception_calendars WHERE id = $1`, id)
+	return scanCalendar(row)
+}
+
+// CreateCalendar creates a new exception calendar
+func (s *CalendarStore) CreateCalendar(ctx context.Context, calendar *models.ExceptionCalendar) error {
+	entriesJSON, err := json.Marshal(calendar.Entries)
+	if err != nil {
+		return fmt.Errorf("failed to marshal entries: %w", err)
+	}
+
+	return s.db.QueryRowContext(ctx, `
+		INSERT INTO exception_calendars (name, description, entries)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, updated_at`,
+		calendar.Name, calendar.Description, entriesJSON,
+	).Scan(&calendar.ID, &calendar.CreatedAt, &calendar.UpdatedAt)
+}
+
+// UpdateCalendar updates an exception calendar's name, description and entries
+// Returns sql.ErrNoRows if the calendar does not exist
+func (s *CalendarStore) UpdateCalendar(ctx context.Context, calendar *models.ExceptionCalendar) error {
+	entriesJSON, err := json.Marshal(calendar.Entries)
+	if err != nil {
+		return fmt.Errorf("failed to marshal entries: %w", err)
+	}
+
+	return s.db.QueryRowContext(ctx, `
+		UPDATE exception_calendars SET name = $1, description = $2, entries = $3, updated_at = NOW()
+		WHERE id = $4
+		RETURNING created_at, updated_at`,
+		calendar.Name, calendar.Description, entriesJSON, calendar.ID,
+	).Scan(&calendar.CreatedAt, &calendar.UpdatedAt)
+}
+
+// DeleteCalendar deletes an exception calendar
+// Returns sql.ErrNoRows if the calendar does not exist
+func (s *CalendarStore) DeleteCalendar(ctx context.Context, id string) error {
+	affected, err := s.db.Exec(ctx, `DELETE FROM exception_calendars WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete calendar: %w", err)
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func scanCalendar(row rowScanner) (*models.ExceptionCalendar, error) {
+	var calendar models.ExceptionCalendar
+	var entriesJSON []byte
+
+	err := row.Scan(&calendar.ID, &calendar.Name, &calendar.Description, &entriesJSON,
+		&calendar.CreatedAt, &calendar.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar.Entries = []models.CalendarEntry{}
+	if len(entriesJSON) > 0 {
+		if err := json.Unmarshal(entriesJSON, &calendar.Entries); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal entries: %w", err)
+		}
+	}
+	return &calendar, nil
+}
@@ -0,0 +1,117 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"snoozeql/internal/models"
+)
+
+// IdlePolicyStore provides idle policy CRUD operations
+type IdlePolicyStore struct {
+	db *Postgres
+}
+
+// NewIdlePolicyStore creates a new idle policy store
+func NewIdlePolicyStore(db *Postgres) *IdlePolicyStore {
+	return &IdlePolicyStore{db: db}
+}
+
+const idlePolicyColumns = `id, name, COALESCE(description, ''), selectors, max_connections, max_cpu_percent, idle_minutes, enabled, created_at, updated_at`
+
+// ListIdlePolicies returns all idle policies ordered by name
+func (s *IdlePolicyStore) ListIdlePolicies(ctx context.Context) ([]models.IdlePolicy, error) {
+	rows, err := s.db.Query(ctx, `SELECT `+idlePolicyColumns+` FROM idle_policies ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query idle policies: %w", err)
+	}
+	defer rows.Close()
+
+	policies := []models.IdlePolicy{} // Initialize as empty slice, not nil
+	for rows.Next() {
+		policy, err := scanIdlePolicy(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan idle policy: %w", err)
+		}
+		policies = append(policies, *policy)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return policies, nil
+}
+
+// GetIdlePolicy retrieves an idle policy by ID
+func (s *IdlePolicyStore) GetIdlePolicy(ctx context.Context, id string) (*models.IdlePolicy, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+idlePolicyColumns+` FROM idle_policies WHERE id = $1`, id)
+	return scanIdlePolicy(row)
+}
+
+// CreateIdlePolicy creates a new idle policy
+func (s *IdlePolicyStore) CreateIdlePolicy(ctx context.Context, policy *models.IdlePolicy) error {
+	selectorsJSON, err := json.Marshal(policy.Selectors)
+	if err != nil {
+		return fmt.Errorf("failed to marshal selectors: %w", err)
+	}
+
+	return s.db.QueryRowContext(ctx, `
+		INSERT INTO idle_policies (name, description, selectors, max_connections, max_cpu_percent, idle_minutes, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at`,
+		policy.Name, policy.Description, selectorsJSON, policy.MaxConnections, policy.MaxCPUPercent,
+		policy.IdleMinutes, policy.Enabled,
+	).Scan(&policy.ID, &policy.CreatedAt, &policy.UpdatedAt)
+}
+
+// UpdateIdlePolicy replaces an idle policy's settings
+// Returns sql.ErrNoRows if the policy does not exist
+func (s *IdlePolicyStore) UpdateIdlePolicy(ctx context.Context, policy *models.IdlePolicy) error {
+	selectorsJSON, err := json.Marshal(policy.Selectors)
+	if err != nil {
+		return fmt.Errorf("failed to marshal selectors: %w", err)
+	}
+
+	return s.db.QueryRowContext(ctx, `
+		UPDATE idle_policies SET name = $1, description = $2, selectors = $3, max_connections = $4,
+			max_cpu_percent = $5, idle_minutes = $6, enabled = $7, updated_at = NOW()
+		WHERE id = $8
+		RETURNING created_at, updated_at`,
+		policy.Name, policy.Description, selectorsJSON, policy.MaxConnections, policy.MaxCPUPercent,
+		policy.IdleMinutes, policy.Enabled, policy.ID,
+	).Scan(&policy.CreatedAt, &policy.UpdatedAt)
+}
+
+// DeleteIdlePolicy deletes an idle policy
+// Returns sql.ErrNoRows if the policy does not exist
+func (s *IdlePolicyStore) DeleteIdlePolicy(ctx context.Context, id string) error {
+	affected, err := s.db.Exec(ctx, `DELETE FROM idle_policies WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete idle policy: %w", err)
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func scanIdlePolicy(row rowScanner) (*models.IdlePolicy, error) {
+	var policy models.IdlePolicy
+	var selectorsJSON []byte
+
+	err := row.Scan(&policy.ID, &policy.Name, &policy.Description, &selectorsJSON,
+		&policy.MaxConnections, &policy.MaxCPUPercent, &policy.IdleMinutes, &policy.Enabled,
+		&policy.CreatedAt, &policy.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	policy.Selectors = []models.Selector{}
+	if len(selectorsJSON) > 0 {
+		if err := json.Unmarshal(selectorsJSON, &policy.Selectors); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal selectors: %w", err)
+		}
+	}
+	return &policy, nil
+}
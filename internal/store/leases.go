@@ -0,0 +1,55 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// LeaseStore provides leader election leases backed by the leases table
+// Expiry is evaluated with the database clock so replicas don't need synchronized clocks
+type LeaseStore struct {
+	db *Postgres
+}
+
+// NewLeaseStore creates a new lease store
+func NewLeaseStore(db *Postgres) *LeaseStore {
+	return &LeaseStore{db: db}
+}
+
+// TryAcquire acquires or renews the named lease for holder for the given TTL
+// Returns true if holder now holds the lease, false if another holder's lease is still valid
+func (s *LeaseStore) TryAcquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	query := `
+		INSERT INTO leases (name, holder, acquired_at, renewed_at, expires_at)
+		VALUES ($1, $2, NOW(), NOW(), NOW() + make_interval(secs => $3))
+		ON CONFLICT (name) DO UPDATE SET
+			holder = EXCLUDED.holder,
+			acquired_at = CASE WHEN leases.holder = EXCLUDED.holder THEN leases.acquired_at ELSE NOW() END,
+			renewed_at = NOW(),
+			expires_at = EXCLUDED.expires_at
+		WHERE leases.holder = EXCLUDED.holder OR leases.expires_at < NOW()
+		RETURNING holder`
+
+	var current string
+	err := s.db.QueryRowContext(ctx, query, name, holder, ttl.Seconds()).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Conflict row was not updated: someone else holds a valid lease
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lease %s: %w", name, err)
+	}
+	return current == holder, nil
+}
+
+// Release gives up the named lease if holder still holds it, so another replica can take over immediately
+func (s *LeaseStore) Release(ctx context.Context, name, holder string) error {
+	_, err := s.db.Exec(ctx, `DELETE FROM leases WHERE name = $1 AND holder = $2`, name, holder)
+	if err != nil {
+		return fmt.Errorf("failed to release lease %s: %w", name, err)
+	}
+	return nil
+}
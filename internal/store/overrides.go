@@ -0,0 +1,151 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"snoozeql/internal/models"
+)
+
+// OverrideStore provides override CRUD operations
+type OverrideStore struct {
+	db *Postgres
+}
+
+// NewOverrideStore creates a new override store
+func NewOverrideStore(db *Postgres) *OverrideStore {
+	return &OverrideStore{db: db}
+}
+
+const overrideColumns = `id, instance_id, type, skip_action, until_time, reason, created_by, created_at, COALESCE(expired, FALSE)`
+
+// CreateOverride inserts a new override for an instance
+// Any active override of the same type (and skip action) is expired first, so a new
+// keep-alive replaces the old one instead of conflicting with it
+func (s *OverrideStore) CreateOverride(ctx context.Context, override *models.Override) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE overrides SET expired = TRUE
+		WHERE instance_id = $1 AND type = $2
+			AND COALESCE(skip_action, '') = COALESCE($3, '')
+			AND expired = FALSE`,
+		override.InstanceID, override.Type, override.SkipAction)
+	if err != nil {
+		return fmt.Errorf("failed to replace existing override: %w", err)
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO overrides (instance_id, type, skip_action, until_time, reason, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`,
+		override.InstanceID, override.Type, override.SkipAction,
+		override.UntilTime, override.Reason, override.CreatedBy,
+	).Scan(&override.ID, &override.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert override: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// GetOverride retrieves an override by ID
+func (s *OverrideStore) GetOverride(ctx context.Context, id string) (*models.Override, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+overrideColumns+` FROM overrides WHERE id = $1`, id)
+	override, err := scanOverride(row)
+	if err != nil {
+		return nil, err
+	}
+	return override, nil
+}
+
+// ListOverridesByInstance returns overrides for an instance (most recent first)
+// Expired overrides are only included when includeExpired is true
+func (s *OverrideStore) ListOverridesByInstance(ctx context.Context, instanceID string, includeExpired bool) ([]models.Override, error) {
+	query := `SELECT ` + overrideColumns + ` FROM overrides WHERE instance_id = $1`
+	if !includeExpired {
+		query += ` AND expired = FALSE`
+	}
+	query += ` ORDER BY created_at DESC`
+
+	return s.queryOverrides(ctx, query, instanceID)
+}
+
+// ListActiveOverrides returns all overrides that have not expired
+func (s *OverrideStore) ListActiveOverrides(ctx context.Context) ([]models.Override, error) {
+	query := `SELECT ` + overrideColumns + ` FROM overrides WHERE expired = FALSE ORDER BY created_at DESC`
+	return s.queryOverrides(ctx, query)
+}
+
+// ExpireOverride marks a single override as expired (cancelled or consumed)
+// Returns sql.ErrNoRows if the override does not exist or has already expired
+func (s *OverrideStore) ExpireOverride(ctx context.Context, id string) error {
+	affected, err := s.db.Exec(ctx, `UPDATE overrides SET expired = TRUE WHERE id = $1 AND expired = FALSE`, id)
+	if err != nil {
+		return fmt.Errorf("failed to expire override %s: %w", id, err)
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ExpireOverrides marks every override whose until_time has passed as expired
+// Returns the number of overrides that were expired
+func (s *OverrideStore) ExpireOverrides(ctx context.Context, now time.Time) (int64, error) {
+	affected, err := s.db.Exec(ctx, `
+		UPDATE overrides SET expired = TRUE
+		WHERE expired = FALSE AND until_time IS NOT NULL AND until_time <= $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire overrides: %w", err)
+	}
+	return affected, nil
+}
+
+// queryOverrides runs an override query and scans all rows
+func (s *OverrideStore) queryOverrides(ctx context.Context, query string, args ...any) ([]models.Override, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query overrides: %w", err)
+	}
+	defer rows.Close()
+
+	overrides := []models.Override{} // Initialize as empty slice, not nil
+	for rows.Next() {
+		override, err := scanOverride(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan override: %w", err)
+		}
+		overrides = append(overrides, *override)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return overrides, nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanOverride(row rowScanner) (*models.Override, error) {
+	var override models.Override
+	var createdBy sql.NullString
+
+	err := row.Scan(
+		&override.ID, &override.InstanceID, &override.Type, &override.SkipAction,
+		&override.UntilTime, &override.Reason, &createdBy, &override.CreatedAt, &override.Expired,
+	)
+	if err != nil {
+		return nil, err
+	}
+	override.CreatedBy = createdBy.String
+	return &override, nil
+}
@@ -7,11 +7,12 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"regexp"
 	"sort"
 	"strings"
+	"time"
 
 	"snoozeql/internal/models"
+	"snoozeql/internal/selector"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
@@ -227,27 +228,43 @@ func (s *InstanceStore) UpsertInstance(ctx context.Context, instance *models.Ins
 		return fmt.Errorf("failed to marshal tags: %w", err)
 	}
 
+	maintenance := instance.PendingMaintenance
+	if maintenance == nil {
+		maintenance = []models.PendingMaintenanceAction{}
+	}
+	maintenanceJSON, err := json.Marshal(maintenance)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pending maintenance: %w", err)
+	}
+
 	// Use ON CONFLICT on (provider, provider_id) to handle duplicates
 	// When a conflict occurs (same provider/provider_id but different cloud_account_id),
 	// update the cloud_account_id to the new value
 	query := `
 		INSERT INTO instances (
 			cloud_account_id, provider, provider_name, provider_id, name, region,
-			instance_type, engine, status, managed, tags, hourly_cost_cents
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			instance_type, engine, status, managed, tags, hourly_cost_cents,
+			backup_window, maintenance_window, pending_maintenance, cluster_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NULLIF($13, ''), NULLIF($14, ''), $15, NULLIF($16, ''))
 		ON CONFLICT (provider, provider_id, cloud_account_id) DO UPDATE SET
 			name = EXCLUDED.name,
 			provider_name = EXCLUDED.provider_name,
 			provider_id = EXCLUDED.provider_id,
 			status = EXCLUDED.status,
+			managed = EXCLUDED.managed,
 			tags = EXCLUDED.tags,
 			hourly_cost_cents = EXCLUDED.hourly_cost_cents,
+			backup_window = EXCLUDED.backup_window,
+			maintenance_window = EXCLUDED.maintenance_window,
+			pending_maintenance = EXCLUDED.pending_maintenance,
+			cluster_id = EXCLUDED.cluster_id,
 			updated_at = NOW()
 		RETURNING id`
 	return s.db.QueryRowContext(ctx, query,
 		instance.CloudAccountID, instance.Provider, instance.ProviderName, instance.ProviderID,
 		instance.Name, instance.Region, instance.InstanceType, instance.Engine,
 		instance.Status, instance.Managed, tagsJSON, instance.HourlyCostCents,
+		instance.BackupWindow, instance.MaintenanceWindow, maintenanceJSON, instance.ClusterID,
 	).Scan(&instance.ID)
 }
 
@@ -256,6 +273,8 @@ func (s *InstanceStore) ListInstances(ctx context.Context) ([]models.Instance, e
 	query := `
 		SELECT i.id, i.cloud_account_id, i.provider, i.provider_name, i.provider_id, i.name, i.region,
 			i.instance_type, i.engine, i.status, i.managed, i.tags, i.hourly_cost_cents,
+			COALESCE(i.backup_window, ''), COALESCE(i.maintenance_window, ''), i.pending_maintenance,
+			COALESCE(i.cluster_id, ''),
 			i.created_at, i.updated_at
 		FROM instances i
 		JOIN cloud_accounts ca ON i.cloud_account_id = ca.id
@@ -271,7 +290,7 @@ func (s *InstanceStore) ListInstances(ctx context.Context) ([]models.Instance, e
 	var instances []models.Instance
 	for rows.Next() {
 		var instance models.Instance
-		var tagsJSON []byte
+		var tagsJSON, maintenanceJSON []byte
 		var providerName sql.NullString
 
 		err := rows.Scan(
@@ -279,6 +298,8 @@ func (s *InstanceStore) ListInstances(ctx context.Context) ([]models.Instance, e
 			&providerName, &instance.ProviderID, &instance.Name, &instance.Region,
 			&instance.InstanceType, &instance.Engine, &instance.Status,
 			&instance.Managed, &tagsJSON, &instance.HourlyCostCents,
+			&instance.BackupWindow, &instance.MaintenanceWindow, &maintenanceJSON,
+			&instance.ClusterID,
 			&instance.CreatedAt, &instance.UpdatedAt,
 		)
 		if err != nil {
@@ -297,6 +318,9 @@ func (s *InstanceStore) ListInstances(ctx context.Context) ([]models.Instance, e
 		} else {
 			instance.Tags = make(map[string]string)
 		}
+		if err := unmarshalMaintenance(maintenanceJSON, &instance); err != nil {
+			return nil, err
+		}
 
 		instances = append(instances, instance)
 	}
@@ -313,6 +337,8 @@ func (s *InstanceStore) GetInstanceByProviderID(ctx context.Context, provider st
 	query := `
 		SELECT i.id, i.cloud_account_id, i.provider, i.provider_name, i.provider_id, i.name, i.region,
 			i.instance_type, i.engine, i.status, i.managed, i.tags, i.hourly_cost_cents,
+			COALESCE(i.backup_window, ''), COALESCE(i.maintenance_window, ''), i.pending_maintenance,
+			COALESCE(i.cluster_id, ''),
 			i.created_at, i.updated_at
 		FROM instances i
 		JOIN cloud_accounts ca ON i.cloud_account_id = ca.id
@@ -339,13 +365,15 @@ func (s *InstanceStore) GetInstanceByProviderID(ctx context.Context, provider st
 
 	if rows.Next() {
 		var instance models.Instance
-		var tagsJSON []byte
+		var tagsJSON, maintenanceJSON []byte
 
 		err := rows.Scan(
 			&instance.ID, &instance.CloudAccountID, &instance.Provider,
 			&instance.ProviderName, &instance.ProviderID, &instance.Name, &instance.Region,
 			&instance.InstanceType, &instance.Engine, &instance.Status,
 			&instance.Managed, &tagsJSON, &instance.HourlyCostCents,
+			&instance.BackupWindow, &instance.MaintenanceWindow, &maintenanceJSON,
+			&instance.ClusterID,
 			&instance.CreatedAt, &instance.UpdatedAt,
 		)
 		if err != nil {
@@ -359,6 +387,9 @@ func (s *InstanceStore) GetInstanceByProviderID(ctx context.Context, provider st
 		} else {
 			instance.Tags = make(map[string]string)
 		}
+		if err := unmarshalMaintenance(maintenanceJSON, &instance); err != nil {
+			return nil, err
+		}
 
 		return &instance, nil
 	}
@@ -371,19 +402,23 @@ func (s *InstanceStore) GetInstanceByID(ctx context.Context, id string) (*models
 	query := `
 		SELECT i.id, i.cloud_account_id, i.provider, i.provider_name, i.provider_id, i.name, i.region,
 			i.instance_type, i.engine, i.status, i.managed, i.tags, i.hourly_cost_cents,
+			COALESCE(i.backup_window, ''), COALESCE(i.maintenance_window, ''), i.pending_maintenance,
+			COALESCE(i.cluster_id, ''),
 			i.created_at, i.updated_at
 		FROM instances i
 		JOIN cloud_accounts ca ON i.cloud_account_id = ca.id
 		WHERE i.id = $1 AND ca.deleted_at IS NULL`
 
 	var instance models.Instance
-	var tagsJSON []byte
+	var tagsJSON, maintenanceJSON []byte
 
 	err := s.db.db.QueryRowContext(ctx, query, id).Scan(
 		&instance.ID, &instance.CloudAccountID, &instance.Provider,
 		&instance.ProviderName, &instance.ProviderID, &instance.Name, &instance.Region,
 		&instance.InstanceType, &instance.Engine, &instance.Status,
 		&instance.Managed, &tagsJSON, &instance.HourlyCostCents,
+		&instance.BackupWindow, &instance.MaintenanceWindow, &maintenanceJSON,
+		&instance.ClusterID,
 		&instance.CreatedAt, &instance.UpdatedAt,
 	)
 	if err != nil {
@@ -397,10 +432,24 @@ func (s *InstanceStore) GetInstanceByID(ctx context.Context, id string) (*models
 	} else {
 		instance.Tags = make(map[string]string)
 	}
+	if err := unmarshalMaintenance(maintenanceJSON, &instance); err != nil {
+		return nil, err
+	}
 
 	return &instance, nil
 }
 
+// unmarshalMaintenance parses an instance's pending_maintenance JSONB
+func unmarshalMaintenance(maintenanceJSON []byte, instance *models.Instance) error {
+	if len(maintenanceJSON) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(maintenanceJSON, &instance.PendingMaintenance); err != nil {
+		return fmt.Errorf("failed to unmarshal pending maintenance: %w", err)
+	}
+	return nil
+}
+
 // EventStore provides event CRUD operations
 type EventStore struct {
 	db *Postgres
@@ -477,6 +526,125 @@ func (s *EventStore) ListEventsByInstance(ctx context.Context, instanceID string
 	return events, rows.Err()
 }
 
+// ListEventsByScheduledAction returns the events recorded by a one-off scheduled action
+func (s *EventStore) ListEventsByScheduledAction(ctx context.Context, actionID string) ([]models.Event, error) {
+	query := `
+		SELECT id, instance_id, event_type, triggered_by, previous_status, new_status, metadata, created_at
+		FROM events WHERE metadata->>'scheduled_action_id' = $1 ORDER BY created_at DESC`
+	rows, err := s.db.Query(ctx, query, actionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.Event{} // Initialize as empty slice, not nil
+	for rows.Next() {
+		var e models.Event
+		err := rows.Scan(&e.ID, &e.InstanceID, &e.EventType, &e.TriggeredBy,
+			&e.PreviousStatus, &e.NewStatus, &e.Metadata, &e.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// HasStateEventSince reports whether the instance has had a wake/sleep/start/stop
+// (or skipped) event at or after the given time
+func (s *EventStore) HasStateEventSince(ctx context.Context, instanceID string, since time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM events
+			WHERE instance_id = $1 AND created_at >= $2
+				AND event_type IN ('wake', 'sleep', 'start', 'stop', 'wake_skipped', 'sleep_skipped')
+		)`
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, query, instanceID, since).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check events: %w", err)
+	}
+	return exists, nil
+}
+
+// LatestStateEvent returns the instance's most recent wake/sleep/start/stop or
+// aws_auto_restart event. Returns sql.ErrNoRows if it has none.
+func (s *EventStore) LatestStateEvent(ctx context.Context, instanceID string) (*models.Event, error) {
+	query := `
+		SELECT id, instance_id, event_type, triggered_by, previous_status, new_status, metadata, created_at
+		FROM events
+		WHERE instance_id = $1 AND event_type IN ('wake', 'sleep', 'start', 'stop', 'aws_auto_restart')
+		ORDER BY created_at DESC LIMIT 1`
+	var e models.Event
+	err := s.db.QueryRowContext(ctx, query, instanceID).Scan(&e.ID, &e.InstanceID, &e.EventType, &e.TriggeredBy,
+		&e.PreviousStatus, &e.NewStatus, &e.Metadata, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// ListStateEventsSince returns the instance's wake/sleep/start/stop, aws_auto_restart and
+// action outcome events at or after the given time (oldest first), preceded by the latest
+// one before it, so the instance's state at that time is known
+func (s *EventStore) ListStateEventsSince(ctx context.Context, instanceID string, since time.Time) ([]models.Event, error) {
+	query := `
+		SELECT id, instance_id, event_type, triggered_by, previous_status, new_status, metadata, created_at
+		FROM (
+			(SELECT * FROM events
+			WHERE instance_id = $1 AND created_at < $2
+				AND event_type IN ('wake', 'sleep', 'start', 'stop', 'aws_auto_restart',
+					'action_completed', 'action_failed')
+			ORDER BY created_at DESC LIMIT 1)
+			UNION ALL
+			(SELECT * FROM events
+			WHERE instance_id = $1 AND created_at >= $2
+				AND event_type IN ('wake', 'sleep', 'start', 'stop', 'aws_auto_restart',
+					'action_completed', 'action_failed'))
+		) state_events
+		ORDER BY created_at`
+	rows, err := s.db.Query(ctx, query, instanceID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.Event{} // Initialize as empty slice, not nil
+	for rows.Next() {
+		var e models.Event
+		err := rows.Scan(&e.ID, &e.InstanceID, &e.EventType, &e.TriggeredBy,
+			&e.PreviousStatus, &e.NewStatus, &e.Metadata, &e.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// ListEventsByTypeSince returns events of one type recorded at or after the given time (most recent first)
+func (s *EventStore) ListEventsByTypeSince(ctx context.Context, eventType string, since time.Time) ([]models.Event, error) {
+	query := `
+		SELECT id, instance_id, event_type, triggered_by, previous_status, new_status, metadata, created_at
+		FROM events WHERE event_type = $1 AND created_at >= $2 ORDER BY created_at DESC`
+	rows, err := s.db.Query(ctx, query, eventType, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.Event{} // Initialize as empty slice, not nil
+	for rows.Next() {
+		var e models.Event
+		err := rows.Scan(&e.ID, &e.InstanceID, &e.EventType, &e.TriggeredBy,
+			&e.PreviousStatus, &e.NewStatus, &e.Metadata, &e.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
 // RecommendationStore provides recommendation CRUD operations
 type RecommendationStore struct {
 	db *Postgres
@@ -658,16 +826,20 @@ func (s *CloudAccountStore) GetCloudAccount(id string) (*models.CloudAccount, er
 	var credentialsJSON []byte
 	var connectionStatus, lastError sql.NullString
 	var lastSyncAt sql.NullTime
+	var managedTagsJSON, filtersJSON []byte
 
 	err := s.db.db.QueryRowContext(context.Background(), `
-		SELECT id, name, provider, regions, credentials, connection_status, last_sync_at, last_error, created_at
+		SELECT id, name, provider, regions, credentials, managed_tags, discovery_filters, connection_status, last_sync_at, last_error, created_at
 		FROM cloud_accounts WHERE id = $1`, id).Scan(
 		&account.ID, &account.Name, &account.Provider, &regionsStr,
-		&credentialsJSON, &connectionStatus, &lastSyncAt, &lastError, &account.CreatedAt,
+		&credentialsJSON, &managedTagsJSON, &filtersJSON, &connectionStatus, &lastSyncAt, &lastError, &account.CreatedAt,
 	)
 	if err != nil {
 		return nil, err
 	}
+	if err := unmarshalDiscoveryConfig(managedTagsJSON, filtersJSON, &account); err != nil {
+		return nil, err
+	}
 	account.ConnectionStatus = connectionStatus.String
 	if lastSyncAt.Valid {
 		account.LastSyncAt = &lastSyncAt.Time
@@ -710,7 +882,7 @@ func (s *CloudAccountStore) UpdateConnectionStatus(ctx context.Context, id strin
 func (s *CloudAccountStore) ListCloudAccounts() ([]models.CloudAccount, error) {
 	log.Printf("DEBUG: Listing cloud accounts...")
 	query := `
-		SELECT id, name, provider, regions, credentials, connection_status, last_sync_at, last_error, deleted_at, created_at
+		SELECT id, name, provider, regions, credentials, managed_tags, discovery_filters, connection_status, last_sync_at, last_error, deleted_at, created_at
 		FROM cloud_accounts
 		WHERE deleted_at IS NULL
 		ORDER BY created_at DESC`
@@ -731,15 +903,19 @@ func (s *CloudAccountStore) ListCloudAccounts() ([]models.CloudAccount, error) {
 		var connectionStatus, lastError sql.NullString
 		var lastSyncAt sql.NullTime
 		var deletedAt sql.NullTime
+		var managedTagsJSON, filtersJSON []byte
 
 		err := rows.Scan(
 			&account.ID, &account.Name, &account.Provider, &regionsStr,
-			&credentialsJSON, &connectionStatus, &lastSyncAt, &lastError, &deletedAt, &account.CreatedAt,
+			&credentialsJSON, &managedTagsJSON, &filtersJSON, &connectionStatus, &lastSyncAt, &lastError, &deletedAt, &account.CreatedAt,
 		)
 		if err != nil {
 			log.Printf("ERROR: Scan failed: %v", err)
 			return nil, fmt.Errorf("failed to scan: %w", err)
 		}
+		if err := unmarshalDiscoveryConfig(managedTagsJSON, filtersJSON, &account); err != nil {
+			return nil, err
+		}
 		// Parse credentials JSONB
 		if len(credentialsJSON) > 0 {
 			if err := json.Unmarshal(credentialsJSON, &account.Credentials); err != nil {
@@ -783,10 +959,14 @@ func (s *CloudAccountStore) CreateCloudAccount(account *models.CloudAccount) err
 	if err != nil {
 		return err
 	}
+	managedTagsJSON, filtersJSON, err := marshalDiscoveryConfig(account)
+	if err != nil {
+		return err
+	}
 	err = s.db.db.QueryRowContext(context.Background(), `
-		INSERT INTO cloud_accounts (name, provider, regions, credentials)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id`, account.Name, account.Provider, account.Regions, credentialsJSON).Scan(&account.ID)
+		INSERT INTO cloud_accounts (name, provider, regions, credentials, managed_tags, discovery_filters)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`, account.Name, account.Provider, account.Regions, credentialsJSON, managedTagsJSON, filtersJSON).Scan(&account.ID)
 	return err
 }
 
@@ -796,14 +976,56 @@ func (s *CloudAccountStore) UpdateCloudAccount(account *models.CloudAccount) err
 	if err != nil {
 		return fmt.Errorf("failed to marshal credentials: %w", err)
 	}
+	managedTagsJSON, filtersJSON, err := marshalDiscoveryConfig(account)
+	if err != nil {
+		return err
+	}
 	_, err = s.db.db.ExecContext(context.Background(), `
 		UPDATE cloud_accounts SET
-			name = $1, regions = $2, credentials = $3, connection_status = 'unknown'
-		WHERE id = $4`,
-		account.Name, account.Regions, credentialsJSON, account.ID)
+			name = $1, regions = $2, credentials = $3, managed_tags = $4, discovery_filters = $5, connection_status = 'unknown'
+		WHERE id = $6`,
+		account.Name, account.Regions, credentialsJSON, managedTagsJSON, filtersJSON, account.ID)
 	return err
 }
 
+// marshalDiscoveryConfig encodes an account's managed tags and discovery filters for
+// their JSONB columns
+func marshalDiscoveryConfig(account *models.CloudAccount) ([]byte, []byte, error) {
+	managedTags := account.ManagedTags
+	if managedTags == nil {
+		managedTags = []string{}
+	}
+	filters := account.DiscoveryFilters
+	if filters == nil {
+		filters = []models.ProviderFilter{}
+	}
+	managedTagsJSON, err := json.Marshal(managedTags)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal managed tags: %w", err)
+	}
+	filtersJSON, err := json.Marshal(filters)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal discovery filters: %w", err)
+	}
+	return managedTagsJSON, filtersJSON, nil
+}
+
+// unmarshalDiscoveryConfig decodes the managed_tags and discovery_filters JSONB columns
+// into the account
+func unmarshalDiscoveryConfig(managedTagsJSON, filtersJSON []byte, account *models.CloudAccount) error {
+	if len(managedTagsJSON) > 0 {
+		if err := json.Unmarshal(managedTagsJSON, &account.ManagedTags); err != nil {
+			return fmt.Errorf("failed to unmarshal managed tags: %w", err)
+		}
+	}
+	if len(filtersJSON) > 0 {
+		if err := json.Unmarshal(filtersJSON, &account.DiscoveryFilters); err != nil {
+			return fmt.Errorf("failed to unmarshal discovery filters: %w", err)
+		}
+	}
+	return nil
+}
+
 // DeleteCloudAccount deletes a cloud account (soft delete)
 func (s *CloudAccountStore) DeleteCloudAccount(id string) error {
 	_, err := s.db.db.ExecContext(context.Background(), "UPDATE cloud_accounts SET deleted_at = NOW() WHERE id = $1", id)
@@ -829,14 +1051,14 @@ func NewScheduleStore(db *Postgres) *ScheduleStore {
 // GetSchedule retrieves a schedule by ID
 func (s *ScheduleStore) GetSchedule(id string) (*models.Schedule, error) {
 	var schedule models.Schedule
-	var selectorsJSON []byte
+	var selectorsJSON, exclusionsJSON, exceptionsJSON, orderingJSON, budgetJSON []byte
 
 	err := s.db.db.QueryRowContext(context.Background(), `
-		SELECT id, name, description, selectors, timezone, sleep_cron, wake_cron, enabled, created_at, updated_at
+		SELECT id, name, description, selectors, selector_operator, COALESCE(selector_expression, ''), exclusions, timezone, sleep_cron, wake_cron, enabled, exceptions, ordering, budget, priority, created_at, updated_at
 		FROM schedules WHERE id = $1`, id).Scan(
-		&schedule.ID, &schedule.Name, &schedule.Description, &selectorsJSON,
+		&schedule.ID, &schedule.Name, &schedule.Description, &selectorsJSON, &schedule.Operator, &schedule.Expression, &exclusionsJSON,
 		&schedule.Timezone, &schedule.SleepCron, &schedule.WakeCron, &schedule.Enabled,
-		&schedule.CreatedAt, &schedule.UpdatedAt,
+		&exceptionsJSON, &orderingJSON, &budgetJSON, &schedule.Priority, &schedule.CreatedAt, &schedule.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -851,13 +1073,29 @@ func (s *ScheduleStore) GetSchedule(id string) (*models.Schedule, error) {
 		schedule.Selectors = []models.Selector{}
 	}
 
+	if err := unmarshalExclusions(exclusionsJSON, &schedule); err != nil {
+		return nil, err
+	}
+
+	if err := unmarshalExceptions(exceptionsJSON, &schedule); err != nil {
+		return nil, err
+	}
+
+	if err := unmarshalOrdering(orderingJSON, &schedule); err != nil {
+		return nil, err
+	}
+
+	if err := unmarshalBudget(budgetJSON, &schedule); err != nil {
+		return nil, err
+	}
+
 	return &schedule, nil
 }
 
 // ListSchedules returns all schedules from the database
 func (s *ScheduleStore) ListSchedules() ([]models.Schedule, error) {
 	query := `
-		SELECT id, name, description, selectors, timezone, sleep_cron, wake_cron, enabled, created_at, updated_at
+		SELECT id, name, description, selectors, selector_operator, COALESCE(selector_expression, ''), exclusions, timezone, sleep_cron, wake_cron, enabled, exceptions, ordering, budget, priority, created_at, updated_at
 		FROM schedules ORDER BY created_at DESC`
 
 	rows, err := s.db.db.QueryContext(context.Background(), query)
@@ -869,12 +1107,12 @@ func (s *ScheduleStore) ListSchedules() ([]models.Schedule, error) {
 	var schedules []models.Schedule
 	for rows.Next() {
 		var schedule models.Schedule
-		var selectorsJSON []byte
+		var selectorsJSON, exclusionsJSON, exceptionsJSON, orderingJSON, budgetJSON []byte
 
 		err := rows.Scan(
-			&schedule.ID, &schedule.Name, &schedule.Description, &selectorsJSON,
+			&schedule.ID, &schedule.Name, &schedule.Description, &selectorsJSON, &schedule.Operator, &schedule.Expression, &exclusionsJSON,
 			&schedule.Timezone, &schedule.SleepCron, &schedule.WakeCron, &schedule.Enabled,
-			&schedule.CreatedAt, &schedule.UpdatedAt,
+			&exceptionsJSON, &orderingJSON, &budgetJSON, &schedule.Priority, &schedule.CreatedAt, &schedule.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan schedule: %w", err)
@@ -889,6 +1127,22 @@ func (s *ScheduleStore) ListSchedules() ([]models.Schedule, error) {
 			schedule.Selectors = []models.Selector{}
 		}
 
+		if err := unmarshalExclusions(exclusionsJSON, &schedule); err != nil {
+			return nil, err
+		}
+
+		if err := unmarshalExceptions(exceptionsJSON, &schedule); err != nil {
+			return nil, err
+		}
+
+		if err := unmarshalOrdering(orderingJSON, &schedule); err != nil {
+			return nil, err
+		}
+
+		if err := unmarshalBudget(budgetJSON, &schedule); err != nil {
+			return nil, err
+		}
+
 		schedules = append(schedules, schedule)
 	}
 
@@ -906,12 +1160,32 @@ func (s *ScheduleStore) CreateSchedule(schedule *models.Schedule) error {
 		return fmt.Errorf("failed to marshal selectors: %w", err)
 	}
 
+	exclusionsJSON, err := marshalExclusions(schedule)
+	if err != nil {
+		return err
+	}
+
+	exceptionsJSON, err := marshalExceptions(schedule)
+	if err != nil {
+		return err
+	}
+
+	orderingJSON, err := marshalOrdering(schedule)
+	if err != nil {
+		return err
+	}
+
+	budgetJSON, err := marshalBudget(schedule)
+	if err != nil {
+		return err
+	}
+
 	err = s.db.db.QueryRowContext(context.Background(), `
 		INSERT INTO schedules (
-			name, description, selectors, timezone, sleep_cron, wake_cron, enabled
-		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+			name, description, selectors, selector_operator, selector_expression, exclusions, timezone, sleep_cron, wake_cron, enabled, exceptions, ordering, budget, priority
+		) VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, $10, $11, $12, $13, $14)
 		RETURNING id, created_at`, schedule.Name, schedule.Description,
-		selectorsJSON, schedule.Timezone, schedule.SleepCron, schedule.WakeCron, schedule.Enabled).Scan(
+		selectorsJSON, scheduleOperator(schedule), schedule.Expression, exclusionsJSON, schedule.Timezone, schedule.SleepCron, schedule.WakeCron, schedule.Enabled, exceptionsJSON, orderingJSON, budgetJSON, schedule.Priority).Scan(
 		&schedule.ID, &schedule.CreatedAt,
 	)
 	return err
@@ -924,124 +1198,165 @@ func (s *ScheduleStore) UpdateSchedule(schedule *models.Schedule) error {
 		return fmt.Errorf("failed to marshal selectors: %w", err)
 	}
 
+	exclusionsJSON, err := marshalExclusions(schedule)
+	if err != nil {
+		return err
+	}
+
+	exceptionsJSON, err := marshalExceptions(schedule)
+	if err != nil {
+		return err
+	}
+
+	orderingJSON, err := marshalOrdering(schedule)
+	if err != nil {
+		return err
+	}
+
+	budgetJSON, err := marshalBudget(schedule)
+	if err != nil {
+		return err
+	}
+
 	_, err = s.db.db.ExecContext(context.Background(), `
 		UPDATE schedules SET
-			name = $1, description = $2, selectors = $3,
-			timezone = $4, sleep_cron = $5, wake_cron = $6,
-			enabled = $7, updated_at = NOW()
-		WHERE id = $8`,
-		schedule.Name, schedule.Description, selectorsJSON,
-		schedule.Timezone, schedule.SleepCron, schedule.WakeCron, schedule.Enabled, schedule.ID,
+			name = $1, description = $2, selectors = $3, selector_operator = $4, selector_expression = NULLIF($5, ''), exclusions = $6,
+			timezone = $7, sleep_cron = $8, wake_cron = $9,
+			enabled = $10, exceptions = $11, ordering = $12, budget = $13, priority = $14, updated_at = NOW()
+		WHERE id = $15`,
+		schedule.Name, schedule.Description, selectorsJSON, scheduleOperator(schedule), schedule.Expression, exclusionsJSON,
+		schedule.Timezone, schedule.SleepCron, schedule.WakeCron, schedule.Enabled, exceptionsJSON, orderingJSON, budgetJSON, schedule.Priority, schedule.ID,
 	)
 	return err
 }
 
-// DeleteSchedule deletes a schedule
-func (s *ScheduleStore) DeleteSchedule(id string) error {
-	_, err := s.db.db.ExecContext(context.Background(), "DELETE FROM schedules WHERE id = $1", id)
-	return err
+// scheduleOperator returns the schedule's selector operator, defaulting to "or"
+func scheduleOperator(schedule *models.Schedule) string {
+	return selector.Operator(schedule.Operator)
 }
 
-// GetMatchingSchedules returns schedules that match a given instance
-func (s *ScheduleStore) GetMatchingSchedules(instance models.Instance) ([]models.Schedule, error) {
-	// Get all enabled schedules and filter in Go (since selector matching is complex)
-	schedules, err := s.ListSchedules()
+// marshalExclusions encodes a schedule's exclusion selectors for the exclusions JSONB column
+func marshalExclusions(schedule *models.Schedule) ([]byte, error) {
+	exclusions := schedule.Exclusions
+	if exclusions == nil {
+		exclusions = []models.Selector{}
+	}
+	exclusionsJSON, err := json.Marshal(exclusions)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to marshal exclusions: %w", err)
 	}
+	return exclusionsJSON, nil
+}
 
-	var matching []models.Schedule
-	for _, schedule := range schedules {
-		if !schedule.Enabled {
-			continue
-		}
-
-		if matchesInstance(instance, schedule.Selectors) {
-			matching = append(matching, schedule)
+// unmarshalExclusions decodes the exclusions JSONB column into the schedule
+func unmarshalExclusions(exclusionsJSON []byte, schedule *models.Schedule) error {
+	schedule.Exclusions = []models.Selector{}
+	if len(exclusionsJSON) > 0 {
+		if err := json.Unmarshal(exclusionsJSON, &schedule.Exclusions); err != nil {
+			return fmt.Errorf("failed to unmarshal exclusions: %w", err)
 		}
 	}
-
-	return matching, nil
+	return nil
 }
 
-// matchesInstance checks if an instance matches any of the schedule's selectors
-func matchesInstance(instance models.Instance, selectors []models.Selector) bool {
-	if len(selectors) == 0 {
-		return true
+// marshalOrdering encodes a schedule's tier ordering for the ordering JSONB column (nil for none)
+func marshalOrdering(schedule *models.Schedule) ([]byte, error) {
+	if schedule.Ordering == nil || len(schedule.Ordering.Tiers) == 0 {
+		return nil, nil
 	}
-
-	for _, selector := range selectors {
-		if selectorMatchesInstance(instance, selector) {
-			return true
-		}
+	orderingJSON, err := json.Marshal(schedule.Ordering)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal ordering: %w", err)
 	}
-
-	return false
+	return orderingJSON, nil
 }
 
-func selectorMatchesInstance(instance models.Instance, selector models.Selector) bool {
-	if selector.Name != nil {
-		if !matchesMatcher(instance.Name, selector.Name) {
-			return false
-		}
+// unmarshalOrdering decodes the ordering JSONB column into the schedule
+func unmarshalOrdering(orderingJSON []byte, schedule *models.Schedule) error {
+	if len(orderingJSON) == 0 {
+		return nil
+	}
+	var ordering models.ScheduleOrdering
+	if err := json.Unmarshal(orderingJSON, &ordering); err != nil {
+		return fmt.Errorf("failed to unmarshal ordering: %w", err)
 	}
+	schedule.Ordering = &ordering
+	return nil
+}
 
-	if selector.Provider != nil {
-		if instance.Provider != *selector.Provider {
-			return false
-		}
+// marshalBudget encodes a schedule's awake-hours budget for the budget JSONB column (nil for none)
+func marshalBudget(schedule *models.Schedule) ([]byte, error) {
+	if schedule.Budget == nil {
+		return nil, nil
+	}
+	budgetJSON, err := json.Marshal(schedule.Budget)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal budget: %w", err)
 	}
+	return budgetJSON, nil
+}
 
-	if selector.Region != nil {
-		if !matchesMatcher(instance.Region, selector.Region) {
-			return false
-		}
+// unmarshalBudget decodes the budget JSONB column into the schedule
+func unmarshalBudget(budgetJSON []byte, schedule *models.Schedule) error {
+	if len(budgetJSON) == 0 {
+		return nil
 	}
+	var budget models.ScheduleBudget
+	if err := json.Unmarshal(budgetJSON, &budget); err != nil {
+		return fmt.Errorf("failed to unmarshal budget: %w", err)
+	}
+	schedule.Budget = &budget
+	return nil
+}
 
-	if selector.Engine != nil {
-		if !matchesMatcher(instance.Engine, selector.Engine) {
-			return false
-		}
+// marshalExceptions encodes a schedule's calendar exceptions for the exceptions JSONB column
+func marshalExceptions(schedule *models.Schedule) ([]byte, error) {
+	exceptions := schedule.Exceptions
+	if exceptions == nil {
+		exceptions = []models.ScheduleException{}
 	}
+	exceptionsJSON, err := json.Marshal(exceptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal exceptions: %w", err)
+	}
+	return exceptionsJSON, nil
+}
 
-	if selector.Tags != nil {
-		for key, matcher := range selector.Tags {
-			tagValue, exists := instance.Tags[key]
-			if !exists {
-				return false
-			}
-			if !matchesMatcher(tagValue, matcher) {
-				return false
-			}
+// unmarshalExceptions decodes the exceptions JSONB column into the schedule
+func unmarshalExceptions(exceptionsJSON []byte, schedule *models.Schedule) error {
+	schedule.Exceptions = []models.ScheduleException{}
+	if len(exceptionsJSON) > 0 {
+		if err := json.Unmarshal(exceptionsJSON, &schedule.Exceptions); err != nil {
+			return fmt.Errorf("failed to unmarshal exceptions: %w", err)
 		}
 	}
+	return nil
+}
 
-	return true
+// DeleteSchedule deletes a schedule
+func (s *ScheduleStore) DeleteSchedule(id string) error {
+	_, err := s.db.db.ExecContext(context.Background(), "DELETE FROM schedules WHERE id = $1", id)
+	return err
 }
 
-func matchesMatcher(value string, matcher *models.Matcher) bool {
-	if matcher == nil {
-		return true
+// GetMatchingSchedules returns schedules that match a given instance
+func (s *ScheduleStore) GetMatchingSchedules(instance models.Instance) ([]models.Schedule, error) {
+	// Get all enabled schedules and filter in Go with the selector engine
+	schedules, err := s.ListSchedules()
+	if err != nil {
+		return nil, err
 	}
 
-	pattern := matcher.Pattern
+	var matching []models.Schedule
+	for _, schedule := range schedules {
+		if !schedule.Enabled {
+			continue
+		}
 
-	switch models.MatchType(matcher.Type) {
-	case models.MatchExact:
-		return value == pattern
-	case models.MatchContains:
-		return strings.Contains(value, pattern)
-	case models.MatchPrefix:
-		return strings.HasPrefix(value, pattern)
-	case models.MatchSuffix:
-		return strings.HasSuffix(value, pattern)
-	case models.MatchRegex:
-		re, err := regexp.Compile(pattern)
-		if err != nil {
-			return false
+		if selector.MatchSchedule(instance, schedule) {
+			matching = append(matching, schedule)
 		}
-		return re.MatchString(value)
-	default:
-		return strings.Contains(value, pattern)
 	}
+
+	return matching, nil
 }
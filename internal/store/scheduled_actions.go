@@ -0,0 +1,148 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"snoozeql/internal/models"
+)
+
+// ScheduledActionStore provides one-off scheduled action operations
+type ScheduledActionStore struct {
+	db *Postgres
+}
+
+// NewScheduledActionStore creates a new scheduled action store
+func NewScheduledActionStore(db *Postgres) *ScheduledActionStore {
+	return &ScheduledActionStore{db: db}
+}
+
+const scheduledActionColumns = `id, name, action, execute_at, instance_id, selectors, status, reason,
+	COALESCE(created_by, ''), executed_at, instance_count, error, created_at`
+
+// CreateScheduledAction inserts a new pending scheduled action
+func (s *ScheduledActionStore) CreateScheduledAction(ctx context.Context, action *models.ScheduledAction) error {
+	selectors := action.Selectors
+	if selectors == nil {
+		selectors = []models.Selector{}
+	}
+	selectorsJSON, err := json.Marshal(selectors)
+	if err != nil {
+		return fmt.Errorf("failed to marshal selectors: %w", err)
+	}
+
+	action.Status = models.ScheduledActionPending
+	return s.db.QueryRowContext(ctx, `
+		INSERT INTO scheduled_actions (name, action, execute_at, instance_id, selectors, status, reason, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at`,
+		action.Name, action.Action, action.ExecuteAt, action.InstanceID, selectorsJSON,
+		action.Status, action.Reason, action.CreatedBy,
+	).Scan(&action.ID, &action.CreatedAt)
+}
+
+// GetScheduledAction retrieves a scheduled action by ID
+func (s *ScheduledActionStore) GetScheduledAction(ctx context.Context, id string) (*models.ScheduledAction, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+scheduledActionColumns+` FROM scheduled_actions WHERE id = $1`, id)
+	return scanScheduledAction(row)
+}
+
+// ListScheduledActions returns scheduled actions ordered by execution time
+// If status is non-empty only actions with that status are returned
+func (s *ScheduledActionStore) ListScheduledActions(ctx context.Context, status string) ([]models.ScheduledAction, error) {
+	query := `SELECT ` + scheduledActionColumns + ` FROM scheduled_actions`
+	var args []any
+	if status != "" {
+		query += ` WHERE status = $1`
+		args = append(args, status)
+	}
+	query += ` ORDER BY execute_at`
+
+	return s.queryScheduledActions(ctx, query, args...)
+}
+
+// ClaimDueActions marks pending actions due at or before now as running and returns them,
+// along with running actions claimed before staleBefore, whose claimer never recorded an
+// outcome (e.g. it crashed or lost leadership). The claim is a single UPDATE, so an action
+// is only ever claimed by one scheduler at a time.
+func (s *ScheduledActionStore) ClaimDueActions(ctx context.Context, now, staleBefore time.Time) ([]models.ScheduledAction, error) {
+	query := `
+		UPDATE scheduled_actions SET status = 'running', claimed_at = $1
+		WHERE (status = 'pending' AND execute_at <= $1)
+			OR (status = 'running' AND (claimed_at IS NULL OR claimed_at < $2))
+		RETURNING ` + scheduledActionColumns
+	return s.queryScheduledActions(ctx, query, now, staleBefore)
+}
+
+// FinishScheduledAction records the outcome of an executed action
+func (s *ScheduledActionStore) FinishScheduledAction(ctx context.Context, id, status string, instanceCount int, errMsg *string) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE scheduled_actions SET status = $1, instance_count = $2, error = $3, executed_at = NOW()
+		WHERE id = $4`,
+		status, instanceCount, errMsg, id)
+	if err != nil {
+		return fmt.Errorf("failed to finish scheduled action %s: %w", id, err)
+	}
+	return nil
+}
+
+// CancelScheduledAction cancels a pending action
+// Returns sql.ErrNoRows if the action does not exist or is no longer pending
+func (s *ScheduledActionStore) CancelScheduledAction(ctx context.Context, id string) error {
+	affected, err := s.db.Exec(ctx,
+		`UPDATE scheduled_actions SET status = 'cancelled' WHERE id = $1 AND status = 'pending'`, id)
+	if err != nil {
+		return fmt.Errorf("failed to cancel scheduled action %s: %w", id, err)
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// queryScheduledActions runs a scheduled action query and scans all rows
+func (s *ScheduledActionStore) queryScheduledActions(ctx context.Context, query string, args ...any) ([]models.ScheduledAction, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scheduled actions: %w", err)
+	}
+	defer rows.Close()
+
+	actions := []models.ScheduledAction{} // Initialize as empty slice, not nil
+	for rows.Next() {
+		action, err := scanScheduledAction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled action: %w", err)
+		}
+		actions = append(actions, *action)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return actions, nil
+}
+
+func scanScheduledAction(row rowScanner) (*models.ScheduledAction, error) {
+	var action models.ScheduledAction
+	var selectorsJSON []byte
+
+	err := row.Scan(
+		&action.ID, &action.Name, &action.Action, &action.ExecuteAt, &action.InstanceID,
+		&selectorsJSON, &action.Status, &action.Reason, &action.CreatedBy, &action.ExecutedAt,
+		&action.InstanceCount, &action.Error, &action.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	action.Selectors = []models.Selector{}
+	if len(selectorsJSON) > 0 {
+		if err := json.Unmarshal(selectorsJSON, &action.Selectors); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal selectors: %w", err)
+		}
+	}
+	return &action, nil
+}
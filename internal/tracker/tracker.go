@@ -0,0 +1,312 @@
+// Package tracker follows start/stop actions until the provider reports the target state
+
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/googleapi"
+
+	"snoozeql/internal/models"
+	"snoozeql/internal/provider"
+	"snoozeql/internal/store"
+)
+
+// Polling and retry timings; variables so tests can shorten them
+var (
+	// pollInterval is how often provider status is checked while an action is in flight
+	pollInterval = 15 * time.Second
+
+	// Transient errors are retried with exponential backoff starting at initialBackoff
+	initialBackoff = 2 * time.Second
+	maxBackoff     = 2 * time.Minute
+)
+
+const maxCallRetries = 4
+
+// Event types recorded when a tracked action finishes
+const (
+	EventActionCompleted = "action_completed"
+	EventActionFailed    = "action_failed"
+)
+
+// Tracker executes start/stop calls and follows them in the background until the
+// instance reaches the target state or the timeout expires
+type Tracker struct {
+	registry   *provider.Registry
+	eventStore *store.EventStore
+	timeout    time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewTracker creates a new action tracker
+// timeout is how long an action may take to reach its target state
+func NewTracker(registry *provider.Registry, eventStore *store.EventStore, timeout time.Duration) *Tracker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Tracker{
+		registry:   registry,
+		eventStore: eventStore,
+		timeout:    timeout,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// Stop cancels all in-flight tracking and waits for it to finish
+func (t *Tracker) Stop() {
+	t.cancel()
+	t.wg.Wait()
+}
+
+// Execute starts or stops the instance, retrying transient provider errors with backoff.
+// If the call succeeds the action is tracked in the background; if it fails an
+// action_failed event is recorded and the error returned. metadata is the metadata
+// of the event that triggered the action and is copied into the outcome event.
+func (t *Tracker) Execute(ctx context.Context, instance models.Instance, action, triggeredBy string, metadata []byte) error {
+	started := time.Now()
+
+	attempts, err := t.call(ctx, instance, action)
+	if err != nil {
+		t.recordOutcome(ctx, instance, action, triggeredBy, metadata, outcome{
+			started:  started,
+			attempts: attempts,
+			status:   instance.Status,
+			err:      err,
+		})
+		return err
+	}
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		t.track(instance, action, triggeredBy, metadata, started, attempts)
+	}()
+	return nil
+}
+
+// call issues the start/stop request, retrying transient errors
+// Returns the number of attempts made
+func (t *Tracker) call(ctx context.Context, instance models.Instance, action string) (int, error) {
+	backoff := initialBackoff
+	for attempt := 1; ; attempt++ {
+		var err error
+		switch action {
+		case "start":
+			err = t.registry.StartDatabase(ctx, instance.ProviderName, instance.ProviderID)
+		case "stop":
+			err = t.registry.StopDatabase(ctx, instance.ProviderName, instance.ProviderID)
+		default:
+			return attempt, fmt.Errorf("unknown action %s", action)
+		}
+
+		if err == nil {
+			return attempt, nil
+		}
+		if !IsTransient(err) || attempt > maxCallRetries {
+			return attempt, err
+		}
+
+		log.Printf("Transient error on %s of %s (attempt %d), retrying in %s: %v", action, instance.Name, attempt, backoff, err)
+		select {
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = nextBackoff(backoff)
+	}
+}
+
+// track polls provider status until the instance reaches the action's target state,
+// then records the outcome
+func (t *Tracker) track(instance models.Instance, action, triggeredBy string, metadata []byte, started time.Time, attempts int) {
+	ctx, cancel := context.WithTimeout(t.ctx, t.timeout)
+	defer cancel()
+
+	result := outcome{started: started, attempts: attempts}
+	result.status, result.err = t.wait(ctx, instance, action)
+	if result.err != nil && t.ctx.Err() != nil {
+		// Shutting down: the outcome is unknown, not failed
+		log.Printf("Stopped tracking %s of %s: shutting down", action, instance.Name)
+		return
+	}
+
+	t.recordOutcome(t.ctx, instance, action, triggeredBy, metadata, result)
+}
+
+// wait polls the provider until the target state is reached, the instance fails or ctx ends
+// Status errors are retried with backoff. Returns the last status seen.
+func (t *Tracker) wait(ctx context.Context, instance models.Instance, action string) (string, error) {
+	lastStatus := ""
+	var lastErr error
+	delay := pollInterval
+	for {
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return lastStatus, fmt.Errorf("timed out after %s (last error: %v)", t.timeout, lastErr)
+			}
+			return lastStatus, fmt.Errorf("timed out after %s waiting for %s (last status: %s)", t.timeout, TargetStatus(action), lastStatus)
+		case <-time.After(delay):
+		}
+
+		status, err := t.registry.GetDatabaseStatus(ctx, instance.ProviderName, instance.ProviderID)
+		if err != nil {
+			if !IsTransient(err) && ctx.Err() == nil {
+				return lastStatus, fmt.Errorf("failed to get status: %w", err)
+			}
+			lastErr = err
+			delay = nextBackoff(delay)
+			continue
+		}
+
+		lastStatus = status
+		lastErr = nil
+		delay = pollInterval
+
+		if ReachedTarget(status, action) {
+			return status, nil
+		}
+		if strings.EqualFold(status, "failed") {
+			return status, fmt.Errorf("instance entered status %s", status)
+		}
+	}
+}
+
+// outcome is the result of a tracked action
+type outcome struct {
+	started  time.Time
+	attempts int
+	status   string
+	err      error
+}
+
+// recordOutcome writes an action_completed or action_failed event with the duration
+// (and error) merged into the triggering event's metadata
+func (t *Tracker) recordOutcome(ctx context.Context, instance models.Instance, action, triggeredBy string, metadata []byte, result outcome) {
+	duration := time.Since(result.started)
+
+	fields := map[string]any{}
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &fields); err != nil {
+			fields = map[string]any{}
+		}
+	}
+	fields["action"] = action
+	fields["duration_seconds"] = int(duration.Seconds())
+	fields["attempts"] = result.attempts
+
+	eventType := EventActionCompleted
+	if result.err != nil {
+		eventType = EventActionFailed
+		fields["error"] = result.err.Error()
+		log.Printf("%s of %s failed after %s: %v", action, instance.Name, duration.Round(time.Second), result.err)
+	} else {
+		log.Printf("%s of %s completed in %s (%s)", action, instance.Name, duration.Round(time.Second), result.status)
+	}
+
+	if t.eventStore == nil {
+		return
+	}
+
+	newStatus := result.status
+	if newStatus == "" {
+		newStatus = instance.Status
+	}
+	eventMetadata, _ := json.Marshal(fields)
+	event := &models.Event{
+		InstanceID:     instance.ID,
+		EventType:      eventType,
+		TriggeredBy:    triggeredBy,
+		PreviousStatus: instance.Status,
+		NewStatus:      newStatus,
+		Metadata:       eventMetadata,
+	}
+	if err := t.eventStore.CreateEvent(ctx, event); err != nil {
+		log.Printf("Warning: Failed to create %s event for %s: %v", eventType, instance.Name, err)
+	}
+}
+
+// TargetStatus returns the steady status an action leads to
+func TargetStatus(action string) string {
+	if action == "start" {
+		return "available"
+	}
+	return "stopped"
+}
+
+// ReachedTarget reports whether a provider status is the steady state an action leads to
+func ReachedTarget(status, action string) bool {
+	switch strings.ToLower(status) {
+	case "available", "running", "runnable":
+		return action == "start"
+	case "stopped":
+		return action == "stop"
+	}
+	return false
+}
+
+// IsTransient reports whether a provider error is worth retrying:
+// throttling, server-side errors, timeouts and network failures
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	// AWS API errors expose an error code
+	var apiErr interface{ ErrorCode() string }
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "Throttling", "ThrottlingException", "RequestLimitExceeded", "TooManyRequestsException",
+			"RequestThrottled", "ServiceUnavailable", "InternalFailure", "InternalError":
+			return true
+		}
+	}
+
+	// AWS HTTP response errors expose the status code
+	var httpErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &httpErr) {
+		code := httpErr.HTTPStatusCode()
+		if code == 429 || code >= 500 {
+			return true
+		}
+	}
+
+	// Google API errors
+	var googleErr *googleapi.Error
+	if errors.As(err, &googleErr) {
+		return googleErr.Code == 429 || googleErr.Code >= 500
+	}
+
+	return false
+}
+
+// nextBackoff doubles the delay up to maxBackoff
+func nextBackoff(d time.Duration) time.Duration {
+	d *= 2
+	if d > maxBackoff {
+		return maxBackoff
+	}
+	return d
+}
@@ -0,0 +1,256 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+
+	"snoozeql/internal/models"
+	"snoozeql/internal/provider"
+)
+
+// fakeProvider answers start/stop calls with scripted errors and status checks with
+// scripted statuses
+type fakeProvider struct {
+	mu         sync.Mutex
+	errs       []error // Returned by successive start/stop calls, then nil
+	calls      int
+	statuses   []string // Returned by successive status checks; the last one repeats
+	statusErrs []error  // Returned by status checks before the statuses
+}
+
+func (f *fakeProvider) action(context.Context, string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if len(f.errs) == 0 {
+		return nil
+	}
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+	return err
+}
+
+func (f *fakeProvider) StartDatabase(ctx context.Context, id string) error { return f.action(ctx, id) }
+func (f *fakeProvider) StopDatabase(ctx context.Context, id string) error  { return f.action(ctx, id) }
+
+func (f *fakeProvider) GetDatabaseStatus(context.Context, string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.statusErrs) > 0 {
+		err := f.statusErrs[0]
+		f.statusErrs = f.statusErrs[1:]
+		return "", err
+	}
+	status := f.statuses[0]
+	if len(f.statuses) > 1 {
+		f.statuses = f.statuses[1:]
+	}
+	return status, nil
+}
+
+func (f *fakeProvider) ListDatabases(context.Context) ([]models.Instance, error) { return nil, nil }
+func (f *fakeProvider) GetMetrics(context.Context, string, string, string) (map[string]any, error) {
+	return nil, nil
+}
+func (f *fakeProvider) GetDatabaseByID(context.Context, string) (*models.Instance, error) {
+	return nil, nil
+}
+
+// awsError is an AWS API error code
+type awsError string
+
+func (e awsError) Error() string     { return string(e) }
+func (e awsError) ErrorCode() string { return string(e) }
+
+// httpError is an AWS HTTP response error
+type httpError int
+
+func (e httpError) Error() string       { return fmt.Sprintf("status %d", int(e)) }
+func (e httpError) HTTPStatusCode() int { return int(e) }
+
+// fastTimings shortens the poll and backoff intervals for the test
+func fastTimings(t *testing.T) {
+	t.Helper()
+	poll, initial, max := pollInterval, initialBackoff, maxBackoff
+	pollInterval, initialBackoff, maxBackoff = time.Millisecond, time.Millisecond, 4*time.Millisecond
+	t.Cleanup(func() { pollInterval, initialBackoff, maxBackoff = poll, initial, max })
+}
+
+func newTestTracker(fake *fakeProvider, timeout time.Duration) *Tracker {
+	registry := provider.NewRegistry()
+	registry.Register("aws_1_eu-west-1", fake)
+	return NewTracker(registry, nil, timeout)
+}
+
+var testInstance = models.Instance{ID: "i-1", Name: "orders", ProviderName: "aws_1_eu-west-1", ProviderID: "orders", Status: "available"}
+
+func TestCallRetries(t *testing.T) {
+	fastTimings(t)
+	throttled := awsError("ThrottlingException")
+
+	tests := []struct {
+		name         string
+		errs         []error
+		wantAttempts int
+		wantErr      error
+	}{
+		{name: "succeeds first time", wantAttempts: 1},
+		{name: "retries throttling", errs: []error{throttled, throttled}, wantAttempts: 3},
+		{name: "retries server errors", errs: []error{httpError(503), &googleapi.Error{Code: 500}}, wantAttempts: 3},
+		{name: "gives up after max retries", errs: []error{throttled, throttled, throttled, throttled, throttled, throttled}, wantAttempts: maxCallRetries + 1, wantErr: throttled},
+		{name: "permanent error isn't retried", errs: []error{awsError("InvalidDBInstanceState")}, wantAttempts: 1, wantErr: awsError("InvalidDBInstanceState")},
+		{name: "client error isn't retried", errs: []error{throttled, httpError(403)}, wantAttempts: 2, wantErr: httpError(403)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeProvider{errs: tt.errs}
+			tracker := newTestTracker(fake, time.Second)
+
+			attempts, err := tracker.call(context.Background(), testInstance, "stop")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts || fake.calls != tt.wantAttempts {
+				t.Errorf("attempts = %d (%d calls), want %d", attempts, fake.calls, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestCallStopsRetryingWhenCanceled(t *testing.T) {
+	fastTimings(t)
+	initialBackoff = time.Hour
+	fake := &fakeProvider{errs: []error{awsError("Throttling")}}
+	tracker := newTestTracker(fake, time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	attempts, err := tracker.call(ctx, testInstance, "start")
+	if !errors.Is(err, context.DeadlineExceeded) || attempts != 1 {
+		t.Errorf("attempts = %d, err = %v; want 1, the context's error", attempts, err)
+	}
+}
+
+func TestWait(t *testing.T) {
+	fastTimings(t)
+
+	tests := []struct {
+		name       string
+		action     string
+		statuses   []string
+		statusErrs []error
+		wantStatus string
+		wantErr    string
+	}{
+		{name: "reaches stopped", action: "stop", statuses: []string{"available", "stopping", "stopped"}, wantStatus: "stopped"},
+		{name: "reaches available", action: "start", statuses: []string{"starting", "available"}, wantStatus: "available"},
+		{name: "cloud sql runnable", action: "start", statuses: []string{"RUNNABLE"}, wantStatus: "RUNNABLE"},
+		{name: "transient status errors are retried", action: "stop", statusErrs: []error{httpError(500), &net.DNSError{IsTimeout: true}}, statuses: []string{"stopped"}, wantStatus: "stopped"},
+		{name: "permanent status error", action: "stop", statusErrs: []error{awsError("DBInstanceNotFound")}, statuses: []string{"stopped"}, wantErr: "failed to get status"},
+		{name: "instance fails", action: "start", statuses: []string{"starting", "failed"}, wantStatus: "failed", wantErr: "entered status failed"},
+		{name: "times out", action: "stop", statuses: []string{"stopping"}, wantStatus: "stopping", wantErr: "timed out"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeProvider{statuses: tt.statuses, statusErrs: tt.statusErrs}
+			tracker := newTestTracker(fake, 50*time.Millisecond)
+			ctx, cancel := context.WithTimeout(context.Background(), tracker.timeout)
+			defer cancel()
+
+			status, err := tracker.wait(ctx, testInstance, tt.action)
+			if status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", status, tt.wantStatus)
+			}
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecuteTracksInBackground(t *testing.T) {
+	fastTimings(t)
+	fake := &fakeProvider{statuses: []string{"stopping", "stopped"}}
+	tracker := newTestTracker(fake, time.Second)
+
+	if err := tracker.Execute(context.Background(), testInstance, "stop", "schedule", nil); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	tracker.wg.Wait()
+
+	fake.errs = []error{awsError("InvalidDBInstanceState")}
+	if err := tracker.Execute(context.Background(), testInstance, "stop", "schedule", nil); err == nil {
+		t.Error("Execute returned no error for a failed call")
+	}
+	tracker.Stop()
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil},
+		{name: "canceled", err: context.Canceled},
+		{name: "deadline", err: fmt.Errorf("call: %w", context.DeadlineExceeded), want: true},
+		{name: "network", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, want: true},
+		{name: "aws throttling", err: fmt.Errorf("stop: %w", awsError("RequestLimitExceeded")), want: true},
+		{name: "aws invalid state", err: awsError("InvalidDBClusterStateFault")},
+		{name: "http 429", err: httpError(429), want: true},
+		{name: "http 502", err: httpError(502), want: true},
+		{name: "http 400", err: httpError(400)},
+		{name: "google 503", err: &googleapi.Error{Code: 503}, want: true},
+		{name: "google 404", err: &googleapi.Error{Code: 404}},
+		{name: "plain", err: errors.New("boom")},
+	}
+	for _, tt := range tests {
+		if got := IsTransient(tt.err); got != tt.want {
+			t.Errorf("%s: IsTransient = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNextBackoff(t *testing.T) {
+	tests := []struct {
+		in, want time.Duration
+	}{
+		{2 * time.Second, 4 * time.Second},
+		{time.Minute, 2 * time.Minute},
+		{90 * time.Second, maxBackoff},
+		{maxBackoff, maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := nextBackoff(tt.in); got != tt.want {
+			t.Errorf("nextBackoff(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestReachedTarget(t *testing.T) {
+	tests := []struct {
+		status, action string
+		want           bool
+	}{
+		{"available", "start", true},
+		{"RUNNABLE", "start", true},
+		{"running", "start", true},
+		{"stopped", "start", false},
+		{"stopped", "stop", true},
+		{"stopping", "stop", false},
+		{"available", "stop", false},
+	}
+	for _, tt := range tests {
+		if got := ReachedTarget(tt.status, tt.action); got != tt.want {
+			t.Errorf("ReachedTarget(%q, %q) = %v, want %v", tt.status, tt.action, got, tt.want)
+		}
+	}
+}
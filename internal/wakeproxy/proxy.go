@@ -0,0 +1,400 @@
+// Package wakeproxy fronts managed databases with TCP listeners that wake a sleeping
+// instance when a client connects, then splice the connection through to it
+
+package wakeproxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"snoozeql/internal/models"
+	"snoozeql/internal/provider"
+	"snoozeql/internal/tracker"
+)
+
+// pollInterval is how often the instance status is checked while waking it; a
+// variable so tests can shorten it
+var pollInterval = 15 * time.Second
+
+const (
+	// statusCacheTTL is how long an instance seen awake is trusted without asking the provider
+	statusCacheTTL = 30 * time.Second
+	// dialTimeout bounds connecting to the upstream database
+	dialTimeout = 10 * time.Second
+)
+
+// InstanceReader looks up the instances routes refer to
+type InstanceReader interface {
+	GetInstanceByID(ctx context.Context, id string) (*models.Instance, error)
+	ListInstances(ctx context.Context) ([]models.Instance, error)
+}
+
+// EventCreator records the wake events of starts requested by the proxy
+type EventCreator interface {
+	CreateEvent(ctx context.Context, event *models.Event) error
+}
+
+// OverrideStore reads and creates the keep-alive overrides refreshed by traffic
+type OverrideStore interface {
+	ListOverridesByInstance(ctx context.Context, instanceID string, includeExpired bool) ([]models.Override, error)
+	CreateOverride(ctx context.Context, override *models.Override) error
+}
+
+// Proxy accepts client connections for its routes, wakes stopped instances and
+// splices connections through once they are available. Traffic on spliced
+// connections keeps the instance awake through a keep-alive override.
+type Proxy struct {
+	registry      *provider.Registry
+	instanceStore InstanceReader
+	eventStore    EventCreator
+	overrideStore OverrideStore
+	routes        []Route
+	wakeTimeout   time.Duration // Give up on a client once its instance has been waking this long
+	keepAlive     time.Duration // How long a keep-alive override lasts after the latest traffic
+
+	mu            sync.Mutex
+	wakes         map[string]*wake     // instanceID -> wake in progress
+	awake         map[string]time.Time // instanceID -> when it was last seen available
+	lastKeepAlive map[string]time.Time // instanceID -> when its keep-alive was last refreshed
+}
+
+// wake is a start in progress that concurrent connections to the instance wait on
+type wake struct {
+	done chan struct{}
+	err  error
+}
+
+// NewProxy creates a wake-on-connect proxy for the given routes
+func NewProxy(registry *provider.Registry, instanceStore InstanceReader, eventStore EventCreator, overrideStore OverrideStore, routes []Route, wakeTimeout, keepAlive time.Duration) *Proxy {
+	return &Proxy{
+		registry:      registry,
+		instanceStore: instanceStore,
+		eventStore:    eventStore,
+		overrideStore: overrideStore,
+		routes:        routes,
+		wakeTimeout:   wakeTimeout,
+		keepAlive:     keepAlive,
+		wakes:         make(map[string]*wake),
+		awake:         make(map[string]time.Time),
+		lastKeepAlive: make(map[string]time.Time),
+	}
+}
+
+// Run listens on every route and serves connections until ctx is cancelled
+func (p *Proxy) Run(ctx context.Context) error {
+	listeners := make([]net.Listener, 0, len(p.routes))
+	for _, route := range p.routes {
+		ln, err := net.Listen("tcp", route.Listen)
+		if err != nil {
+			for _, open := range listeners {
+				open.Close()
+			}
+			return fmt.Errorf("failed to listen on %s: %w", route.Listen, err)
+		}
+		listeners = append(listeners, ln)
+		log.Printf("✓ Wake proxy listening on %s for %s (upstream %s)", route.Listen, route.Instance, route.Upstream)
+	}
+
+	var wg sync.WaitGroup
+	for i, ln := range listeners {
+		wg.Add(1)
+		go func(ln net.Listener, route Route) {
+			defer wg.Done()
+			p.serve(ctx, ln, route)
+		}(ln, p.routes[i])
+	}
+
+	<-ctx.Done()
+	for _, ln := range listeners {
+		ln.Close()
+	}
+	wg.Wait()
+	return nil
+}
+
+// serve accepts connections on one route's listener until it is closed
+func (p *Proxy) serve(ctx context.Context, ln net.Listener, route Route) {
+	var conns sync.WaitGroup
+	defer conns.Wait()
+
+	for {
+		client, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Printf("Warning: Accept on %s failed: %v", route.Listen, err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		conns.Add(1)
+		go func() {
+			defer conns.Done()
+			p.handle(ctx, client, route)
+		}()
+	}
+}
+
+// handle holds a client until its instance is available, then splices it through
+func (p *Proxy) handle(ctx context.Context, client net.Conn, route Route) {
+	defer client.Close()
+
+	instance, err := p.resolve(ctx, route.Instance)
+	if err != nil {
+		log.Printf("Warning: Rejecting connection from %s: %v", client.RemoteAddr(), err)
+		return
+	}
+
+	// The client isn't read from until the instance is up, so it just sees a slow connect
+	if err := p.ensureAwake(ctx, *instance); err != nil {
+		log.Printf("Warning: Closing connection from %s to %s: %v", client.RemoteAddr(), instance.Name, err)
+		return
+	}
+
+	upstream, err := net.DialTimeout("tcp", route.Upstream, dialTimeout)
+	if err != nil {
+		log.Printf("Warning: Failed to connect to %s (%s): %v", instance.Name, route.Upstream, err)
+		return
+	}
+	defer upstream.Close()
+
+	p.reportActivity(ctx, *instance)
+	p.splice(ctx, client, upstream, *instance)
+}
+
+// resolve looks an instance up by ID, falling back to its name
+func (p *Proxy) resolve(ctx context.Context, ref string) (*models.Instance, error) {
+	if instance, err := p.instanceStore.GetInstanceByID(ctx, ref); err == nil {
+		return instance, nil
+	}
+
+	instances, err := p.instanceStore.ListInstances(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list instances: %w", err)
+	}
+	for i := range instances {
+		if instances[i].Name == ref {
+			return &instances[i], nil
+		}
+	}
+	return nil, fmt.Errorf("instance %s not found", ref)
+}
+
+// ensureAwake returns once the instance is available, starting it if needed.
+// Connections arriving while a start is in progress wait on the same start.
+func (p *Proxy) ensureAwake(ctx context.Context, instance models.Instance) error {
+	p.mu.Lock()
+	if seen, ok := p.awake[instance.ID]; ok && time.Since(seen) < statusCacheTTL {
+		p.mu.Unlock()
+		return nil
+	}
+	w, ok := p.wakes[instance.ID]
+	if !ok {
+		w = &wake{done: make(chan struct{})}
+		p.wakes[instance.ID] = w
+		go func() {
+			w.err = p.wake(ctx, instance)
+			p.mu.Lock()
+			delete(p.wakes, instance.ID)
+			if w.err == nil {
+				p.awake[instance.ID] = time.Now()
+			}
+			p.mu.Unlock()
+			close(w.done)
+		}()
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return w.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// wake polls the instance's provider status, calling StartDatabase once it is
+// stopped, until the instance is available or the wake timeout passes
+func (p *Proxy) wake(ctx context.Context, instance models.Instance) error {
+	prov, err := p.registry.Get(instance.ProviderName)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(p.wakeTimeout)
+	started, recorded := false, false
+	for {
+		status, err := prov.GetDatabaseStatus(ctx, instance.ProviderID)
+		if err != nil && !tracker.IsTransient(err) {
+			return fmt.Errorf("failed to get status: %w", err)
+		}
+		if err == nil {
+			if tracker.ReachedTarget(status, "start") {
+				if started {
+					log.Printf("%s is available, splicing waiting connections", instance.Name)
+				}
+				return nil
+			}
+			// A stopping instance has to finish stopping before it can be started
+			if !started && strings.EqualFold(status, "stopped") {
+				if !recorded {
+					p.recordWake(ctx, instance, status)
+					recorded = true
+				}
+				if err := prov.StartDatabase(ctx, instance.ProviderID); err != nil {
+					if !tracker.IsTransient(err) {
+						return fmt.Errorf("failed to start: %w", err)
+					}
+					log.Printf("Warning: Transient error starting %s, retrying: %v", instance.Name, err)
+				} else {
+					started = true
+					log.Printf("Waking %s for incoming connection", instance.Name)
+				}
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("not available after %s (status %s)", p.wakeTimeout, status)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// recordWake logs a wake event for a start requested by the proxy
+func (p *Proxy) recordWake(ctx context.Context, instance models.Instance, status string) {
+	if p.eventStore == nil {
+		return
+	}
+	metadata, _ := json.Marshal(map[string]any{"reason": "incoming connection"})
+	event := &models.Event{
+		InstanceID:     instance.ID,
+		EventType:      "wake",
+		TriggeredBy:    "wakeproxy",
+		PreviousStatus: status,
+		NewStatus:      "starting",
+		Metadata:       metadata,
+	}
+	if err := p.eventStore.CreateEvent(ctx, event); err != nil {
+		log.Printf("Warning: Failed to create wake event for %s: %v", instance.Name, err)
+	}
+}
+
+// splice copies data both ways until either side closes, refreshing the instance's
+// keep-alive while traffic flows. Open connections without traffic (e.g. idle pool
+// connections) don't keep the instance awake.
+func (p *Proxy) splice(ctx context.Context, client, upstream net.Conn, instance models.Instance) {
+	var lastTraffic atomic.Int64
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(&activityWriter{w: upstream, last: &lastTraffic}, client)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(&activityWriter{w: client, last: &lastTraffic}, upstream)
+		done <- struct{}{}
+	}()
+
+	// Without a keep-alive period there is no activity to report
+	var ticks <-chan time.Time
+	if p.keepAlive > 0 {
+		ticker := time.NewTicker(p.keepAlive / 3)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+	reported := time.Now()
+
+loop:
+	for {
+		select {
+		case <-ticks:
+			if lastTraffic.Load() > reported.UnixNano() {
+				p.reportActivity(ctx, instance)
+				reported = time.Now()
+			}
+		case <-done:
+			break loop
+		case <-ctx.Done():
+			break loop
+		}
+	}
+
+	// Closing both sides unblocks whichever copy is still running
+	client.Close()
+	upstream.Close()
+	if lastTraffic.Load() > reported.UnixNano() {
+		p.reportActivity(context.WithoutCancel(ctx), instance)
+	}
+}
+
+// reportActivity makes sure a keep-alive override covers the instance for at least
+// half the keep-alive period, creating a fresh one (created_by "wakeproxy") if not
+func (p *Proxy) reportActivity(ctx context.Context, instance models.Instance) {
+	if p.overrideStore == nil || p.keepAlive <= 0 {
+		return
+	}
+
+	now := time.Now()
+	p.mu.Lock()
+	if last, ok := p.lastKeepAlive[instance.ID]; ok && now.Sub(last) < p.keepAlive/3 {
+		p.mu.Unlock()
+		return
+	}
+	p.lastKeepAlive[instance.ID] = now
+	p.mu.Unlock()
+
+	active, err := p.overrideStore.ListOverridesByInstance(ctx, instance.ID, false)
+	if err != nil {
+		log.Printf("Warning: Failed to list overrides for %s: %v", instance.Name, err)
+		return
+	}
+	for _, override := range active {
+		if override.Type != models.OverrideKeepAlive || !override.IsActive(now) {
+			continue
+		}
+		// Never shorten a longer keep-alive (e.g. one set by a person)
+		if override.UntilTime == nil || override.UntilTime.After(now.Add(p.keepAlive/2)) {
+			return
+		}
+	}
+
+	until := now.Add(p.keepAlive)
+	reason := "connection activity via wake proxy"
+	override := &models.Override{
+		InstanceID: instance.ID,
+		Type:       models.OverrideKeepAlive,
+		UntilTime:  &until,
+		Reason:     &reason,
+		CreatedBy:  "wakeproxy",
+	}
+	if err := p.overrideStore.CreateOverride(ctx, override); err != nil {
+		log.Printf("Warning: Failed to refresh keep-alive for %s: %v", instance.Name, err)
+	}
+}
+
+// activityWriter records when data was last written through it
+type activityWriter struct {
+	w    io.Writer
+	last *atomic.Int64
+}
+
+func (a *activityWriter) Write(b []byte) (int, error) {
+	n, err := a.w.Write(b)
+	if n > 0 {
+		a.last.Store(time.Now().UnixNano())
+	}
+	return n, err
+}
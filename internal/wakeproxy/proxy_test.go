@@ -0,0 +1,312 @@
+package wakeproxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"snoozeql/internal/models"
+	"snoozeql/internal/provider"
+)
+
+func TestSpliceWithoutKeepAlive(t *testing.T) {
+	p := NewProxy(nil, nil, nil, nil, nil, time.Minute, 0)
+	client, clientEnd := net.Pipe()
+	upstream, upstreamEnd := net.Pipe()
+
+	done := make(chan struct{})
+	go func() {
+		p.splice(context.Background(), clientEnd, upstreamEnd, models.Instance{ID: "i-1", Name: "orders"})
+		close(done)
+	}()
+
+	go client.Write([]byte("ping"))
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(upstream, buf); err != nil || string(buf) != "ping" {
+		t.Fatalf("upstream read %q, %v", buf, err)
+	}
+	client.Close()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("splice didn't return after the client closed")
+	}
+	upstream.Close()
+}
+
+// fakeProvider reports the queued statuses one poll at a time, repeating the last
+// one. Starting it replaces the queue with afterStart.
+type fakeProvider struct {
+	mu         sync.Mutex
+	statuses   []string
+	afterStart []string
+	startErr   error
+	starts     int
+	polls      int
+}
+
+func (p *fakeProvider) GetDatabaseStatus(ctx context.Context, id string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.polls++
+	status := p.statuses[0]
+	if len(p.statuses) > 1 {
+		p.statuses = p.statuses[1:]
+	}
+	return status, nil
+}
+func (p *fakeProvider) StartDatabase(ctx context.Context, id string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.starts++
+	if p.startErr != nil {
+		return p.startErr
+	}
+	p.statuses = p.afterStart
+	return nil
+}
+func (p *fakeProvider) StopDatabase(context.Context, string) error {
+	return nil
+}
+func (p *fakeProvider) ListDatabases(context.Context) ([]models.Instance, error) {
+	return nil, nil
+}
+func (p *fakeProvider) GetMetrics(context.Context, string, string, string) (map[string]any, error) {
+	return nil, nil
+}
+func (p *fakeProvider) GetDatabaseByID(context.Context, string) (*models.Instance, error) {
+	return nil, nil
+}
+
+// fakeEvents records the events created by the proxy
+type fakeEvents struct {
+	mu     sync.Mutex
+	events []models.Event
+}
+
+func (f *fakeEvents) CreateEvent(ctx context.Context, event *models.Event) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, *event)
+	return nil
+}
+
+// fakeOverrides serves the given overrides and records the ones created
+type fakeOverrides struct {
+	active  []models.Override
+	created []models.Override
+}
+
+func (f *fakeOverrides) ListOverridesByInstance(ctx context.Context, instanceID string, includeExpired bool) ([]models.Override, error) {
+	return f.active, nil
+}
+func (f *fakeOverrides) CreateOverride(ctx context.Context, override *models.Override) error {
+	f.created = append(f.created, *override)
+	return nil
+}
+
+var proxiedInstance = models.Instance{ID: "i-1", Name: "orders", ProviderID: "orders", ProviderName: "aws_1_eu-west-1"}
+
+// newWakingProxy returns a proxy waking instances through the given provider, polling
+// every millisecond
+func newWakingProxy(t *testing.T, prov *fakeProvider, wakeTimeout time.Duration) (*Proxy, *fakeEvents) {
+	t.Helper()
+	interval := pollInterval
+	pollInterval = time.Millisecond
+	t.Cleanup(func() { pollInterval = interval })
+
+	registry := provider.NewRegistry()
+	registry.Register(proxiedInstance.ProviderName, prov)
+	events := &fakeEvents{}
+	return NewProxy(registry, nil, events, nil, nil, wakeTimeout, 0), events
+}
+
+func TestEnsureAwake(t *testing.T) {
+	tests := []struct {
+		name       string
+		prov       *fakeProvider
+		wantStarts int
+		wantWakes  int
+		wantErr    string
+	}{
+		{
+			name: "available instance isn't started",
+			prov: &fakeProvider{statuses: []string{"available"}},
+		},
+		{
+			name:       "stopped instance is started",
+			prov:       &fakeProvider{statuses: []string{"stopped"}, afterStart: []string{"starting", "starting", "available"}},
+			wantStarts: 1,
+			wantWakes:  1,
+		},
+		{
+			name:       "stopping instance is started once stopped",
+			prov:       &fakeProvider{statuses: []string{"stopping", "stopping", "stopped"}, afterStart: []string{"starting", "available"}},
+			wantStarts: 1,
+			wantWakes:  1,
+		},
+		{
+			name:       "instance not available in time",
+			prov:       &fakeProvider{statuses: []string{"stopped"}, afterStart: []string{"starting"}},
+			wantStarts: 1,
+			wantWakes:  1,
+			wantErr:    "not available after",
+		},
+		{
+			name:       "start fails",
+			prov:       &fakeProvider{statuses: []string{"stopped"}, startErr: errors.New("access denied")},
+			wantStarts: 1,
+			wantWakes:  1,
+			wantErr:    "failed to start: access denied",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, events := newWakingProxy(t, tt.prov, 50*time.Millisecond)
+
+			err := p.ensureAwake(context.Background(), proxiedInstance)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ensureAwake error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("ensureAwake: %v", err)
+			}
+
+			if tt.prov.starts != tt.wantStarts {
+				t.Errorf("started %d times, want %d", tt.prov.starts, tt.wantStarts)
+			}
+			if len(events.events) != tt.wantWakes {
+				t.Fatalf("recorded %d wake events, want %d", len(events.events), tt.wantWakes)
+			}
+			if tt.wantWakes > 0 {
+				if event := events.events[0]; event.EventType != "wake" || event.PreviousStatus != "stopped" || event.TriggeredBy != "wakeproxy" {
+					t.Errorf("wake event = %+v", event)
+				}
+			}
+		})
+	}
+}
+
+func TestEnsureAwakeSharesOneWake(t *testing.T) {
+	prov := &fakeProvider{statuses: []string{"stopped"}, afterStart: []string{"starting", "starting", "available"}}
+	p, _ := newWakingProxy(t, prov, time.Minute)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- p.ensureAwake(context.Background(), proxiedInstance)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("ensureAwake: %v", err)
+		}
+	}
+	if prov.starts != 1 {
+		t.Errorf("started %d times for concurrent connections, want 1", prov.starts)
+	}
+
+	// An instance just seen awake isn't polled again
+	polls := prov.polls
+	if err := p.ensureAwake(context.Background(), proxiedInstance); err != nil {
+		t.Fatalf("ensureAwake: %v", err)
+	}
+	if prov.polls != polls {
+		t.Errorf("polled %d more times for an instance seen awake, want 0", prov.polls-polls)
+	}
+}
+
+func TestReportActivity(t *testing.T) {
+	const keepAlive = 30 * time.Minute
+	now := time.Now()
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	tests := []struct {
+		name        string
+		keepAlive   time.Duration
+		active      []models.Override
+		wantCreated bool
+	}{
+		{
+			name:        "no keep-alive",
+			keepAlive:   keepAlive,
+			wantCreated: true,
+		},
+		{
+			name:      "keep-alive disabled",
+			keepAlive: 0,
+		},
+		{
+			name:      "longer keep-alive",
+			keepAlive: keepAlive,
+			active:    []models.Override{{Type: models.OverrideKeepAlive, UntilTime: at(2 * time.Hour), CreatedBy: "alice"}},
+		},
+		{
+			name:      "keep-alive without an end",
+			keepAlive: keepAlive,
+			active:    []models.Override{{Type: models.OverrideKeepAlive}},
+		},
+		{
+			name:        "keep-alive ending within half the period",
+			keepAlive:   keepAlive,
+			active:      []models.Override{{Type: models.OverrideKeepAlive, UntilTime: at(10 * time.Minute), CreatedBy: "wakeproxy"}},
+			wantCreated: true,
+		},
+		{
+			name:        "expired keep-alive",
+			keepAlive:   keepAlive,
+			active:      []models.Override{{Type: models.OverrideKeepAlive, Expired: true}},
+			wantCreated: true,
+		},
+		{
+			name:        "other override",
+			keepAlive:   keepAlive,
+			active:      []models.Override{{Type: models.OverrideSkipNext}},
+			wantCreated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			overrides := &fakeOverrides{active: tt.active}
+			p := NewProxy(nil, nil, nil, overrides, nil, time.Minute, tt.keepAlive)
+
+			p.reportActivity(context.Background(), proxiedInstance)
+			if created := len(overrides.created) > 0; created != tt.wantCreated {
+				t.Fatalf("keep-alive created = %v, want %v", created, tt.wantCreated)
+			}
+			if !tt.wantCreated {
+				return
+			}
+			override := overrides.created[0]
+			if override.Type != models.OverrideKeepAlive || override.CreatedBy != "wakeproxy" || override.InstanceID != proxiedInstance.ID {
+				t.Errorf("created override = %+v", override)
+			}
+			if override.UntilTime == nil || override.UntilTime.Sub(now) < keepAlive {
+				t.Errorf("keep-alive until %v, want %s from now", override.UntilTime, keepAlive)
+			}
+
+			// Traffic right after a refresh doesn't refresh again
+			p.reportActivity(context.Background(), proxiedInstance)
+			if len(overrides.created) != 1 {
+				t.Errorf("created %d keep-alives for back-to-back activity, want 1", len(overrides.created))
+			}
+		})
+	}
+}
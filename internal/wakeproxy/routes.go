@@ -0,0 +1,62 @@
+package wakeproxy
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Route fronts one managed instance: connections to Listen are spliced to Upstream
+// once the instance is awake
+type Route struct {
+	Listen   string // Local address to accept connections on, e.g. ":5432"
+	Instance string // SnoozeQL instance ID or name
+	Upstream string // Database endpoint, e.g. "dev-orders.abc123.us-east-1.rds.amazonaws.com:5432"
+}
+
+// ParseRoutes parses a comma-separated route list of the form
+// "listen=instance@upstream", e.g. ":6432=dev-orders@dev-orders.abc123.rds.amazonaws.com:5432"
+func ParseRoutes(spec string) ([]Route, error) {
+	var routes []Route
+	listens := make(map[string]bool)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		listen, target, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("route %q: expected listen=instance@upstream", entry)
+		}
+		instance, upstream, ok := strings.Cut(target, "@")
+		if !ok {
+			return nil, fmt.Errorf("route %q: expected listen=instance@upstream", entry)
+		}
+
+		route := Route{
+			Listen:   strings.TrimSpace(listen),
+			Instance: strings.TrimSpace(instance),
+			Upstream: strings.TrimSpace(upstream),
+		}
+		if route.Instance == "" {
+			return nil, fmt.Errorf("route %q: instance is required", entry)
+		}
+		if _, _, err := net.SplitHostPort(route.Listen); err != nil {
+			return nil, fmt.Errorf("route %q: invalid listen address: %w", entry, err)
+		}
+		if _, _, err := net.SplitHostPort(route.Upstream); err != nil {
+			return nil, fmt.Errorf("route %q: invalid upstream address: %w", entry, err)
+		}
+		if listens[route.Listen] {
+			return nil, fmt.Errorf("route %q: listen address %s used twice", entry, route.Listen)
+		}
+		listens[route.Listen] = true
+		routes = append(routes, route)
+	}
+
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("no routes configured")
+	}
+	return routes, nil
+}
@@ -0,0 +1,83 @@
+package wakeproxy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRoutes(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    []Route
+		wantErr string
+	}{
+		{
+			name: "single route",
+			spec: ":6432=dev-orders@dev-orders.abc123.rds.amazonaws.com:5432",
+			want: []Route{{Listen: ":6432", Instance: "dev-orders", Upstream: "dev-orders.abc123.rds.amazonaws.com:5432"}},
+		},
+		{
+			name: "several routes with spaces and empty entries",
+			spec: " :6432 = dev-orders @ orders.internal:5432 ,, 127.0.0.1:3307=i-123@billing.internal:3306,",
+			want: []Route{
+				{Listen: ":6432", Instance: "dev-orders", Upstream: "orders.internal:5432"},
+				{Listen: "127.0.0.1:3307", Instance: "i-123", Upstream: "billing.internal:3306"},
+			},
+		},
+		{
+			name:    "no routes",
+			spec:    " , ",
+			wantErr: "no routes configured",
+		},
+		{
+			name:    "missing upstream",
+			spec:    ":6432=dev-orders",
+			wantErr: "expected listen=instance@upstream",
+		},
+		{
+			name:    "missing listen address",
+			spec:    "dev-orders@orders.internal:5432",
+			wantErr: "expected listen=instance@upstream",
+		},
+		{
+			name:    "missing instance",
+			spec:    ":6432=@orders.internal:5432",
+			wantErr: "instance is required",
+		},
+		{
+			name:    "listen address without port",
+			spec:    "localhost=dev-orders@orders.internal:5432",
+			wantErr: "invalid listen address",
+		},
+		{
+			name:    "upstream without port",
+			spec:    ":6432=dev-orders@orders.internal",
+			wantErr: "invalid upstream address",
+		},
+		{
+			name:    "listen address used twice",
+			spec:    ":6432=dev-orders@orders.internal:5432,:6432=billing@billing.internal:5432",
+			wantErr: "listen address :6432 used twice",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes, err := ParseRoutes(tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseRoutes(%q) error = %v, want %q", tt.spec, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRoutes(%q): %v", tt.spec, err)
+			}
+			if !reflect.DeepEqual(routes, tt.want) {
+				t.Errorf("ParseRoutes(%q) = %+v, want %+v", tt.spec, routes, tt.want)
+			}
+		})
+	}
+}
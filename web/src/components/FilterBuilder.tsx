@@ -1,46 +1,61 @@
-import { useState, useEffect, useMemo } from 'react';
+import { useState, useEffect } from 'react';
 import { Plus, Filter } from 'lucide-react';
 import type { Instance, Selector } from '../lib/api';
 import api from '../lib/api';
 import { FilterRule } from './FilterRule';
 import { FilterPreview } from './FilterPreview';
-import { matchInstance, createEmptySelector } from '../lib/filterUtils';
+import { createEmptySelector } from '../lib/filterUtils';
+
+// Delay before previewing edited rules, so typing a pattern doesn't send a request per key
+const PREVIEW_DEBOUNCE_MS = 300;
 
 interface FilterBuilderProps {
   selectors: Selector[];
   onChange: (selectors: Selector[]) => void;
-  /** Optional: pre-fetched instances for client-side preview */
-  instances?: Instance[];
+  /** How selectors combine; 'or' (the schedule default) if not given */
+  operator?: 'and' | 'or';
+  onOperatorChange?: (operator: 'and' | 'or') => void;
+  /** The schedule's selector expression and exclusions, previewed along with the rules */
+  expression?: string;
+  exclusions?: Selector[];
 }
 
-export function FilterBuilder({ selectors, onChange, instances: propInstances }: FilterBuilderProps) {
-  const [operator, setOperator] = useState<'and' | 'or'>('and');
-  const [instances, setInstances] = useState<Instance[]>(propInstances || []);
-  const [loading, setLoading] = useState(!propInstances);
+export function FilterBuilder({
+  selectors,
+  onChange,
+  operator: propOperator,
+  onOperatorChange,
+  expression,
+  exclusions,
+}: FilterBuilderProps) {
+  const [localOperator, setLocalOperator] = useState<'and' | 'or'>('or');
+  const operator = propOperator ?? localOperator;
+  const setOperator = onOperatorChange ?? setLocalOperator;
+  const [matchedInstances, setMatchedInstances] = useState<Instance[]>([]);
+  const [totalInstances, setTotalInstances] = useState(0);
+  const [loading, setLoading] = useState(true);
 
-  // Fetch instances if not provided
+  // Preview the matches with the server, which selects instances exactly as the
+  // scheduler does. A rule that doesn't validate yet keeps the last preview.
   useEffect(() => {
-    if (!propInstances) {
-      setLoading(true);
-      api.getInstances()
-        .then(setInstances)
+    let cancelled = false;
+    const timer = setTimeout(() => {
+      api.previewFilter(selectors, operator, { expression, exclusions })
+        .then((preview) => {
+          if (cancelled) return;
+          setMatchedInstances(preview.instances ?? []);
+          setTotalInstances(preview.total_count);
+        })
         .catch(console.error)
-        .finally(() => setLoading(false));
-    }
-  }, [propInstances]);
-
-  // Update instances if props change
-  useEffect(() => {
-    if (propInstances) {
-      setInstances(propInstances);
-    }
-  }, [propInstances]);
-
-  // Compute matched instances client-side for instant preview
-  const matchedInstances = useMemo(() => {
-    if (selectors.length === 0) return [];
-    return instances.filter((instance) => matchInstance(instance, selectors, operator));
-  }, [instances, selectors, operator]);
+        .finally(() => {
+          if (!cancelled) setLoading(false);
+        });
+    }, PREVIEW_DEBOUNCE_MS);
+    return () => {
+      cancelled = true;
+      clearTimeout(timer);
+    };
+  }, [selectors, operator, expression, exclusions]);
 
   const addRule = () => {
     onChange([...selectors, createEmptySelector()]);
@@ -137,7 +152,7 @@ export function FilterBuilder({ selectors, onChange, instances: propInstances }:
       {/* Preview panel */}
       <FilterPreview
         matchedInstances={matchedInstances}
-        totalInstances={instances.length}
+        totalInstances={totalInstances}
         loading={loading}
       />
     </div>
@@ -4,7 +4,7 @@ import { X } from 'lucide-react';
 import clsx from 'clsx';
 import { describeCron } from '../lib/cronUtils';
 import api from '../lib/api';
-import { Schedule, Instance, Selector } from '../lib/api';
+import { Schedule, Selector } from '../lib/api';
 import { FilterBuilder } from './FilterBuilder';
 
 interface ScheduleModalProps {
@@ -38,7 +38,7 @@ export function ScheduleModal({
   const [sleepCron, setSleepCron] = useState('');
   const [wakeCron, setWakeCron] = useState('');
   const [selectors, setSelectors] = useState<Selector[]>([]);
-  const [instances, setInstances] = useState<Instance[]>([]);
+  const [operator, setOperator] = useState<'and' | 'or'>('or');
   const [loading, setLoading] = useState(false);
   const [error, setError] = useState<string | null>(null);
   const [nameError, setNameError] = useState('');
@@ -54,6 +54,7 @@ export function ScheduleModal({
         setSleepCron(schedule.sleep_cron);
         setWakeCron(schedule.wake_cron);
         setSelectors(schedule.selectors || []);
+        setOperator(schedule.operator ?? 'or');
       } else {
         // Create mode: reset form with sensible defaults
         setName('');
@@ -62,21 +63,13 @@ export function ScheduleModal({
         setSleepCron('0 22 * * 1-5'); // Default: 10pm weekdays
         setWakeCron('0 7 * * 1-5');   // Default: 7am weekdays
         setSelectors([]);
+        setOperator('or');
         setNameError('');
       }
       setError(null);
     }
   }, [isOpen, schedule]);
 
-  // Fetch instances for preview
-  useEffect(() => {
-    if (isOpen) {
-      api.getInstances()
-        .then(setInstances)
-        .catch(console.error);
-    }
-  }, [isOpen]);
-
   // Validate form
   const validateForm = (): boolean => {
     if (!name.trim()) {
@@ -116,12 +109,15 @@ export function ScheduleModal({
         sleep_cron: sleepCron,
         wake_cron: wakeCron,
         selectors,
+        operator,
         enabled: true,
       };
 
       if (schedule) {
-        // Update existing schedule
-        await api.updateSchedule(schedule.id, scheduleData);
+        // Update existing schedule, sending back the fields this form doesn't edit
+        // (exceptions, ordering, budget, ...) so the update keeps them
+        const { id, created_at, updated_at, ...stored } = schedule;
+        await api.updateSchedule(id, { ...stored, ...scheduleData, enabled: schedule.enabled });
       } else {
         // Create new schedule
         await api.createSchedule(scheduleData);
@@ -282,7 +278,10 @@ export function ScheduleModal({
               <FilterBuilder
                 selectors={selectors}
                 onChange={setSelectors}
-                instances={instances}
+                operator={operator}
+                onOperatorChange={setOperator}
+                expression={schedule?.expression}
+                exclusions={schedule?.exclusions}
               />
             </div>
 
@@ -31,10 +31,17 @@ export interface Schedule {
   name: string
   description: string
   selectors: Selector[]
+  operator?: 'and' | 'or'
+  expression?: string
+  exclusions?: Selector[]
   timezone: string
   sleep_cron: string
   wake_cron: string
   enabled: boolean
+  exceptions?: { calendar_id: string; mode: string }[]
+  ordering?: unknown
+  budget?: { daily_hours?: number; weekly_hours?: number }
+  priority?: number
   created_at: string
   updated_at: string
 }
@@ -236,11 +243,15 @@ const api = {
   updateSchedule: (id: string, data: Partial<Schedule>) => api.put<Schedule>(`/schedules/${id}`, data),
   deleteSchedule: (id: string) => api.del(`/schedules/${id}`),
   
-  // Schedule filter preview
-  previewFilter: (selectors: Selector[], operator: 'and' | 'or' = 'and') =>
-    api.post<{ matched_count: number; total_count: number; instances: Instance[] }>(
+  // Schedule filter preview, selecting instances exactly as the scheduler does
+  previewFilter: (
+    selectors: Selector[],
+    operator: 'and' | 'or' = 'or',
+    options: { expression?: string; exclusions?: Selector[] } = {}
+  ) =>
+    api.post<{ matched_count: number; total_count: number; instances: Instance[] | null }>(
       '/schedules/preview-filter',
-      { selectors, operator }
+      { selectors, operator, ...options }
     ),
 
   // Recommendations
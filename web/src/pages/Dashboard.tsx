@@ -16,6 +16,7 @@ const Dashboard = () => {
   const [groups, setGroups] = useState<RecommendationGroup[]>([])
   const [cloudAccounts, setCloudAccounts] = useState<CloudAccount[]>([])
   const [events, setEvents] = useState<Event[]>([])
+  const [pendingActions, setPendingActions] = useState(0)
   const [selectedRecommendation, setSelectedRecommendation] = useState<RecommendationEnriched | null>(null)
   const [modalOpen, setModalOpen] = useState(false)
   const [confirmLoading, setConfirmLoading] = useState(false)
@@ -23,16 +24,18 @@ const Dashboard = () => {
   useEffect(() => {
     const fetchData = async () => {
       try {
-        const [instancesData, recommendationsResponse, accountsData, eventsData] = await Promise.all([
+        const [instancesData, recommendationsResponse, accountsData, eventsData, stats] = await Promise.all([
           api.getInstances(),
           api.getRecommendations('pending'),
           api.getCloudAccounts(),
-          api.getEvents(100, 0) // Get more events for cost history
+          api.getEvents(100, 0), // Get more events for cost history
+          api.getStats()
         ])
         setInstances(instancesData || [])
         setGroups(recommendationsResponse?.groups || [])
         setCloudAccounts(accountsData || [])
         setEvents(eventsData || [])
+        setPendingActions(stats?.pending_actions || 0)
       } catch (err) {
         console.error(err)
         setInstances([])
@@ -61,7 +64,7 @@ const Dashboard = () => {
     .reduce((sum, inst) => sum + (inst.hourly_cost_cents / 100) * 24, 0)
   const runningCount = filteredInstances.filter(i => i.status === 'available' || i.status === 'running' || i.status === 'starting').length
   const sleepingCount = filteredInstances.filter(i => i.status === 'stopped' || i.status === 'stopping').length
-  const pendingActions = groups.reduce((sum, g) => sum + g.instance_count, 0)
+  const pendingRecommendations = groups.reduce((sum, g) => sum + g.instance_count, 0)
   
   // Create instance name lookup map for events
   const instanceNameMap = useMemo(() => 
@@ -198,7 +201,7 @@ const Dashboard = () => {
         </div>
         <div 
           className="bg-slate-800/50 rounded-xl p-5 shadow-lg border border-slate-700 hover:border-yellow-500/50 transition-all group cursor-pointer"
-          onClick={() => navigate('/recommendations')}
+          onClick={() => navigate('/schedules')}
         >
           <div className="flex items-center justify-between mb-3">
             <p className="text-sm text-slate-400 font-medium">Pending Actions</p>
@@ -207,7 +210,7 @@ const Dashboard = () => {
             </div>
           </div>
           <p className="text-3xl font-bold text-white mb-1">{pendingActions}</p>
-          <p className="text-sm text-yellow-400">Needs attention</p>
+          <p className="text-sm text-yellow-400">Scheduled in the next 24h</p>
         </div>
       </div>
 
@@ -309,7 +312,7 @@ const Dashboard = () => {
       <div className="bg-slate-800/50 rounded-xl p-6 shadow-lg border border-slate-700">
         <h2 className="text-lg font-semibold text-white mb-4">AI Recommendations</h2>
         
-        {pendingActions > 0 ? (
+        {pendingRecommendations > 0 ? (
           <div className="space-y-4">
             {/* Show first group's recommendations (up to 3) */}
             {groups.length > 0 ? groups[0].recommendations.slice(0, 3).map((rec: RecommendationEnriched) => (
@@ -12,6 +12,7 @@ const ScheduleEditPage = () => {
   const [saving, setSaving] = useState(false)
   const [error, setError] = useState<string | null>(null)
   const [success, setSuccess] = useState(false)
+  const [stored, setStored] = useState<Schedule | null>(null)
 
   const [form, setForm] = useState({
     name: '',
@@ -32,6 +33,7 @@ const ScheduleEditPage = () => {
       
       try {
         const data: Schedule = await api.getSchedule(id)
+        setStored(data)
         setForm({
           name: data.name || '',
           description: data.description || '',
@@ -59,7 +61,11 @@ const ScheduleEditPage = () => {
     setError(null)
     
     try {
+      // Send back the fields this form doesn't edit (exceptions, ordering, budget, ...)
+      // so the update keeps them
+      const { id: _id, created_at, updated_at, ...rest } = stored ?? ({} as Schedule)
       await api.updateSchedule(id || '', {
+        ...rest,
         name: form.name,
         description: form.description,
         timezone: form.timezone,
@@ -1,14 +1,18 @@
 import { useState, useEffect } from 'react'
 import { Clock, Plus } from 'lucide-react'
 import api from '../lib/api'
-import type { Schedule, Instance } from '../lib/api'
+import type { Schedule } from '../lib/api'
 import { ScheduleModal } from '../components/ScheduleModal'
 import { describeCron } from '../lib/cronUtils'
-import { matchInstance } from '../lib/filterUtils'
+
+// A schedule selects instances with its selectors or its expression; without either it
+// matches nothing
+const hasFilters = (schedule: Schedule) =>
+  (schedule.selectors && schedule.selectors.length > 0) || !!schedule.expression
 
 const SchedulesPage = () => {
   const [schedules, setSchedules] = useState<Schedule[]>([])
-  const [instances, setInstances] = useState<Instance[]>([])
+  const [matchedCounts, setMatchedCounts] = useState<Record<string, number>>({})
   const [loading, setLoading] = useState(true)
   const [error, setError] = useState<string | null>(null)
   const [isModalOpen, setIsModalOpen] = useState(false)
@@ -25,10 +29,7 @@ const SchedulesPage = () => {
           selectors: sched.selectors || []
         }))
         setSchedules(safeSchedules)
-
-        // Fetch instances for count calculation
-        const instancesData = await api.getInstances()
-        setInstances(Array.isArray(instancesData) ? instancesData : [])
+        fetchMatchedCounts(safeSchedules)
       } catch (err) {
         setError('Failed to load schedules')
         console.error(err)
@@ -39,13 +40,23 @@ const SchedulesPage = () => {
     fetchSchedules()
   }, [])
 
-  // Count matched instances for a schedule
-  const getMatchedCount = (schedule: Schedule): number => {
-    if (!schedule.selectors || schedule.selectors.length === 0) {
-      return 0;
-    }
-    return instances.filter(inst => matchInstance(inst, schedule.selectors, 'and')).length;
-  };
+  // Count matched instances with the server's filter preview, so the counts follow the
+  // scheduler's own selection (expressions, exclusions, in/exists/not matchers, ...)
+  const fetchMatchedCounts = async (schedules: Schedule[]) => {
+    const counts: Record<string, number> = {}
+    await Promise.all(schedules.filter(hasFilters).map(async sched => {
+      try {
+        const preview = await api.previewFilter(sched.selectors, sched.operator ?? 'or', {
+          expression: sched.expression,
+          exclusions: sched.exclusions,
+        })
+        counts[sched.id] = preview.matched_count
+      } catch (err) {
+        console.error(`Failed to count instances for schedule ${sched.name}:`, err)
+      }
+    }))
+    setMatchedCounts(counts)
+  }
 
   const handleToggle = async (id: string, enabled: boolean) => {
     try {
@@ -99,6 +110,7 @@ const SchedulesPage = () => {
       selectors: sched.selectors || []
     }))
     setSchedules(safeSchedules)
+    fetchMatchedCounts(safeSchedules)
     setIsModalOpen(false)
   }
 
@@ -169,9 +181,9 @@ const SchedulesPage = () => {
                    </td>
                    <td className="px-6 py-4 whitespace-nowrap">
                      <div className="flex items-center gap-2">
-                       {schedule.selectors && schedule.selectors.length > 0 ? (
+                       {hasFilters(schedule) ? (
                          <>
-                           <span className="text-sm font-medium text-white">{getMatchedCount(schedule)}</span>
+                           <span className="text-sm font-medium text-white">{matchedCounts[schedule.id] ?? '-'}</span>
                            <span className="text-xs text-slate-400">matched</span>
                          </>
                        ) : (